@@ -60,7 +60,7 @@ func setupPayments(log *zap.Logger, db satellite.DB) (*stripe.Service, error) {
 		return nil, err
 	}
 
-	priceOverrides, err := pc.UsagePriceOverrides.ToModels()
+	priceOverrides, err := pc.UsagePriceOverrides.ToModels(pc.UsagePrice)
 	if err != nil {
 		return nil, err
 	}
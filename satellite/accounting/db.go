@@ -295,6 +295,7 @@ type ProjectAccounting interface {
 	GetProjectTotal(ctx context.Context, projectID uuid.UUID, since, before time.Time) (*ProjectUsage, error)
 	// GetProjectTotalByPartner retrieves project usage for a given period categorized by partner name.
 	// Unpartnered usage or usage for a partner not present in partnerNames is mapped to the empty string.
+	// Partner names are matched case-insensitively and the keys are the names as given in partnerNames.
 	GetProjectTotalByPartner(ctx context.Context, projectID uuid.UUID, partnerNames []string, since, before time.Time) (usages map[string]ProjectUsage, err error)
 	// GetProjectObjectsSegments returns project objects and segments number.
 	GetProjectObjectsSegments(ctx context.Context, projectID uuid.UUID) (ProjectObjectsSegments, error)
@@ -184,7 +184,7 @@ func NewAdmin(log *zap.Logger, full *identity.FullIdentity, db DB, metabaseDB *m
 			return nil, errs.Combine(err, peer.Close())
 		}
 
-		priceOverrides, err := pc.UsagePriceOverrides.ToModels()
+		priceOverrides, err := pc.UsagePriceOverrides.ToModels(pc.UsagePrice)
 		if err != nil {
 			return nil, errs.Combine(err, peer.Close())
 		}
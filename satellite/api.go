@@ -544,7 +544,7 @@ func NewAPI(log *zap.Logger, full *identity.FullIdentity, db DB,
 			return nil, errs.Combine(err, peer.Close())
 		}
 
-		priceOverrides, err := pc.UsagePriceOverrides.ToModels()
+		priceOverrides, err := pc.UsagePriceOverrides.ToModels(pc.UsagePrice)
 		if err != nil {
 			return nil, errs.Combine(err, peer.Close())
 		}
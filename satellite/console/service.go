@@ -615,7 +615,7 @@ func (payment Payments) ProjectsCharges(ctx context.Context, since, before time.
 		return nil, Error.Wrap(err)
 	}
 
-	return payment.service.accounts.ProjectCharges(ctx, user.ID, since, before)
+	return payment.service.accounts.ProjectCharges(ctx, user.ID, since, before, nil)
 }
 
 // ListCreditCards returns a list of credit cards for a given payment account.
@@ -472,7 +472,7 @@ func New(log *zap.Logger, full *identity.FullIdentity, db DB,
 			return nil, errs.Combine(err, peer.Close())
 		}
 
-		priceOverrides, err := pc.UsagePriceOverrides.ToModels()
+		priceOverrides, err := pc.UsagePriceOverrides.ToModels(pc.UsagePrice)
 		if err != nil {
 			return nil, errs.Combine(err, peer.Close())
 		}
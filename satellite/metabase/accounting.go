@@ -11,6 +11,7 @@ import (
 	"cloud.google.com/go/spanner"
 	"golang.org/x/exp/slices"
 
+	"storj.io/common/uuid"
 	"storj.io/storj/shared/dbutil/spannerutil"
 	"storj.io/storj/shared/tagsql"
 )
@@ -157,3 +158,124 @@ func (s *SpannerAdapter) CollectBucketTallies(ctx context.Context, opts CollectB
 		)
 	})
 }
+
+// BucketObjectCounts contains the number of objects and versions in a bucket.
+type BucketObjectCounts struct {
+	// ObjectCount is the number of objects whose latest version is not a delete marker.
+	ObjectCount int64
+	// VersionCount is the number of committed versions, excluding delete markers.
+	VersionCount int64
+	// TotalEncryptedSize is the encrypted size of all committed versions.
+	TotalEncryptedSize int64
+}
+
+// CountObjectsPerBucket contains arguments necessary for counting objects in project buckets.
+type CountObjectsPerBucket struct {
+	ProjectID          uuid.UUID
+	AsOfSystemInterval time.Duration
+}
+
+// Verify verifies CountObjectsPerBucket request fields.
+func (opts *CountObjectsPerBucket) Verify() error {
+	if opts.ProjectID.IsZero() {
+		return ErrInvalidRequest.New("ProjectID missing")
+	}
+	return nil
+}
+
+// CountObjectsPerBucket returns the number of objects and versions per bucket in a project.
+// Pending and expired objects are not counted. Buckets without any objects are missing
+// from the result.
+func (db *DB) CountObjectsPerBucket(ctx context.Context, opts CountObjectsPerBucket) (result map[string]BucketObjectCounts, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return nil, err
+	}
+
+	return db.ChooseAdapter(opts.ProjectID).CountObjectsPerBucket(ctx, opts)
+}
+
+// CountObjectsPerBucket returns the number of objects and versions per bucket in a project.
+func (p *PostgresAdapter) CountObjectsPerBucket(ctx context.Context, opts CountObjectsPerBucket) (result map[string]BucketObjectCounts, err error) {
+	result = make(map[string]BucketObjectCounts)
+	err = withRows(p.db.QueryContext(ctx, `
+		SELECT
+			bucket_name,
+			SUM(CASE WHEN latest_version = latest_committed_version THEN 1 ELSE 0 END),
+			SUM(version_count), SUM(total_encrypted_size)
+		FROM (
+			SELECT
+				bucket_name,
+				MAX(version) AS latest_version,
+				MAX(CASE WHEN status IN `+statusesCommitted+` THEN version END) AS latest_committed_version,
+				SUM(CASE WHEN status IN `+statusesCommitted+` THEN 1 ELSE 0 END) AS version_count,
+				SUM(total_encrypted_size) AS total_encrypted_size
+			FROM objects
+			`+LimitedAsOfSystemTime(p.impl, time.Now(), time.Time{}, opts.AsOfSystemInterval)+`
+			WHERE
+				project_id = $1 AND
+				status <> `+statusPending+` AND
+				(expires_at IS NULL OR expires_at > now())
+			GROUP BY bucket_name, object_key
+		) AS per_object
+		GROUP BY bucket_name
+	`, opts.ProjectID))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			var bucketName string
+			var counts BucketObjectCounts
+			if err := rows.Scan(&bucketName, &counts.ObjectCount, &counts.VersionCount, &counts.TotalEncryptedSize); err != nil {
+				return Error.New("unable to scan object counts: %w", err)
+			}
+			result[bucketName] = counts
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to count objects: %w", err)
+	}
+	return result, nil
+}
+
+// CountObjectsPerBucket returns the number of objects and versions per bucket in a project.
+func (s *SpannerAdapter) CountObjectsPerBucket(ctx context.Context, opts CountObjectsPerBucket) (result map[string]BucketObjectCounts, err error) {
+	result = make(map[string]BucketObjectCounts)
+	err = s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				bucket_name,
+				SUM(CASE WHEN latest_version = latest_committed_version THEN 1 ELSE 0 END),
+				SUM(version_count), SUM(total_encrypted_size)
+			FROM (
+				SELECT
+					bucket_name,
+					MAX(version) AS latest_version,
+					MAX(CASE WHEN status IN ` + statusesCommitted + ` THEN version END) AS latest_committed_version,
+					SUM(CASE WHEN status IN ` + statusesCommitted + ` THEN 1 ELSE 0 END) AS version_count,
+					SUM(total_encrypted_size) AS total_encrypted_size
+				FROM objects
+				WHERE
+					project_id = @project_id AND
+					status <> ` + statusPending + ` AND
+					(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+				GROUP BY bucket_name, object_key
+			)
+			GROUP BY bucket_name
+		`,
+		Params: map[string]any{
+			"project_id": opts.ProjectID,
+		},
+	}).Do(func(row *spanner.Row) error {
+		var bucketName string
+		var counts BucketObjectCounts
+		if err := row.Columns(&bucketName, &counts.ObjectCount, &counts.VersionCount, &counts.TotalEncryptedSize); err != nil {
+			return Error.New("unable to scan object counts: %w", err)
+		}
+		result[bucketName] = counts
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to count objects: %w", err)
+	}
+	return result, nil
+}
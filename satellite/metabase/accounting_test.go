@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
 	"storj.io/common/testcontext"
 	"storj.io/common/testrand"
 	"storj.io/common/uuid"
@@ -277,6 +279,86 @@ func TestCollectBucketTallies(t *testing.T) {
 	})
 }
 
+func TestCountObjectsPerBucket(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		t.Run("missing project", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.CountObjectsPerBucket{
+				Opts:     metabase.CountObjectsPerBucket{},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "ProjectID missing",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("empty project", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.CountObjectsPerBucket{
+				Opts: metabase.CountObjectsPerBucket{
+					ProjectID: testrand.UUID(),
+				},
+				Result: nil,
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("versions, delete markers, pending and expired", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID := testrand.UUID()
+
+			stream := func(bucketName string, key metabase.ObjectKey) metabase.ObjectStream {
+				obj := metabasetest.RandObjectStream()
+				obj.ProjectID = projectID
+				obj.BucketName = bucketName
+				obj.ObjectKey = key
+				return obj
+			}
+
+			// bucket-a: "a" has two versions, "b" is deleted with a marker,
+			// "c" is pending only, "d" is expired.
+			a1 := metabasetest.CreateObjectVersioned(ctx, t, db, stream("bucket-a", "a"), 1)
+			aSecond := stream("bucket-a", "a")
+			aSecond.Version = a1.Version + 1
+			a2 := metabasetest.CreateObjectVersioned(ctx, t, db, aSecond, 2)
+
+			b := metabasetest.CreateObjectVersioned(ctx, t, db, stream("bucket-a", "b"), 1)
+			_, err := db.DeleteObjectLastCommitted(ctx, metabase.DeleteObjectLastCommitted{
+				ObjectLocation: b.Location(),
+				Versioned:      true,
+			})
+			require.NoError(t, err)
+
+			metabasetest.CreatePendingObject(ctx, t, db, stream("bucket-a", "c"), 1)
+			metabasetest.CreateExpiredObject(ctx, t, db, stream("bucket-a", "d"), 1, time.Now().Add(-time.Hour))
+
+			// bucket-b: a single unversioned object.
+			e := metabasetest.CreateObject(ctx, t, db, stream("bucket-b", "e"), 1)
+
+			// other projects are not counted.
+			metabasetest.CreateObject(ctx, t, db, metabasetest.RandObjectStream(), 1)
+
+			metabasetest.CountObjectsPerBucket{
+				Opts: metabase.CountObjectsPerBucket{
+					ProjectID: projectID,
+				},
+				Result: map[string]metabase.BucketObjectCounts{
+					"bucket-a": {
+						ObjectCount:        1,
+						VersionCount:       3,
+						TotalEncryptedSize: a1.TotalEncryptedSize + a2.TotalEncryptedSize + b.TotalEncryptedSize,
+					},
+					"bucket-b": {
+						ObjectCount:        1,
+						VersionCount:       1,
+						TotalEncryptedSize: e.TotalEncryptedSize,
+					},
+				},
+			}.Check(ctx, t, db)
+		})
+	})
+}
+
 func bucketTallyFromRaw(m metabase.RawObject) metabase.BucketTally {
 	return metabase.BucketTally{
 		BucketLocation: metabase.BucketLocation{
@@ -10,6 +10,7 @@ import (
 	"cloud.google.com/go/spanner"
 	"go.uber.org/zap"
 
+	"storj.io/common/storj"
 	"storj.io/common/uuid"
 	"storj.io/storj/shared/dbutil"
 	"storj.io/storj/shared/tagsql"
@@ -21,9 +22,14 @@ type Adapter interface {
 	Name() string
 	Now(ctx context.Context) (time.Time, error)
 	Ping(ctx context.Context) error
+	PoolStats() PoolStats
 
 	BeginObjectNextVersion(context.Context, BeginObjectNextVersion, *Object) error
 	GetObjectLastCommitted(ctx context.Context, opts GetObjectLastCommitted) (Object, error)
+	GetObjectsLastCommitted(ctx context.Context, opts GetObjectsLastCommitted) ([]Object, error)
+	GetObjectsExactVersion(ctx context.Context, opts GetObjectsExactVersion) ([]Object, error)
+	HeadObject(ctx context.Context, location ObjectLocation) (HeadObjectResult, error)
+	GetObjectByStreamID(ctx context.Context, streamID uuid.UUID) (Object, error)
 	IterateLoopSegments(ctx context.Context, aliasCache *NodeAliasCache, opts IterateLoopSegments, fn func(context.Context, LoopSegmentsIterator) error) error
 	PendingObjectExists(ctx context.Context, opts BeginSegment) (exists bool, err error)
 	CommitPendingObjectSegment(ctx context.Context, opts CommitSegment, aliasPieces AliasPieces) error
@@ -33,23 +39,41 @@ type Adapter interface {
 	GetTableStats(ctx context.Context, opts GetTableStats) (result TableStats, err error)
 	UpdateTableStats(ctx context.Context) error
 	BucketEmpty(ctx context.Context, opts BucketEmpty) (empty bool, err error)
+	BucketHasMultipleVersions(ctx context.Context, projectID uuid.UUID, bucketName string) (bool, error)
 
 	WithTx(ctx context.Context, f func(context.Context, TransactionAdapter) error) error
 
 	CollectBucketTallies(ctx context.Context, opts CollectBucketTallies) (result []BucketTally, err error)
+	CountObjectsPerBucket(ctx context.Context, opts CountObjectsPerBucket) (result map[string]BucketObjectCounts, err error)
+	VerifyObjectTotals(ctx context.Context, opts VerifyObjectTotals) (totals ObjectTotals, err error)
+	ExpiringUsage(ctx context.Context, projectID uuid.UUID, before time.Time) (usage ExpiringUsage, err error)
+	CurrentProjectStorage(ctx context.Context, projectID uuid.UUID) (storage ProjectStorage, err error)
+	PendingObjectAgeStats(ctx context.Context, projectID uuid.UUID, now time.Time) (stats PendingObjectAgeStats, err error)
+	ListObjectSegmentCounts(ctx context.Context, opts ListObjectSegmentCountMismatches) (counts []ObjectSegmentCount, err error)
+	ListExpiredRetentionObjects(ctx context.Context, opts ListExpiredRetentionObjects) (objects []ExpiredRetentionObject, err error)
+	FindDuplicateStreamIDs(ctx context.Context, opts FindDuplicateStreamIDs) (objects []ObjectStream, err error)
+	ClearLegalHold(ctx context.Context, opts ClearLegalHold) (retention Retention, err error)
+	GetObjectRetention(ctx context.Context, loc ObjectLocation, version Version) (retention Retention, legalHold bool, err error)
+	SetObjectTags(ctx context.Context, opts SetObjectTags) error
+	GetObjectTags(ctx context.Context, opts GetObjectTags) (tags map[string]string, err error)
+	ReencryptObjectParameters(ctx context.Context, obj ObjectStream, encryption storj.EncryptionParameters) (Object, error)
 
 	GetSegmentByPosition(ctx context.Context, opts GetSegmentByPosition) (segment Segment, aliasPieces AliasPieces, err error)
 	GetObjectExactVersion(ctx context.Context, opts GetObjectExactVersion) (_ Object, err error)
 	GetSegmentPositionsAndKeys(ctx context.Context, streamID uuid.UUID) (keysNonces []EncryptedKeyAndNonce, err error)
 	GetLatestObjectLastSegment(ctx context.Context, opts GetLatestObjectLastSegment) (segment Segment, aliasPieces AliasPieces, err error)
 
-	ListObjects(ctx context.Context, opts ListObjects) (result ListObjectsResult, err error)
+	IterateObjects(ctx context.Context, opts ListObjects, fn func(ObjectEntry) error) (err error)
+	ExplainListObjects(ctx context.Context, opts ListObjects) (_ string, err error)
 	ListSegments(ctx context.Context, opts ListSegments, aliasCache *NodeAliasCache) (result ListSegmentsResult, err error)
+	ListProjectSegments(ctx context.Context, opts IterateProjectSegments, aliasCache *NodeAliasCache) (segments []Segment, err error)
 	ListStreamPositions(ctx context.Context, opts ListStreamPositions) (result ListStreamPositionsResult, err error)
 	ListVerifySegments(ctx context.Context, opts ListVerifySegments) (segments []VerifySegment, err error)
+	ListOrphanedSegmentStreams(ctx context.Context, opts ListOrphanedSegmentStreams) (streams []segmentStream, err error)
 	ListBucketsStreamIDs(ctx context.Context, opts ListBucketsStreamIDs, bucketNamesBytes [][]byte, projectIDs []uuid.UUID) (result ListBucketsStreamIDsResult, err error)
 
 	UpdateSegmentPieces(ctx context.Context, opts UpdateSegmentPieces, oldPieces, newPieces AliasPieces) (resultPieces AliasPieces, err error)
+	UpdateSegmentRedundancy(ctx context.Context, opts UpdateSegmentRedundancy, pieces AliasPieces) (resultPieces AliasPieces, err error)
 	UpdateObjectLastCommittedMetadata(ctx context.Context, opts UpdateObjectLastCommittedMetadata) (affected int64, err error)
 
 	DeleteObjectExactVersion(ctx context.Context, opts DeleteObjectExactVersion) (result DeleteObjectResult, err error)
@@ -118,7 +142,13 @@ type TransactionAdapter interface {
 	commitObjectWithSegmentsTransactionAdapter
 	copyObjectTransactionAdapter
 	moveObjectTransactionAdapter
+	reopenObjectTransactionAdapter
+	restoreObjectVersionTransactionAdapter
+	setExpirationTransactionAdapter
+	transitionToExpiryTransactionAdapter
+	setObjectsRetentionTransactionAdapter
 	deleteTransactionAdapter
+	deleteExpiredProjectObjectsTransactionAdapter
 }
 
 type postgresTransactionAdapter struct {
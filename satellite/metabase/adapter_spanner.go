@@ -20,22 +20,26 @@ type SpannerConfig struct {
 type SpannerAdapter struct {
 	log    *zap.Logger
 	client *spanner.Client
+
+	sessionPool spanner.SessionPoolConfig
 }
 
 // NewSpannerAdapter creates a new Spanner adapter.
 func NewSpannerAdapter(ctx context.Context, cfg SpannerConfig, log *zap.Logger) (*SpannerAdapter, error) {
 	log = log.Named("spanner")
+	sessionPool := spanner.DefaultSessionPoolConfig
 	client, err := spanner.NewClientWithConfig(ctx, cfg.Database,
 		spanner.ClientConfig{
 			Logger:               zap.NewStdLog(log.Named("stdlog")),
-			SessionPoolConfig:    spanner.DefaultSessionPoolConfig,
+			SessionPoolConfig:    sessionPool,
 			DisableRouteToLeader: false})
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
 	return &SpannerAdapter{
-		client: client,
-		log:    log,
+		client:      client,
+		log:         log,
+		sessionPool: sessionPool,
 	}, nil
 }
 
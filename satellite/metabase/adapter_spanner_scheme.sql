@@ -16,6 +16,7 @@ CREATE TABLE IF NOT EXISTS segments
     inline_data         BYTES(MAX),
     remote_alias_pieces BYTES(MAX),
     placement           INT64,
+    is_manifest         BOOL NOT NULL DEFAULT (FALSE),
     ) PRIMARY KEY(stream_id, position);
 
 CREATE TABLE IF NOT EXISTS objects
@@ -37,8 +38,13 @@ CREATE TABLE IF NOT EXISTS objects
     fixed_segment_size               INT64     NOT NULL DEFAULT (0),
     encryption                       INT64     NOT NULL DEFAULT (0),
     zombie_deletion_deadline         TIMESTAMP,
+    -- the lowest two bits contain the retention mode, where 0=none and 1=compliance,
+    -- and the bit with value 4 is set when the object version is under legal hold.
     retention_mode                   INT64,
     retain_until                     TIMESTAMP,
+    system_labels                    JSON,
+    computed_etag                    BYTES(MAX),
+    object_tags                      JSON,
 ) PRIMARY KEY (project_id, bucket_name, object_key, version);
 
 CREATE TABLE IF NOT EXISTS node_aliases
@@ -4,6 +4,7 @@
 package metabase
 
 import (
+	"crypto/sha256"
 	"database/sql/driver"
 	"encoding/base64"
 	"encoding/binary"
@@ -199,6 +200,16 @@ func (aliases AliasPieces) EncodeSpanner() (any, error) {
 	return aliases.Value()
 }
 
+// Hash returns sha256 hash of the encoded alias pieces.
+func (aliases AliasPieces) Hash() ([]byte, error) {
+	data, err := aliases.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	return hash[:], nil
+}
+
 // EqualAliasPieces compares whether xs and ys are equal.
 func EqualAliasPieces(xs, ys AliasPieces) bool {
 	if len(xs) != len(ys) {
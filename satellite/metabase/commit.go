@@ -4,21 +4,28 @@
 package metabase
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/spanner"
 	pgxerrcode "github.com/jackc/pgerrcode"
+	"github.com/spacemonkeygo/monkit/v3"
 	"github.com/zeebo/errs"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 
 	"storj.io/common/memory"
 	"storj.io/common/storj"
 	"storj.io/common/uuid"
 	"storj.io/storj/shared/dbutil/pgutil/pgerrcode"
+	"storj.io/storj/shared/dbutil/spannerutil"
 	"storj.io/storj/shared/dbutil/txutil"
 	"storj.io/storj/shared/tagsql"
 )
@@ -26,6 +33,8 @@ import (
 // ValidatePlainSize determines whether we disable PlainSize validation for old uplinks.
 const ValidatePlainSize = false
 
+// defaultZombieDeletionPeriod is used when the project doesn't have
+// an override in Config.ZombieDeletionPeriods.
 const defaultZombieDeletionPeriod = 24 * time.Hour
 
 var (
@@ -37,14 +46,46 @@ var (
 	ErrFailedPrecondition = errs.Class("metabase: failed precondition")
 	// ErrConflict is used to indicate conflict with the request.
 	ErrConflict = errs.Class("metabase: conflict")
+	// ErrEncryptionMissing is used to indicate that the encryption parameters were
+	// neither set when beginning the object nor when committing it.
+	ErrEncryptionMissing = errs.Class("metabase: encryption missing")
+	// ErrObjectLock is used to indicate that the object version is protected by
+	// retention or legal hold.
+	ErrObjectLock = errs.Class("metabase: object lock")
 )
 
+// ErrPartTooSmall is returned, wrapped by ErrFailedPrecondition, when a part
+// other than the last one is smaller than the minimum part size.
+type ErrPartTooSmall struct {
+	Part    uint32
+	Size    memory.Size
+	MinSize memory.Size
+}
+
+// Error implements the error interface.
+func (err *ErrPartTooSmall) Error() string {
+	return fmt.Sprintf("size of part number %d is below minimum threshold, got: %s, min: %s", err.Part, err.Size, err.MinSize)
+}
+
+// ErrTooManyParts is returned, wrapped by ErrFailedPrecondition, when an
+// object has more parts than allowed.
+type ErrTooManyParts struct {
+	Parts    int
+	MaxParts int
+}
+
+// Error implements the error interface.
+func (err *ErrTooManyParts) Error() string {
+	return fmt.Sprintf("exceeded maximum number of parts: %d", err.MaxParts)
+}
+
 type commitObjectTransactionAdapter interface {
 	updateSegmentOffsets(ctx context.Context, streamID uuid.UUID, updates []segmentToCommit) (err error)
 	finalizeObjectCommit(ctx context.Context, opts CommitObject, nextStatus ObjectStatus, nextVersion Version, finalSegments []segmentInfoForCommit, totalPlainSize int64, totalEncryptedSize int64, fixedSegmentSize int32, object *Object) error
 	finalizeInlineObjectCommit(ctx context.Context, object *Object, segment *Segment) (err error)
 
 	precommitTransactionAdapter
+	pruneVersionsTransactionAdapter
 }
 
 // BeginObjectNextVersion contains arguments necessary for starting an object upload.
@@ -59,6 +100,16 @@ type BeginObjectNextVersion struct {
 	EncryptedMetadataEncryptedKey []byte // optional
 
 	Encryption storj.EncryptionParameters
+
+	// Retention and LegalHold are the Object Lock configuration of the pending object.
+	// When neither is set, the bucket defaults from Config.BucketLockDefaults are used.
+	Retention Retention // optional
+	LegalHold bool
+}
+
+// lockConfigured returns whether the pending object has an Object Lock configuration.
+func (opts *BeginObjectNextVersion) lockConfigured() bool {
+	return opts.Retention.Enabled() || opts.LegalHold
 }
 
 // Verify verifies get object request fields.
@@ -86,9 +137,25 @@ func (db *DB) BeginObjectNextVersion(ctx context.Context, opts BeginObjectNextVe
 	if err := opts.Verify(); err != nil {
 		return Object{}, err
 	}
+	if err := db.verifyObjectKeyCharacters(opts.ObjectStream); err != nil {
+		return Object{}, err
+	}
+
+	if !opts.lockConfigured() && db.config.BucketLockDefaults != nil {
+		opts.Retention, opts.LegalHold, err = db.config.BucketLockDefaults(ctx, opts.ProjectID, opts.BucketName)
+		if err != nil {
+			return Object{}, Error.New("unable to resolve bucket lock defaults: %w", err)
+		}
+	}
+	if err := opts.Retention.Verify(db.nowFn()); err != nil {
+		return Object{}, err
+	}
+	if opts.ExpiresAt != nil && opts.lockConfigured() {
+		return Object{}, ErrInvalidRequest.New("ExpiresAt must not be set if Retention or LegalHold is set")
+	}
 
 	if opts.ZombieDeletionDeadline == nil {
-		deadline := time.Now().Add(defaultZombieDeletionPeriod)
+		deadline := time.Now().Add(db.config.zombieDeletionPeriod(opts.ProjectID))
 		opts.ZombieDeletionDeadline = &deadline
 	}
 
@@ -102,6 +169,8 @@ func (db *DB) BeginObjectNextVersion(ctx context.Context, opts BeginObjectNextVe
 		ExpiresAt:              opts.ExpiresAt,
 		Encryption:             opts.Encryption,
 		ZombieDeletionDeadline: opts.ZombieDeletionDeadline,
+		Retention:              opts.Retention,
+		LegalHold:              opts.LegalHold,
 	}
 
 	err = db.ChooseAdapter(opts.ProjectID).BeginObjectNextVersion(ctx, opts, &object)
@@ -121,7 +190,8 @@ func (p *PostgresAdapter) BeginObjectNextVersion(ctx context.Context, opts Begin
 				project_id, bucket_name, object_key, version, stream_id,
 				expires_at, encryption,
 				zombie_deletion_deadline,
-				encrypted_metadata, encrypted_metadata_nonce, encrypted_metadata_encrypted_key
+				encrypted_metadata, encrypted_metadata_nonce, encrypted_metadata_encrypted_key,
+				retention_mode, retain_until
 			) VALUES (
 				$1, $2, $3,
 					coalesce((
@@ -133,12 +203,15 @@ func (p *PostgresAdapter) BeginObjectNextVersion(ctx context.Context, opts Begin
 					), 1),
 				$4, $5, $6,
 				$7,
-				$8, $9, $10)
+				$8, $9, $10,
+				$11, $12)
 			RETURNING status, version, created_at
 		`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.StreamID,
 		opts.ExpiresAt, encryptionParameters{&opts.Encryption},
 		opts.ZombieDeletionDeadline,
 		opts.EncryptedMetadata, opts.EncryptedMetadataNonce, opts.EncryptedMetadataEncryptedKey,
+		lockModeWrapper{retentionMode: &opts.Retention.Mode, legalHold: &opts.LegalHold},
+		timeWrapper{&opts.Retention.RetainUntil},
 	).Scan(&object.Status, &object.Version, &object.CreatedAt)
 }
 
@@ -155,7 +228,8 @@ func (s *SpannerAdapter) BeginObjectNextVersion(ctx context.Context, opts BeginO
 					project_id, bucket_name, object_key, version, stream_id,
 					expires_at, encryption,
 					zombie_deletion_deadline,
-					encrypted_metadata, encrypted_metadata_nonce, encrypted_metadata_encrypted_key)
+					encrypted_metadata, encrypted_metadata_nonce, encrypted_metadata_encrypted_key,
+					retention_mode, retain_until)
 				  VALUES(
                   	@project_id, @bucket_name, @object_key,
 					coalesce(
@@ -167,7 +241,8 @@ func (s *SpannerAdapter) BeginObjectNextVersion(ctx context.Context, opts BeginO
 					,1),
 					@stream_id, @expires_at,
 					@encryption, @zombie_deletion_deadline,
-					@encrypted_metadata, @encrypted_metadata_nonce, @encrypted_metadata_encrypted_key)
+					@encrypted_metadata, @encrypted_metadata_nonce, @encrypted_metadata_encrypted_key,
+					@retention_mode, @retain_until)
                   THEN RETURN status,version,created_at`,
 			Params: map[string]interface{}{
 				"project_id":                       opts.ProjectID.Bytes(),
@@ -180,6 +255,8 @@ func (s *SpannerAdapter) BeginObjectNextVersion(ctx context.Context, opts BeginO
 				"encrypted_metadata":               opts.EncryptedMetadata,
 				"encrypted_metadata_nonce":         opts.EncryptedMetadataNonce,
 				"encrypted_metadata_encrypted_key": opts.EncryptedMetadataEncryptedKey,
+				"retention_mode":                   lockModeWrapper{retentionMode: &opts.Retention.Mode, legalHold: &opts.LegalHold},
+				"retain_until":                     timeWrapper{&opts.Retention.RetainUntil},
 			},
 		}).Do(func(row *spanner.Row) error {
 			return Error.Wrap(row.Columns(&object.Status, &object.Version, &object.CreatedAt))
@@ -227,9 +304,12 @@ func (db *DB) TestingBeginObjectExactVersion(ctx context.Context, opts BeginObje
 	if err := opts.Verify(); err != nil {
 		return Object{}, err
 	}
+	if err := db.verifyObjectKeyCharacters(opts.ObjectStream); err != nil {
+		return Object{}, err
+	}
 
 	if opts.ZombieDeletionDeadline == nil {
-		deadline := time.Now().Add(defaultZombieDeletionPeriod)
+		deadline := time.Now().Add(db.config.zombieDeletionPeriod(opts.ProjectID))
 		opts.ZombieDeletionDeadline = &deadline
 	}
 
@@ -432,6 +512,13 @@ type CommitSegment struct {
 	Position    SegmentPosition
 	RootPieceID storj.PieceID
 
+	// ExpiresAt is usually the same as the expiration of the object, but it may
+	// be earlier, e.g. for tiered expiry. When the object has an expiration, ExpiresAt
+	// must not be later than it. Nil means that the segment expires with the object.
+	//
+	// The pieces of a segment may be deleted by the storage nodes after ExpiresAt.
+	// Readers should treat such segments as unavailable: the object is still listed
+	// until it expires, however downloading the ranges of expired segments fails.
 	ExpiresAt *time.Time
 
 	EncryptedKeyNonce []byte
@@ -449,9 +536,51 @@ type CommitSegment struct {
 
 	Placement storj.PlacementConstraint
 
+	// IsManifest marks the segment, which contains the index of the object, so that
+	// range readers can find it without scanning the segments. At most one segment
+	// of an object may be marked, which is enforced by CommitObject.
+	IsManifest bool
+
 	mode string
 }
 
+// verifySize checks that the encrypted size and the pieces are consistent with the redundancy scheme.
+func (opts *CommitSegment) verifySize() error {
+	rs := opts.Redundancy
+	switch {
+	case rs.ShareSize <= 0:
+		return ErrInvalidRequest.New("Redundancy share size is not positive: %d", rs.ShareSize)
+	case rs.RequiredShares <= 0 ||
+		rs.RequiredShares > rs.RepairShares ||
+		rs.RepairShares > rs.OptimalShares ||
+		rs.OptimalShares > rs.TotalShares:
+		return ErrInvalidRequest.New("Redundancy shares are inconsistent: %d/%d/%d/%d",
+			rs.RequiredShares, rs.RepairShares, rs.OptimalShares, rs.TotalShares)
+	case len(opts.Pieces) > int(rs.TotalShares):
+		return ErrInvalidRequest.New("number of pieces %d exceeds redundancy total shares %d", len(opts.Pieces), rs.TotalShares)
+	case int(opts.Pieces[len(opts.Pieces)-1].Number) >= int(rs.TotalShares):
+		// pieces are verified to be ordered.
+		return ErrInvalidRequest.New("piece number %d exceeds redundancy total shares %d", opts.Pieces[len(opts.Pieces)-1].Number, rs.TotalShares)
+	case opts.EncryptedSize < opts.PlainSize:
+		// encryption only ever adds overhead.
+		return ErrInvalidRequest.New("EncryptedSize %d is smaller than PlainSize %d", opts.EncryptedSize, opts.PlainSize)
+	}
+
+	// the encrypted data is erasure coded in stripes of RequiredShares*ShareSize bytes,
+	// each piece stores one share of every stripe. The encryption overhead is much
+	// smaller than the data, hence anything requiring more than twice the stripes of
+	// the plain data, plus one for padding, is a gross mismatch. Legacy uplinks
+	// may not send the plain size, so there is nothing to compare with.
+	stripeSize := int64(rs.RequiredShares) * int64(rs.ShareSize)
+	plainStripes := (int64(opts.PlainSize) + stripeSize - 1) / stripeSize
+	encryptedStripes := (int64(opts.EncryptedSize) + stripeSize - 1) / stripeSize
+	if maxStripes := 2*plainStripes + 1; opts.PlainSize > 0 && encryptedStripes > maxStripes {
+		return ErrInvalidRequest.New("EncryptedSize %d requires %d stripes of %d bytes, expected at most %d for PlainSize %d",
+			opts.EncryptedSize, encryptedStripes, stripeSize, maxStripes, opts.PlainSize)
+	}
+	return nil
+}
+
 // CommitSegment commits segment to the database.
 func (db *DB) CommitSegment(ctx context.Context, opts CommitSegment) (err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -459,6 +588,9 @@ func (db *DB) CommitSegment(ctx context.Context, opts CommitSegment) (err error)
 	if err := opts.ObjectStream.Verify(); err != nil {
 		return err
 	}
+	if err := db.verifyObjectKeyCharacters(opts.ObjectStream); err != nil {
+		return err
+	}
 
 	if err := opts.Pieces.Verify(); err != nil {
 		return err
@@ -485,6 +617,12 @@ func (db *DB) CommitSegment(ctx context.Context, opts CommitSegment) (err error)
 		return ErrInvalidRequest.New("number of pieces is less than redundancy optimal shares value")
 	}
 
+	if db.config.ValidateSegmentSize {
+		if err := opts.verifySize(); err != nil {
+			return err
+		}
+	}
+
 	aliasPieces, err := db.aliasCache.EnsurePiecesToAliases(ctx, opts.Pieces)
 	if err != nil {
 		return Error.New("unable to convert pieces to aliases: %w", err)
@@ -493,7 +631,7 @@ func (db *DB) CommitSegment(ctx context.Context, opts CommitSegment) (err error)
 	opts.mode = db.config.TestingCommitSegmentMode
 	err = db.ChooseAdapter(opts.ProjectID).CommitPendingObjectSegment(ctx, opts, aliasPieces)
 	if err != nil {
-		if ErrPendingObjectMissing.Has(err) {
+		if ErrPendingObjectMissing.Has(err) || ErrInvalidRequest.Has(err) {
 			return err
 		}
 		return Error.New("unable to insert segment: %w", err)
@@ -517,19 +655,20 @@ func (p *PostgresAdapter) CommitPendingObjectSegment(ctx context.Context, opts C
 			encrypted_size, plain_offset, plain_size, encrypted_etag,
 			redundancy,
 			remote_alias_pieces,
-			placement
+			placement, is_manifest
 		) VALUES (
 			(
 				SELECT stream_id
 				FROM objects
 				WHERE (project_id, bucket_name, object_key, version, stream_id) = ($12, $13, $14, $15, $16) AND
-					status = `+statusPending+`
+					status = `+statusPending+` AND
+					(expires_at IS NULL OR $2::TIMESTAMPTZ IS NULL OR $2::TIMESTAMPTZ <= expires_at)
 			), $1, $2,
 			$3, $4, $5,
 			$6, $7, $8, $9,
 			$10,
 			$11,
-			$17
+			$17, $18
 		)
 		ON CONFLICT(stream_id, position)
 		DO UPDATE SET
@@ -538,23 +677,53 @@ func (p *PostgresAdapter) CommitPendingObjectSegment(ctx context.Context, opts C
 			encrypted_size = $6, plain_offset = $7, plain_size = $8, encrypted_etag = $9,
 			redundancy = $10,
 			remote_alias_pieces = $11,
-			placement = $17
+			placement = $17, is_manifest = $18
 		`, opts.Position, opts.ExpiresAt,
 		opts.RootPieceID, opts.EncryptedKeyNonce, opts.EncryptedKey,
 		opts.EncryptedSize, opts.PlainOffset, opts.PlainSize, opts.EncryptedETag,
 		redundancyScheme{&opts.Redundancy},
 		aliasPieces,
 		opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, opts.StreamID,
-		opts.Placement,
+		opts.Placement, opts.IsManifest,
 	)
 	if err != nil {
 		if code := pgerrcode.FromError(err); code == pgxerrcode.NotNullViolation {
-			return ErrPendingObjectMissing.New("")
+			return p.commitSegmentFailure(ctx, opts)
 		}
 	}
 	return err
 }
 
+// commitSegmentFailure returns why the pending object of the segment wasn't found.
+// It's only called when committing the segment failed, so it's not on the usual path.
+func (p *PostgresAdapter) commitSegmentFailure(ctx context.Context, opts CommitSegment) error {
+	var objectExpiresAt *time.Time
+	err := p.db.QueryRowContext(ctx, `
+		SELECT expires_at
+		FROM objects
+		WHERE (project_id, bucket_name, object_key, version, stream_id) = ($1, $2, $3, $4, $5) AND
+			status = `+statusPending+`
+	`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, opts.StreamID).Scan(&objectExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPendingObjectMissing.New("")
+		}
+		return Error.Wrap(err)
+	}
+	if err := verifySegmentExpiration(opts.ExpiresAt, objectExpiresAt); err != nil {
+		return err
+	}
+	return ErrPendingObjectMissing.New("")
+}
+
+// verifySegmentExpiration checks that the segment doesn't expire after the object.
+func verifySegmentExpiration(segmentExpiresAt, objectExpiresAt *time.Time) error {
+	if segmentExpiresAt != nil && objectExpiresAt != nil && segmentExpiresAt.After(*objectExpiresAt) {
+		return ErrInvalidRequest.New("segment ExpiresAt %s is later than object ExpiresAt %s", segmentExpiresAt.UTC(), objectExpiresAt.UTC())
+	}
+	return nil
+}
+
 // CommitPendingObjectSegment commits segment to the database.
 func (p *CockroachAdapter) CommitPendingObjectSegment(ctx context.Context, opts CommitSegment, aliasPieces AliasPieces) (err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -563,7 +732,7 @@ func (p *CockroachAdapter) CommitPendingObjectSegment(ctx context.Context, opts
 	case commitSegmentModeTransaction:
 		err = txutil.WithTx(ctx, p.db, nil, func(ctx context.Context, tx tagsql.Tx) error {
 			rows, err := tx.QueryContext(ctx, `
-				SELECT 1
+				SELECT expires_at
 				FROM objects
 				WHERE (project_id, bucket_name, object_key, version, stream_id) = ($1, $2, $3, $4, $5)
 				AND status = `+statusPending+`
@@ -572,7 +741,13 @@ func (p *CockroachAdapter) CommitPendingObjectSegment(ctx context.Context, opts
 				return errs.Wrap(err)
 			}
 
+			var objectExpiresAt *time.Time
 			pendingObjectFound := rows.Next()
+			if pendingObjectFound {
+				if err := rows.Scan(&objectExpiresAt); err != nil {
+					return errs.Combine(errs.Wrap(err), rows.Close())
+				}
+			}
 			if err := errs.Combine(rows.Err(), rows.Close()); err != nil {
 				return errs.Wrap(err)
 			}
@@ -580,6 +755,9 @@ func (p *CockroachAdapter) CommitPendingObjectSegment(ctx context.Context, opts
 			if !pendingObjectFound {
 				return ErrPendingObjectMissing.New("")
 			}
+			if err := verifySegmentExpiration(opts.ExpiresAt, objectExpiresAt); err != nil {
+				return err
+			}
 
 			_, err = tx.ExecContext(ctx, `
 				UPSERT INTO segments (
@@ -588,19 +766,19 @@ func (p *CockroachAdapter) CommitPendingObjectSegment(ctx context.Context, opts
 					encrypted_size, plain_offset, plain_size, encrypted_etag,
 					redundancy,
 					remote_alias_pieces,
-					placement
+					placement, is_manifest
 				) VALUES (
 					$1, $2,
 					$3, $4, $5,
 					$6, $7, $8, $9,
 					$10, $11, $12,
-					$13
+					$13, $14
 				)`, opts.StreamID, opts.Position, opts.ExpiresAt,
 				opts.RootPieceID, opts.EncryptedKeyNonce, opts.EncryptedKey,
 				opts.EncryptedSize, opts.PlainOffset, opts.PlainSize, opts.EncryptedETag,
 				redundancyScheme{&opts.Redundancy},
 				aliasPieces,
-				opts.Placement,
+				opts.Placement, opts.IsManifest,
 			)
 			return errs.Wrap(err)
 		})
@@ -612,19 +790,19 @@ func (p *CockroachAdapter) CommitPendingObjectSegment(ctx context.Context, opts
 				encrypted_size, plain_offset, plain_size, encrypted_etag,
 				redundancy,
 				remote_alias_pieces,
-				placement
+				placement, is_manifest
 			) VALUES (
 				$1, $2,
 				$3, $4, $5,
 				$6, $7, $8, $9,
 				$10, $11, $12,
-				$13
+				$13, $14
 			)`, opts.StreamID, opts.Position, opts.ExpiresAt,
 			opts.RootPieceID, opts.EncryptedKeyNonce, opts.EncryptedKey,
 			opts.EncryptedSize, opts.PlainOffset, opts.PlainSize, opts.EncryptedETag,
 			redundancyScheme{&opts.Redundancy},
 			aliasPieces,
-			opts.Placement,
+			opts.Placement, opts.IsManifest,
 		)
 	default:
 		// Verify that object exists and is partial.
@@ -635,30 +813,31 @@ func (p *CockroachAdapter) CommitPendingObjectSegment(ctx context.Context, opts
 				encrypted_size, plain_offset, plain_size, encrypted_etag,
 				redundancy,
 				remote_alias_pieces,
-				placement
+				placement, is_manifest
 			) VALUES (
 				(
 					SELECT stream_id
 					FROM objects
 					WHERE (project_id, bucket_name, object_key, version, stream_id) = ($12, $13, $14, $15, $16) AND
-						status = `+statusPending+`
+						status = `+statusPending+` AND
+						(expires_at IS NULL OR $2::TIMESTAMPTZ IS NULL OR $2::TIMESTAMPTZ <= expires_at)
 				), $1, $2,
 				$3, $4, $5,
 				$6, $7, $8, $9,
 				$10,
 				$11,
-				$17
+				$17, $18
 			)`, opts.Position, opts.ExpiresAt,
 			opts.RootPieceID, opts.EncryptedKeyNonce, opts.EncryptedKey,
 			opts.EncryptedSize, opts.PlainOffset, opts.PlainSize, opts.EncryptedETag,
 			redundancyScheme{&opts.Redundancy},
 			aliasPieces,
 			opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, opts.StreamID,
-			opts.Placement,
+			opts.Placement, opts.IsManifest,
 		)
 		if err != nil {
 			if code := pgerrcode.FromError(err); code == pgxerrcode.NotNullViolation {
-				return ErrPendingObjectMissing.New("")
+				return p.commitSegmentFailure(ctx, opts)
 			}
 		}
 	}
@@ -680,19 +859,20 @@ func (s *SpannerAdapter) CommitPendingObjectSegment(ctx context.Context, opts Co
 					encrypted_size, plain_offset, plain_size, encrypted_etag,
 					redundancy,
 					remote_alias_pieces,
-					placement
+					placement, is_manifest
 				) VALUES (
 					(
 						SELECT stream_id
 						FROM objects
 						WHERE (project_id, bucket_name, object_key, version, stream_id) = (@project_id, @bucket_name, @object_key, @version, @stream_id) AND
-							status = ` + statusPending + `
+							status = ` + statusPending + ` AND
+							(expires_at IS NULL OR @expires_at IS NULL OR @expires_at <= expires_at)
 					), @position,
 					@expires_at, @root_piece_id, @encrypted_key_nonce, @encrypted_key,
 					@encrypted_size, @plain_offset, @plain_size, @encrypted_etag,
 					@redundancy,
 					@alias_pieces,
-					@placement
+					@placement, @is_manifest
 				)
 			`,
 			Params: map[string]interface{}{
@@ -713,6 +893,7 @@ func (s *SpannerAdapter) CommitPendingObjectSegment(ctx context.Context, opts Co
 				"version":             opts.Version,
 				"stream_id":           opts.StreamID.Bytes(),
 				"placement":           int64(opts.Placement),
+				"is_manifest":         opts.IsManifest,
 			},
 		}
 		numRows, err = txn.Update(ctx, stmt)
@@ -721,7 +902,7 @@ func (s *SpannerAdapter) CommitPendingObjectSegment(ctx context.Context, opts Co
 	if err != nil {
 		if spanner.ErrCode(err) == codes.FailedPrecondition {
 			if strings.Contains(err.Error(), "column: segments.stream_id") {
-				return ErrPendingObjectMissing.New("")
+				return s.commitSegmentFailure(ctx, opts)
 			}
 			return ErrFailedPrecondition.Wrap(err)
 		}
@@ -733,6 +914,38 @@ func (s *SpannerAdapter) CommitPendingObjectSegment(ctx context.Context, opts Co
 	return nil
 }
 
+// commitSegmentFailure returns why the pending object of the segment wasn't found.
+// It's only called when committing the segment failed, so it's not on the usual path.
+func (s *SpannerAdapter) commitSegmentFailure(ctx context.Context, opts CommitSegment) error {
+	objectExpiresAt, err := spannerutil.CollectRow(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT expires_at
+			FROM objects
+			WHERE (project_id, bucket_name, object_key, version, stream_id) = (@project_id, @bucket_name, @object_key, @version, @stream_id) AND
+				status = ` + statusPending + `
+		`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.BucketName,
+			"object_key":  opts.ObjectKey,
+			"version":     opts.Version,
+			"stream_id":   opts.StreamID,
+		},
+	}), func(row *spanner.Row, expiresAt **time.Time) error {
+		return Error.Wrap(row.Columns(expiresAt))
+	})
+	if err != nil {
+		if errors.Is(err, iterator.Done) {
+			return ErrPendingObjectMissing.New("")
+		}
+		return Error.Wrap(err)
+	}
+	if err := verifySegmentExpiration(opts.ExpiresAt, objectExpiresAt); err != nil {
+		return err
+	}
+	return ErrPendingObjectMissing.New("")
+}
+
 // CommitInlineSegment contains all necessary information about the segment.
 type CommitInlineSegment struct {
 	ObjectStream
@@ -775,6 +988,9 @@ func (db *DB) CommitInlineSegment(ctx context.Context, opts CommitInlineSegment)
 	if err := opts.ObjectStream.Verify(); err != nil {
 		return err
 	}
+	if err := db.verifyObjectKeyCharacters(opts.ObjectStream); err != nil {
+		return err
+	}
 
 	if err := opts.Verify(); err != nil {
 		return err
@@ -1006,6 +1222,84 @@ type CommitObject struct {
 
 	// Versioned indicates whether an object is allowed to have multiple versions.
 	Versioned bool
+
+	// RequireExisting allows the commit only when a committed object,
+	// which is not a delete marker, already exists at the location.
+	RequireExisting bool
+
+	// MaxPlacements limits the number of distinct placements the object's
+	// segments may reside in. Zero means no limit.
+	MaxPlacements int
+
+	// RequireInlineFirstSegment requires that the first segment is inline and
+	// at position (0,0), and that all the other segments are remote.
+	RequireInlineFirstSegment bool
+
+	// RequireSegments rejects the commit of objects without any segments.
+	// CommitInlineObject should be used for committing empty objects.
+	RequireSegments bool
+
+	// RequireFixedSegmentSize rejects the commit of objects with an irregular
+	// segment layout, i.e. when FixedSegmentSize would be -1. Objects use a fixed
+	// segment size, when all segments are in part 0, have consecutive indexes and
+	// all but the last segment have the same plain size.
+	RequireFixedSegmentSize bool
+
+	// SoftDeleteOnOverwrite keeps the overwritten unversioned object, instead of
+	// deleting it, by converting it into a versioned delete marker that still
+	// references its segments. It's meant for rolling back risky migrations.
+	//
+	// The segments of such delete markers aren't removed by anything automatically,
+	// they're deleted only once the delete marker version is deleted explicitly.
+	SoftDeleteOnOverwrite bool
+
+	// SystemLabels are server-side labels stored outside of the encrypted metadata.
+	SystemLabels map[string]string // optional
+
+	// ComputedETag is the S3 compatible ETag computed by the gateway. It's the
+	// hex encoded MD5 of the content, or for multipart objects the hex encoded
+	// MD5 of the concatenated part MD5s followed by "-" and the number of parts.
+	ComputedETag []byte // optional
+
+	// Retention and LegalHold are the Object Lock configuration of the committed
+	// object. They're set as part of the commit, so that an object in a bucket with
+	// default retention is never unprotected. The object must not have an expiration.
+	Retention Retention // optional
+	LegalHold bool
+
+	// CollectOrphans returns the remote segments of the overwritten unversioned object
+	// with CommitObjectWithResult, so that their pieces can be deleted directly. It
+	// requires an additional query, hence it should be set only when the result is used.
+	CollectOrphans bool
+}
+
+// CommitObjectResult contains the result of CommitObjectWithResult.
+type CommitObjectResult struct {
+	Object Object
+
+	// Orphaned contains the remote segments of the overwritten object,
+	// when CollectOrphans was set.
+	Orphaned []OrphanedSegment
+
+	// Replaced is true when the commit replaced an existing unversioned object
+	// or delete marker, either by deleting it or by converting it into a delete
+	// marker. Versioned commits never replace anything.
+	Replaced bool
+	// PreviousVersion is the version of the deleted object, when Replaced is set.
+	// It's zero when nothing was deleted, including when the replaced object was
+	// soft-deleted, see CommitObject.SoftDeleteOnOverwrite.
+	PreviousVersion Version
+
+	// Pruned contains the oldest versions of the object, which were deleted to
+	// keep at most Config.MaxVersionsPerKey versions.
+	Pruned []Object
+	// PrunedSegments contains the remote segments of the pruned versions.
+	PrunedSegments []OrphanedSegment
+}
+
+// lockConfigured returns whether the commit sets the Object Lock configuration.
+func (c *CommitObject) lockConfigured() bool {
+	return c.Retention.Enabled() || c.LegalHold
 }
 
 // Verify verifies request fields.
@@ -1018,6 +1312,20 @@ func (c *CommitObject) Verify() error {
 		return ErrInvalidRequest.New("Encryption.BlockSize is negative or zero")
 	}
 
+	if c.MaxPlacements < 0 {
+		return ErrInvalidRequest.New("MaxPlacements is negative")
+	}
+
+	if err := verifySystemLabels(c.SystemLabels); err != nil {
+		return err
+	}
+
+	if c.ComputedETag != nil {
+		if _, _, err := parseComputedETag(c.ComputedETag); err != nil {
+			return err
+		}
+	}
+
 	if c.OverrideEncryptedMetadata {
 		if c.EncryptedMetadata == nil && (c.EncryptedMetadataNonce != nil || c.EncryptedMetadataEncryptedKey != nil) {
 			return ErrInvalidRequest.New("EncryptedMetadataNonce and EncryptedMetadataEncryptedKey must be not set if EncryptedMetadata is not set")
@@ -1028,6 +1336,22 @@ func (c *CommitObject) Verify() error {
 	return nil
 }
 
+// verifySystemLabels checks that system labels have non-empty keys and
+// that their total size doesn't exceed MaxSystemLabelsSize.
+func verifySystemLabels(labels map[string]string) error {
+	size := 0
+	for key, value := range labels {
+		if key == "" {
+			return ErrInvalidRequest.New("SystemLabels contains an empty key")
+		}
+		size += len(key) + len(value)
+	}
+	if size > MaxSystemLabelsSize {
+		return ErrInvalidRequest.New("SystemLabels is too large: %d bytes, maximum allowed: %d", size, MaxSystemLabelsSize)
+	}
+	return nil
+}
+
 // WithTx provides a TransactionAdapter for the context of a database transaction.
 func (p *PostgresAdapter) WithTx(ctx context.Context, f func(context.Context, TransactionAdapter) error) error {
 	return txutil.WithTx(ctx, p.db, nil, func(ctx context.Context, tx tagsql.Tx) error {
@@ -1050,21 +1374,76 @@ func (s *SpannerAdapter) WithTx(ctx context.Context, f func(context.Context, Tra
 func (db *DB) CommitObject(ctx context.Context, opts CommitObject) (object Object, err error) {
 	defer mon.Task()(&ctx)(&err)
 
+	result, err := db.CommitObjectWithResult(ctx, opts)
+	return result.Object, err
+}
+
+// CommitObjectWithResult commits a pending object to the database, same as CommitObject,
+// and additionally returns the segments orphaned by overwriting an object.
+func (db *DB) CommitObjectWithResult(ctx context.Context, opts CommitObject) (result CommitObjectResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, precommit, err := db.commitObject(ctx, opts)
+	if err != nil {
+		return CommitObjectResult{}, err
+	}
+	result.Orphaned = precommit.Orphaned
+	result.Replaced = precommit.DeletedObjectCount > 0 || precommit.SoftDeletedObjectCount > 0
+	for _, deleted := range precommit.Deleted {
+		if result.PreviousVersion < deleted.Version {
+			result.PreviousVersion = deleted.Version
+		}
+	}
+	return result, nil
+}
+
+func (db *DB) commitObject(ctx context.Context, opts CommitObject) (result CommitObjectResult, precommit PrecommitConstraintResult, err error) {
 	if err := opts.Verify(); err != nil {
-		return Object{}, err
+		return CommitObjectResult{}, PrecommitConstraintResult{}, err
+	}
+	if err := db.verifyObjectKeyCharacters(opts.ObjectStream); err != nil {
+		return CommitObjectResult{}, PrecommitConstraintResult{}, err
+	}
+	if err := opts.Retention.Verify(db.nowFn()); err != nil {
+		return CommitObjectResult{}, PrecommitConstraintResult{}, err
 	}
 
-	var precommit PrecommitConstraintResult
+	object := &result.Object
+
 	err = db.ChooseAdapter(opts.ProjectID).WithTx(ctx, func(ctx context.Context, adapter TransactionAdapter) error {
 		segments, err := adapter.fetchSegmentsForCommit(ctx, opts.StreamID)
 		if err != nil {
 			return Error.New("failed to fetch segments: %w", err)
 		}
 
+		if opts.RequireSegments && len(segments) == 0 {
+			return ErrFailedPrecondition.New("object has no segments")
+		}
+
 		if err = db.validateParts(segments); err != nil {
 			return err
 		}
 
+		if err = validatePlacements(segments, opts.MaxPlacements); err != nil {
+			return err
+		}
+
+		if err = validateManifest(segments); err != nil {
+			return err
+		}
+
+		if opts.RequireInlineFirstSegment {
+			if err = validateInlineFirstSegment(segments); err != nil {
+				return err
+			}
+		}
+
+		if opts.ComputedETag != nil {
+			if err = validateComputedETag(opts.ComputedETag, segments); err != nil {
+				return err
+			}
+		}
+
 		finalSegments := convertToFinalSegments(segments)
 		if err := adapter.updateSegmentOffsets(ctx, opts.StreamID, finalSegments); err != nil {
 			return Error.New("failed to update segments: %w", err)
@@ -1086,18 +1465,29 @@ func (db *DB) CommitObject(ctx context.Context, opts CommitObject) (object Objec
 			}
 		}
 
+		if opts.RequireFixedSegmentSize && fixedSegmentSize == -1 {
+			return ErrFailedPrecondition.New("object doesn't have a fixed segment size")
+		}
+
 		var totalPlainSize, totalEncryptedSize int64
 		for _, seg := range finalSegments {
 			totalPlainSize += int64(seg.PlainSize)
 			totalEncryptedSize += int64(seg.EncryptedSize)
 		}
 
+		if err = db.validateMinObjectSize(segments, totalEncryptedSize); err != nil {
+			return err
+		}
+
 		nextStatus := committedWhereVersioned(opts.Versioned)
 
 		precommit, err = db.PrecommitConstraint(ctx, PrecommitConstraint{
 			Location:            opts.Location(),
 			Versioned:           opts.Versioned,
 			DisallowDelete:      opts.DisallowDelete,
+			RequireExisting:     opts.RequireExisting,
+			SoftDelete:          opts.SoftDeleteOnOverwrite,
+			CollectOrphans:      opts.CollectOrphans,
 			PrecommitDeleteMode: db.config.TestingPrecommitDeleteMode,
 		}, adapter)
 		if err != nil {
@@ -1109,11 +1499,18 @@ func (db *DB) CommitObject(ctx context.Context, opts CommitObject) (object Objec
 			nextVersion = precommit.HighestVersion + 1
 		}
 
-		err = adapter.finalizeObjectCommit(ctx, opts, nextStatus, nextVersion, segments, totalPlainSize, totalEncryptedSize, fixedSegmentSize, &object)
+		err = adapter.finalizeObjectCommit(ctx, opts, nextStatus, nextVersion, segments, totalPlainSize, totalEncryptedSize, fixedSegmentSize, object)
 		if err != nil {
 			return err
 		}
 
+		if db.config.MaxVersionsPerKey > 0 {
+			result.Pruned, result.PrunedSegments, err = db.pruneObjectVersions(ctx, adapter, opts.Location(), db.config.MaxVersionsPerKey)
+			if err != nil {
+				return err
+			}
+		}
+
 		object.StreamID = opts.StreamID
 		object.ProjectID = opts.ProjectID
 		object.BucketName = opts.BucketName
@@ -1127,7 +1524,7 @@ func (db *DB) CommitObject(ctx context.Context, opts CommitObject) (object Objec
 		return nil
 	})
 	if err != nil {
-		return Object{}, err
+		return CommitObjectResult{}, PrecommitConstraintResult{}, err
 	}
 
 	precommit.submitMetrics()
@@ -1135,8 +1532,35 @@ func (db *DB) CommitObject(ctx context.Context, opts CommitObject) (object Objec
 	mon.Meter("object_commit").Mark(1)
 	mon.IntVal("object_commit_segments").Observe(int64(object.SegmentCount))
 	mon.IntVal("object_commit_encrypted_size").Observe(object.TotalEncryptedSize)
+	mon.Meter("object_commit_segments_distribution",
+		monkit.NewSeriesTag("segments", segmentCountBucket(object.SegmentCount))).Mark(1)
+	// -1 is recorded when segments don't have a fixed size.
+	mon.IntVal("object_commit_fixed_segment_size").Observe(int64(object.FixedSegmentSize))
 
-	return object, nil
+	return result, precommit, nil
+}
+
+// segmentCountBucket returns the distribution bucket for the specified segment count.
+// The buckets are 0, 1, 2 and then doubling ranges, e.g. "3-4", "5-8", up to "1025+".
+func segmentCountBucket(count int32) string {
+	if count < 0 {
+		count = 0
+	}
+	if count <= 2 {
+		return strconv.Itoa(int(count))
+	}
+	for low, high := int32(3), int32(4); high <= 1024; low, high = high+1, high*2 {
+		if count <= high {
+			return strconv.Itoa(int(low)) + "-" + strconv.Itoa(int(high))
+		}
+	}
+	return "1025+"
+}
+
+// errEncryptionMissing is returned when the encryption parameters were set
+// neither at BeginObject nor at CommitObject.
+func errEncryptionMissing() error {
+	return ErrEncryptionMissing.New("encryption parameters not set at BeginObject or CommitObject")
 }
 
 func (ptx *postgresTransactionAdapter) finalizeObjectCommit(ctx context.Context, opts CommitObject, nextStatus ObjectStatus, nextVersion Version, finalSegments []segmentInfoForCommit, totalPlainSize int64, totalEncryptedSize int64, fixedSegmentSize int32, object *Object) (err error) {
@@ -1167,6 +1591,35 @@ func (ptx *postgresTransactionAdapter) finalizeObjectCommit(ctx context.Context,
 				encrypted_metadata_encrypted_key = $15
 			`
 	}
+
+	labelsColumn := ""
+	if len(opts.SystemLabels) > 0 {
+		args = append(args, systemLabels{&opts.SystemLabels})
+		labelsColumn = `,
+				system_labels = $` + strconv.Itoa(len(args)) + `::JSONB
+			`
+	}
+
+	etagColumn := ""
+	if opts.ComputedETag != nil {
+		args = append(args, opts.ComputedETag)
+		etagColumn = `,
+				computed_etag = $` + strconv.Itoa(len(args)) + `
+			`
+	}
+
+	lockColumns := ""
+	if opts.lockConfigured() {
+		args = append(args,
+			lockModeWrapper{retentionMode: &opts.Retention.Mode, legalHold: &opts.LegalHold},
+			timeWrapper{&opts.Retention.RetainUntil},
+		)
+		lockColumns = `,
+				retention_mode = $` + strconv.Itoa(len(args)-1) + `,
+				retain_until   = $` + strconv.Itoa(len(args)) + `
+			`
+	}
+
 	err = ptx.tx.QueryRowContext(ctx, `
 			UPDATE objects SET
 				version = $12,
@@ -1181,30 +1634,43 @@ func (ptx *postgresTransactionAdapter) finalizeObjectCommit(ctx context.Context,
 				-- TODO should we allow to override existing encryption parameters or return error if don't match with opts?
 				encryption = CASE
 					WHEN objects.encryption = 0 AND $11 <> 0 THEN $11
-					WHEN objects.encryption = 0 AND $11 = 0 THEN NULL
 					ELSE objects.encryption
 				END
 				`+metadataColumns+`
+				`+labelsColumn+`
+				`+etagColumn+`
+				`+lockColumns+`
 			WHERE (project_id, bucket_name, object_key, version, stream_id) = ($1, $2, $3, $4, $5) AND
 				status       = `+statusPending+`
 			RETURNING
 				created_at, expires_at,
 				encrypted_metadata, encrypted_metadata_encrypted_key, encrypted_metadata_nonce,
-				encryption
+				encryption,
+				system_labels,
+				computed_etag,
+				retention_mode, retain_until
 			`, args...).Scan(
 		&object.CreatedAt, &object.ExpiresAt,
 		&object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey, &object.EncryptedMetadataNonce,
 		encryptionParameters{&object.Encryption},
+		systemLabels{&object.SystemLabels},
+		&object.ComputedETag,
+		lockModeWrapper{retentionMode: &object.Retention.Mode, legalHold: &object.LegalHold}, timeWrapper{&object.Retention.RetainUntil},
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return ErrObjectNotFound.Wrap(Error.New("object with specified version and pending status is missing"))
-		} else if code := pgerrcode.FromError(err); code == pgxerrcode.NotNullViolation {
-			// TODO maybe we should check message if 'encryption' label is there
-			return ErrInvalidRequest.New("Encryption is missing")
 		}
 		return Error.New("failed to update object: %w", err)
 	}
+	if object.Encryption.IsZero() {
+		// returning the error rolls back the transaction.
+		return errEncryptionMissing()
+	}
+	if object.ExpiresAt != nil && opts.lockConfigured() {
+		// returning the error rolls back the transaction.
+		return ErrInvalidRequest.New("ExpiresAt must not be set if Retention or LegalHold is set")
+	}
 	return nil
 }
 
@@ -1218,6 +1684,9 @@ func (stx *spannerTransactionAdapter) finalizeObjectCommit(ctx context.Context,
 		oldEncryptedMetadataEncryptedKey []byte
 		oldEncryptedMetadataNonce        []byte
 		oldEncryptionParameters          storj.EncryptionParameters
+		oldSystemLabels                  map[string]string
+		oldRetention                     Retention
+		oldLegalHold                     bool
 	)
 
 	// We can not simply UPDATE the row, because we are changing the 'version' column,
@@ -1236,7 +1705,9 @@ func (stx *spannerTransactionAdapter) finalizeObjectCommit(ctx context.Context,
 				THEN RETURN
 					created_at, expires_at,
 					encrypted_metadata, encrypted_metadata_encrypted_key, encrypted_metadata_nonce,
-					encryption
+					encryption,
+					system_labels,
+					retention_mode, retain_until
 			`,
 		Params: map[string]interface{}{
 			"project_id":  opts.ProjectID,
@@ -1251,6 +1722,8 @@ func (stx *spannerTransactionAdapter) finalizeObjectCommit(ctx context.Context,
 			&object.CreatedAt, &object.ExpiresAt,
 			&oldEncryptedMetadata, &oldEncryptedMetadataEncryptedKey, &oldEncryptedMetadataNonce,
 			encryptionParameters{&oldEncryptionParameters},
+			systemLabels{&oldSystemLabels},
+			lockModeWrapper{retentionMode: &oldRetention.Mode, legalHold: &oldLegalHold}, timeWrapper{&oldRetention.RetainUntil},
 		))
 	})
 	if err != nil {
@@ -1261,19 +1734,28 @@ func (stx *spannerTransactionAdapter) finalizeObjectCommit(ctx context.Context,
 	}
 
 	// TODO should we allow to override existing encryption parameters or return error if don't match with opts?
-	var encryptionArg *storj.EncryptionParameters
-	if oldEncryptionParameters.IsZero() && !requestedEncryptionParameters.IsZero() {
+	encryptionArg := &oldEncryptionParameters
+	if oldEncryptionParameters.IsZero() {
 		encryptionArg = &requestedEncryptionParameters
-	} else if oldEncryptionParameters.IsZero() && requestedEncryptionParameters.IsZero() {
-		return ErrInvalidRequest.New("Encryption is missing")
-	} else {
-		encryptionArg = &oldEncryptionParameters
+	}
+	if encryptionArg.IsZero() {
+		return errEncryptionMissing()
 	}
 	if opts.OverrideEncryptedMetadata {
 		oldEncryptedMetadataNonce = opts.EncryptedMetadataNonce
 		oldEncryptedMetadata = opts.EncryptedMetadata
 		oldEncryptedMetadataEncryptedKey = opts.EncryptedMetadataEncryptedKey
 	}
+	if len(opts.SystemLabels) > 0 {
+		oldSystemLabels = opts.SystemLabels
+	}
+	if opts.lockConfigured() {
+		if object.ExpiresAt != nil {
+			return ErrInvalidRequest.New("ExpiresAt must not be set if Retention or LegalHold is set")
+		}
+		oldRetention = opts.Retention
+		oldLegalHold = opts.LegalHold
+	}
 	args := map[string]interface{}{
 		"project_id":                       opts.ProjectID,
 		"bucket_name":                      opts.BucketName,
@@ -1291,6 +1773,10 @@ func (stx *spannerTransactionAdapter) finalizeObjectCommit(ctx context.Context,
 		"total_encrypted_size":             totalEncryptedSize,
 		"fixed_segment_size":               int64(fixedSegmentSize),
 		"encryption":                       encryptionParameters{encryptionArg},
+		"system_labels":                    systemLabels{&oldSystemLabels},
+		"computed_etag":                    opts.ComputedETag,
+		"retention_mode":                   lockModeWrapper{retentionMode: &oldRetention.Mode, legalHold: &oldLegalHold},
+		"retain_until":                     timeWrapper{&oldRetention.RetainUntil},
 		"next_version":                     nextVersion,
 	}
 
@@ -1301,28 +1787,34 @@ func (stx *spannerTransactionAdapter) finalizeObjectCommit(ctx context.Context,
 				stream_id, created_at, expires_at, status, segment_count,
 				encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
 			    total_plain_size, total_encrypted_size, fixed_segment_size,
-			    encryption, zombie_deletion_deadline
+			    encryption, zombie_deletion_deadline,
+				system_labels,
+				computed_etag,
+				retention_mode, retain_until
 			) VALUES (
 			    @project_id, @bucket_name, @object_key, @version,
 				@stream_id, @created_at, @expires_at, @status, @segment_count,
 				@encrypted_metadata_nonce, @encrypted_metadata, @encrypted_metadata_encrypted_key,
 				@total_plain_size, @total_encrypted_size, @fixed_segment_size,
-				@encryption, NULL
+				@encryption, NULL,
+				@system_labels,
+				@computed_etag,
+				@retention_mode, @retain_until
 			)
 		`,
 		Params: args,
 	})
 	if err != nil {
-		if code := spanner.ErrCode(err); code == codes.FailedPrecondition {
-			// TODO maybe we should check message if 'encryption' label is there
-			return ErrInvalidRequest.New("Encryption is missing (%w)", err)
-		}
 		return Error.New("failed to update object: %w", err)
 	}
 	object.Encryption = *encryptionArg
 	object.EncryptedMetadataNonce = oldEncryptedMetadataNonce
 	object.EncryptedMetadata = oldEncryptedMetadata
 	object.EncryptedMetadataEncryptedKey = oldEncryptedMetadataEncryptedKey
+	object.SystemLabels = oldSystemLabels
+	object.ComputedETag = opts.ComputedETag
+	object.Retention = oldRetention
+	object.LegalHold = oldLegalHold
 	return nil
 }
 
@@ -1338,7 +1830,10 @@ func (db *DB) validateParts(segments []segmentInfoForCommit) error {
 	}
 
 	if len(partSize) > db.config.MaxNumberOfParts {
-		return ErrFailedPrecondition.New("exceeded maximum number of parts: %d", db.config.MaxNumberOfParts)
+		return ErrFailedPrecondition.Wrap(&ErrTooManyParts{
+			Parts:    len(partSize),
+			MaxParts: db.config.MaxNumberOfParts,
+		})
 	}
 
 	for part, size := range partSize {
@@ -1348,10 +1843,133 @@ func (db *DB) validateParts(segments []segmentInfoForCommit) error {
 		}
 
 		if size < db.config.MinPartSize {
-			return ErrFailedPrecondition.New("size of part number %d is below minimum threshold, got: %s, min: %s", part, size, db.config.MinPartSize)
+			return ErrFailedPrecondition.Wrap(&ErrPartTooSmall{
+				Part:    part,
+				Size:    size,
+				MinSize: db.config.MinPartSize,
+			})
+		}
+	}
+
+	return nil
+}
+
+// validateMinObjectSize checks that the object isn't smaller than the minimum
+// object size of any placement its segments reside in.
+func (db *DB) validateMinObjectSize(segments []segmentInfoForCommit, totalEncryptedSize int64) error {
+	if len(db.config.MinObjectSizes) == 0 {
+		return nil
+	}
+
+	for _, segment := range segments {
+		minSize, ok := db.config.MinObjectSizes[segment.Placement]
+		if ok && totalEncryptedSize < minSize.Int64() {
+			return ErrFailedPrecondition.New("size of object is below minimum threshold of placement %d, got: %s, min: %s",
+				segment.Placement, memory.Size(totalEncryptedSize), minSize)
+		}
+	}
+
+	return nil
+}
+
+// validatePlacements checks that segments don't reside in more than maxPlacements
+// distinct placements. Zero maxPlacements means no limit.
+func validatePlacements(segments []segmentInfoForCommit, maxPlacements int) error {
+	if maxPlacements <= 0 {
+		return nil
+	}
+
+	placements := make(map[storj.PlacementConstraint]struct{})
+	for _, segment := range segments {
+		placements[segment.Placement] = struct{}{}
+	}
+
+	if len(placements) > maxPlacements {
+		return ErrFailedPrecondition.New("segments span %d placements, maximum allowed: %d", len(placements), maxPlacements)
+	}
+
+	return nil
+}
+
+// validateManifest checks that at most one segment is marked as the manifest.
+func validateManifest(segments []segmentInfoForCommit) error {
+	manifests := 0
+	for _, segment := range segments {
+		if segment.IsManifest {
+			manifests++
 		}
 	}
 
+	if manifests > 1 {
+		return ErrFailedPrecondition.New("%d segments are marked as manifest, maximum allowed: 1", manifests)
+	}
+
+	return nil
+}
+
+// validateInlineFirstSegment checks that the first segment is inline at position (0,0)
+// and the rest of the segments are remote. segments must be ordered by position.
+func validateInlineFirstSegment(segments []segmentInfoForCommit) error {
+	if len(segments) == 0 || segments[0].Position != (SegmentPosition{}) || !segments[0].Inline {
+		return ErrFailedPrecondition.New("first segment must be inline at position (0,0)")
+	}
+
+	for _, segment := range segments[1:] {
+		if segment.Inline {
+			return ErrFailedPrecondition.New("segment at position (%d,%d) must be remote", segment.Position.Part, segment.Position.Index)
+		}
+	}
+
+	return nil
+}
+
+// computedETagDigestLength is the length of a hex encoded MD5 digest.
+const computedETagDigestLength = 32
+
+// parseComputedETag parses an ETag in the form of "<md5 hex>" or "<md5 hex>-<part count>".
+// partCount is zero when the ETag doesn't have the part count suffix.
+func parseComputedETag(etag []byte) (digest []byte, partCount int, err error) {
+	digest, suffix, multipart := bytes.Cut(etag, []byte("-"))
+	if len(digest) != computedETagDigestLength {
+		return nil, 0, ErrInvalidRequest.New("ComputedETag is invalid: %q", etag)
+	}
+	if _, err := hex.DecodeString(string(digest)); err != nil {
+		return nil, 0, ErrInvalidRequest.New("ComputedETag is invalid: %q", etag)
+	}
+	if !multipart {
+		return digest, 0, nil
+	}
+
+	partCount, err = strconv.Atoi(string(suffix))
+	if err != nil || partCount <= 0 || strconv.Itoa(partCount) != string(suffix) {
+		return nil, 0, ErrInvalidRequest.New("ComputedETag has an invalid part count: %q", etag)
+	}
+	return digest, partCount, nil
+}
+
+// validateComputedETag checks that etag has the part count suffix only for multipart
+// objects and that the suffix matches the number of parts in segments.
+func validateComputedETag(etag []byte, segments []segmentInfoForCommit) error {
+	_, partCount, err := parseComputedETag(etag)
+	if err != nil {
+		return err
+	}
+
+	parts := map[uint32]struct{}{}
+	multipart := false
+	for _, segment := range segments {
+		parts[segment.Position.Part] = struct{}{}
+		if segment.Position.Part > 0 {
+			multipart = true
+		}
+	}
+
+	switch {
+	case !multipart && partCount != 0:
+		return ErrInvalidRequest.New("ComputedETag %q has a part count, but the object isn't multipart", etag)
+	case multipart && partCount != len(parts):
+		return ErrInvalidRequest.New("ComputedETag %q doesn't match the number of parts: %d", etag, len(parts))
+	}
 	return nil
 }
 
@@ -1403,49 +2021,14 @@ func (db *DB) CommitInlineObject(ctx context.Context, opts CommitInlineObject) (
 	if err := opts.Verify(); err != nil {
 		return Object{}, err
 	}
+	if err := db.verifyObjectKeyCharacters(opts.ObjectStream); err != nil {
+		return Object{}, err
+	}
 
 	var precommit PrecommitConstraintResult
 	err = db.ChooseAdapter(opts.ProjectID).WithTx(ctx, func(ctx context.Context, adapter TransactionAdapter) error {
-		precommit, err = db.PrecommitConstraint(ctx, PrecommitConstraint{
-			Location:       opts.Location(),
-			Versioned:      opts.Versioned,
-			DisallowDelete: opts.DisallowDelete,
-		}, adapter)
-		if err != nil {
-			return err
-		}
-
-		nextVersion := precommit.HighestVersion + 1
-		nextStatus := committedWhereVersioned(opts.Versioned)
-
-		object.StreamID = opts.StreamID
-		object.ProjectID = opts.ProjectID
-		object.BucketName = opts.BucketName
-		object.ObjectKey = opts.ObjectKey
-		object.Version = nextVersion
-		object.Status = nextStatus
-		object.SegmentCount = 1
-		object.TotalPlainSize = int64(opts.PlainSize)
-		object.TotalEncryptedSize = int64(int32(len(opts.InlineData)))
-		object.ExpiresAt = opts.ExpiresAt
-		object.Encryption = opts.Encryption
-		object.EncryptedMetadata = opts.EncryptedMetadata
-		object.EncryptedMetadataEncryptedKey = opts.EncryptedMetadataEncryptedKey
-		object.EncryptedMetadataNonce = opts.EncryptedMetadataNonce
-
-		segment := &Segment{
-			StreamID:          opts.StreamID,
-			Position:          opts.Position,
-			ExpiresAt:         opts.ExpiresAt,
-			EncryptedKey:      opts.EncryptedKey,
-			EncryptedKeyNonce: opts.EncryptedKeyNonce,
-			EncryptedETag:     opts.EncryptedETag,
-			PlainSize:         opts.PlainSize,
-			EncryptedSize:     int32(len(opts.InlineData)),
-			InlineData:        opts.InlineData,
-		}
-
-		return adapter.finalizeInlineObjectCommit(ctx, &object, segment)
+		object, precommit, err = db.commitInlineObject(ctx, opts, adapter)
+		return err
 	})
 	if err != nil {
 		return Object{}, err
@@ -1460,6 +2043,54 @@ func (db *DB) CommitInlineObject(ctx context.Context, opts CommitInlineObject) (
 	return object, nil
 }
 
+// commitInlineObject commits a verified inline object using the transaction adapter.
+func (db *DB) commitInlineObject(ctx context.Context, opts CommitInlineObject, adapter TransactionAdapter) (object Object, precommit PrecommitConstraintResult, err error) {
+	precommit, err = db.PrecommitConstraint(ctx, PrecommitConstraint{
+		Location:       opts.Location(),
+		Versioned:      opts.Versioned,
+		DisallowDelete: opts.DisallowDelete,
+	}, adapter)
+	if err != nil {
+		return Object{}, PrecommitConstraintResult{}, err
+	}
+
+	nextVersion := precommit.HighestVersion + 1
+	nextStatus := committedWhereVersioned(opts.Versioned)
+
+	object.StreamID = opts.StreamID
+	object.ProjectID = opts.ProjectID
+	object.BucketName = opts.BucketName
+	object.ObjectKey = opts.ObjectKey
+	object.Version = nextVersion
+	object.Status = nextStatus
+	object.SegmentCount = 1
+	object.TotalPlainSize = int64(opts.PlainSize)
+	object.TotalEncryptedSize = int64(int32(len(opts.InlineData)))
+	object.ExpiresAt = opts.ExpiresAt
+	object.Encryption = opts.Encryption
+	object.EncryptedMetadata = opts.EncryptedMetadata
+	object.EncryptedMetadataEncryptedKey = opts.EncryptedMetadataEncryptedKey
+	object.EncryptedMetadataNonce = opts.EncryptedMetadataNonce
+
+	segment := &Segment{
+		StreamID:          opts.StreamID,
+		Position:          opts.Position,
+		ExpiresAt:         opts.ExpiresAt,
+		EncryptedKey:      opts.EncryptedKey,
+		EncryptedKeyNonce: opts.EncryptedKeyNonce,
+		EncryptedETag:     opts.EncryptedETag,
+		PlainSize:         opts.PlainSize,
+		EncryptedSize:     int32(len(opts.InlineData)),
+		InlineData:        opts.InlineData,
+	}
+
+	err = adapter.finalizeInlineObjectCommit(ctx, &object, segment)
+	if err != nil {
+		return Object{}, PrecommitConstraintResult{}, err
+	}
+	return object, precommit, nil
+}
+
 func (ptx *postgresTransactionAdapter) finalizeInlineObjectCommit(ctx context.Context, object *Object, segment *Segment) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
@@ -0,0 +1,104 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+)
+
+// CommitInlineObjectResult contains the result of committing a single inline object
+// as part of CommitInlineObjects.
+type CommitInlineObjectResult struct {
+	Object Object
+	// Err is set when the object conflicted with an existing object and wasn't committed,
+	// e.g. ErrPermissionDenied when the existing object is not allowed to be deleted.
+	Err error
+}
+
+// CommitInlineObjectsLimit is the maximum number of objects that can be
+// committed with a single CommitInlineObjects call.
+const CommitInlineObjectsLimit = 100
+
+// CommitInlineObjects commits many full inline objects using a single transaction.
+// Each of the objects follows the same rules as CommitInlineObject.
+//
+// All the objects must belong to projects that use the same adapter, otherwise
+// the batch couldn't be committed atomically and it's rejected with ErrInvalidRequest.
+//
+// The results are in the same order as objects. An object that conflicts with an existing object
+// doesn't prevent committing the other objects and the conflict is reported in its result.
+// Any other failure rolls back the whole batch and it's returned as an error.
+func (db *DB) CommitInlineObjects(ctx context.Context, objects []CommitInlineObject) (results []CommitInlineObjectResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if len(objects) > CommitInlineObjectsLimit {
+		return nil, ErrInvalidRequest.New("too many objects: %d, maximum allowed: %d", len(objects), CommitInlineObjectsLimit)
+	}
+
+	var adapter Adapter
+	for i := range objects {
+		if err := objects[i].Verify(); err != nil {
+			return nil, err
+		}
+		if err := db.verifyObjectKeyCharacters(objects[i].ObjectStream); err != nil {
+			return nil, err
+		}
+
+		objectAdapter := db.ChooseAdapter(objects[i].ProjectID)
+		if adapter == nil {
+			adapter = objectAdapter
+		} else if adapter != objectAdapter {
+			return nil, ErrInvalidRequest.New("objects must belong to projects using the same database adapter")
+		}
+	}
+	if adapter == nil {
+		return nil, nil
+	}
+
+	results = make([]CommitInlineObjectResult, len(objects))
+	precommits := make([]PrecommitConstraintResult, len(objects))
+	err = adapter.WithTx(ctx, func(ctx context.Context, tx TransactionAdapter) error {
+		// the transaction may be retried, so the results must not leak between the attempts.
+		for i := range objects {
+			results[i], precommits[i] = CommitInlineObjectResult{}, PrecommitConstraintResult{}
+		}
+
+		for i := range objects {
+			object, precommit, err := db.commitInlineObject(ctx, objects[i], tx)
+			if err != nil {
+				if isCommitInlineObjectConflict(err) {
+					results[i].Err = err
+					continue
+				}
+				return err
+			}
+			results[i].Object, precommits[i] = object, precommit
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	committed := 0
+	for i, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		committed++
+		precommits[i].submitMetrics()
+		mon.IntVal("object_commit_segments").Observe(int64(result.Object.SegmentCount))
+		mon.IntVal("object_commit_encrypted_size").Observe(result.Object.TotalEncryptedSize)
+	}
+	mon.Meter("object_commit").Mark(committed)
+	mon.IntVal("object_commit_batch_size").Observe(int64(len(objects)))
+
+	return results, nil
+}
+
+// isCommitInlineObjectConflict returns whether the error is caused by a conflict
+// with an existing object, which leaves the transaction usable.
+func isCommitInlineObjectConflict(err error) bool {
+	return ErrPermissionDenied.Has(err) || ErrFailedPrecondition.Has(err)
+}
@@ -0,0 +1,126 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/common/uuid"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestCommitInlineObjects(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		newInlineObject := func(obj metabase.ObjectStream) metabase.CommitInlineObject {
+			return metabase.CommitInlineObject{
+				ObjectStream: obj,
+				Encryption:   metabasetest.DefaultEncryption,
+				CommitInlineSegment: metabase.CommitInlineSegment{
+					EncryptedKey:      testrand.Bytes(32),
+					EncryptedKeyNonce: testrand.Bytes(32),
+					PlainSize:         512,
+					InlineData:        testrand.Bytes(100),
+				},
+			}
+		}
+
+		t.Run("invalid request", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			invalid := newInlineObject(metabasetest.RandObjectStream())
+			invalid.EncryptedKey = nil
+
+			_, err := db.CommitInlineObjects(ctx, []metabase.CommitInlineObject{
+				newInlineObject(metabasetest.RandObjectStream()),
+				invalid,
+			})
+			require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+			require.ErrorContains(t, err, "EncryptedKey missing")
+
+			_, err = db.CommitInlineObjects(ctx, make([]metabase.CommitInlineObject, metabase.CommitInlineObjectsLimit+1))
+			require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+			require.ErrorContains(t, err, "too many objects: 101, maximum allowed: 100")
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("commit", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			existing := metabasetest.CreateObject(ctx, t, db, metabasetest.RandObjectStream(), 0)
+
+			conflicting := existing.ObjectStream
+			conflicting.StreamID = testrand.UUID()
+
+			overwriting := existing.ObjectStream
+			overwriting.StreamID = testrand.UUID()
+
+			batch := []metabase.CommitInlineObject{
+				newInlineObject(metabasetest.RandObjectStream()),
+				newInlineObject(conflicting),
+				newInlineObject(metabasetest.RandObjectStream()),
+			}
+			batch[1].DisallowDelete = true
+
+			results, err := db.CommitInlineObjects(ctx, batch)
+			require.NoError(t, err)
+			require.Len(t, results, len(batch))
+
+			require.NoError(t, results[0].Err)
+			require.Equal(t, batch[0].StreamID, results[0].Object.StreamID)
+			require.True(t, metabase.ErrPermissionDenied.Has(results[1].Err), results[1].Err)
+			require.Zero(t, results[1].Object)
+			require.NoError(t, results[2].Err)
+			require.Equal(t, batch[2].StreamID, results[2].Object.StreamID)
+
+			// without DisallowDelete the existing object is overwritten.
+			results, err = db.CommitInlineObjects(ctx, []metabase.CommitInlineObject{
+				newInlineObject(overwriting),
+			})
+			require.NoError(t, err)
+			require.NoError(t, results[0].Err)
+			require.Equal(t, existing.Version+1, results[0].Object.Version)
+
+			objects, err := db.TestingAllObjects(ctx)
+			require.NoError(t, err)
+			require.Len(t, objects, 3)
+
+			var streamIDs []uuid.UUID
+			for _, object := range objects {
+				streamIDs = append(streamIDs, object.StreamID)
+			}
+			require.ElementsMatch(t, []uuid.UUID{batch[0].StreamID, batch[2].StreamID, overwriting.StreamID}, streamIDs)
+
+			segments, err := db.TestingAllSegments(ctx)
+			require.NoError(t, err)
+			require.Len(t, segments, 3)
+		})
+
+		t.Run("rollback", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			existing, segments := metabasetest.CreateTestObject{}.Run(ctx, t, db, metabasetest.RandObjectStream(), 1)
+
+			// reusing the stream ID results in a conflicting segment.
+			duplicate := metabasetest.RandObjectStream()
+			duplicate.StreamID = existing.StreamID
+
+			_, err := db.CommitInlineObjects(ctx, []metabase.CommitInlineObject{
+				newInlineObject(metabasetest.RandObjectStream()),
+				newInlineObject(duplicate),
+			})
+			require.Error(t, err)
+
+			metabasetest.Verify{
+				Objects:  []metabase.RawObject{metabase.RawObject(existing)},
+				Segments: metabasetest.SegmentsToRaw(segments),
+			}.Check(ctx, t, db)
+		})
+	})
+}
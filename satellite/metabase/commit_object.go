@@ -12,6 +12,7 @@ import (
 	"cloud.google.com/go/spanner"
 	"github.com/zeebo/errs"
 
+	"storj.io/common/storj"
 	"storj.io/common/uuid"
 	"storj.io/storj/shared/dbutil/pgutil"
 	"storj.io/storj/shared/dbutil/spannerutil"
@@ -308,6 +309,9 @@ type segmentInfoForCommit struct {
 	EncryptedSize int32
 	PlainOffset   int64
 	PlainSize     int32
+	Placement     storj.PlacementConstraint
+	Inline        bool
+	IsManifest    bool
 }
 
 // fetchSegmentsForCommit loads information necessary for validating segment existence and offsets.
@@ -315,14 +319,14 @@ func (ptx *postgresTransactionAdapter) fetchSegmentsForCommit(ctx context.Contex
 	defer mon.Task()(&ctx)(&err)
 
 	err = withRows(ptx.tx.QueryContext(ctx, `
-		SELECT position, encrypted_size, plain_offset, plain_size
+		SELECT position, encrypted_size, plain_offset, plain_size, placement, remote_alias_pieces IS NULL, is_manifest
 		FROM segments
 		WHERE stream_id = $1
 		ORDER BY position
 	`, streamID))(func(rows tagsql.Rows) error {
 		for rows.Next() {
 			var segment segmentInfoForCommit
-			err := rows.Scan(&segment.Position, &segment.EncryptedSize, &segment.PlainOffset, &segment.PlainSize)
+			err := rows.Scan(&segment.Position, &segment.EncryptedSize, &segment.PlainOffset, &segment.PlainSize, &segment.Placement, &segment.Inline, &segment.IsManifest)
 			if err != nil {
 				return Error.New("failed to scan segments: %w", err)
 			}
@@ -341,7 +345,7 @@ func (stx *spannerTransactionAdapter) fetchSegmentsForCommit(ctx context.Context
 
 	segments, err = spannerutil.CollectRows(stx.tx.Query(ctx, spanner.Statement{
 		SQL: `
-			SELECT position, encrypted_size, plain_offset, plain_size
+			SELECT position, encrypted_size, plain_offset, plain_size, placement, remote_alias_pieces IS NULL, is_manifest
 			FROM segments
 			WHERE stream_id = @stream_id
 			ORDER BY position
@@ -352,6 +356,7 @@ func (stx *spannerTransactionAdapter) fetchSegmentsForCommit(ctx context.Context
 	}), func(row *spanner.Row, segment *segmentInfoForCommit) error {
 		return Error.Wrap(row.Columns(
 			&segment.Position, spannerutil.Int(&segment.EncryptedSize), &segment.PlainOffset, spannerutil.Int(&segment.PlainSize),
+			&segment.Placement, &segment.Inline, &segment.IsManifest,
 		))
 	})
 
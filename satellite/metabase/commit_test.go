@@ -4,14 +4,20 @@
 package metabase_test
 
 import (
+	"context"
+	"errors"
 	"math"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
 	"storj.io/common/memory"
 	"storj.io/common/storj"
 	"storj.io/common/testcontext"
 	"storj.io/common/testrand"
+	"storj.io/common/uuid"
 	"storj.io/storj/satellite/metabase"
 	"storj.io/storj/satellite/metabase/metabasetest"
 	"storj.io/storj/shared/dbutil"
@@ -338,6 +344,297 @@ func TestBeginObjectNextVersion(t *testing.T) {
 	})
 }
 
+func TestBeginObjectZombieDeletionPeriod(t *testing.T) {
+	obj := metabasetest.RandObjectStream()
+
+	metabasetest.RunWithConfig(t, metabase.Config{
+		ApplicationName: "metabase-tests",
+		ZombieDeletionPeriods: map[uuid.UUID]time.Duration{
+			obj.ProjectID: 72 * time.Hour,
+		},
+	}, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+		other := metabasetest.RandObjectStream()
+
+		for _, test := range []struct {
+			stream metabase.ObjectStream
+			period time.Duration
+		}{
+			{stream: obj, period: 72 * time.Hour},
+			{stream: other, period: 24 * time.Hour},
+		} {
+			object, err := db.BeginObjectNextVersion(ctx, metabase.BeginObjectNextVersion{
+				ObjectStream: metabase.ObjectStream{
+					ProjectID:  test.stream.ProjectID,
+					BucketName: test.stream.BucketName,
+					ObjectKey:  test.stream.ObjectKey,
+					StreamID:   test.stream.StreamID,
+					Version:    metabase.NextVersion,
+				},
+				Encryption: metabasetest.DefaultEncryption,
+			})
+			require.NoError(t, err)
+			require.NotNil(t, object.ZombieDeletionDeadline)
+			require.WithinDuration(t, time.Now().Add(test.period), *object.ZombieDeletionDeadline, 5*time.Second)
+
+			object, err = db.TestingBeginObjectExactVersion(ctx, metabase.BeginObjectExactVersion{
+				ObjectStream: metabase.ObjectStream{
+					ProjectID:  test.stream.ProjectID,
+					BucketName: test.stream.BucketName,
+					ObjectKey:  test.stream.ObjectKey + "/exact",
+					StreamID:   testrand.UUID(),
+					Version:    1,
+				},
+				Encryption: metabasetest.DefaultEncryption,
+			})
+			require.NoError(t, err)
+			require.NotNil(t, object.ZombieDeletionDeadline)
+			require.WithinDuration(t, time.Now().Add(test.period), *object.ZombieDeletionDeadline, 5*time.Second)
+		}
+	})
+}
+
+func TestBeginObjectBucketLockDefaults(t *testing.T) {
+	lockedBucket := metabasetest.RandObjectStream()
+	retainUntil := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+
+	var defaultRetention metabase.Retention
+	metabasetest.RunWithConfig(t, metabase.Config{
+		ApplicationName: "metabase-tests",
+		BucketLockDefaults: func(ctx context.Context, projectID uuid.UUID, bucketName string) (metabase.Retention, bool, error) {
+			if projectID == lockedBucket.ProjectID && bucketName == lockedBucket.BucketName {
+				return defaultRetention, true, nil
+			}
+			return metabase.Retention{}, false, nil
+		},
+	}, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		begin := func(stream metabase.ObjectStream, opts metabase.BeginObjectNextVersion) (metabase.Object, error) {
+			opts.ObjectStream = metabase.ObjectStream{
+				ProjectID:  stream.ProjectID,
+				BucketName: stream.BucketName,
+				ObjectKey:  stream.ObjectKey,
+				StreamID:   stream.StreamID,
+				Version:    metabase.NextVersion,
+			}
+			opts.Encryption = metabasetest.DefaultEncryption
+			return db.BeginObjectNextVersion(ctx, opts)
+		}
+
+		t.Run("defaults applied", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+			defaultRetention = metabase.Retention{Mode: storj.ComplianceMode, RetainUntil: retainUntil}
+
+			object, err := begin(lockedBucket, metabase.BeginObjectNextVersion{})
+			require.NoError(t, err)
+			require.Equal(t, defaultRetention, object.Retention)
+			require.True(t, object.LegalHold)
+
+			committed, err := db.CommitObject(ctx, metabase.CommitObject{
+				ObjectStream: object.ObjectStream,
+			})
+			require.NoError(t, err)
+			require.Equal(t, defaultRetention.Mode, committed.Retention.Mode)
+			require.WithinDuration(t, retainUntil, committed.Retention.RetainUntil, time.Microsecond)
+			require.True(t, committed.LegalHold)
+		})
+
+		t.Run("explicit configuration", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+			defaultRetention = metabase.Retention{Mode: storj.ComplianceMode, RetainUntil: retainUntil}
+
+			explicit := metabase.Retention{Mode: storj.ComplianceMode, RetainUntil: retainUntil.Add(time.Hour)}
+			object, err := begin(lockedBucket, metabase.BeginObjectNextVersion{Retention: explicit})
+			require.NoError(t, err)
+			require.Equal(t, explicit, object.Retention)
+			require.False(t, object.LegalHold)
+		})
+
+		t.Run("other bucket", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+			defaultRetention = metabase.Retention{Mode: storj.ComplianceMode, RetainUntil: retainUntil}
+
+			expiresAt := time.Now().Add(time.Hour)
+			object, err := begin(metabasetest.RandObjectStream(), metabase.BeginObjectNextVersion{ExpiresAt: &expiresAt})
+			require.NoError(t, err)
+			require.False(t, object.Retention.Enabled())
+			require.False(t, object.LegalHold)
+		})
+
+		t.Run("expiring object", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+			defaultRetention = metabase.Retention{Mode: storj.ComplianceMode, RetainUntil: retainUntil}
+
+			expiresAt := time.Now().Add(time.Hour)
+			_, err := begin(lockedBucket, metabase.BeginObjectNextVersion{ExpiresAt: &expiresAt})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "ExpiresAt must not be set if Retention or LegalHold is set")
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("invalid default", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+			defaultRetention = metabase.Retention{Mode: storj.ComplianceMode, RetainUntil: time.Now().Add(-time.Hour)}
+
+			_, err := begin(lockedBucket, metabase.BeginObjectNextVersion{})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "RetainUntil must be in the future")
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+	})
+}
+
+func TestCommitObjectMaxVersionsPerKey(t *testing.T) {
+	metabasetest.RunWithConfig(t, metabase.Config{
+		ApplicationName:   "metabase-tests",
+		MaxVersionsPerKey: 2,
+	}, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		commitVersion := func(obj metabase.ObjectStream, version metabase.Version) metabase.CommitObjectResult {
+			obj.Version = version
+			obj.StreamID = testrand.UUID()
+			metabasetest.CreatePendingObject(ctx, t, db, obj, 2)
+
+			result, err := db.CommitObjectWithResult(ctx, metabase.CommitObject{
+				ObjectStream: obj,
+				Versioned:    true,
+			})
+			require.NoError(t, err)
+			return result
+		}
+
+		t.Run("prunes oldest versions", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := metabasetest.RandObjectStream()
+
+			first := commitVersion(obj, 1)
+			require.Empty(t, first.Pruned)
+			second := commitVersion(obj, 2)
+			require.Empty(t, second.Pruned)
+
+			third := commitVersion(obj, 3)
+			require.Len(t, third.Pruned, 1)
+			require.Equal(t, first.Object.StreamID, third.Pruned[0].StreamID)
+			require.Equal(t, metabase.Version(1), third.Pruned[0].Version)
+			require.Len(t, third.PrunedSegments, 2)
+			for _, segment := range third.PrunedSegments {
+				require.Equal(t, first.Object.StreamID, segment.StreamID)
+				require.NotEmpty(t, segment.Pieces)
+			}
+
+			objects, err := db.TestingAllObjects(ctx)
+			require.NoError(t, err)
+			require.Len(t, objects, 2)
+			for _, object := range objects {
+				require.NotEqual(t, first.Object.StreamID, object.StreamID)
+			}
+
+			segments, err := db.TestingAllSegments(ctx)
+			require.NoError(t, err)
+			require.Len(t, segments, 4)
+		})
+
+		t.Run("pending objects are not counted", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := metabasetest.RandObjectStream()
+
+			pending := obj
+			pending.Version = 1
+			metabasetest.CreatePendingObject(ctx, t, db, pending, 0)
+
+			require.Empty(t, commitVersion(obj, 2).Pruned)
+			require.Empty(t, commitVersion(obj, 3).Pruned)
+
+			objects, err := db.TestingAllObjects(ctx)
+			require.NoError(t, err)
+			require.Len(t, objects, 3)
+		})
+
+		t.Run("locked versions are kept", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := metabasetest.RandObjectStream()
+
+			locked := obj
+			locked.Version = 1
+			metabasetest.CreateTestObject{
+				CommitObject: &metabase.CommitObject{
+					ObjectStream: locked,
+					Encryption:   metabasetest.DefaultEncryption,
+					Versioned:    true,
+					LegalHold:    true,
+				},
+			}.Run(ctx, t, db, locked, 1)
+
+			second := commitVersion(obj, 2)
+			require.Empty(t, second.Pruned)
+
+			third := commitVersion(obj, 3)
+			require.Empty(t, third.Pruned)
+
+			fourth := commitVersion(obj, 4)
+			require.Len(t, fourth.Pruned, 1)
+			require.Equal(t, second.Object.StreamID, fourth.Pruned[0].StreamID)
+
+			objects, err := db.TestingAllObjects(ctx)
+			require.NoError(t, err)
+			require.Len(t, objects, 3)
+		})
+	})
+}
+
+func TestRejectControlCharactersInKeys(t *testing.T) {
+	metabasetest.RunWithConfig(t, metabase.Config{
+		ApplicationName:               "metabase-tests",
+		RejectControlCharactersInKeys: true,
+	}, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+		obj := metabasetest.RandObjectStream()
+		obj.ObjectKey = "prefix/\x00key"
+		obj.Version = metabase.NextVersion
+
+		metabasetest.BeginObjectNextVersion{
+			Opts: metabase.BeginObjectNextVersion{
+				ObjectStream: obj,
+				Encryption:   metabasetest.DefaultEncryption,
+			},
+			ErrClass: &metabase.ErrInvalidRequest,
+			ErrText:  "ObjectKey contains control character 0x00 at position 7",
+		}.Check(ctx, t, db)
+
+		obj.Version = 1
+		metabasetest.CommitInlineObject{
+			Opts: metabase.CommitInlineObject{
+				ObjectStream: obj,
+				Encryption:   metabasetest.DefaultEncryption,
+				CommitInlineSegment: metabase.CommitInlineSegment{
+					ObjectStream:      obj,
+					EncryptedKey:      testrand.Bytes(32),
+					EncryptedKeyNonce: testrand.Bytes(32),
+					PlainSize:         512,
+					InlineData:        testrand.Bytes(100),
+				},
+			},
+			ErrClass: &metabase.ErrInvalidRequest,
+			ErrText:  "ObjectKey contains control character 0x00 at position 7",
+		}.Check(ctx, t, db)
+
+		metabasetest.Verify{}.Check(ctx, t, db)
+
+		obj.ObjectKey = "prefix/key"
+		obj.Version = metabase.NextVersion
+		metabasetest.BeginObjectNextVersion{
+			Opts: metabase.BeginObjectNextVersion{
+				ObjectStream: obj,
+				Encryption:   metabasetest.DefaultEncryption,
+			},
+			Version: 1,
+		}.Check(ctx, t, db)
+	})
+}
+
 func TestBeginObjectExactVersion(t *testing.T) {
 	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
 		obj := metabasetest.RandObjectStream()
@@ -945,6 +1242,85 @@ func TestBeginSegment(t *testing.T) {
 	})
 }
 
+func TestCommitSegmentValidateSize(t *testing.T) {
+	metabasetest.RunWithConfig(t, metabase.Config{
+		ApplicationName:     "metabase-tests",
+		ValidateSegmentSize: true,
+	}, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+		obj := metabasetest.RandObjectStream()
+		metabasetest.CreatePendingObject(ctx, t, db, obj, 0)
+
+		newSegment := func() metabase.CommitSegment {
+			return metabase.CommitSegment{
+				ObjectStream:      obj,
+				RootPieceID:       testrand.PieceID(),
+				EncryptedKey:      testrand.Bytes(32),
+				EncryptedKeyNonce: testrand.Bytes(32),
+				PlainSize:         512,
+				EncryptedSize:     1024,
+				Redundancy:        metabasetest.DefaultRedundancy,
+				Pieces:            metabase.Pieces{{Number: 0, StorageNode: testrand.NodeID()}},
+			}
+		}
+
+		for _, test := range []struct {
+			name    string
+			modify  func(opts *metabase.CommitSegment)
+			errText string
+		}{
+			{
+				name: "inconsistent shares",
+				modify: func(opts *metabase.CommitSegment) {
+					opts.Redundancy.RequiredShares = 2
+				},
+				errText: "Redundancy shares are inconsistent: 2/1/1/1",
+			},
+			{
+				name: "too many pieces",
+				modify: func(opts *metabase.CommitSegment) {
+					opts.Pieces = append(opts.Pieces, metabase.Piece{Number: 1, StorageNode: testrand.NodeID()})
+				},
+				errText: "number of pieces 2 exceeds redundancy total shares 1",
+			},
+			{
+				name: "piece number out of range",
+				modify: func(opts *metabase.CommitSegment) {
+					opts.Pieces[0].Number = 1
+				},
+				errText: "piece number 1 exceeds redundancy total shares 1",
+			},
+			{
+				name: "encrypted size smaller than plain size",
+				modify: func(opts *metabase.CommitSegment) {
+					opts.EncryptedSize = 256
+				},
+				errText: "EncryptedSize 256 is smaller than PlainSize 512",
+			},
+			{
+				name: "encrypted size larger than redundancy stripes",
+				modify: func(opts *metabase.CommitSegment) {
+					opts.EncryptedSize = 8192
+				},
+				errText: "EncryptedSize 8192 requires 4 stripes of 2048 bytes, expected at most 3 for PlainSize 512",
+			},
+		} {
+			opts := newSegment()
+			test.modify(&opts)
+			metabasetest.CommitSegment{
+				Opts:     opts,
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  test.errText,
+			}.Check(ctx, t, db)
+		}
+
+		metabasetest.CommitSegment{
+			Opts: newSegment(),
+		}.Check(ctx, t, db)
+	})
+}
+
 func TestCommitSegment(t *testing.T) {
 	for _, mode := range []string{"", "transaction", "no-pending-object-check"} {
 		mode := mode
@@ -1548,6 +1924,80 @@ func TestCommitSegment(t *testing.T) {
 				}.Check(ctx, t, db)
 			})
 
+			t.Run("commit segment with expires at different from object", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				// the database doesn't store nanoseconds, which would be visible in the error message.
+				now := time.Now().Truncate(time.Second)
+				objectExpiresAt := now.Add(33 * time.Hour)
+				earlierExpiresAt := now.Add(10 * time.Hour)
+				laterExpiresAt := now.Add(48 * time.Hour)
+
+				commitSegment := func(obj metabase.ObjectStream, index uint32, expiresAt *time.Time) metabase.CommitSegment {
+					return metabase.CommitSegment{
+						ObjectStream: obj,
+						Position:     metabase.SegmentPosition{Index: index},
+						ExpiresAt:    expiresAt,
+						RootPieceID:  testrand.PieceID(),
+						Pieces:       metabase.Pieces{{Number: 0, StorageNode: testrand.NodeID()}},
+
+						EncryptedKey:      testrand.Bytes(32),
+						EncryptedKeyNonce: testrand.Bytes(32),
+
+						EncryptedSize: 1024,
+						PlainSize:     512,
+						PlainOffset:   int64(index) * 512,
+						Redundancy:    metabasetest.DefaultRedundancy,
+					}
+				}
+
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: obj,
+						Encryption:   metabasetest.DefaultEncryption,
+						ExpiresAt:    &objectExpiresAt,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitSegment{
+					Opts: commitSegment(obj, 0, &earlierExpiresAt),
+				}.Check(ctx, t, db)
+
+				if mode != "no-pending-object-check" {
+					metabasetest.CommitSegment{
+						Opts:     commitSegment(obj, 1, &laterExpiresAt),
+						ErrClass: &metabase.ErrInvalidRequest,
+						ErrText:  "segment ExpiresAt " + laterExpiresAt.UTC().String() + " is later than object ExpiresAt " + objectExpiresAt.UTC().String(),
+					}.Check(ctx, t, db)
+				}
+
+				// objects without expiration accept any segment expiration.
+				unexpiring := metabasetest.RandObjectStream()
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: unexpiring,
+						Encryption:   metabasetest.DefaultEncryption,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitSegment{
+					Opts: commitSegment(unexpiring, 0, &laterExpiresAt),
+				}.Check(ctx, t, db)
+
+				segments, err := db.TestingAllSegments(ctx)
+				require.NoError(t, err)
+				require.Len(t, segments, 2)
+				for _, segment := range segments {
+					require.NotNil(t, segment.ExpiresAt)
+					switch segment.StreamID {
+					case obj.StreamID:
+						require.WithinDuration(t, earlierExpiresAt, *segment.ExpiresAt, time.Second)
+					case unexpiring.StreamID:
+						require.WithinDuration(t, laterExpiresAt, *segment.ExpiresAt, time.Second)
+					}
+				}
+			})
+
 			t.Run("commit segment of pending object", func(t *testing.T) {
 				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
 
@@ -2631,8 +3081,8 @@ func TestCommitObject(t *testing.T) {
 						ObjectStream: obj,
 						Encryption:   storj.EncryptionParameters{},
 					},
-					ErrClass: &metabase.ErrInvalidRequest,
-					ErrText:  "Encryption is missing",
+					ErrClass: &metabase.ErrEncryptionMissing,
+					ErrText:  "encryption parameters not set at BeginObject or CommitObject",
 				}.Check(ctx, t, db)
 
 				metabasetest.CommitObject{
@@ -2856,96 +3306,1042 @@ func TestCommitObject(t *testing.T) {
 					},
 				}.Check(ctx, t, db)
 			})
-		})
-	}
-}
 
-func TestCommitObjectVersioned(t *testing.T) {
-	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
-		obj := metabasetest.RandObjectStream()
-		obj.Version = metabase.NextVersion
-		now := time.Now()
-		zombieExpiration := now.Add(24 * time.Hour)
+			t.Run("max placements", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
 
-		t.Run("Commit versioned only", func(t *testing.T) {
-			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+				now := time.Now()
+				zombieDeadline := now.Add(24 * time.Hour)
 
-			v1 := obj
-			metabasetest.BeginObjectNextVersion{
-				Opts: metabase.BeginObjectNextVersion{
-					ObjectStream:           v1,
-					Encryption:             metabasetest.DefaultEncryption,
-					ZombieDeletionDeadline: &zombieExpiration,
-				},
-				Version: 1,
-			}.Check(ctx, t, db)
-			v1.Version = 1
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: obj,
+						Encryption:   metabasetest.DefaultEncryption,
+					},
+				}.Check(ctx, t, db)
 
-			v2 := obj
-			metabasetest.BeginObjectNextVersion{
-				Opts: metabase.BeginObjectNextVersion{
-					ObjectStream:           v2,
-					Encryption:             metabasetest.DefaultEncryption,
-					ZombieDeletionDeadline: &zombieExpiration,
-				},
-				Version: 2,
-			}.Check(ctx, t, db)
-			v2.Version = 2
+				rootPieceID := testrand.PieceID()
+				pieces := metabase.Pieces{{Number: 0, StorageNode: testrand.NodeID()}}
+				encryptedKey := testrand.Bytes(32)
+				encryptedKeyNonce := testrand.Nonce()
 
-			v3 := obj
-			metabasetest.BeginObjectNextVersion{
-				Opts: metabase.BeginObjectNextVersion{
-					ObjectStream:           v3,
-					Encryption:             metabasetest.DefaultEncryption,
-					ZombieDeletionDeadline: &zombieExpiration,
-				},
-				Version: 3,
-			}.Check(ctx, t, db)
-			v3.Version = 3
+				var segments []metabase.RawSegment
+				for i, placement := range []storj.PlacementConstraint{1, 2} {
+					metabasetest.CommitSegment{
+						Opts: metabase.CommitSegment{
+							ObjectStream: obj,
+							Position:     metabase.SegmentPosition{Index: uint32(i)},
+							RootPieceID:  rootPieceID,
+							Pieces:       pieces,
 
-			metabasetest.Verify{
-				Objects: []metabase.RawObject{
-					{
-						ObjectStream:           v1,
-						CreatedAt:              now,
-						Status:                 metabase.Pending,
-						Encryption:             metabasetest.DefaultEncryption,
-						ZombieDeletionDeadline: &zombieExpiration,
-					},
-					{
-						ObjectStream:           v2,
-						CreatedAt:              now,
-						Status:                 metabase.Pending,
-						Encryption:             metabasetest.DefaultEncryption,
-						ZombieDeletionDeadline: &zombieExpiration,
-					},
-					{
-						ObjectStream:           v3,
-						CreatedAt:              now,
-						Status:                 metabase.Pending,
-						Encryption:             metabasetest.DefaultEncryption,
-						ZombieDeletionDeadline: &zombieExpiration,
-					},
-				},
-			}.Check(ctx, t, db)
+							EncryptedKey:      encryptedKey,
+							EncryptedKeyNonce: encryptedKeyNonce[:],
 
-			metabasetest.CommitObject{
-				Opts: metabase.CommitObject{
-					ObjectStream: v1,
-					Versioned:    true,
-				},
-				ExpectVersion: v3.Version + 1,
-			}.Check(ctx, t, db)
-			v1.Version = v3.Version + 1
+							EncryptedSize: 1024,
+							PlainSize:     512,
+							PlainOffset:   int64(i) * 512,
+							Redundancy:    metabasetest.DefaultRedundancy,
+							Placement:     placement,
+						},
+					}.Check(ctx, t, db)
 
-			metabasetest.CommitObject{
-				Opts: metabase.CommitObject{
-					ObjectStream: v2,
-					Versioned:    true,
-				},
-				ExpectVersion: v3.Version + 2,
-			}.Check(ctx, t, db)
-			v2.Version = v1.Version + 1
+					segments = append(segments, metabase.RawSegment{
+						StreamID:  obj.StreamID,
+						Position:  metabase.SegmentPosition{Index: uint32(i)},
+						CreatedAt: now,
+
+						RootPieceID:       rootPieceID,
+						EncryptedKey:      encryptedKey,
+						EncryptedKeyNonce: encryptedKeyNonce[:],
+
+						EncryptedSize: 1024,
+						PlainSize:     512,
+						PlainOffset:   int64(i) * 512,
+
+						Redundancy: metabasetest.DefaultRedundancy,
+						Placement:  placement,
+
+						Pieces: pieces,
+					})
+				}
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:  obj,
+						MaxPlacements: -1,
+					},
+					ErrClass: &metabase.ErrInvalidRequest,
+					ErrText:  "MaxPlacements is negative",
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:  obj,
+						MaxPlacements: 1,
+					},
+					ErrClass: &metabase.ErrFailedPrecondition,
+					ErrText:  "segments span 2 placements, maximum allowed: 1",
+				}.Check(ctx, t, db)
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						{
+							ObjectStream: obj,
+							CreatedAt:    now,
+							Status:       metabase.Pending,
+
+							Encryption:             metabasetest.DefaultEncryption,
+							ZombieDeletionDeadline: &zombieDeadline,
+						},
+					},
+					Segments: segments,
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:  obj,
+						MaxPlacements: 2,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						{
+							ObjectStream: obj,
+							CreatedAt:    now,
+							Status:       metabase.CommittedUnversioned,
+
+							SegmentCount:       2,
+							TotalPlainSize:     1024,
+							TotalEncryptedSize: 2048,
+							FixedSegmentSize:   512,
+
+							Encryption: metabasetest.DefaultEncryption,
+						},
+					},
+					Segments: segments,
+				}.Check(ctx, t, db)
+			})
+
+			t.Run("manifest segment", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				now := time.Now()
+
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: obj,
+						Encryption:   metabasetest.DefaultEncryption,
+					},
+				}.Check(ctx, t, db)
+
+				rootPieceID := testrand.PieceID()
+				pieces := metabase.Pieces{{Number: 0, StorageNode: testrand.NodeID()}}
+				encryptedKey := testrand.Bytes(32)
+				encryptedKeyNonce := testrand.Nonce()
+
+				commitSegment := func(index uint32, isManifest bool) metabase.RawSegment {
+					metabasetest.CommitSegment{
+						Opts: metabase.CommitSegment{
+							ObjectStream: obj,
+							Position:     metabase.SegmentPosition{Index: index},
+							RootPieceID:  rootPieceID,
+							Pieces:       pieces,
+
+							EncryptedKey:      encryptedKey,
+							EncryptedKeyNonce: encryptedKeyNonce[:],
+
+							EncryptedSize: 1024,
+							PlainSize:     512,
+							PlainOffset:   int64(index) * 512,
+							Redundancy:    metabasetest.DefaultRedundancy,
+							IsManifest:    isManifest,
+						},
+					}.Check(ctx, t, db)
+
+					return metabase.RawSegment{
+						StreamID:  obj.StreamID,
+						Position:  metabase.SegmentPosition{Index: index},
+						CreatedAt: now,
+
+						RootPieceID:       rootPieceID,
+						EncryptedKey:      encryptedKey,
+						EncryptedKeyNonce: encryptedKeyNonce[:],
+
+						EncryptedSize: 1024,
+						PlainSize:     512,
+						PlainOffset:   int64(index) * 512,
+
+						Redundancy: metabasetest.DefaultRedundancy,
+						IsManifest: isManifest,
+
+						Pieces: pieces,
+					}
+				}
+
+				commitSegment(0, true)
+				commitSegment(1, true)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: obj,
+					},
+					ErrClass: &metabase.ErrFailedPrecondition,
+					ErrText:  "2 segments are marked as manifest, maximum allowed: 1",
+				}.Check(ctx, t, db)
+
+				// recommitting the segment clears the flag.
+				segments := []metabase.RawSegment{
+					commitSegment(0, false),
+					commitSegment(1, true),
+				}
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: obj,
+					},
+				}.Check(ctx, t, db)
+
+				segment, err := db.GetSegmentByPosition(ctx, metabase.GetSegmentByPosition{
+					StreamID: obj.StreamID,
+					Position: metabase.SegmentPosition{Index: 1},
+				})
+				require.NoError(t, err)
+				require.True(t, segment.IsManifest)
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						{
+							ObjectStream: obj,
+							CreatedAt:    now,
+							Status:       metabase.CommittedUnversioned,
+
+							SegmentCount:       2,
+							TotalPlainSize:     1024,
+							TotalEncryptedSize: 2048,
+							FixedSegmentSize:   512,
+
+							Encryption: metabasetest.DefaultEncryption,
+						},
+					},
+					Segments: segments,
+				}.Check(ctx, t, db)
+			})
+
+			t.Run("require segments", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				pending := metabasetest.CreatePendingObject(ctx, t, db, obj, 0)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:    obj,
+						RequireSegments: true,
+					},
+					ErrClass: &metabase.ErrFailedPrecondition,
+					ErrText:  "object has no segments",
+				}.Check(ctx, t, db)
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						metabase.RawObject(pending),
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				metabasetest.CreatePendingObject(ctx, t, db, obj, 1)
+
+				object, err := db.CommitObject(ctx, metabase.CommitObject{
+					ObjectStream:    obj,
+					RequireSegments: true,
+				})
+				require.NoError(t, err)
+				require.EqualValues(t, 1, object.SegmentCount)
+			})
+
+			t.Run("require fixed segment size", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				pending := metabasetest.CreatePendingObject(ctx, t, db, obj, 0)
+
+				metabasetest.CommitSegment{
+					Opts: metabase.CommitSegment{
+						ObjectStream: obj,
+						Position:     metabase.SegmentPosition{Part: 1, Index: 0},
+						RootPieceID:  testrand.PieceID(),
+						Pieces:       metabase.Pieces{{Number: 0, StorageNode: testrand.NodeID()}},
+
+						EncryptedKey:      testrand.Bytes(32),
+						EncryptedKeyNonce: testrand.Bytes(32),
+
+						EncryptedSize: 1024,
+						PlainSize:     512,
+						Redundancy:    metabasetest.DefaultRedundancy,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:            obj,
+						RequireFixedSegmentSize: true,
+					},
+					ErrClass: &metabase.ErrFailedPrecondition,
+					ErrText:  "object doesn't have a fixed segment size",
+				}.Check(ctx, t, db)
+
+				objects, err := db.TestingAllObjects(ctx)
+				require.NoError(t, err)
+				require.Len(t, objects, 1)
+				require.Equal(t, pending.StreamID, objects[0].StreamID)
+				require.Equal(t, metabase.Pending, objects[0].Status)
+
+				metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				metabasetest.CreatePendingObject(ctx, t, db, obj, 2)
+
+				object, err := db.CommitObject(ctx, metabase.CommitObject{
+					ObjectStream:            obj,
+					RequireFixedSegmentSize: true,
+				})
+				require.NoError(t, err)
+				require.NotEqual(t, int32(-1), object.FixedSegmentSize)
+			})
+
+			t.Run("require inline first segment", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				now := time.Now()
+				zombieDeadline := now.Add(24 * time.Hour)
+
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: obj,
+						Encryption:   metabasetest.DefaultEncryption,
+					},
+				}.Check(ctx, t, db)
+
+				rootPieceID := testrand.PieceID()
+				pieces := metabase.Pieces{{Number: 0, StorageNode: testrand.NodeID()}}
+				encryptedKey := testrand.Bytes(32)
+				encryptedKeyNonce := testrand.Nonce()
+
+				metabasetest.CommitSegment{
+					Opts: metabase.CommitSegment{
+						ObjectStream: obj,
+						Position:     metabase.SegmentPosition{Index: 1},
+						RootPieceID:  rootPieceID,
+						Pieces:       pieces,
+
+						EncryptedKey:      encryptedKey,
+						EncryptedKeyNonce: encryptedKeyNonce[:],
+
+						EncryptedSize: 1024,
+						PlainSize:     512,
+						PlainOffset:   512,
+						Redundancy:    metabasetest.DefaultRedundancy,
+					},
+				}.Check(ctx, t, db)
+
+				remoteSegment := metabase.RawSegment{
+					StreamID:  obj.StreamID,
+					Position:  metabase.SegmentPosition{Index: 1},
+					CreatedAt: now,
+
+					RootPieceID:       rootPieceID,
+					EncryptedKey:      encryptedKey,
+					EncryptedKeyNonce: encryptedKeyNonce[:],
+
+					EncryptedSize: 1024,
+					PlainSize:     512,
+					PlainOffset:   512,
+
+					Redundancy: metabasetest.DefaultRedundancy,
+
+					Pieces: pieces,
+				}
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:              obj,
+						RequireInlineFirstSegment: true,
+					},
+					ErrClass: &metabase.ErrFailedPrecondition,
+					ErrText:  "first segment must be inline at position (0,0)",
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitInlineSegment{
+					Opts: metabase.CommitInlineSegment{
+						ObjectStream: obj,
+						InlineData:   []byte{1, 2, 3},
+
+						EncryptedKey:      encryptedKey,
+						EncryptedKeyNonce: encryptedKeyNonce[:],
+
+						PlainSize:   512,
+						PlainOffset: 0,
+					},
+				}.Check(ctx, t, db)
+
+				inlineSegment := metabase.RawSegment{
+					StreamID:  obj.StreamID,
+					CreatedAt: now,
+
+					EncryptedKey:      encryptedKey,
+					EncryptedKeyNonce: encryptedKeyNonce[:],
+
+					PlainOffset: 0,
+					PlainSize:   512,
+
+					InlineData:    []byte{1, 2, 3},
+					EncryptedSize: 3,
+				}
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						{
+							ObjectStream: obj,
+							CreatedAt:    now,
+							Status:       metabase.Pending,
+
+							Encryption:             metabasetest.DefaultEncryption,
+							ZombieDeletionDeadline: &zombieDeadline,
+						},
+					},
+					Segments: []metabase.RawSegment{inlineSegment, remoteSegment},
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:              obj,
+						RequireInlineFirstSegment: true,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						{
+							ObjectStream: obj,
+							CreatedAt:    now,
+							Status:       metabase.CommittedUnversioned,
+
+							SegmentCount:       2,
+							TotalPlainSize:     1024,
+							TotalEncryptedSize: 1027,
+							FixedSegmentSize:   512,
+
+							Encryption: metabasetest.DefaultEncryption,
+						},
+					},
+					Segments: []metabase.RawSegment{inlineSegment, remoteSegment},
+				}.Check(ctx, t, db)
+			})
+
+			t.Run("require existing", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				now := time.Now()
+				zombieDeadline := now.Add(24 * time.Hour)
+
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: obj,
+						Encryption:   metabasetest.DefaultEncryption,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:    obj,
+						RequireExisting: true,
+					},
+					ErrClass: &metabase.ErrFailedPrecondition,
+					ErrText:  "object does not exist",
+				}.Check(ctx, t, db)
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						{
+							ObjectStream: obj,
+							CreatedAt:    now,
+							Status:       metabase.Pending,
+
+							Encryption:             metabasetest.DefaultEncryption,
+							ZombieDeletionDeadline: &zombieDeadline,
+						},
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: obj,
+					},
+				}.Check(ctx, t, db)
+
+				overwrite := obj
+				overwrite.Version = obj.Version + 1
+				overwrite.StreamID = testrand.UUID()
+
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: overwrite,
+						Encryption:   metabasetest.DefaultEncryption,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:    overwrite,
+						RequireExisting: true,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						{
+							ObjectStream: overwrite,
+							CreatedAt:    now,
+							Status:       metabase.CommittedUnversioned,
+
+							Encryption: metabasetest.DefaultEncryption,
+						},
+					},
+				}.Check(ctx, t, db)
+			})
+
+			t.Run("soft delete on overwrite", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				original, segments := metabasetest.CreateTestObject{}.Run(ctx, t, db, obj, 2)
+
+				overwrite := obj
+				overwrite.Version = obj.Version + 1
+				overwrite.StreamID = testrand.UUID()
+
+				metabasetest.CreatePendingObject(ctx, t, db, overwrite, 0)
+				committed := metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:          overwrite,
+						SoftDeleteOnOverwrite: true,
+					},
+				}.Check(ctx, t, db)
+
+				// the overwritten object is kept as a delete marker together with its segments.
+				original.Status = metabase.DeleteMarkerVersioned
+
+				metabasetest.Verify{
+					Objects:  []metabase.RawObject{metabase.RawObject(original), metabase.RawObject(committed)},
+					Segments: metabasetest.SegmentsToRaw(segments),
+				}.Check(ctx, t, db)
+			})
+
+			t.Run("soft delete on overwrite of delete marker", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{{
+					ObjectStream: obj,
+					CreatedAt:    time.Now(),
+					Status:       metabase.DeleteMarkerUnversioned,
+				}}))
+
+				overwrite := obj
+				overwrite.Version = obj.Version + 1
+				overwrite.StreamID = testrand.UUID()
+
+				metabasetest.CreatePendingObject(ctx, t, db, overwrite, 0)
+				committed := metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream:          overwrite,
+						SoftDeleteOnOverwrite: true,
+					},
+				}.Check(ctx, t, db)
+
+				// an unversioned delete marker has nothing to keep, hence it's deleted.
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{metabase.RawObject(committed)},
+				}.Check(ctx, t, db)
+			})
+
+			t.Run("computed etag", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				digest := []byte("0123456789abcdef0123456789abcdef")
+
+				for _, etag := range []string{"", "0123", "0123456789abcdef0123456789abcdeX", string(digest) + "x"} {
+					metabasetest.CommitObject{
+						Opts: metabase.CommitObject{
+							ObjectStream: obj,
+							ComputedETag: []byte(etag),
+						},
+						ErrClass: &metabase.ErrInvalidRequest,
+						ErrText:  "ComputedETag is invalid: " + strconv.Quote(etag),
+					}.Check(ctx, t, db)
+				}
+
+				for _, suffix := range []string{"-", "-0", "-01", "--1", "-a"} {
+					metabasetest.CommitObject{
+						Opts: metabase.CommitObject{
+							ObjectStream: obj,
+							ComputedETag: append(append([]byte{}, digest...), suffix...),
+						},
+						ErrClass: &metabase.ErrInvalidRequest,
+						ErrText:  "ComputedETag has an invalid part count: " + strconv.Quote(string(digest)+suffix),
+					}.Check(ctx, t, db)
+				}
+
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: obj,
+						Encryption:   metabasetest.DefaultEncryption,
+					},
+				}.Check(ctx, t, db)
+
+				for _, part := range []uint32{1, 2} {
+					metabasetest.CommitInlineSegment{
+						Opts: metabase.CommitInlineSegment{
+							ObjectStream: obj,
+							Position:     metabase.SegmentPosition{Part: part},
+							InlineData:   []byte{1, 2, 3},
+
+							EncryptedKey:      testrand.Bytes(32),
+							EncryptedKeyNonce: testrand.Bytes(32),
+
+							PlainSize: 3,
+						},
+					}.Check(ctx, t, db)
+				}
+
+				for _, test := range []struct {
+					etag    string
+					errText string
+				}{
+					{string(digest), `ComputedETag "` + string(digest) + `" doesn't match the number of parts: 2`},
+					{string(digest) + "-3", `ComputedETag "` + string(digest) + `-3" doesn't match the number of parts: 2`},
+				} {
+					metabasetest.CommitObject{
+						Opts: metabase.CommitObject{
+							ObjectStream: obj,
+							ComputedETag: []byte(test.etag),
+						},
+						ErrClass: &metabase.ErrInvalidRequest,
+						ErrText:  test.errText,
+					}.Check(ctx, t, db)
+				}
+
+				etag := append(append([]byte{}, digest...), "-2"...)
+				object := metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: obj,
+						ComputedETag: etag,
+					},
+				}.Check(ctx, t, db)
+				require.Equal(t, etag, object.ComputedETag)
+
+				metabasetest.GetObjectExactVersion{
+					Opts: metabase.GetObjectExactVersion{
+						ObjectLocation: obj.Location(),
+						Version:        obj.Version,
+					},
+					Result: object,
+				}.Check(ctx, t, db)
+
+				// the part count suffix is allowed only for multipart objects.
+				plain := metabasetest.RandObjectStream()
+				metabasetest.CreatePendingObject(ctx, t, db, plain, 1)
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: plain,
+						ComputedETag: etag,
+					},
+					ErrClass: &metabase.ErrInvalidRequest,
+					ErrText:  `ComputedETag "` + string(etag) + `" has a part count, but the object isn't multipart`,
+				}.Check(ctx, t, db)
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: plain,
+						ComputedETag: digest,
+					},
+				}.Check(ctx, t, db)
+			})
+
+			t.Run("retention", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				now := time.Now().Truncate(time.Second)
+				retention := metabase.Retention{
+					Mode:        storj.ComplianceMode,
+					RetainUntil: now.Add(time.Hour),
+				}
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: obj,
+						Retention: metabase.Retention{
+							Mode:        storj.ComplianceMode,
+							RetainUntil: now.Add(-time.Hour),
+						},
+					},
+					ErrClass: &metabase.ErrInvalidRequest,
+					ErrText:  "RetainUntil must be in the future: " + now.Add(-time.Hour).Format(time.RFC3339Nano),
+				}.Check(ctx, t, db)
+
+				// objects with an expiration can't be locked.
+				expiring := metabasetest.RandObjectStream()
+				expiresAt := now.Add(24 * time.Hour)
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: expiring,
+						Encryption:   metabasetest.DefaultEncryption,
+						ExpiresAt:    &expiresAt,
+					},
+				}.Check(ctx, t, db)
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: expiring,
+						LegalHold:    true,
+					},
+					ErrClass: &metabase.ErrInvalidRequest,
+					ErrText:  "ExpiresAt must not be set if Retention or LegalHold is set",
+				}.Check(ctx, t, db)
+
+				retained := metabasetest.RandObjectStream()
+				metabasetest.CreatePendingObject(ctx, t, db, retained, 0)
+				retainedObject := metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: retained,
+						Retention:    retention,
+					},
+				}.Check(ctx, t, db)
+				require.Equal(t, retention, retainedObject.Retention)
+				require.False(t, retainedObject.LegalHold)
+
+				held := metabasetest.RandObjectStream()
+				metabasetest.CreatePendingObject(ctx, t, db, held, 0)
+				heldObject := metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: held,
+						Retention:    retention,
+						LegalHold:    true,
+					},
+				}.Check(ctx, t, db)
+				require.Equal(t, retention, heldObject.Retention)
+				require.True(t, heldObject.LegalHold)
+
+				objects, err := db.TestingAllObjects(ctx)
+				require.NoError(t, err)
+				require.Len(t, objects, 3)
+				for _, object := range objects {
+					switch object.StreamID {
+					case expiring.StreamID:
+						// the failed commit is rolled back.
+						require.Equal(t, metabase.Pending, object.Status)
+						require.False(t, object.Retention.Enabled())
+						require.False(t, object.LegalHold)
+					case retained.StreamID:
+						require.Equal(t, retention.Mode, object.Retention.Mode)
+						require.WithinDuration(t, retention.RetainUntil, object.Retention.RetainUntil, time.Second)
+						require.False(t, object.LegalHold)
+					case held.StreamID:
+						require.Equal(t, retention.Mode, object.Retention.Mode)
+						require.True(t, object.LegalHold)
+					}
+				}
+			})
+
+			t.Run("system labels", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				now := time.Now()
+				zombieDeadline := now.Add(24 * time.Hour)
+
+				metabasetest.BeginObjectExactVersion{
+					Opts: metabase.BeginObjectExactVersion{
+						ObjectStream: obj,
+						Encryption:   metabasetest.DefaultEncryption,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: obj,
+						SystemLabels: map[string]string{"": "value"},
+					},
+					ErrClass: &metabase.ErrInvalidRequest,
+					ErrText:  "SystemLabels contains an empty key",
+				}.Check(ctx, t, db)
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: obj,
+						SystemLabels: map[string]string{
+							"key": string(testrand.BytesInt(metabase.MaxSystemLabelsSize)),
+						},
+					},
+					ErrClass: &metabase.ErrInvalidRequest,
+					ErrText:  "SystemLabels is too large: 1027 bytes, maximum allowed: 1024",
+				}.Check(ctx, t, db)
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						{
+							ObjectStream: obj,
+							CreatedAt:    now,
+							Status:       metabase.Pending,
+
+							Encryption:             metabasetest.DefaultEncryption,
+							ZombieDeletionDeadline: &zombieDeadline,
+						},
+					},
+				}.Check(ctx, t, db)
+
+				labels := map[string]string{
+					"immutable-backup": "true",
+					"owner":            "ops",
+				}
+
+				metabasetest.CommitObject{
+					Opts: metabase.CommitObject{
+						ObjectStream: obj,
+						SystemLabels: labels,
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.ListObjects{
+					Opts: metabase.ListObjects{
+						ProjectID:           obj.ProjectID,
+						BucketName:          obj.BucketName,
+						Recursive:           true,
+						IncludeSystemLabels: true,
+					},
+					Result: metabase.ListObjectsResult{
+						Objects: []metabase.ObjectEntry{
+							{
+								ObjectKey:    obj.ObjectKey,
+								Version:      obj.Version,
+								StreamID:     obj.StreamID,
+								Status:       metabase.CommittedUnversioned,
+								Encryption:   metabasetest.DefaultEncryption,
+								SystemLabels: labels,
+							},
+						},
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.Verify{
+					Objects: []metabase.RawObject{
+						{
+							ObjectStream: obj,
+							CreatedAt:    now,
+							Status:       metabase.CommittedUnversioned,
+
+							Encryption:   metabasetest.DefaultEncryption,
+							SystemLabels: labels,
+						},
+					},
+				}.Check(ctx, t, db)
+			})
+
+			t.Run("collect orphans", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				_, segments := metabasetest.CreateTestObject{}.Run(ctx, t, db, obj, 2)
+
+				overwrite := obj
+				overwrite.Version++
+				overwrite.StreamID = testrand.UUID()
+				metabasetest.CreatePendingObject(ctx, t, db, overwrite, 1)
+
+				result, err := db.CommitObjectWithResult(ctx, metabase.CommitObject{
+					ObjectStream:   overwrite,
+					CollectOrphans: true,
+				})
+				require.NoError(t, err)
+				require.Equal(t, overwrite.StreamID, result.Object.StreamID)
+
+				var expected []metabase.OrphanedSegment
+				for _, segment := range segments {
+					expected = append(expected, metabase.OrphanedSegment{
+						StreamID:    segment.StreamID,
+						Position:    segment.Position,
+						RootPieceID: segment.RootPieceID,
+						Pieces:      segment.Pieces,
+					})
+				}
+				require.Equal(t, expected, result.Orphaned)
+
+				// without the flag the orphaned segments aren't collected.
+				withoutFlag := overwrite
+				withoutFlag.Version++
+				withoutFlag.StreamID = testrand.UUID()
+				metabasetest.CreatePendingObject(ctx, t, db, withoutFlag, 1)
+
+				result, err = db.CommitObjectWithResult(ctx, metabase.CommitObject{
+					ObjectStream: withoutFlag,
+				})
+				require.NoError(t, err)
+				require.Empty(t, result.Orphaned)
+
+				// versioned commits don't delete anything.
+				versioned := withoutFlag
+				versioned.Version++
+				versioned.StreamID = testrand.UUID()
+				metabasetest.CreatePendingObject(ctx, t, db, versioned, 1)
+
+				result, err = db.CommitObjectWithResult(ctx, metabase.CommitObject{
+					ObjectStream:   versioned,
+					Versioned:      true,
+					CollectOrphans: true,
+				})
+				require.NoError(t, err)
+				require.Empty(t, result.Orphaned)
+
+				objects, err := db.TestingAllObjects(ctx)
+				require.NoError(t, err)
+				require.Len(t, objects, 2)
+			})
+
+			t.Run("replaced", func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				metabasetest.CreatePendingObject(ctx, t, db, obj, 1)
+				result, err := db.CommitObjectWithResult(ctx, metabase.CommitObject{
+					ObjectStream: obj,
+				})
+				require.NoError(t, err)
+				require.False(t, result.Replaced)
+				require.Zero(t, result.PreviousVersion)
+
+				overwrite := obj
+				overwrite.Version++
+				overwrite.StreamID = testrand.UUID()
+				metabasetest.CreatePendingObject(ctx, t, db, overwrite, 1)
+
+				result, err = db.CommitObjectWithResult(ctx, metabase.CommitObject{
+					ObjectStream: overwrite,
+				})
+				require.NoError(t, err)
+				require.True(t, result.Replaced)
+				require.Equal(t, obj.Version, result.PreviousVersion)
+				require.Equal(t, overwrite.Version, result.Object.Version)
+
+				// versioned commits don't replace anything.
+				versioned := overwrite
+				versioned.Version++
+				versioned.StreamID = testrand.UUID()
+				metabasetest.CreatePendingObject(ctx, t, db, versioned, 1)
+
+				result, err = db.CommitObjectWithResult(ctx, metabase.CommitObject{
+					ObjectStream: versioned,
+					Versioned:    true,
+				})
+				require.NoError(t, err)
+				require.False(t, result.Replaced)
+				require.Zero(t, result.PreviousVersion)
+
+				// soft-deleted objects are replaced, but kept with their version.
+				softDeleted := versioned
+				softDeleted.Version++
+				softDeleted.StreamID = testrand.UUID()
+				metabasetest.CreatePendingObject(ctx, t, db, softDeleted, 1)
+
+				result, err = db.CommitObjectWithResult(ctx, metabase.CommitObject{
+					ObjectStream:          softDeleted,
+					SoftDeleteOnOverwrite: true,
+				})
+				require.NoError(t, err)
+				require.True(t, result.Replaced)
+				require.Zero(t, result.PreviousVersion)
+			})
+		})
+	}
+}
+
+func TestCommitObjectVersioned(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+		obj.Version = metabase.NextVersion
+		now := time.Now()
+		zombieExpiration := now.Add(24 * time.Hour)
+
+		t.Run("Commit versioned only", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			v1 := obj
+			metabasetest.BeginObjectNextVersion{
+				Opts: metabase.BeginObjectNextVersion{
+					ObjectStream:           v1,
+					Encryption:             metabasetest.DefaultEncryption,
+					ZombieDeletionDeadline: &zombieExpiration,
+				},
+				Version: 1,
+			}.Check(ctx, t, db)
+			v1.Version = 1
+
+			v2 := obj
+			metabasetest.BeginObjectNextVersion{
+				Opts: metabase.BeginObjectNextVersion{
+					ObjectStream:           v2,
+					Encryption:             metabasetest.DefaultEncryption,
+					ZombieDeletionDeadline: &zombieExpiration,
+				},
+				Version: 2,
+			}.Check(ctx, t, db)
+			v2.Version = 2
+
+			v3 := obj
+			metabasetest.BeginObjectNextVersion{
+				Opts: metabase.BeginObjectNextVersion{
+					ObjectStream:           v3,
+					Encryption:             metabasetest.DefaultEncryption,
+					ZombieDeletionDeadline: &zombieExpiration,
+				},
+				Version: 3,
+			}.Check(ctx, t, db)
+			v3.Version = 3
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{
+					{
+						ObjectStream:           v1,
+						CreatedAt:              now,
+						Status:                 metabase.Pending,
+						Encryption:             metabasetest.DefaultEncryption,
+						ZombieDeletionDeadline: &zombieExpiration,
+					},
+					{
+						ObjectStream:           v2,
+						CreatedAt:              now,
+						Status:                 metabase.Pending,
+						Encryption:             metabasetest.DefaultEncryption,
+						ZombieDeletionDeadline: &zombieExpiration,
+					},
+					{
+						ObjectStream:           v3,
+						CreatedAt:              now,
+						Status:                 metabase.Pending,
+						Encryption:             metabasetest.DefaultEncryption,
+						ZombieDeletionDeadline: &zombieExpiration,
+					},
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.CommitObject{
+				Opts: metabase.CommitObject{
+					ObjectStream: v1,
+					Versioned:    true,
+				},
+				ExpectVersion: v3.Version + 1,
+			}.Check(ctx, t, db)
+			v1.Version = v3.Version + 1
+
+			metabasetest.CommitObject{
+				Opts: metabase.CommitObject{
+					ObjectStream: v2,
+					Versioned:    true,
+				},
+				ExpectVersion: v3.Version + 2,
+			}.Check(ctx, t, db)
+			v2.Version = v1.Version + 1
 
 			metabasetest.CommitObject{
 				Opts: metabase.CommitObject{
@@ -3620,6 +5016,84 @@ func TestCommitObjectVersioned(t *testing.T) {
 	})
 }
 
+func TestCommitObjectMinObjectSize(t *testing.T) {
+	const coldPlacement = storj.PlacementConstraint(5)
+
+	metabasetest.RunWithConfig(t, metabase.Config{
+		ApplicationName: "satellite-test",
+		MinObjectSizes: map[storj.PlacementConstraint]memory.Size{
+			coldPlacement: 2 * memory.KiB,
+		},
+	}, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		createPending := func(placement storj.PlacementConstraint, segments int) metabase.ObjectStream {
+			obj := metabasetest.RandObjectStream()
+			metabasetest.BeginObjectExactVersion{
+				Opts: metabase.BeginObjectExactVersion{
+					ObjectStream: obj,
+					Encryption:   metabasetest.DefaultEncryption,
+				},
+			}.Check(ctx, t, db)
+
+			for i := 0; i < segments; i++ {
+				metabasetest.CommitSegment{
+					Opts: metabase.CommitSegment{
+						ObjectStream: obj,
+						Position:     metabase.SegmentPosition{Index: uint32(i)},
+						RootPieceID:  testrand.PieceID(),
+						Pieces:       metabase.Pieces{{Number: 0, StorageNode: testrand.NodeID()}},
+						Placement:    placement,
+
+						EncryptedKey:      testrand.Bytes(32),
+						EncryptedKeyNonce: testrand.Bytes(32),
+
+						EncryptedSize: 1024,
+						PlainSize:     512,
+						Redundancy:    metabasetest.DefaultRedundancy,
+					},
+				}.Check(ctx, t, db)
+			}
+			return obj
+		}
+
+		t.Run("below minimum", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := createPending(coldPlacement, 1)
+			metabasetest.CommitObject{
+				Opts: metabase.CommitObject{
+					ObjectStream: obj,
+				},
+				ErrClass: &metabase.ErrFailedPrecondition,
+				ErrText:  "size of object is below minimum threshold of placement 5, got: 1.0 KiB, min: 2.0 KiB",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("at minimum", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := createPending(coldPlacement, 2)
+			metabasetest.CommitObject{
+				Opts: metabase.CommitObject{
+					ObjectStream: obj,
+				},
+				ExpectVersion: obj.Version,
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("placement without minimum", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := createPending(storj.DefaultPlacement, 1)
+			metabasetest.CommitObject{
+				Opts: metabase.CommitObject{
+					ObjectStream: obj,
+				},
+				ExpectVersion: obj.Version,
+			}.Check(ctx, t, db)
+		})
+	})
+}
+
 func TestCommitObjectWithIncorrectPartSize(t *testing.T) {
 	metabasetest.RunWithConfig(t, metabase.Config{
 		ApplicationName:  "satellite-test",
@@ -3685,6 +5159,13 @@ func TestCommitObjectWithIncorrectPartSize(t *testing.T) {
 				ErrText:  "size of part number 0 is below minimum threshold, got: 2.0 MiB, min: 5.0 MiB",
 			}.Check(ctx, t, db)
 
+			_, err := db.CommitObject(ctx, metabase.CommitObject{ObjectStream: obj})
+			var partTooSmall *metabase.ErrPartTooSmall
+			require.True(t, errors.As(err, &partTooSmall))
+			require.Equal(t, uint32(0), partTooSmall.Part)
+			require.Equal(t, 2*memory.MiB, partTooSmall.Size)
+			require.Equal(t, 5*memory.MiB, partTooSmall.MinSize)
+
 			metabasetest.Verify{
 				Objects: []metabase.RawObject{
 					{
@@ -4020,6 +5501,12 @@ func TestCommitObjectWithIncorrectAmountOfParts(t *testing.T) {
 				ErrText:  "exceeded maximum number of parts: 3",
 			}.Check(ctx, t, db)
 
+			_, err := db.CommitObject(ctx, metabase.CommitObject{ObjectStream: obj})
+			var tooManyParts *metabase.ErrTooManyParts
+			require.True(t, errors.As(err, &tooManyParts))
+			require.Equal(t, 4, tooManyParts.Parts)
+			require.Equal(t, 3, tooManyParts.MaxParts)
+
 			metabasetest.Verify{
 				Objects: []metabase.RawObject{
 					{
@@ -49,6 +49,16 @@ const MoveSegmentLimit = int64(10000)
 // CopySegmentLimit is the maximum number of segments that can be copied.
 const CopySegmentLimit = int64(10000)
 
+// MaxSystemLabelsSize is the maximum total size of system label keys and values in bytes.
+const MaxSystemLabelsSize = 1024
+
+// Limits of S3 compatible object tags. The lengths are counted in unicode characters.
+const (
+	MaxObjectTags           = 10
+	MaxObjectTagKeyLength   = 128
+	MaxObjectTagValueLength = 256
+)
+
 // batchsizeLimit specifies up to how many items fetch from the storage layer at
 // a time.
 //
@@ -350,6 +360,16 @@ func (obj *ObjectStream) Verify() error {
 	return nil
 }
 
+// VerifyObjectKeyCharacters checks that the object key doesn't contain control characters.
+func (obj *ObjectStream) VerifyObjectKeyCharacters() error {
+	for i := 0; i < len(obj.ObjectKey); i++ {
+		if b := obj.ObjectKey[i]; b < 0x20 || b == 0x7f {
+			return ErrInvalidRequest.New("ObjectKey contains control character 0x%02x at position %d", b, i)
+		}
+	}
+	return nil
+}
+
 // Location returns object location.
 func (obj *ObjectStream) Location() ObjectLocation {
 	return ObjectLocation{
@@ -756,6 +756,29 @@ func TestStreamVersionID(t *testing.T) {
 	require.Equal(t, expectedStreamID[8:], streamVersionID.StreamIDSuffix())
 }
 
+func TestObjectStreamVerifyObjectKeyCharacters(t *testing.T) {
+	for _, test := range []struct {
+		key     metabase.ObjectKey
+		errText string
+	}{
+		{key: "a/b/c.txt"},
+		{key: "emoji \U0001F600 and spaces"},
+		{key: "\x00key", errText: "ObjectKey contains control character 0x00 at position 0"},
+		{key: "a/b\nc", errText: "ObjectKey contains control character 0x0a at position 3"},
+		{key: "tab\t", errText: "ObjectKey contains control character 0x09 at position 3"},
+		{key: "del\x7f", errText: "ObjectKey contains control character 0x7f at position 3"},
+	} {
+		stream := metabase.ObjectStream{ObjectKey: test.key}
+		err := stream.VerifyObjectKeyCharacters()
+		if test.errText == "" {
+			require.NoError(t, err, "%q", test.key)
+			continue
+		}
+		require.True(t, metabase.ErrInvalidRequest.Has(err), "%q", test.key)
+		require.Equal(t, metabase.ErrInvalidRequest.New("%s", test.errText).Error(), err.Error())
+	}
+}
+
 func BenchmarkSegmentPieceSize(b *testing.B) {
 	segment := metabase.Segment{
 		EncryptedSize: 64 * memory.MiB.Int32(),
@@ -140,7 +140,8 @@ type transposedSegmentList struct {
 	InlineDatas [][]byte
 	PiecesLists [][]byte
 
-	Placements []storj.PlacementConstraint
+	Placements  []storj.PlacementConstraint
+	IsManifests []bool
 }
 
 // FinishCopyObject accepts new encryption keys for copied object and insert the corresponding new object ObjectKey and segments EncryptedKey.
@@ -248,6 +249,7 @@ func (ptx *postgresTransactionAdapter) getSegmentsForCopy(ctx context.Context, s
 	segments.PlainOffsets = make([]int64, sourceObject.SegmentCount)
 	segments.InlineDatas = make([][]byte, sourceObject.SegmentCount)
 	segments.Placements = make([]storj.PlacementConstraint, sourceObject.SegmentCount)
+	segments.IsManifests = make([]bool, sourceObject.SegmentCount)
 	segments.PiecesLists = make([][]byte, sourceObject.SegmentCount)
 
 	segments.RedundancySchemes = make([]int64, sourceObject.SegmentCount)
@@ -260,7 +262,7 @@ func (ptx *postgresTransactionAdapter) getSegmentsForCopy(ctx context.Context, s
 					encrypted_size, plain_offset, plain_size,
 					redundancy,
 					remote_alias_pieces,
-					placement,
+					placement, is_manifest,
 					inline_data
 				FROM segments
 				WHERE stream_id = $1
@@ -276,7 +278,7 @@ func (ptx *postgresTransactionAdapter) getSegmentsForCopy(ctx context.Context, s
 				&segments.EncryptedSizes[index], &segments.PlainOffsets[index], &segments.PlainSizes[index],
 				&segments.RedundancySchemes[index],
 				&segments.PiecesLists[index],
-				&segments.Placements[index],
+				&segments.Placements[index], &segments.IsManifests[index],
 				&segments.InlineDatas[index],
 			)
 			if err != nil {
@@ -309,6 +311,7 @@ func (stx *spannerTransactionAdapter) getSegmentsForCopy(ctx context.Context, so
 	segments.PlainOffsets = make([]int64, sourceObject.SegmentCount)
 	segments.InlineDatas = make([][]byte, sourceObject.SegmentCount)
 	segments.Placements = make([]storj.PlacementConstraint, sourceObject.SegmentCount)
+	segments.IsManifests = make([]bool, sourceObject.SegmentCount)
 	segments.PiecesLists = make([][]byte, sourceObject.SegmentCount)
 
 	segments.RedundancySchemes = make([]int64, sourceObject.SegmentCount)
@@ -323,7 +326,7 @@ func (stx *spannerTransactionAdapter) getSegmentsForCopy(ctx context.Context, so
 				encrypted_size, plain_offset, plain_size,
 				redundancy,
 				remote_alias_pieces,
-				placement,
+				placement, is_manifest,
 				COALESCE(inline_data, B'') AS inline_data
 			FROM segments
 			WHERE stream_id = @stream_id
@@ -342,7 +345,7 @@ func (stx *spannerTransactionAdapter) getSegmentsForCopy(ctx context.Context, so
 			spannerutil.Int(&segments.EncryptedSizes[index]), &segments.PlainOffsets[index], spannerutil.Int(&segments.PlainSizes[index]),
 			&segments.RedundancySchemes[index],
 			&segments.PiecesLists[index],
-			&segments.Placements[index],
+			&segments.Placements[index], &segments.IsManifests[index],
 			&segments.InlineDatas[index],
 		)
 		if err != nil {
@@ -405,7 +408,7 @@ func (ptx *postgresTransactionAdapter) finalizeObjectCopy(ctx context.Context, o
 				root_piece_id,
 				redundancy,
 				encrypted_size, plain_offset, plain_size,
-				remote_alias_pieces, placement,
+				remote_alias_pieces, placement, is_manifest,
 				inline_data
 			) SELECT
 				$1, UNNEST($2::INT8[]), UNNEST($3::timestamptz[]),
@@ -413,7 +416,7 @@ func (ptx *postgresTransactionAdapter) finalizeObjectCopy(ctx context.Context, o
 				UNNEST($6::BYTEA[]),
 				UNNEST($7::INT8[]),
 				UNNEST($8::INT4[]), UNNEST($9::INT8[]),	UNNEST($10::INT4[]),
-				UNNEST($11::BYTEA[]), UNNEST($12::INT2[]),
+				UNNEST($11::BYTEA[]), UNNEST($12::INT2[]), UNNEST($14::BOOL[]),
 				UNNEST($13::BYTEA[])
 		`, opts.NewStreamID, pgutil.Int8Array(newSegments.Positions), pgutil.NullTimestampTZArray(newSegments.ExpiresAts),
 		pgutil.ByteaArray(newSegments.EncryptedKeyNonces), pgutil.ByteaArray(newSegments.EncryptedKeys),
@@ -422,6 +425,7 @@ func (ptx *postgresTransactionAdapter) finalizeObjectCopy(ctx context.Context, o
 		pgutil.Int4Array(newSegments.EncryptedSizes), pgutil.Int8Array(newSegments.PlainOffsets), pgutil.Int4Array(newSegments.PlainSizes),
 		pgutil.ByteaArray(newSegments.PiecesLists), pgutil.PlacementConstraintArray(newSegments.Placements),
 		pgutil.ByteaArray(newSegments.InlineDatas),
+		pgutil.BoolArray(newSegments.IsManifests),
 	)
 	if err != nil {
 		return Object{}, Error.New("unable to copy segments: %w", err)
@@ -498,7 +502,7 @@ func (stx *spannerTransactionAdapter) finalizeObjectCopy(ctx context.Context, op
 				"root_piece_id",
 				"redundancy",
 				"encrypted_size", "plain_offset", "plain_size",
-				"remote_alias_pieces", "placement",
+				"remote_alias_pieces", "placement", "is_manifest",
 				"inline_data",
 			}, []any{
 				opts.NewStreamID, newSegments.Positions[i], newSegments.ExpiresAts[i],
@@ -506,7 +510,7 @@ func (stx *spannerTransactionAdapter) finalizeObjectCopy(ctx context.Context, op
 				newSegments.RootPieceIDs[i],
 				newSegments.RedundancySchemes[i],
 				int64(newSegments.EncryptedSizes[i]), newSegments.PlainOffsets[i], int64(newSegments.PlainSizes[i]),
-				newSegments.PiecesLists[i], int64(newSegments.Placements[i]),
+				newSegments.PiecesLists[i], int64(newSegments.Placements[i]), newSegments.IsManifests[i],
 				newSegments.InlineDatas[i],
 			},
 		)
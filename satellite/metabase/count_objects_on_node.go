@@ -0,0 +1,133 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"time"
+
+	"storj.io/common/storj"
+	"storj.io/common/uuid"
+)
+
+// CountObjectsOnNode contains arguments for CountObjectsOnNode.
+type CountObjectsOnNode struct {
+	NodeID storj.NodeID
+
+	// SampleFraction limits the scan to the given fraction of the stream ID space
+	// and extrapolates the counts from it. Stream IDs are random, so the sampled
+	// segments are representative. Zero or one scans all segments.
+	SampleFraction float64
+
+	BatchSize          int
+	AsOfSystemInterval time.Duration
+}
+
+// Verify verifies CountObjectsOnNode request fields.
+func (opts *CountObjectsOnNode) Verify() error {
+	switch {
+	case opts.NodeID.IsZero():
+		return ErrInvalidRequest.New("NodeID missing")
+	case opts.SampleFraction < 0 || opts.SampleFraction > 1:
+		return ErrInvalidRequest.New("SampleFraction must be between 0 and 1: %v", opts.SampleFraction)
+	case opts.BatchSize < 0:
+		return ErrInvalidRequest.New("BatchSize is negative")
+	}
+	return nil
+}
+
+// sampled returns whether only a part of the segments is scanned.
+func (opts *CountObjectsOnNode) sampled() bool {
+	return opts.SampleFraction > 0 && opts.SampleFraction < 1
+}
+
+// CountObjectsOnNodeResult contains the number of segments and objects with a piece on the node.
+type CountObjectsOnNodeResult struct {
+	Segments int64
+	Objects  int64
+
+	// Estimated is set when the counts are extrapolated from a sample.
+	Estimated bool
+}
+
+// CountObjectsOnNode counts the segments, which have a piece on the node, and the
+// objects they belong to. It's meant for estimating the impact of decommissioning a node.
+//
+// The pieces are stored in an encoded form, which can't be filtered by the database,
+// hence every segment is read, the same way as the segment loop does. On a large
+// satellite this takes hours, use SampleFraction to get an estimate instead.
+func (db *DB) CountObjectsOnNode(ctx context.Context, opts CountObjectsOnNode) (result CountObjectsOnNodeResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return CountObjectsOnNodeResult{}, err
+	}
+
+	aliasMap, err := db.aliasCache.Latest(ctx)
+	if err != nil {
+		return CountObjectsOnNodeResult{}, err
+	}
+	alias, ok := aliasMap.Alias(opts.NodeID)
+	if !ok {
+		// the node has never stored a piece.
+		return CountObjectsOnNodeResult{}, nil
+	}
+
+	iterateOpts := IterateLoopSegments{
+		BatchSize:          opts.BatchSize,
+		AsOfSystemInterval: opts.AsOfSystemInterval,
+	}
+	if opts.sampled() {
+		iterateOpts.EndStreamID = sampleEndStreamID(opts.SampleFraction)
+	}
+
+	err = db.IterateLoopSegments(ctx, iterateOpts, func(ctx context.Context, it LoopSegmentsIterator) error {
+		var entry LoopSegmentEntry
+		var lastStreamID uuid.UUID
+		for it.Next(ctx, &entry) {
+			if !containsAlias(entry.AliasPieces, alias) {
+				continue
+			}
+			result.Segments++
+			// segments are ordered by stream ID, so it's enough to compare with the previous one.
+			if entry.StreamID != lastStreamID {
+				result.Objects++
+				lastStreamID = entry.StreamID
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return CountObjectsOnNodeResult{}, Error.New("unable to count segments: %w", err)
+	}
+
+	if opts.sampled() {
+		result.Segments = int64(float64(result.Segments) / opts.SampleFraction)
+		result.Objects = int64(float64(result.Objects) / opts.SampleFraction)
+		result.Estimated = true
+	}
+	return result, nil
+}
+
+// containsAlias returns whether one of the pieces is on the node with the alias.
+func containsAlias(pieces AliasPieces, alias NodeAlias) bool {
+	for _, piece := range pieces {
+		if piece.Alias == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleEndStreamID returns the last stream ID of the first fraction of the stream ID space.
+func sampleEndStreamID(fraction float64) uuid.UUID {
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[:8], uint64(fraction*math.MaxUint64))
+	for i := 8; i < len(id); i++ {
+		id[i] = 0xff
+	}
+	return id
+}
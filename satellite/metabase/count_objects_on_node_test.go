@@ -0,0 +1,92 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/common/uuid"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestCountObjectsOnNode(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		nodeID := testrand.NodeID()
+
+		t.Run("invalid request", func(t *testing.T) {
+			for _, test := range []struct {
+				opts    metabase.CountObjectsOnNode
+				errText string
+			}{
+				{
+					opts:    metabase.CountObjectsOnNode{},
+					errText: "NodeID missing",
+				},
+				{
+					opts:    metabase.CountObjectsOnNode{NodeID: nodeID, SampleFraction: 2},
+					errText: "SampleFraction must be between 0 and 1: 2",
+				},
+				{
+					opts:    metabase.CountObjectsOnNode{NodeID: nodeID, BatchSize: -1},
+					errText: "BatchSize is negative",
+				},
+			} {
+				_, err := db.CountObjectsOnNode(ctx, test.opts)
+				require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+				require.ErrorContains(t, err, test.errText)
+			}
+		})
+
+		t.Run("unknown node", func(t *testing.T) {
+			result, err := db.CountObjectsOnNode(ctx, metabase.CountObjectsOnNode{NodeID: testrand.NodeID()})
+			require.NoError(t, err)
+			require.Zero(t, result)
+		})
+
+		t.Run("count", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			newSegment := func(streamID uuid.UUID, index uint32, nodes ...storj.NodeID) metabase.RawSegment {
+				stream := metabasetest.RandObjectStream()
+				stream.StreamID = streamID
+				segment := metabasetest.DefaultRawSegment(stream, metabase.SegmentPosition{Index: index})
+				segment.Pieces = nil
+				for i, node := range nodes {
+					segment.Pieces = append(segment.Pieces, metabase.Piece{Number: uint16(i), StorageNode: node})
+				}
+				return segment
+			}
+
+			otherNode := testrand.NodeID()
+			first := uuid.UUID{0x10}
+			second := uuid.UUID{0x20}
+			third := uuid.UUID{0xf0}
+			require.NoError(t, db.TestingBatchInsertSegments(ctx, []metabase.RawSegment{
+				newSegment(first, 0, nodeID, otherNode),
+				newSegment(first, 1, otherNode, nodeID),
+				newSegment(second, 0, otherNode),
+				newSegment(third, 0, nodeID),
+			}))
+
+			result, err := db.CountObjectsOnNode(ctx, metabase.CountObjectsOnNode{NodeID: nodeID})
+			require.NoError(t, err)
+			require.Equal(t, metabase.CountObjectsOnNodeResult{Segments: 3, Objects: 2}, result)
+
+			result, err = db.CountObjectsOnNode(ctx, metabase.CountObjectsOnNode{NodeID: otherNode, BatchSize: 1})
+			require.NoError(t, err)
+			require.Equal(t, metabase.CountObjectsOnNodeResult{Segments: 3, Objects: 2}, result)
+
+			// only the first half of the stream IDs is scanned.
+			result, err = db.CountObjectsOnNode(ctx, metabase.CountObjectsOnNode{NodeID: nodeID, SampleFraction: 0.5})
+			require.NoError(t, err)
+			require.Equal(t, metabase.CountObjectsOnNodeResult{Segments: 4, Objects: 2, Estimated: true}, result)
+		})
+	})
+}
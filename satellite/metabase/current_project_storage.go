@@ -0,0 +1,85 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/spannerutil"
+)
+
+// ProjectStorage contains the instantaneous storage footprint of a project.
+type ProjectStorage struct {
+	SegmentCount       int64
+	TotalEncryptedSize int64
+}
+
+// CurrentProjectStorage returns the summed total encrypted size and segment count
+// of all committed, non-expired objects in the project.
+//
+// The result is the footprint at the time of the query, it's not time-integrated
+// usage (byte-hours) as computed by accounting tallies and rollups.
+func (db *DB) CurrentProjectStorage(ctx context.Context, projectID uuid.UUID) (storage ProjectStorage, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if projectID.IsZero() {
+		return ProjectStorage{}, ErrInvalidRequest.New("ProjectID missing")
+	}
+
+	return db.ChooseAdapter(projectID).CurrentProjectStorage(ctx, projectID)
+}
+
+// CurrentProjectStorage returns the current storage footprint of the project.
+func (p *PostgresAdapter) CurrentProjectStorage(ctx context.Context, projectID uuid.UUID) (storage ProjectStorage, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// project_id is the leading column of the primary key, hence only the
+	// project's range of objects is scanned.
+	err = p.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(segment_count), 0),
+			COALESCE(SUM(total_encrypted_size), 0)
+		FROM objects
+		WHERE
+			project_id = $1 AND
+			status IN `+statusesCommitted+` AND
+			(expires_at IS NULL OR expires_at > now())
+	`, projectID).Scan(&storage.SegmentCount, &storage.TotalEncryptedSize)
+	if err != nil {
+		return ProjectStorage{}, Error.New("unable to query project storage: %w", err)
+	}
+	return storage, nil
+}
+
+// CurrentProjectStorage returns the current storage footprint of the project.
+func (s *SpannerAdapter) CurrentProjectStorage(ctx context.Context, projectID uuid.UUID) (storage ProjectStorage, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// project_id is the leading column of the primary key, hence only the
+	// project's range of objects is scanned.
+	storage, err = spannerutil.CollectRow(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				COALESCE(SUM(segment_count), 0),
+				COALESCE(SUM(total_encrypted_size), 0)
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				status IN ` + statusesCommitted + ` AND
+				(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		`,
+		Params: map[string]interface{}{
+			"project_id": projectID,
+		},
+	}), func(row *spanner.Row, storage *ProjectStorage) error {
+		return Error.Wrap(row.Columns(&storage.SegmentCount, &storage.TotalEncryptedSize))
+	})
+	if err != nil {
+		return ProjectStorage{}, Error.New("unable to query project storage: %w", err)
+	}
+	return storage, nil
+}
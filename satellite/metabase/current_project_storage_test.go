@@ -0,0 +1,64 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/common/uuid"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestCurrentProjectStorage(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		now := time.Now()
+
+		t.Run("invalid request", func(t *testing.T) {
+			_, err := db.CurrentProjectStorage(ctx, uuid.UUID{})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "ProjectID missing")
+		})
+
+		t.Run("empty project", func(t *testing.T) {
+			storage, err := db.CurrentProjectStorage(ctx, testrand.UUID())
+			require.NoError(t, err)
+			require.Zero(t, storage)
+		})
+
+		t.Run("storage", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID := testrand.UUID()
+			newObject := func(projectID uuid.UUID) metabase.ObjectStream {
+				obj := metabasetest.RandObjectStream()
+				obj.ProjectID = projectID
+				return obj
+			}
+
+			// committed.
+			metabasetest.CreateObject(ctx, t, db, newObject(projectID), 2)
+			// expires later.
+			metabasetest.CreateExpiredObject(ctx, t, db, newObject(projectID), 3, now.Add(time.Hour))
+			// already expired, but not deleted yet.
+			metabasetest.CreateExpiredObject(ctx, t, db, newObject(projectID), 4, now.Add(-time.Hour))
+			// pending.
+			metabasetest.CreatePendingObject(ctx, t, db, newObject(projectID), 5)
+			// different project.
+			metabasetest.CreateObject(ctx, t, db, newObject(testrand.UUID()), 6)
+
+			storage, err := db.CurrentProjectStorage(ctx, projectID)
+			require.NoError(t, err)
+			require.Equal(t, metabase.ProjectStorage{
+				SegmentCount:       5,
+				TotalEncryptedSize: 5 * 1024,
+			}, storage)
+		})
+	})
+}
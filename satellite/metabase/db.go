@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -22,6 +23,7 @@ import (
 	"go.uber.org/zap"
 
 	"storj.io/common/memory"
+	"storj.io/common/storj"
 	"storj.io/common/uuid"
 	"storj.io/storj/private/logging"
 	"storj.io/storj/private/migrate"
@@ -48,11 +50,107 @@ type Config struct {
 
 	NodeAliasCacheFullRefresh bool
 
+	// RejectControlCharactersInKeys makes uploads fail when the object key
+	// contains ASCII control characters. Object keys are usually encrypted,
+	// so this should only be enabled when existing data has been checked.
+	RejectControlCharactersInKeys bool
+
+	// ValidateSegmentSize makes CommitSegment check that the encrypted size and
+	// the pieces are consistent with the redundancy scheme. Legacy uplinks may
+	// not satisfy the check.
+	ValidateSegmentSize bool
+
+	// ValidateListedEncryption makes ListObjects fail, when a listed object has
+	// encryption parameters with an unknown cipher suite or a non-positive block size.
+	// Legacy objects may have such parameters, so it's disabled by default.
+	ValidateListedEncryption bool
+
+	// ZombieDeletionPeriods overrides, per project, how long a pending object
+	// is kept when BeginObject doesn't specify a zombie deletion deadline.
+	// Projects without an override use the default period of 24h.
+	ZombieDeletionPeriods map[uuid.UUID]time.Duration
+
+	// MinObjectSizes makes CommitObject fail, when the object has segments in
+	// a placement and its total encrypted size is below the placement's minimum.
+	// Placements without an entry have no minimum.
+	MinObjectSizes map[storj.PlacementConstraint]memory.Size
+
+	// ListObjectsLimit is the maximum Limit of ListObjects. Zero means ListLimit,
+	// which is also the largest allowed value.
+	ListObjectsLimit int
+	// RejectListObjectsOverLimit makes ListObjects fail with ErrInvalidRequest,
+	// instead of clamping the limit, when Limit exceeds ListObjectsLimit.
+	RejectListObjectsOverLimit bool
+
+	// MaxVersionsPerKey makes CommitObject delete the oldest versions of the object
+	// key, such that at most MaxVersionsPerKey committed versions and delete markers
+	// remain. Versions under Object Lock are never deleted. Zero disables pruning.
+	MaxVersionsPerKey int
+
+	// BucketLockDefaults resolves the default Object Lock configuration of a bucket.
+	// BeginObjectNextVersion applies it when the request specifies neither
+	// Retention nor LegalHold. A nil resolver means that buckets have no defaults.
+	BucketLockDefaults BucketLockDefaultsFunc
+
 	TestingUniqueUnversioned   bool
 	TestingCommitSegmentMode   string
 	TestingPrecommitDeleteMode int
 }
 
+// Validate checks that the part validation settings are consistent with each other.
+func (config Config) Validate() error {
+	switch {
+	case config.MinPartSize < 0:
+		return Error.New("MinPartSize is negative: %d", config.MinPartSize)
+	case config.MaxNumberOfParts < 0:
+		return Error.New("MaxNumberOfParts is negative: %d", config.MaxNumberOfParts)
+	case int64(config.MaxNumberOfParts) > math.MaxUint32+1:
+		// part numbers are stored as uint32 in SegmentPosition.
+		return Error.New("MaxNumberOfParts %d exceeds the number of possible part numbers", config.MaxNumberOfParts)
+	case config.MinPartSize > 0 && config.MaxNumberOfParts == 0:
+		return Error.New("MinPartSize is set to %s, but MaxNumberOfParts is zero", config.MinPartSize)
+	case config.MaxNumberOfParts > 0 && int64(config.MinPartSize) > math.MaxInt64/int64(config.MaxNumberOfParts):
+		return Error.New("MinPartSize %s times MaxNumberOfParts %d overflows", config.MinPartSize, config.MaxNumberOfParts)
+	case config.ListObjectsLimit < 0:
+		return Error.New("ListObjectsLimit is negative: %d", config.ListObjectsLimit)
+	case config.ListObjectsLimit > ListLimit.Max():
+		return Error.New("ListObjectsLimit %d exceeds the maximum %d", config.ListObjectsLimit, ListLimit.Max())
+	case config.MaxVersionsPerKey < 0:
+		return Error.New("MaxVersionsPerKey is negative: %d", config.MaxVersionsPerKey)
+	}
+	for projectID, period := range config.ZombieDeletionPeriods {
+		if period <= 0 {
+			return Error.New("ZombieDeletionPeriod for project %s is not positive: %s", projectID, period)
+		}
+	}
+	for placement, size := range config.MinObjectSizes {
+		if size < 0 {
+			return Error.New("MinObjectSize for placement %d is negative: %s", placement, size)
+		}
+	}
+	return nil
+}
+
+// listObjectsLimit returns the effective maximum Limit of ListObjects.
+func (config Config) listObjectsLimit() int {
+	if config.ListObjectsLimit > 0 {
+		return config.ListObjectsLimit
+	}
+	return ListLimit.Max()
+}
+
+// zombieDeletionPeriod returns the zombie deletion period for pending objects of the project.
+func (config Config) zombieDeletionPeriod(projectID uuid.UUID) time.Duration {
+	if period, ok := config.ZombieDeletionPeriods[projectID]; ok {
+		return period
+	}
+	return defaultZombieDeletionPeriod
+}
+
+// BucketLockDefaultsFunc returns the default retention and legal hold for new
+// objects in the bucket.
+type BucketLockDefaultsFunc func(ctx context.Context, projectID uuid.UUID, bucketName string) (retention Retention, legalHold bool, err error)
+
 const commitSegmentModeTransaction = "transaction"
 const commitSegmentModeNoCheck = "no-pending-object-check"
 
@@ -69,11 +167,18 @@ type DB struct {
 
 	config Config
 
+	// nowFn returns the current time, it's replaced in tests.
+	nowFn func() time.Time
+
 	adapters []Adapter
 }
 
 // Open opens a connection to metabase.
 func Open(ctx context.Context, log *zap.Logger, connstr string, config Config) (*DB, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	var driverName string
 	_, source, impl, err := dbutil.SplitConnStr(connstr)
 	if err != nil {
@@ -112,6 +217,7 @@ func Open(ctx context.Context, log *zap.Logger, connstr string, config Config) (
 		impl:        impl,
 		testCleanup: func() error { return nil },
 		config:      config,
+		nowFn:       time.Now,
 	}
 	db.aliasCache = NewNodeAliasCache(db, config.NodeAliasCacheFullRefresh)
 	switch impl {
@@ -141,6 +247,11 @@ func Open(ctx context.Context, log *zap.Logger, connstr string, config Config) (
 		return nil, Error.New("unsupported implementation: %s", connstr)
 	}
 
+	// the pools of postgres and cockroach are already exported as db_stats by dbutil.Configure.
+	if rawdb == nil {
+		registerPoolStats(db)
+	}
+
 	if log.Level() == zap.DebugLevel {
 		log.Debug("Connected", zap.String("db source", logging.Redacted(connstr)))
 	}
@@ -199,8 +310,28 @@ func (db *DB) TestingSetCleanup(cleanup func() error) {
 	db.testCleanup = cleanup
 }
 
+// verifyObjectKeyCharacters checks the object key for control characters, when enabled in the config.
+func (db *DB) verifyObjectKeyCharacters(obj ObjectStream) error {
+	if !db.config.RejectControlCharactersInKeys {
+		return nil
+	}
+	return obj.VerifyObjectKeyCharacters()
+}
+
+// ListObjectsLimit returns the maximum Limit accepted by ListObjects.
+func (db *DB) ListObjectsLimit() int {
+	return db.config.listObjectsLimit()
+}
+
+// TestingSetNow is used to override the current time used for verifying requests.
+func (db *DB) TestingSetNow(nowFn func() time.Time) {
+	db.nowFn = nowFn
+}
+
 // Close closes the connection to database.
 func (db *DB) Close() error {
+	unregisterPoolStats(db)
+
 	var err error
 	if db.db != nil {
 		err = Error.Wrap(db.db.Close())
@@ -272,7 +403,7 @@ func (db *DB) TestMigrateToLatest(ctx context.Context) error {
 			{
 				DB:          &db.db,
 				Description: "Test snapshot",
-				Version:     20,
+				Version:     25,
 				Action: migrate.SQL{
 					`CREATE TABLE objects (
 						project_id   BYTEA NOT NULL,
@@ -302,6 +433,12 @@ func (db *DB) TestMigrateToLatest(ctx context.Context) error {
 						retention_mode INT2,
 						retain_until   TIMESTAMPTZ,
 
+						system_labels JSONB,
+
+						computed_etag BYTEA,
+
+						object_tags JSONB,
+
 						PRIMARY KEY (project_id, bucket_name, object_key, version)
 					);
 
@@ -330,9 +467,15 @@ func (db *DB) TestMigrateToLatest(ctx context.Context) error {
 
 					COMMENT ON COLUMN objects.zombie_deletion_deadline is 'zombie_deletion_deadline defines when a pending object can be deleted due to a failed upload.';
 
-					COMMENT ON COLUMN objects.retention_mode is 'retention_mode specifies an object version''s retention mode: NULL/0=none, and 1=compliance.';
+					COMMENT ON COLUMN objects.retention_mode is 'retention_mode specifies an object version''s retention mode and legal hold: the lowest two bits contain the retention mode, where 0=none and 1=compliance, and the bit with value 4 is set when the object version is under legal hold. NULL is the same as 0.';
 					COMMENT ON COLUMN objects.retain_until   is 'retain_until specifies when an object version''s retention period ends.';
 
+					COMMENT ON COLUMN objects.system_labels is 'system_labels contains server-side key-value labels, which are stored outside of the encrypted metadata.';
+
+					COMMENT ON COLUMN objects.computed_etag is 'computed_etag is the S3 compatible ETag computed by the gateway.';
+
+					COMMENT ON COLUMN objects.object_tags is 'object_tags contains the S3 compatible key-value tags of the object version.';
+
 					CREATE TABLE segments (
 						stream_id  BYTEA NOT NULL,
 						position   INT8  NOT NULL,
@@ -357,6 +500,8 @@ func (db *DB) TestMigrateToLatest(ctx context.Context) error {
 						placement integer,
 						encrypted_etag BYTEA default NULL,
 
+						is_manifest BOOL NOT NULL default false,
+
 						PRIMARY KEY (stream_id, position)
 					);
 
@@ -384,6 +529,8 @@ func (db *DB) TestMigrateToLatest(ctx context.Context) error {
 					COMMENT ON COLUMN segments.placement is 'placement is the country or region restriction for the segment data. See storj.PlacementConstraint for the values.';
 					COMMENT ON COLUMN segments.encrypted_etag is 'encrypted_etag is etag that has been encrypted.';
 
+					COMMENT ON COLUMN segments.is_manifest is 'is_manifest marks the segment, which contains the index of the object. At most one segment of a committed object is marked.';
+
 					CREATE SEQUENCE node_alias_seq
 						INCREMENT BY 1
 						MINVALUE 1 MAXVALUE 2147483647 -- MaxInt32
@@ -406,7 +553,7 @@ func (db *DB) TestMigrateToLatest(ctx context.Context) error {
 		migration.Steps = append(migration.Steps, &migrate.Step{
 			DB:          &db.db,
 			Description: "Constraint for ensuring our metabase correctness.",
-			Version:     21,
+			Version:     26,
 			Action: migrate.SQL{
 				`CREATE UNIQUE INDEX objects_one_unversioned_per_location ON objects (project_id, bucket_name, object_key) WHERE status IN ` + statusesUnversioned + `;`,
 			},
@@ -808,6 +955,50 @@ func (db *DB) PostgresMigration() *migrate.Migration {
 					`DROP TABLE IF EXISTS segment_copies`,
 				},
 			},
+			{
+				DB:          &db.db,
+				Description: "add system_labels column to objects table",
+				Version:     21,
+				Action: migrate.SQL{
+					`ALTER TABLE objects ADD COLUMN system_labels JSONB`,
+					`COMMENT ON COLUMN objects.system_labels is 'system_labels contains server-side key-value labels, which are stored outside of the encrypted metadata.';`,
+				},
+			},
+			{
+				DB:          &db.db,
+				Description: "add computed_etag column to objects table",
+				Version:     22,
+				Action: migrate.SQL{
+					`ALTER TABLE objects ADD COLUMN computed_etag BYTEA`,
+					`COMMENT ON COLUMN objects.computed_etag is 'computed_etag is the S3 compatible ETag computed by the gateway.';`,
+				},
+			},
+			{
+				DB:          &db.db,
+				Description: "add object_tags column to objects table",
+				Version:     23,
+				Action: migrate.SQL{
+					`ALTER TABLE objects ADD COLUMN object_tags JSONB`,
+					`COMMENT ON COLUMN objects.object_tags is 'object_tags contains the S3 compatible key-value tags of the object version.';`,
+				},
+			},
+			{
+				DB:          &db.db,
+				Description: "add is_manifest column to segments table",
+				Version:     24,
+				Action: migrate.SQL{
+					`ALTER TABLE segments ADD COLUMN is_manifest BOOL NOT NULL DEFAULT false`,
+					`COMMENT ON COLUMN segments.is_manifest is 'is_manifest marks the segment, which contains the index of the object. At most one segment of a committed object is marked.';`,
+				},
+			},
+			{
+				DB:          &db.db,
+				Description: "describe legal hold in objects.retention_mode comment",
+				Version:     25,
+				Action: migrate.SQL{
+					`COMMENT ON COLUMN objects.retention_mode is 'retention_mode specifies an object version''s retention mode and legal hold: the lowest two bits contain the retention mode, where 0=none and 1=compliance, and the bit with value 4 is set when the object version is under legal hold. NULL is the same as 0.';`,
+				},
+			},
 		},
 	}
 }
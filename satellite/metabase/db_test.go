@@ -10,14 +10,111 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"storj.io/common/memory"
+	"storj.io/common/storj"
 	"storj.io/common/testcontext"
 	"storj.io/common/testrand"
+	"storj.io/common/uuid"
 	"storj.io/storj/satellite/metabase"
 	"storj.io/storj/satellite/metabase/metabasetest"
 	"storj.io/storj/shared/dbutil"
 	"storj.io/storj/shared/dbutil/pgutil/pgerrcode"
 )
 
+func TestConfigValidate(t *testing.T) {
+	projectID := testrand.UUID()
+
+	for _, tc := range []struct {
+		name    string
+		config  metabase.Config
+		errText string
+	}{
+		{
+			name:   "empty",
+			config: metabase.Config{},
+		},
+		{
+			name:   "defaults",
+			config: metabase.Config{MinPartSize: 5 * memory.MiB, MaxNumberOfParts: 10000},
+		},
+		{
+			name:   "no minimum part size",
+			config: metabase.Config{MaxNumberOfParts: 10000},
+		},
+		{
+			name:    "negative MinPartSize",
+			config:  metabase.Config{MinPartSize: -1, MaxNumberOfParts: 10000},
+			errText: "metabase: MinPartSize is negative: -1",
+		},
+		{
+			name:    "negative MaxNumberOfParts",
+			config:  metabase.Config{MinPartSize: 5 * memory.MiB, MaxNumberOfParts: -1},
+			errText: "metabase: MaxNumberOfParts is negative: -1",
+		},
+		{
+			name:    "too many parts",
+			config:  metabase.Config{MaxNumberOfParts: 1 << 33},
+			errText: "metabase: MaxNumberOfParts 8589934592 exceeds the number of possible part numbers",
+		},
+		{
+			name:    "no parts allowed",
+			config:  metabase.Config{MinPartSize: 5 * memory.MiB},
+			errText: "metabase: MinPartSize is set to 5.0 MiB, but MaxNumberOfParts is zero",
+		},
+		{
+			name:    "overflow",
+			config:  metabase.Config{MinPartSize: 1 << 40, MaxNumberOfParts: 1 << 30},
+			errText: "metabase: MinPartSize 1.0 TiB times MaxNumberOfParts 1073741824 overflows",
+		},
+		{
+			name:   "ListObjectsLimit",
+			config: metabase.Config{ListObjectsLimit: 100, RejectListObjectsOverLimit: true},
+		},
+		{
+			name:    "negative ListObjectsLimit",
+			config:  metabase.Config{ListObjectsLimit: -1},
+			errText: "metabase: ListObjectsLimit is negative: -1",
+		},
+		{
+			name:    "ListObjectsLimit too large",
+			config:  metabase.Config{ListObjectsLimit: 1001},
+			errText: "metabase: ListObjectsLimit 1001 exceeds the maximum 1000",
+		},
+		{
+			name:    "negative MaxVersionsPerKey",
+			config:  metabase.Config{MaxVersionsPerKey: -1},
+			errText: "metabase: MaxVersionsPerKey is negative: -1",
+		},
+		{
+			name:   "zombie deletion period",
+			config: metabase.Config{ZombieDeletionPeriods: map[uuid.UUID]time.Duration{projectID: 72 * time.Hour}},
+		},
+		{
+			name:    "zombie deletion period not positive",
+			config:  metabase.Config{ZombieDeletionPeriods: map[uuid.UUID]time.Duration{projectID: 0}},
+			errText: "metabase: ZombieDeletionPeriod for project " + projectID.String() + " is not positive: 0s",
+		},
+		{
+			name:   "MinObjectSizes",
+			config: metabase.Config{MinObjectSizes: map[storj.PlacementConstraint]memory.Size{5: memory.MiB}},
+		},
+		{
+			name:    "negative MinObjectSize",
+			config:  metabase.Config{MinObjectSizes: map[storj.PlacementConstraint]memory.Size{5: -1}},
+			errText: "metabase: MinObjectSize for placement 5 is negative: -1 B",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.errText == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.errText)
+		})
+	}
+}
+
 func TestNow(t *testing.T) {
 	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
 		sysnow := time.Now()
@@ -0,0 +1,335 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/pgutil"
+	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
+)
+
+// DeleteExpiredProjectObjects contains arguments necessary for deleting a batch of
+// expired object versions of a project.
+type DeleteExpiredProjectObjects struct {
+	ProjectID uuid.UUID
+	// Now is the time against which the expirations and retention periods are compared.
+	Now time.Time
+	// Cursor is the object version after which the deletion starts.
+	Cursor ExpiredProjectObjectsCursor
+	// BatchSize is the number of expired object versions examined.
+	BatchSize int
+}
+
+// ExpiredProjectObjectsCursor is a position within a project.
+type ExpiredProjectObjectsCursor struct {
+	BucketName string
+	ObjectKey  ObjectKey
+	Version    Version
+}
+
+// DeletedExpiredObject is an expired object version deleted by DeleteExpiredProjectObjects.
+type DeletedExpiredObject struct {
+	ObjectStream
+
+	SegmentCount       int32
+	TotalEncryptedSize int64
+}
+
+// DeleteExpiredProjectObjectsResult is the result of DeleteExpiredProjectObjects.
+type DeleteExpiredProjectObjectsResult struct {
+	Objects []DeletedExpiredObject
+	// Orphaned contains the remote segments of the deleted object versions,
+	// whose pieces should be collected from the storage nodes.
+	Orphaned []OrphanedSegment
+
+	// NextCursor should be used as the cursor of the next request, when More is set.
+	NextCursor ExpiredProjectObjectsCursor
+	More       bool
+}
+
+type deleteExpiredProjectObjectsTransactionAdapter interface {
+	scanExpiredProjectObjects(ctx context.Context, opts DeleteExpiredProjectObjects) (last ExpiredProjectObjectsCursor, scanned int, err error)
+	deleteExpiredProjectObjects(ctx context.Context, opts DeleteExpiredProjectObjects, last ExpiredProjectObjectsCursor) (deleted []DeletedExpiredObject, segments []orphanedAliasSegment, err error)
+}
+
+// Verify verifies delete expired project objects request fields.
+func (opts *DeleteExpiredProjectObjects) Verify() error {
+	switch {
+	case opts.ProjectID.IsZero():
+		return ErrInvalidRequest.New("ProjectID missing")
+	case opts.Now.IsZero():
+		return ErrInvalidRequest.New("Now missing")
+	case opts.BatchSize < 0:
+		return ErrInvalidRequest.New("BatchSize is negative: %d", opts.BatchSize)
+	}
+	return nil
+}
+
+// DeleteExpiredProjectObjects deletes the object versions of a project, which expired before
+// opts.Now, together with their segments. Up to BatchSize expired object versions following the
+// cursor are examined and deleted in a single transaction. Versions under legal hold or with an
+// active retention period are skipped.
+//
+// The result may contain fewer objects than BatchSize, even when there are more expired
+// versions, hence the deletion should continue with NextCursor while More is set.
+func (db *DB) DeleteExpiredProjectObjects(ctx context.Context, opts DeleteExpiredProjectObjects) (result DeleteExpiredProjectObjectsResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return DeleteExpiredProjectObjectsResult{}, err
+	}
+	deleteBatchsizeLimit.Ensure(&opts.BatchSize)
+
+	var segments []orphanedAliasSegment
+	err = db.ChooseAdapter(opts.ProjectID).WithTx(ctx, func(ctx context.Context, adapter TransactionAdapter) error {
+		// the results are reset, because the transaction may be retried.
+		result, segments = DeleteExpiredProjectObjectsResult{}, nil
+
+		last, scanned, err := adapter.scanExpiredProjectObjects(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if scanned == 0 {
+			return nil
+		}
+		if scanned == opts.BatchSize {
+			result.NextCursor = last
+			result.More = true
+		}
+
+		result.Objects, segments, err = adapter.deleteExpiredProjectObjects(ctx, opts, last)
+		return err
+	})
+	if err != nil {
+		return DeleteExpiredProjectObjectsResult{}, err
+	}
+
+	for _, segment := range segments {
+		// inline segments don't have any pieces to delete.
+		if len(segment.AliasPieces) == 0 {
+			continue
+		}
+
+		pieces, err := db.aliasCache.ConvertAliasesToPieces(ctx, segment.AliasPieces)
+		if err != nil {
+			return DeleteExpiredProjectObjectsResult{}, Error.Wrap(err)
+		}
+
+		result.Orphaned = append(result.Orphaned, OrphanedSegment{
+			StreamID:    segment.StreamID,
+			Position:    segment.Position,
+			RootPieceID: segment.RootPieceID,
+			Pieces:      pieces,
+		})
+	}
+
+	mon.Meter("object_delete").Mark(len(result.Objects))
+	mon.Meter("segment_delete").Mark(len(segments))
+
+	return result, nil
+}
+
+// scanExpiredProjectObjects returns the last of up to opts.BatchSize expired object versions after the cursor.
+func (ptx *postgresTransactionAdapter) scanExpiredProjectObjects(ctx context.Context, opts DeleteExpiredProjectObjects) (last ExpiredProjectObjectsCursor, scanned int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = withRows(ptx.tx.QueryContext(ctx, `
+		SELECT bucket_name, object_key, version
+		FROM objects
+		WHERE
+			project_id = $1 AND
+			(bucket_name, object_key, version) > ($2, $3, $4) AND
+			expires_at < $5
+		ORDER BY project_id, bucket_name, object_key, version
+		LIMIT $6
+	`, opts.ProjectID, []byte(opts.Cursor.BucketName), opts.Cursor.ObjectKey, opts.Cursor.Version,
+		opts.Now, opts.BatchSize))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			if err := rows.Scan(&last.BucketName, &last.ObjectKey, &last.Version); err != nil {
+				return Error.Wrap(err)
+			}
+			scanned++
+		}
+		return nil
+	})
+	if err != nil {
+		return ExpiredProjectObjectsCursor{}, 0, Error.New("unable to query expired objects: %w", err)
+	}
+	return last, scanned, nil
+}
+
+func (stx *spannerTransactionAdapter) scanExpiredProjectObjects(ctx context.Context, opts DeleteExpiredProjectObjects) (last ExpiredProjectObjectsCursor, scanned int, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	keys, err := spannerutil.CollectRows(stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT bucket_name, object_key, version
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				` + TupleGreaterThanSQL(
+			[]string{"bucket_name", "object_key", "version"},
+			[]string{"@bucket_name", "@object_key", "@version"}, false) + ` AND
+				expires_at < @now
+			ORDER BY project_id, bucket_name, object_key, version
+			LIMIT @batch_size
+		`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.Cursor.BucketName,
+			"object_key":  opts.Cursor.ObjectKey,
+			"version":     opts.Cursor.Version,
+			"now":         opts.Now,
+			"batch_size":  int64(opts.BatchSize),
+		},
+	}), func(row *spanner.Row, key *ExpiredProjectObjectsCursor) error {
+		return Error.Wrap(row.Columns(&key.BucketName, &key.ObjectKey, &key.Version))
+	})
+	if err != nil {
+		return ExpiredProjectObjectsCursor{}, 0, Error.New("unable to query expired objects: %w", err)
+	}
+	if len(keys) == 0 {
+		return ExpiredProjectObjectsCursor{}, 0, nil
+	}
+	return keys[len(keys)-1], len(keys), nil
+}
+
+// deleteExpiredProjectObjects deletes the expired object versions between the cursor and last,
+// which aren't under Object Lock, and their segments.
+func (ptx *postgresTransactionAdapter) deleteExpiredProjectObjects(ctx context.Context, opts DeleteExpiredProjectObjects, last ExpiredProjectObjectsCursor) (deleted []DeletedExpiredObject, segments []orphanedAliasSegment, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = withRows(ptx.tx.QueryContext(ctx, `
+		DELETE FROM objects
+		WHERE
+			project_id = $1 AND
+			(bucket_name, object_key, version) > ($2, $3, $4) AND
+			(bucket_name, object_key, version) <= ($5, $6, $7) AND
+			expires_at < $8 AND
+			NOT (
+				(COALESCE(retention_mode, 0) & `+retentionModeMaskSQL+` <> 0 AND COALESCE(retain_until > $8, false)) OR
+				COALESCE(retention_mode, 0) & `+legalHoldFlagSQL+` <> 0
+			)
+		RETURNING bucket_name, object_key, version, stream_id, segment_count, total_encrypted_size
+	`, opts.ProjectID,
+		[]byte(opts.Cursor.BucketName), opts.Cursor.ObjectKey, opts.Cursor.Version,
+		[]byte(last.BucketName), last.ObjectKey, last.Version,
+		opts.Now))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			object := DeletedExpiredObject{ObjectStream: ObjectStream{ProjectID: opts.ProjectID}}
+			if err := rows.Scan(
+				&object.BucketName, &object.ObjectKey, &object.Version, &object.StreamID,
+				&object.SegmentCount, &object.TotalEncryptedSize,
+			); err != nil {
+				return Error.Wrap(err)
+			}
+			deleted = append(deleted, object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, Error.New("unable to delete expired objects: %w", err)
+	}
+	if len(deleted) == 0 {
+		return nil, nil, nil
+	}
+
+	streamIDs := make([]uuid.UUID, len(deleted))
+	for i, object := range deleted {
+		streamIDs[i] = object.StreamID
+	}
+
+	err = withRows(ptx.tx.QueryContext(ctx, `
+		DELETE FROM segments
+		WHERE stream_id = ANY($1)
+		RETURNING stream_id, position, root_piece_id, remote_alias_pieces
+	`, pgutil.UUIDArray(streamIDs)))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			var segment orphanedAliasSegment
+			if err := rows.Scan(&segment.StreamID, &segment.Position, &segment.RootPieceID, &segment.AliasPieces); err != nil {
+				return Error.Wrap(err)
+			}
+			segments = append(segments, segment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, Error.New("unable to delete segments: %w", err)
+	}
+	return deleted, segments, nil
+}
+
+func (stx *spannerTransactionAdapter) deleteExpiredProjectObjects(ctx context.Context, opts DeleteExpiredProjectObjects, last ExpiredProjectObjectsCursor) (deleted []DeletedExpiredObject, segments []orphanedAliasSegment, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	deleted, err = spannerutil.CollectRows(stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			DELETE FROM objects
+			WHERE
+				project_id = @project_id AND
+				` + TupleGreaterThanSQL(
+			[]string{"bucket_name", "object_key", "version"},
+			[]string{"@bucket_name", "@object_key", "@version"}, false) + ` AND
+				` + TupleGreaterThanSQL(
+			[]string{"@last_bucket_name", "@last_object_key", "@last_version"},
+			[]string{"bucket_name", "object_key", "version"}, true) + ` AND
+				expires_at < @now AND
+				NOT (
+					(COALESCE(retention_mode, 0) & ` + retentionModeMaskSQL + ` <> 0 AND COALESCE(retain_until > @now, FALSE)) OR
+					COALESCE(retention_mode, 0) & ` + legalHoldFlagSQL + ` <> 0
+				)
+			THEN RETURN bucket_name, object_key, version, stream_id, segment_count, total_encrypted_size
+		`,
+		Params: map[string]interface{}{
+			"project_id":       opts.ProjectID,
+			"bucket_name":      opts.Cursor.BucketName,
+			"object_key":       opts.Cursor.ObjectKey,
+			"version":          opts.Cursor.Version,
+			"last_bucket_name": last.BucketName,
+			"last_object_key":  last.ObjectKey,
+			"last_version":     last.Version,
+			"now":              opts.Now,
+		},
+	}), func(row *spanner.Row, object *DeletedExpiredObject) error {
+		object.ProjectID = opts.ProjectID
+		return Error.Wrap(row.Columns(
+			&object.BucketName, &object.ObjectKey, &object.Version, &object.StreamID,
+			spannerutil.Int(&object.SegmentCount), &object.TotalEncryptedSize,
+		))
+	})
+	if err != nil {
+		return nil, nil, Error.New("unable to delete expired objects: %w", err)
+	}
+	if len(deleted) == 0 {
+		return nil, nil, nil
+	}
+
+	streamIDs := make([][]byte, len(deleted))
+	for i, object := range deleted {
+		streamIDs[i] = object.StreamID.Bytes()
+	}
+
+	segments, err = spannerutil.CollectRows(stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			DELETE FROM segments
+			WHERE stream_id IN UNNEST(@stream_ids)
+			THEN RETURN stream_id, position, root_piece_id, remote_alias_pieces
+		`,
+		Params: map[string]interface{}{
+			"stream_ids": streamIDs,
+		},
+	}), func(row *spanner.Row, segment *orphanedAliasSegment) error {
+		return Error.Wrap(row.Columns(&segment.StreamID, &segment.Position, &segment.RootPieceID, &segment.AliasPieces))
+	})
+	if err != nil {
+		return nil, nil, Error.New("unable to delete segments: %w", err)
+	}
+	return deleted, segments, nil
+}
@@ -0,0 +1,174 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestDeleteExpiredProjectObjects(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+		now := time.Now()
+
+		t.Run("invalid request", func(t *testing.T) {
+			for _, test := range []struct {
+				opts    metabase.DeleteExpiredProjectObjects
+				errText string
+			}{
+				{
+					opts:    metabase.DeleteExpiredProjectObjects{Now: now},
+					errText: "ProjectID missing",
+				},
+				{
+					opts:    metabase.DeleteExpiredProjectObjects{ProjectID: obj.ProjectID},
+					errText: "Now missing",
+				},
+				{
+					opts:    metabase.DeleteExpiredProjectObjects{ProjectID: obj.ProjectID, Now: now, BatchSize: -1},
+					errText: "BatchSize is negative: -1",
+				},
+			} {
+				_, err := db.DeleteExpiredProjectObjects(ctx, test.opts)
+				require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+				require.ErrorContains(t, err, test.errText)
+			}
+		})
+
+		t.Run("empty project", func(t *testing.T) {
+			result, err := db.DeleteExpiredProjectObjects(ctx, metabase.DeleteExpiredProjectObjects{
+				ProjectID: obj.ProjectID,
+				Now:       now,
+			})
+			require.NoError(t, err)
+			require.Empty(t, result.Objects)
+			require.False(t, result.More)
+		})
+
+		t.Run("delete", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			pastTime := now.Add(-time.Hour)
+			futureTime := now.Add(time.Hour)
+
+			expiredA := obj
+			expiredA.ObjectKey = "a"
+			objectA := metabasetest.CreateExpiredObject(ctx, t, db, expiredA, 2, pastTime)
+
+			expiredC := obj
+			expiredC.ObjectKey = "c"
+			expiredC.StreamID = testrand.UUID()
+			objectC := metabasetest.CreateExpiredObject(ctx, t, db, expiredC, 1, pastTime)
+
+			notExpired := obj
+			notExpired.ObjectKey = "b"
+			notExpired.StreamID = testrand.UUID()
+			objectB := metabasetest.CreateExpiredObject(ctx, t, db, notExpired, 1, futureTime)
+
+			otherProject := obj
+			otherProject.ProjectID = testrand.UUID()
+			otherProject.StreamID = testrand.UUID()
+			objectOther := metabasetest.CreateExpiredObject(ctx, t, db, otherProject, 1, pastTime)
+
+			newLocked := func(key metabase.ObjectKey, retention metabase.Retention, legalHold bool) metabase.RawObject {
+				stream := obj
+				stream.ObjectKey = key
+				stream.StreamID = testrand.UUID()
+				return metabase.RawObject{
+					ObjectStream: stream,
+					CreatedAt:    pastTime.Add(-time.Hour),
+					ExpiresAt:    &pastTime,
+					Status:       metabase.CommittedUnversioned,
+					Encryption:   metabasetest.DefaultEncryption,
+					Retention:    retention,
+					LegalHold:    legalHold,
+				}
+			}
+			retained := newLocked("b/retained", metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: futureTime,
+			}, false)
+			held := newLocked("b/held", metabase.Retention{}, true)
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{retained, held}))
+
+			requireDeleted := func(expected []metabase.Object, result metabase.DeleteExpiredProjectObjectsResult) {
+				require.Len(t, result.Objects, len(expected))
+				for i, object := range result.Objects {
+					require.Equal(t, metabase.DeletedExpiredObject{
+						ObjectStream:       expected[i].ObjectStream,
+						SegmentCount:       expected[i].SegmentCount,
+						TotalEncryptedSize: expected[i].TotalEncryptedSize,
+					}, object)
+				}
+			}
+
+			// the locked versions are skipped, but they still count towards the batch.
+			result, err := db.DeleteExpiredProjectObjects(ctx, metabase.DeleteExpiredProjectObjects{
+				ProjectID: obj.ProjectID,
+				Now:       now,
+				BatchSize: 2,
+			})
+			require.NoError(t, err)
+			requireDeleted([]metabase.Object{objectA}, result)
+			require.Len(t, result.Orphaned, 2)
+			for _, segment := range result.Orphaned {
+				require.Equal(t, objectA.StreamID, segment.StreamID)
+				require.NotEmpty(t, segment.Pieces)
+			}
+			require.True(t, result.More)
+			require.Equal(t, metabase.ExpiredProjectObjectsCursor{
+				BucketName: held.BucketName,
+				ObjectKey:  held.ObjectKey,
+				Version:    held.Version,
+			}, result.NextCursor)
+
+			result, err = db.DeleteExpiredProjectObjects(ctx, metabase.DeleteExpiredProjectObjects{
+				ProjectID: obj.ProjectID,
+				Now:       now,
+				Cursor:    result.NextCursor,
+				BatchSize: 2,
+			})
+			require.NoError(t, err)
+			requireDeleted([]metabase.Object{objectC}, result)
+			require.Len(t, result.Orphaned, 1)
+			require.True(t, result.More)
+
+			result, err = db.DeleteExpiredProjectObjects(ctx, metabase.DeleteExpiredProjectObjects{
+				ProjectID: obj.ProjectID,
+				Now:       now,
+				Cursor:    result.NextCursor,
+				BatchSize: 2,
+			})
+			require.NoError(t, err)
+			require.Empty(t, result.Objects)
+			require.False(t, result.More)
+
+			objects, err := db.TestingAllObjects(ctx)
+			require.NoError(t, err)
+			var remaining []metabase.ObjectStream
+			for _, object := range objects {
+				remaining = append(remaining, object.ObjectStream)
+			}
+			require.ElementsMatch(t, []metabase.ObjectStream{
+				objectB.ObjectStream,
+				objectOther.ObjectStream,
+				retained.ObjectStream,
+				held.ObjectStream,
+			}, remaining)
+
+			segments, err := db.TestingAllSegments(ctx)
+			require.NoError(t, err)
+			require.Len(t, segments, 2)
+		})
+	})
+}
@@ -7,9 +7,13 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"encoding/binary"
+	"encoding/json"
 	"strconv"
+	"time"
 
+	"cloud.google.com/go/spanner"
 	"github.com/jackc/pgtype"
+	"github.com/zeebo/errs"
 
 	"storj.io/common/storj"
 )
@@ -38,6 +42,20 @@ var _ struct {
 	BlockSize   int32
 } = storj.EncryptionParameters{}
 
+// verify checks that the decoded encryption parameters are usable. Zero parameters,
+// which are used by delete markers, are accepted.
+func (params encryptionParameters) verify() error {
+	switch {
+	case params.IsZero():
+		return nil
+	case params.CipherSuite == storj.EncUnspecified || params.CipherSuite > storj.EncNullBase64URL:
+		return errs.New("unknown cipher suite: %v", params.CipherSuite)
+	case params.BlockSize <= 0:
+		return errs.New("invalid block size: %d", params.BlockSize)
+	}
+	return nil
+}
+
 // Value implements sql/driver.Valuer interface.
 func (params encryptionParameters) Value() (driver.Value, error) {
 	var bytes [8]byte
@@ -236,3 +254,278 @@ func (pieces *Pieces) Scan(value interface{}) error {
 	*pieces = scan
 	return nil
 }
+
+// systemLabels is used for encoding and decoding object system labels into a JSON column.
+type systemLabels struct {
+	labels *map[string]string
+}
+
+// Value implements sql/driver.Valuer interface.
+func (v systemLabels) Value() (driver.Value, error) {
+	if v.labels == nil || len(*v.labels) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(*v.labels)
+	if err != nil {
+		return nil, Error.New("unable to encode system labels: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner interface.
+func (v systemLabels) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		*v.labels = nil
+		return nil
+	case []byte:
+		return v.decode(value)
+	case string:
+		return v.decode([]byte(value))
+	default:
+		return Error.New("unable to scan %T into system labels", value)
+	}
+}
+
+// EncodeSpanner implements spanner.Encoder interface.
+func (v systemLabels) EncodeSpanner() (interface{}, error) {
+	if v.labels == nil || len(*v.labels) == 0 {
+		return spanner.NullJSON{}, nil
+	}
+	return spanner.NullJSON{Value: *v.labels, Valid: true}, nil
+}
+
+// DecodeSpanner implements spanner.Decoder interface.
+func (v systemLabels) DecodeSpanner(input interface{}) error {
+	switch input := input.(type) {
+	case *string:
+		if input == nil {
+			*v.labels = nil
+			return nil
+		}
+		return v.decode([]byte(*input))
+	case string:
+		return v.decode([]byte(input))
+	default:
+		return Error.New("unable to decode %T into system labels", input)
+	}
+}
+
+func (v systemLabels) decode(data []byte) error {
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return Error.New("unable to decode system labels: %w", err)
+	}
+	if len(labels) == 0 {
+		labels = nil
+	}
+	*v.labels = labels
+	return nil
+}
+
+// objectTags is used for encoding and decoding object tags into a JSON column.
+type objectTags struct {
+	tags *map[string]string
+}
+
+// Value implements sql/driver.Valuer interface.
+func (v objectTags) Value() (driver.Value, error) {
+	if v.tags == nil || len(*v.tags) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(*v.tags)
+	if err != nil {
+		return nil, Error.New("unable to encode object tags: %w", err)
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner interface.
+func (v objectTags) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		*v.tags = nil
+		return nil
+	case []byte:
+		return v.decode(value)
+	case string:
+		return v.decode([]byte(value))
+	default:
+		return Error.New("unable to scan %T into object tags", value)
+	}
+}
+
+// EncodeSpanner implements spanner.Encoder interface.
+func (v objectTags) EncodeSpanner() (interface{}, error) {
+	if v.tags == nil || len(*v.tags) == 0 {
+		return spanner.NullJSON{}, nil
+	}
+	return spanner.NullJSON{Value: *v.tags, Valid: true}, nil
+}
+
+// DecodeSpanner implements spanner.Decoder interface.
+func (v objectTags) DecodeSpanner(input interface{}) error {
+	switch input := input.(type) {
+	case *string:
+		if input == nil {
+			*v.tags = nil
+			return nil
+		}
+		return v.decode([]byte(*input))
+	case string:
+		return v.decode([]byte(input))
+	default:
+		return Error.New("unable to decode %T into object tags", input)
+	}
+}
+
+func (v objectTags) decode(data []byte) error {
+	var tags map[string]string
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return Error.New("unable to decode object tags: %w", err)
+	}
+	if len(tags) == 0 {
+		tags = nil
+	}
+	*v.tags = tags
+	return nil
+}
+
+// lockModeWrapper is used for encoding and decoding the retention mode and the
+// legal hold of an object into the retention_mode column. The lowest bits contain
+// the retention mode and legalHoldFlag is set for objects under legal hold.
+// A zero value is stored as NULL. Either of the pointers may be nil, when the
+// value is not needed.
+type lockModeWrapper struct {
+	retentionMode *storj.RetentionMode
+	legalHold     *bool
+}
+
+// Value implements sql/driver.Valuer interface.
+func (w lockModeWrapper) Value() (driver.Value, error) {
+	if value := w.encode(); value != 0 {
+		return value, nil
+	}
+	return nil, nil
+}
+
+// Scan implements sql.Scanner interface.
+func (w lockModeWrapper) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		return w.decode(0)
+	case int64:
+		return w.decode(value)
+	default:
+		return Error.New("unable to scan %T into lock mode", value)
+	}
+}
+
+// EncodeSpanner implements spanner.Encoder interface.
+func (w lockModeWrapper) EncodeSpanner() (interface{}, error) {
+	if value := w.encode(); value != 0 {
+		return spanner.NullInt64{Int64: value, Valid: true}, nil
+	}
+	return spanner.NullInt64{}, nil
+}
+
+// DecodeSpanner implements spanner.Decoder interface.
+func (w lockModeWrapper) DecodeSpanner(input interface{}) error {
+	switch input := input.(type) {
+	case *string:
+		if input == nil {
+			return w.decode(0)
+		}
+		return w.DecodeSpanner(*input)
+	case string:
+		value, err := strconv.ParseInt(input, 10, 64)
+		if err != nil {
+			return Error.New("unable to decode lock mode: %w", err)
+		}
+		return w.decode(value)
+	default:
+		return Error.New("unable to decode %T into lock mode", input)
+	}
+}
+
+func (w lockModeWrapper) encode() int64 {
+	var value int64
+	if w.retentionMode != nil {
+		value = int64(*w.retentionMode)
+	}
+	if w.legalHold != nil && *w.legalHold {
+		value |= legalHoldFlag
+	}
+	return value
+}
+
+func (w lockModeWrapper) decode(value int64) error {
+	mode := value & retentionModeMask
+	if value&^(retentionModeMask|legalHoldFlag) != 0 || mode > int64(storj.ComplianceMode) {
+		return Error.New("invalid lock mode: %d", value)
+	}
+	if w.retentionMode != nil {
+		*w.retentionMode = storj.RetentionMode(mode)
+	}
+	if w.legalHold != nil {
+		*w.legalHold = value&legalHoldFlag != 0
+	}
+	return nil
+}
+
+// timeWrapper is used for encoding and decoding an optional timestamp column.
+// The zero time is stored as NULL.
+type timeWrapper struct {
+	time *time.Time
+}
+
+// Value implements sql/driver.Valuer interface.
+func (w timeWrapper) Value() (driver.Value, error) {
+	if w.time == nil || w.time.IsZero() {
+		return nil, nil
+	}
+	return *w.time, nil
+}
+
+// Scan implements sql.Scanner interface.
+func (w timeWrapper) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		*w.time = time.Time{}
+		return nil
+	case time.Time:
+		*w.time = value
+		return nil
+	default:
+		return Error.New("unable to scan %T into time", value)
+	}
+}
+
+// EncodeSpanner implements spanner.Encoder interface.
+func (w timeWrapper) EncodeSpanner() (interface{}, error) {
+	if w.time == nil || w.time.IsZero() {
+		return spanner.NullTime{}, nil
+	}
+	return spanner.NullTime{Time: *w.time, Valid: true}, nil
+}
+
+// DecodeSpanner implements spanner.Decoder interface.
+func (w timeWrapper) DecodeSpanner(input interface{}) error {
+	switch input := input.(type) {
+	case *string:
+		if input == nil {
+			*w.time = time.Time{}
+			return nil
+		}
+		return w.DecodeSpanner(*input)
+	case string:
+		value, err := time.Parse(time.RFC3339Nano, input)
+		if err != nil {
+			return Error.New("unable to decode time: %w", err)
+		}
+		*w.time = value
+		return nil
+	default:
+		return Error.New("unable to decode %T into time", input)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/spannerutil"
+)
+
+// ExpiringUsage contains the amount of data of a project, which expires before a given time.
+type ExpiringUsage struct {
+	SegmentCount       int64
+	TotalEncryptedSize int64
+}
+
+// ExpiringUsage returns the number of segments and their total encrypted size in
+// the project, which expire before the given time. Segments that have already expired,
+// but haven't been deleted yet, are included.
+func (db *DB) ExpiringUsage(ctx context.Context, projectID uuid.UUID, before time.Time) (usage ExpiringUsage, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if projectID.IsZero() {
+		return ExpiringUsage{}, ErrInvalidRequest.New("ProjectID missing")
+	}
+	if before.IsZero() {
+		return ExpiringUsage{}, ErrInvalidRequest.New("Before missing")
+	}
+
+	return db.ChooseAdapter(projectID).ExpiringUsage(ctx, projectID, before)
+}
+
+// ExpiringUsage returns the usage of the project, which expires before the given time.
+func (p *PostgresAdapter) ExpiringUsage(ctx context.Context, projectID uuid.UUID, before time.Time) (usage ExpiringUsage, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// segments don't contain the project ID, hence the project's objects are
+	// walked using the primary key and joined with the segments of their streams.
+	err = p.db.QueryRowContext(ctx, `
+		SELECT count(*), coalesce(sum(segments.encrypted_size), 0)
+		FROM objects
+		JOIN segments ON segments.stream_id = objects.stream_id
+		WHERE
+			objects.project_id = $1 AND
+			segments.expires_at < $2
+	`, projectID, before).Scan(&usage.SegmentCount, &usage.TotalEncryptedSize)
+	if err != nil {
+		return ExpiringUsage{}, Error.New("unable to query expiring usage: %w", err)
+	}
+	return usage, nil
+}
+
+// ExpiringUsage returns the usage of the project, which expires before the given time.
+func (s *SpannerAdapter) ExpiringUsage(ctx context.Context, projectID uuid.UUID, before time.Time) (usage ExpiringUsage, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// segments don't contain the project ID, hence the project's objects are
+	// walked using the primary key and joined with the segments of their streams.
+	usage, err = spannerutil.CollectRow(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT COUNT(*), COALESCE(SUM(segments.encrypted_size), 0)
+			FROM objects
+			JOIN segments ON segments.stream_id = objects.stream_id
+			WHERE
+				objects.project_id = @project_id AND
+				segments.expires_at < @before
+		`,
+		Params: map[string]interface{}{
+			"project_id": projectID,
+			"before":     before,
+		},
+	}), func(row *spanner.Row, usage *ExpiringUsage) error {
+		return Error.Wrap(row.Columns(&usage.SegmentCount, &usage.TotalEncryptedSize))
+	})
+	if err != nil {
+		return ExpiringUsage{}, Error.New("unable to query expiring usage: %w", err)
+	}
+	return usage, nil
+}
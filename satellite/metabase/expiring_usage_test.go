@@ -0,0 +1,75 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/common/uuid"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestExpiringUsage(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		now := time.Now()
+
+		t.Run("invalid request", func(t *testing.T) {
+			_, err := db.ExpiringUsage(ctx, uuid.UUID{}, now)
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "ProjectID missing")
+
+			_, err = db.ExpiringUsage(ctx, testrand.UUID(), time.Time{})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "Before missing")
+		})
+
+		t.Run("empty project", func(t *testing.T) {
+			usage, err := db.ExpiringUsage(ctx, testrand.UUID(), now.Add(24*time.Hour))
+			require.NoError(t, err)
+			require.Zero(t, usage)
+		})
+
+		t.Run("expiring", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID := testrand.UUID()
+			newObject := func(projectID uuid.UUID) metabase.ObjectStream {
+				obj := metabasetest.RandObjectStream()
+				obj.ProjectID = projectID
+				return obj
+			}
+
+			// expires within a day.
+			metabasetest.CreateExpiredObject(ctx, t, db, newObject(projectID), 2, now.Add(time.Hour))
+			// already expired, but not deleted yet.
+			metabasetest.CreateExpiredObject(ctx, t, db, newObject(projectID), 1, now.Add(-time.Hour))
+			// expires later.
+			metabasetest.CreateExpiredObject(ctx, t, db, newObject(projectID), 3, now.Add(48*time.Hour))
+			// doesn't expire.
+			metabasetest.CreateObject(ctx, t, db, newObject(projectID), 4)
+			// different project.
+			metabasetest.CreateExpiredObject(ctx, t, db, newObject(testrand.UUID()), 5, now.Add(time.Hour))
+
+			usage, err := db.ExpiringUsage(ctx, projectID, now.Add(24*time.Hour))
+			require.NoError(t, err)
+			require.Equal(t, metabase.ExpiringUsage{
+				SegmentCount:       3,
+				TotalEncryptedSize: 3 * 1024,
+			}, usage)
+
+			usage, err = db.ExpiringUsage(ctx, projectID, now.Add(72*time.Hour))
+			require.NoError(t, err)
+			require.Equal(t, metabase.ExpiringUsage{
+				SegmentCount:       6,
+				TotalEncryptedSize: 6 * 1024,
+			}, usage)
+		})
+	})
+}
@@ -0,0 +1,166 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
+)
+
+// FindDuplicateStreamIDs contains arguments necessary for finding stream IDs,
+// which are used by more than one object of a project.
+type FindDuplicateStreamIDs struct {
+	ProjectID uuid.UUID
+	// Cursor is the stream ID after which the search starts.
+	Cursor uuid.UUID
+	Limit  int
+}
+
+// DuplicateStreamID is a stream ID together with the objects that share it.
+type DuplicateStreamID struct {
+	StreamID uuid.UUID
+	Objects  []ObjectStream
+}
+
+// FindDuplicateStreamIDsResult is the result of FindDuplicateStreamIDs.
+type FindDuplicateStreamIDsResult struct {
+	Duplicates []DuplicateStreamID
+	// NextCursor should be used as the cursor of the next request, when More is set.
+	NextCursor uuid.UUID
+	More       bool
+}
+
+// Verify verifies find duplicate stream IDs request fields.
+func (opts *FindDuplicateStreamIDs) Verify() error {
+	switch {
+	case opts.ProjectID.IsZero():
+		return ErrInvalidRequest.New("ProjectID missing")
+	case opts.Limit < 0:
+		return ErrInvalidRequest.New("Invalid limit: %d", opts.Limit)
+	}
+	return nil
+}
+
+// FindDuplicateStreamIDs finds the stream IDs, which are used by more than one object
+// version of the project. Every object version must have its own stream, hence such
+// stream IDs indicate corrupted data. It's meant to be used for data integrity audits.
+//
+// The stream IDs are returned in order, with at most opts.Limit stream IDs per request,
+// hence the search should continue with NextCursor while More is set.
+func (db *DB) FindDuplicateStreamIDs(ctx context.Context, opts FindDuplicateStreamIDs) (result FindDuplicateStreamIDsResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return FindDuplicateStreamIDsResult{}, err
+	}
+	ListLimit.Ensure(&opts.Limit)
+
+	objects, err := db.ChooseAdapter(opts.ProjectID).FindDuplicateStreamIDs(ctx, opts)
+	if err != nil {
+		return FindDuplicateStreamIDsResult{}, err
+	}
+
+	// objects are ordered by stream ID, hence the objects sharing a stream are adjacent.
+	for _, object := range objects {
+		last := len(result.Duplicates) - 1
+		if last < 0 || result.Duplicates[last].StreamID != object.StreamID {
+			result.Duplicates = append(result.Duplicates, DuplicateStreamID{StreamID: object.StreamID})
+			last++
+		}
+		result.Duplicates[last].Objects = append(result.Duplicates[last].Objects, object)
+	}
+
+	if len(result.Duplicates) == opts.Limit {
+		result.NextCursor = result.Duplicates[len(result.Duplicates)-1].StreamID
+		result.More = true
+	}
+
+	return result, nil
+}
+
+// FindDuplicateStreamIDs returns the objects, which share their stream ID with other objects.
+func (p *PostgresAdapter) FindDuplicateStreamIDs(ctx context.Context, opts FindDuplicateStreamIDs) (objects []ObjectStream, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = withRows(p.db.QueryContext(ctx, `
+		SELECT
+			bucket_name, object_key, version, stream_id
+		FROM objects
+		WHERE
+			project_id = $1 AND
+			stream_id IN (
+				SELECT stream_id
+				FROM objects
+				WHERE
+					project_id = $1 AND
+					stream_id > $2
+				GROUP BY stream_id
+				HAVING COUNT(*) > 1
+				ORDER BY stream_id
+				LIMIT $3
+			)
+		ORDER BY stream_id, bucket_name, object_key, version
+	`, opts.ProjectID, opts.Cursor, opts.Limit))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			object := ObjectStream{ProjectID: opts.ProjectID}
+			if err := rows.Scan(
+				&object.BucketName, &object.ObjectKey, &object.Version, &object.StreamID,
+			); err != nil {
+				return Error.Wrap(err)
+			}
+			objects = append(objects, object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to find duplicate stream ids: %w", err)
+	}
+	return objects, nil
+}
+
+// FindDuplicateStreamIDs returns the objects, which share their stream ID with other objects.
+func (s *SpannerAdapter) FindDuplicateStreamIDs(ctx context.Context, opts FindDuplicateStreamIDs) (objects []ObjectStream, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	objects, err = spannerutil.CollectRows(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				bucket_name, object_key, version, stream_id
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				stream_id IN (
+					SELECT stream_id
+					FROM objects
+					WHERE
+						project_id = @project_id AND
+						stream_id > @stream_id
+					GROUP BY stream_id
+					HAVING COUNT(*) > 1
+					ORDER BY stream_id
+					LIMIT @limit
+				)
+			ORDER BY stream_id, bucket_name, object_key, version
+		`,
+		Params: map[string]interface{}{
+			"project_id": opts.ProjectID,
+			"stream_id":  opts.Cursor,
+			"limit":      int64(opts.Limit),
+		},
+	}), func(row *spanner.Row, object *ObjectStream) error {
+		object.ProjectID = opts.ProjectID
+		return Error.Wrap(row.Columns(
+			&object.BucketName, &object.ObjectKey, &object.Version, &object.StreamID,
+		))
+	})
+	if err != nil {
+		return nil, Error.New("unable to find duplicate stream ids: %w", err)
+	}
+	return objects, nil
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/common/uuid"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestFindDuplicateStreamIDs(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		t.Run("invalid request", func(t *testing.T) {
+			_, err := db.FindDuplicateStreamIDs(ctx, metabase.FindDuplicateStreamIDs{})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "ProjectID missing")
+
+			_, err = db.FindDuplicateStreamIDs(ctx, metabase.FindDuplicateStreamIDs{
+				ProjectID: obj.ProjectID,
+				Limit:     -1,
+			})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "Invalid limit: -1")
+		})
+
+		t.Run("no duplicates", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.CreateObject(ctx, t, db, obj, 0)
+
+			result, err := db.FindDuplicateStreamIDs(ctx, metabase.FindDuplicateStreamIDs{ProjectID: obj.ProjectID})
+			require.NoError(t, err)
+			require.Empty(t, result.Duplicates)
+			require.False(t, result.More)
+		})
+
+		t.Run("duplicates", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			now := time.Now()
+			newObject := func(key metabase.ObjectKey, version metabase.Version, streamID uuid.UUID) metabase.RawObject {
+				stream := obj
+				stream.ObjectKey = key
+				stream.Version = version
+				stream.StreamID = streamID
+				return metabase.RawObject{
+					ObjectStream: stream,
+					CreatedAt:    now,
+					Status:       metabase.CommittedVersioned,
+					Encryption:   metabasetest.DefaultEncryption,
+				}
+			}
+
+			streamIDs := []uuid.UUID{testrand.UUID(), testrand.UUID(), testrand.UUID()}
+			sort.Slice(streamIDs, func(i, k int) bool { return streamIDs[i].Less(streamIDs[k]) })
+
+			first := []metabase.RawObject{newObject("a", 1, streamIDs[0]), newObject("a", 2, streamIDs[0])}
+			second := []metabase.RawObject{newObject("b", 1, streamIDs[1]), newObject("c", 1, streamIDs[1])}
+			third := []metabase.RawObject{newObject("d", 1, streamIDs[2]), newObject("d", 2, streamIDs[2]), newObject("e", 1, streamIDs[2])}
+
+			objects := []metabase.RawObject{newObject("f", 1, testrand.UUID())}
+			objects = append(objects, first...)
+			objects = append(objects, second...)
+			objects = append(objects, third...)
+
+			// the same stream in a different project isn't reported.
+			otherProject := newObject("a", 3, streamIDs[0])
+			otherProject.ProjectID = testrand.UUID()
+			objects = append(objects, otherProject)
+
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, objects))
+
+			requireDuplicate := func(expected []metabase.RawObject, duplicate metabase.DuplicateStreamID) {
+				require.Equal(t, expected[0].StreamID, duplicate.StreamID)
+				require.Len(t, duplicate.Objects, len(expected))
+				for i, object := range duplicate.Objects {
+					require.Equal(t, expected[i].ObjectStream, object)
+				}
+			}
+
+			result, err := db.FindDuplicateStreamIDs(ctx, metabase.FindDuplicateStreamIDs{ProjectID: obj.ProjectID})
+			require.NoError(t, err)
+			require.False(t, result.More)
+			require.Len(t, result.Duplicates, 3)
+			requireDuplicate(first, result.Duplicates[0])
+			requireDuplicate(second, result.Duplicates[1])
+			requireDuplicate(third, result.Duplicates[2])
+
+			result, err = db.FindDuplicateStreamIDs(ctx, metabase.FindDuplicateStreamIDs{
+				ProjectID: obj.ProjectID,
+				Limit:     2,
+			})
+			require.NoError(t, err)
+			require.True(t, result.More)
+			require.Equal(t, streamIDs[1], result.NextCursor)
+			require.Len(t, result.Duplicates, 2)
+			requireDuplicate(first, result.Duplicates[0])
+			requireDuplicate(second, result.Duplicates[1])
+
+			result, err = db.FindDuplicateStreamIDs(ctx, metabase.FindDuplicateStreamIDs{
+				ProjectID: obj.ProjectID,
+				Cursor:    result.NextCursor,
+				Limit:     2,
+			})
+			require.NoError(t, err)
+			require.False(t, result.More)
+			require.Len(t, result.Duplicates, 1)
+			requireDuplicate(third, result.Duplicates[0])
+		})
+	})
+}
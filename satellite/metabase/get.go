@@ -14,7 +14,9 @@ import (
 	"google.golang.org/api/iterator"
 
 	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/pgutil"
 	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
 )
 
 // ErrSegmentNotFound is an error class for non-existing segment.
@@ -100,7 +102,8 @@ func (p *PostgresAdapter) GetObjectExactVersion(ctx context.Context, opts GetObj
 			segment_count,
 			encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
 			total_plain_size, total_encrypted_size, fixed_segment_size,
-			encryption
+			encryption,
+			computed_etag
 		FROM objects
 		WHERE
 			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
@@ -114,6 +117,7 @@ func (p *PostgresAdapter) GetObjectExactVersion(ctx context.Context, opts GetObj
 			&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
 			&object.TotalPlainSize, &object.TotalEncryptedSize, &object.FixedSegmentSize,
 			encryptionParameters{&object.Encryption},
+			&object.ComputedETag,
 		)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -140,7 +144,8 @@ func (s *SpannerAdapter) GetObjectExactVersion(ctx context.Context, opts GetObje
 				segment_count,
 				encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
 				total_plain_size, total_encrypted_size, fixed_segment_size,
-				encryption
+				encryption,
+				computed_etag
 			FROM objects
 			WHERE
 				(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
@@ -165,6 +170,7 @@ func (s *SpannerAdapter) GetObjectExactVersion(ctx context.Context, opts GetObje
 			&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
 			&object.TotalPlainSize, &object.TotalEncryptedSize, spannerutil.Int(&object.FixedSegmentSize),
 			encryptionParameters{&object.Encryption},
+			&object.ComputedETag,
 		))
 	})
 
@@ -208,7 +214,8 @@ func (p *PostgresAdapter) GetObjectLastCommitted(ctx context.Context, opts GetOb
 			segment_count,
 			encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
 			total_plain_size, total_encrypted_size, fixed_segment_size,
-			encryption
+			encryption,
+			computed_etag
 		FROM objects
 		WHERE
 			(project_id, bucket_name, object_key) = ($1, $2, $3) AND
@@ -224,6 +231,7 @@ func (p *PostgresAdapter) GetObjectLastCommitted(ctx context.Context, opts GetOb
 		&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
 		&object.TotalPlainSize, &object.TotalEncryptedSize, &object.FixedSegmentSize,
 		encryptionParameters{&object.Encryption},
+		&object.ComputedETag,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) || object.Status.IsDeleteMarker() {
@@ -246,7 +254,8 @@ func (s *SpannerAdapter) GetObjectLastCommitted(ctx context.Context, opts GetObj
 				segment_count,
 				encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
 				total_plain_size, total_encrypted_size, fixed_segment_size,
-				encryption
+				encryption,
+				computed_etag
 			FROM objects
 			WHERE
 				project_id = @project_id AND
@@ -273,6 +282,7 @@ func (s *SpannerAdapter) GetObjectLastCommitted(ctx context.Context, opts GetObj
 			&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
 			&object.TotalPlainSize, &object.TotalEncryptedSize, spannerutil.Int(&object.FixedSegmentSize),
 			encryptionParameters{&object.Encryption},
+			&object.ComputedETag,
 		))
 	})
 	if err != nil {
@@ -288,6 +298,487 @@ func (s *SpannerAdapter) GetObjectLastCommitted(ctx context.Context, opts GetObj
 	return object, nil
 }
 
+// GetObjectsLastCommitted contains arguments necessary for fetching
+// last committed versions of multiple objects from the same bucket.
+type GetObjectsLastCommitted struct {
+	ProjectID  uuid.UUID
+	BucketName string
+	ObjectKeys []ObjectKey
+
+	IncludeCustomMetadata bool
+}
+
+// Verify verifies get objects request fields.
+func (opts *GetObjectsLastCommitted) Verify() error {
+	switch {
+	case opts.ProjectID.IsZero():
+		return ErrInvalidRequest.New("ProjectID missing")
+	case opts.BucketName == "":
+		return ErrInvalidRequest.New("BucketName missing")
+	}
+	for _, key := range opts.ObjectKeys {
+		if key == "" {
+			return ErrInvalidRequest.New("ObjectKey missing")
+		}
+	}
+	return nil
+}
+
+// GetObjectsLastCommitted returns object information for last committed version
+// of each of the specified objects. Objects without a committed version, or whose
+// last committed version is a delete marker, are omitted from the result.
+func (db *DB) GetObjectsLastCommitted(ctx context.Context, opts GetObjectsLastCommitted) (_ map[ObjectKey]Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[ObjectKey]Object, len(opts.ObjectKeys))
+	if len(opts.ObjectKeys) == 0 {
+		return result, nil
+	}
+
+	objects, err := db.ChooseAdapter(opts.ProjectID).GetObjectsLastCommitted(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, object := range objects {
+		if object.Status.IsDeleteMarker() {
+			continue
+		}
+		result[object.ObjectKey] = object
+	}
+	return result, nil
+}
+
+// GetObjectsLastCommitted implements Adapter.
+func (p *PostgresAdapter) GetObjectsLastCommitted(ctx context.Context, opts GetObjectsLastCommitted) (objects []Object, err error) {
+	objectKeys := make([][]byte, len(opts.ObjectKeys))
+	for i, key := range opts.ObjectKeys {
+		objectKeys[i] = []byte(key)
+	}
+
+	metadataColumns := ""
+	if opts.IncludeCustomMetadata {
+		metadataColumns = "encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,"
+	}
+
+	err = withRows(p.db.QueryContext(ctx, `
+		SELECT latest.*
+		FROM unnest($3::BYTEA[]) AS keys(object_key)
+		JOIN LATERAL (
+			SELECT
+				object_key, stream_id, version, status,
+				created_at, expires_at,
+				segment_count,
+				`+metadataColumns+`
+				total_plain_size, total_encrypted_size, fixed_segment_size,
+				encryption
+			FROM objects
+			WHERE
+				(project_id, bucket_name, object_key) = ($1, $2, keys.object_key) AND
+				status <> `+statusPending+` AND
+				(expires_at IS NULL OR expires_at > now())
+			ORDER BY version DESC
+			LIMIT 1
+		) AS latest ON true`,
+		opts.ProjectID, []byte(opts.BucketName), pgutil.ByteaArray(objectKeys),
+	))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			object := Object{}
+			object.ProjectID = opts.ProjectID
+			object.BucketName = opts.BucketName
+
+			fields := []any{
+				&object.ObjectKey, &object.StreamID, &object.Version, &object.Status,
+				&object.CreatedAt, &object.ExpiresAt,
+				&object.SegmentCount,
+			}
+			if opts.IncludeCustomMetadata {
+				fields = append(fields, &object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey)
+			}
+			fields = append(fields,
+				&object.TotalPlainSize, &object.TotalEncryptedSize, &object.FixedSegmentSize,
+				encryptionParameters{&object.Encryption},
+			)
+
+			if err := rows.Scan(fields...); err != nil {
+				return Error.New("unable to scan object: %w", err)
+			}
+			objects = append(objects, object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to query objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+// GetObjectsLastCommitted implements Adapter.
+func (s *SpannerAdapter) GetObjectsLastCommitted(ctx context.Context, opts GetObjectsLastCommitted) (objects []Object, err error) {
+	objectKeys := make([][]byte, len(opts.ObjectKeys))
+	for i, key := range opts.ObjectKeys {
+		objectKeys[i] = []byte(key)
+	}
+
+	metadataColumns := ""
+	if opts.IncludeCustomMetadata {
+		metadataColumns = "encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,"
+	}
+
+	objects, err = spannerutil.CollectRows(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				object_key, stream_id, version, status,
+				created_at, expires_at,
+				segment_count,
+				` + metadataColumns + `
+				total_plain_size, total_encrypted_size, fixed_segment_size,
+				encryption
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				bucket_name = @bucket_name AND
+				object_key IN UNNEST(@object_keys) AND
+				version = (
+					SELECT latest.version
+					FROM objects AS latest
+					WHERE
+						latest.project_id = objects.project_id AND
+						latest.bucket_name = objects.bucket_name AND
+						latest.object_key = objects.object_key AND
+						latest.status <> ` + statusPending + ` AND
+						(latest.expires_at IS NULL OR latest.expires_at > CURRENT_TIMESTAMP)
+					ORDER BY latest.version DESC
+					LIMIT 1
+				)`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.BucketName,
+			"object_keys": objectKeys,
+		},
+	}), func(row *spanner.Row, object *Object) error {
+		object.ProjectID = opts.ProjectID
+		object.BucketName = opts.BucketName
+
+		fields := []any{
+			&object.ObjectKey, &object.StreamID, &object.Version, &object.Status,
+			&object.CreatedAt, &object.ExpiresAt,
+			spannerutil.Int(&object.SegmentCount),
+		}
+		if opts.IncludeCustomMetadata {
+			fields = append(fields, &object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey)
+		}
+		fields = append(fields,
+			&object.TotalPlainSize, &object.TotalEncryptedSize, spannerutil.Int(&object.FixedSegmentSize),
+			encryptionParameters{&object.Encryption},
+		)
+
+		return Error.Wrap(row.Columns(fields...))
+	})
+	if err != nil {
+		return nil, Error.New("unable to query objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+// GetObjectsExactVersion contains arguments necessary for fetching information
+// about multiple exact object versions.
+type GetObjectsExactVersion struct {
+	ProjectID  uuid.UUID
+	BucketName string
+	Locations  []VersionedLocation
+
+	IncludeCustomMetadata bool
+}
+
+// Verify verifies get objects request fields.
+func (opts *GetObjectsExactVersion) Verify() error {
+	switch {
+	case opts.ProjectID.IsZero():
+		return ErrInvalidRequest.New("ProjectID missing")
+	case opts.BucketName == "":
+		return ErrInvalidRequest.New("BucketName missing")
+	}
+	for _, loc := range opts.Locations {
+		if loc.ObjectKey == "" {
+			return ErrInvalidRequest.New("ObjectKey missing")
+		}
+		if loc.Version <= 0 {
+			return ErrInvalidRequest.New("Version invalid: %v", loc.Version)
+		}
+	}
+	return nil
+}
+
+// GetObjectsExactVersionResult contains the result of GetObjectsExactVersion.
+type GetObjectsExactVersionResult struct {
+	// Objects contains the found objects, in the order of the requested locations.
+	Objects []Object
+	// NotFound contains the requested locations without a committed object.
+	NotFound []VersionedLocation
+}
+
+// GetObjectsExactVersion returns object information for each of the specified exact
+// versions with a single query. Pending and expired objects are reported as not found,
+// same as with GetObjectExactVersion.
+func (db *DB) GetObjectsExactVersion(ctx context.Context, opts GetObjectsExactVersion) (result GetObjectsExactVersionResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return GetObjectsExactVersionResult{}, err
+	}
+	if len(opts.Locations) == 0 {
+		return GetObjectsExactVersionResult{}, nil
+	}
+
+	objects, err := db.ChooseAdapter(opts.ProjectID).GetObjectsExactVersion(ctx, opts)
+	if err != nil {
+		return GetObjectsExactVersionResult{}, err
+	}
+
+	found := make(map[VersionedLocation]Object, len(objects))
+	for _, object := range objects {
+		found[VersionedLocation{ObjectKey: object.ObjectKey, Version: object.Version}] = object
+	}
+
+	for _, loc := range opts.Locations {
+		object, ok := found[loc]
+		if !ok {
+			result.NotFound = append(result.NotFound, loc)
+			continue
+		}
+		result.Objects = append(result.Objects, object)
+	}
+	return result, nil
+}
+
+// GetObjectsExactVersion implements Adapter.
+func (p *PostgresAdapter) GetObjectsExactVersion(ctx context.Context, opts GetObjectsExactVersion) (objects []Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	objectKeys, versions := splitVersionedLocations(opts.Locations)
+
+	metadataColumns := ""
+	if opts.IncludeCustomMetadata {
+		metadataColumns = "encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,"
+	}
+
+	err = withRows(p.db.QueryContext(ctx, `
+		SELECT
+			object_key, stream_id, version, status,
+			created_at, expires_at,
+			segment_count,
+			`+metadataColumns+`
+			total_plain_size, total_encrypted_size, fixed_segment_size,
+			encryption
+		FROM objects
+		WHERE
+			(project_id, bucket_name) = ($1, $2) AND
+			(object_key, version) IN (SELECT unnest($3::BYTEA[]), unnest($4::INT8[])) AND
+			status <> `+statusPending+` AND
+			(expires_at IS NULL OR expires_at > now())`,
+		opts.ProjectID, []byte(opts.BucketName), pgutil.ByteaArray(objectKeys), pgutil.Int8Array(versions),
+	))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			object := Object{}
+			object.ProjectID = opts.ProjectID
+			object.BucketName = opts.BucketName
+
+			fields := []any{
+				&object.ObjectKey, &object.StreamID, &object.Version, &object.Status,
+				&object.CreatedAt, &object.ExpiresAt,
+				&object.SegmentCount,
+			}
+			if opts.IncludeCustomMetadata {
+				fields = append(fields, &object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey)
+			}
+			fields = append(fields,
+				&object.TotalPlainSize, &object.TotalEncryptedSize, &object.FixedSegmentSize,
+				encryptionParameters{&object.Encryption},
+			)
+
+			if err := rows.Scan(fields...); err != nil {
+				return Error.New("unable to scan object: %w", err)
+			}
+			objects = append(objects, object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to query objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+// GetObjectsExactVersion implements Adapter.
+func (s *SpannerAdapter) GetObjectsExactVersion(ctx context.Context, opts GetObjectsExactVersion) (objects []Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	metadataColumns := ""
+	if opts.IncludeCustomMetadata {
+		metadataColumns = "encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,"
+	}
+
+	objects, err = spannerutil.CollectRows(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				object_key, stream_id, version, status,
+				created_at, expires_at,
+				segment_count,
+				` + metadataColumns + `
+				total_plain_size, total_encrypted_size, fixed_segment_size,
+				encryption
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				bucket_name = @bucket_name AND
+				STRUCT<ObjectKey BYTES, Version INT64>(object_key, version) IN UNNEST(@locations) AND
+				status <> ` + statusPending + ` AND
+				(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.BucketName,
+			"locations":   spannerVersionedLocations(opts.Locations),
+		},
+	}), func(row *spanner.Row, object *Object) error {
+		object.ProjectID = opts.ProjectID
+		object.BucketName = opts.BucketName
+
+		fields := []any{
+			&object.ObjectKey, &object.StreamID, &object.Version, &object.Status,
+			&object.CreatedAt, &object.ExpiresAt,
+			spannerutil.Int(&object.SegmentCount),
+		}
+		if opts.IncludeCustomMetadata {
+			fields = append(fields, &object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey)
+		}
+		fields = append(fields,
+			&object.TotalPlainSize, &object.TotalEncryptedSize, spannerutil.Int(&object.FixedSegmentSize),
+			encryptionParameters{&object.Encryption},
+		)
+
+		return Error.Wrap(row.Columns(fields...))
+	})
+	if err != nil {
+		return nil, Error.New("unable to query objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+// GetObjectByStreamID returns the object with the specified stream ID in any status.
+// It's meant for debugging, when only the stream ID of an object is known. The query
+// requires scanning all objects, hence it shouldn't be used on the hot path.
+func (db *DB) GetObjectByStreamID(ctx context.Context, streamID uuid.UUID) (_ Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if streamID.IsZero() {
+		return Object{}, ErrInvalidRequest.New("StreamID missing")
+	}
+
+	// the project isn't known, so all the adapters need to be checked.
+	for _, adapter := range db.adapters {
+		object, err := adapter.GetObjectByStreamID(ctx, streamID)
+		if err != nil {
+			if ErrObjectNotFound.Has(err) {
+				continue
+			}
+			return Object{}, err
+		}
+		return object, nil
+	}
+
+	return Object{}, ErrObjectNotFound.Wrap(Error.New("object not found"))
+}
+
+// GetObjectByStreamID implements Adapter.
+func (p *PostgresAdapter) GetObjectByStreamID(ctx context.Context, streamID uuid.UUID) (object Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = p.db.QueryRowContext(ctx, `
+		SELECT
+			project_id, bucket_name, object_key, version, stream_id, status,
+			created_at, expires_at,
+			segment_count,
+			encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
+			total_plain_size, total_encrypted_size, fixed_segment_size,
+			encryption,
+			zombie_deletion_deadline,
+			computed_etag
+		FROM objects
+		WHERE stream_id = $1
+		LIMIT 1`,
+		streamID).
+		Scan(
+			&object.ProjectID, &object.BucketName, &object.ObjectKey, &object.Version, &object.StreamID, &object.Status,
+			&object.CreatedAt, &object.ExpiresAt,
+			&object.SegmentCount,
+			&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
+			&object.TotalPlainSize, &object.TotalEncryptedSize, &object.FixedSegmentSize,
+			encryptionParameters{&object.Encryption},
+			&object.ZombieDeletionDeadline,
+			&object.ComputedETag,
+		)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Object{}, ErrObjectNotFound.Wrap(Error.New("object not found"))
+		}
+		return Object{}, Error.New("unable to query object by stream id: %w", err)
+	}
+
+	return object, nil
+}
+
+// GetObjectByStreamID implements Adapter.
+func (s *SpannerAdapter) GetObjectByStreamID(ctx context.Context, streamID uuid.UUID) (object Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	object, err = spannerutil.CollectRow(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				project_id, bucket_name, object_key, version, stream_id, status,
+				created_at, expires_at,
+				segment_count,
+				encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
+				total_plain_size, total_encrypted_size, fixed_segment_size,
+				encryption,
+				zombie_deletion_deadline,
+				computed_etag
+			FROM objects
+			WHERE stream_id = @stream_id
+			LIMIT 1`,
+		Params: map[string]interface{}{
+			"stream_id": streamID,
+		},
+	}), func(row *spanner.Row, object *Object) error {
+		return Error.Wrap(row.Columns(
+			&object.ProjectID, &object.BucketName, &object.ObjectKey, &object.Version, &object.StreamID, &object.Status,
+			&object.CreatedAt, &object.ExpiresAt,
+			spannerutil.Int(&object.SegmentCount),
+			&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
+			&object.TotalPlainSize, &object.TotalEncryptedSize, spannerutil.Int(&object.FixedSegmentSize),
+			encryptionParameters{&object.Encryption},
+			&object.ZombieDeletionDeadline,
+			&object.ComputedETag,
+		))
+	})
+	if err != nil {
+		if errors.Is(err, iterator.Done) {
+			return Object{}, ErrObjectNotFound.Wrap(Error.New("object not found"))
+		}
+		return Object{}, Error.New("unable to query object by stream id: %w", err)
+	}
+
+	return object, nil
+}
+
 // GetSegmentByPosition contains arguments necessary for fetching a segment on specific position.
 type GetSegmentByPosition struct {
 	StreamID uuid.UUID
@@ -337,7 +828,7 @@ func (p *PostgresAdapter) GetSegmentByPosition(ctx context.Context, opts GetSegm
 			encrypted_etag,
 			redundancy,
 			inline_data, remote_alias_pieces,
-			placement
+			placement, is_manifest
 		FROM segments
 		WHERE (stream_id, position) = ($1, $2)
 	`, opts.StreamID, opts.Position.Encode()).
@@ -348,7 +839,7 @@ func (p *PostgresAdapter) GetSegmentByPosition(ctx context.Context, opts GetSegm
 			&segment.EncryptedETag,
 			redundancyScheme{&segment.Redundancy},
 			&segment.InlineData, &aliasPieces,
-			&segment.Placement,
+			&segment.Placement, &segment.IsManifest,
 		)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -371,7 +862,7 @@ func (s *SpannerAdapter) GetSegmentByPosition(ctx context.Context, opts GetSegme
 				encrypted_etag,
 				redundancy,
 				inline_data, remote_alias_pieces,
-				placement
+				placement, is_manifest
 			FROM segments
 			WHERE (stream_id, position) = (@stream_id, @position)
 		`,
@@ -387,7 +878,7 @@ func (s *SpannerAdapter) GetSegmentByPosition(ctx context.Context, opts GetSegme
 			&segment.EncryptedETag,
 			redundancyScheme{&segment.Redundancy},
 			&segment.InlineData, &aliasPieces,
-			&segment.Placement,
+			&segment.Placement, &segment.IsManifest,
 		))
 	})
 	if err != nil {
@@ -439,7 +930,7 @@ func (p *PostgresAdapter) GetLatestObjectLastSegment(ctx context.Context, opts G
 			encrypted_etag,
 			redundancy,
 			inline_data, remote_alias_pieces,
-			placement
+			placement, is_manifest
 		FROM segments
 		WHERE
 			stream_id IN (
@@ -462,7 +953,7 @@ func (p *PostgresAdapter) GetLatestObjectLastSegment(ctx context.Context, opts G
 			&segment.EncryptedETag,
 			redundancyScheme{&segment.Redundancy},
 			&segment.InlineData, &aliasPieces,
-			&segment.Placement,
+			&segment.Placement, &segment.IsManifest,
 		)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -485,7 +976,7 @@ func (s *SpannerAdapter) GetLatestObjectLastSegment(ctx context.Context, opts Ge
 				encrypted_etag,
 				redundancy,
 				inline_data, remote_alias_pieces,
-				placement
+				placement, is_manifest
 			FROM segments
 			WHERE
 				stream_id IN (
@@ -514,7 +1005,7 @@ func (s *SpannerAdapter) GetLatestObjectLastSegment(ctx context.Context, opts Ge
 			&segment.EncryptedETag,
 			redundancyScheme{&segment.Redundancy},
 			&segment.InlineData, &aliasPieces,
-			&segment.Placement,
+			&segment.Placement, &segment.IsManifest,
 		))
 	})
 
@@ -579,6 +1070,81 @@ func (s *SpannerAdapter) BucketEmpty(ctx context.Context, opts BucketEmpty) (emp
 	})
 }
 
+// BucketHasMultipleVersions returns true if any object key in the bucket has more
+// than one committed version, which means that the bucket has been versioned at some point.
+// It's meant for diagnostics, when the bucket versioning state isn't available.
+func (db *DB) BucketHasMultipleVersions(ctx context.Context, projectID uuid.UUID, bucketName string) (_ bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	switch {
+	case projectID.IsZero():
+		return false, ErrInvalidRequest.New("ProjectID missing")
+	case bucketName == "":
+		return false, ErrInvalidRequest.New("BucketName missing")
+	}
+
+	return db.ChooseAdapter(projectID).BucketHasMultipleVersions(ctx, projectID, bucketName)
+}
+
+// BucketHasMultipleVersions returns true if any object key in the bucket has more than one committed version.
+func (p *PostgresAdapter) BucketHasMultipleVersions(ctx context.Context, projectID uuid.UUID, bucketName string) (_ bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var value bool
+	err = p.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM objects AS o
+			WHERE
+				(o.project_id, o.bucket_name) = ($1, $2)
+				AND o.status IN `+statusesCommitted+`
+				AND EXISTS (
+					SELECT 1 FROM objects AS older
+					WHERE
+						(older.project_id, older.bucket_name, older.object_key) = (o.project_id, o.bucket_name, o.object_key)
+						AND older.version < o.version
+						AND older.status IN `+statusesCommitted+`
+				)
+		)
+	`, projectID, []byte(bucketName)).Scan(&value)
+	if err != nil {
+		return false, Error.New("unable to query objects: %w", err)
+	}
+
+	return value, nil
+}
+
+// BucketHasMultipleVersions returns true if any object key in the bucket has more than one committed version.
+func (s *SpannerAdapter) BucketHasMultipleVersions(ctx context.Context, projectID uuid.UUID, bucketName string) (_ bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	value, err := spannerutil.CollectRow(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `SELECT EXISTS (
+			SELECT 1 FROM objects AS o
+			WHERE
+				(o.project_id, o.bucket_name) = (@project_id, @bucket_name)
+				AND o.status IN ` + statusesCommitted + `
+				AND EXISTS (
+					SELECT 1 FROM objects AS older
+					WHERE
+						(older.project_id, older.bucket_name, older.object_key) = (o.project_id, o.bucket_name, o.object_key)
+						AND older.version < o.version
+						AND older.status IN ` + statusesCommitted + `
+				)
+		)`,
+		Params: map[string]interface{}{
+			"project_id":  projectID,
+			"bucket_name": bucketName,
+		},
+	}), func(row *spanner.Row, value *bool) error {
+		return Error.Wrap(row.Columns(value))
+	})
+	if err != nil {
+		return false, Error.New("unable to query objects: %w", err)
+	}
+
+	return value, nil
+}
+
 // TestingAllObjects gets all objects.
 // Use only for testing purposes.
 func (db *DB) TestingAllObjects(ctx context.Context) (objects []Object, err error) {
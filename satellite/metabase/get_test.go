@@ -516,6 +516,344 @@ func TestGetObjectLastCommitted(t *testing.T) {
 	})
 }
 
+func TestGetObjectsLastCommitted(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		t.Run("ProjectID missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsLastCommitted{
+				Opts:     metabase.GetObjectsLastCommitted{},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "ProjectID missing",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("BucketName missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsLastCommitted{
+				Opts: metabase.GetObjectsLastCommitted{
+					ProjectID: obj.ProjectID,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "BucketName missing",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("ObjectKey missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsLastCommitted{
+				Opts: metabase.GetObjectsLastCommitted{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					ObjectKeys: []metabase.ObjectKey{obj.ObjectKey, ""},
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "ObjectKey missing",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("no keys", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsLastCommitted{
+				Opts: metabase.GetObjectsLastCommitted{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+				},
+				Result: map[metabase.ObjectKey]metabase.Object{},
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("multiple objects", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			withVersions := obj
+			withVersions.ObjectKey = "with-versions"
+			withVersions.Version = 10
+			first := metabasetest.CreateObjectVersioned(ctx, t, db, withVersions, 0)
+			withVersions.Version = 11
+			withVersions.StreamID = testrand.UUID()
+			second := metabasetest.CreateObjectVersioned(ctx, t, db, withVersions, 0)
+
+			pendingLatest := obj
+			pendingLatest.ObjectKey = "pending-latest"
+			pendingLatest.Version = 1
+			pendingLatest.StreamID = testrand.UUID()
+			committed := metabasetest.CreateObjectVersioned(ctx, t, db, pendingLatest, 0)
+			pendingLatest.Version = 2
+			pendingLatest.StreamID = testrand.UUID()
+			pending := metabasetest.BeginObjectExactVersion{
+				Opts: metabase.BeginObjectExactVersion{
+					ObjectStream: pendingLatest,
+					Encryption:   metabasetest.DefaultEncryption,
+				},
+			}.Check(ctx, t, db)
+
+			deleted := obj
+			deleted.ObjectKey = "deleted"
+			deleted.Version = 1
+			deleted.StreamID = testrand.UUID()
+			deletedObject := metabasetest.CreateObjectVersioned(ctx, t, db, deleted, 0)
+			deleteResult, err := db.DeleteObjectLastCommitted(ctx, metabase.DeleteObjectLastCommitted{
+				ObjectLocation: deleted.Location(),
+				Versioned:      true,
+			})
+			require.NoError(t, err)
+
+			encryptedMetadata := testrand.Bytes(1024)
+			encryptedMetadataNonce := testrand.Nonce()
+			encryptedMetadataKey := testrand.Bytes(265)
+
+			withMetadata := obj
+			withMetadata.ObjectKey = "with-metadata"
+			withMetadata.StreamID = testrand.UUID()
+			metadataObject, _ := metabasetest.CreateTestObject{
+				CommitObject: &metabase.CommitObject{
+					ObjectStream:                  withMetadata,
+					EncryptedMetadataNonce:        encryptedMetadataNonce[:],
+					EncryptedMetadata:             encryptedMetadata,
+					EncryptedMetadataEncryptedKey: encryptedMetadataKey,
+					OverrideEncryptedMetadata:     true,
+				},
+			}.Run(ctx, t, db, withMetadata, 0)
+
+			keys := []metabase.ObjectKey{
+				withVersions.ObjectKey, pendingLatest.ObjectKey, deleted.ObjectKey,
+				withMetadata.ObjectKey, "missing",
+			}
+
+			metadataObjectWithoutMetadata := metadataObject
+			metadataObjectWithoutMetadata.EncryptedMetadataNonce = nil
+			metadataObjectWithoutMetadata.EncryptedMetadata = nil
+			metadataObjectWithoutMetadata.EncryptedMetadataEncryptedKey = nil
+
+			metabasetest.GetObjectsLastCommitted{
+				Opts: metabase.GetObjectsLastCommitted{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					ObjectKeys: keys,
+				},
+				Result: map[metabase.ObjectKey]metabase.Object{
+					withVersions.ObjectKey:  second,
+					pendingLatest.ObjectKey: committed,
+					withMetadata.ObjectKey:  metadataObjectWithoutMetadata,
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsLastCommitted{
+				Opts: metabase.GetObjectsLastCommitted{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					ObjectKeys: keys,
+
+					IncludeCustomMetadata: true,
+				},
+				Result: map[metabase.ObjectKey]metabase.Object{
+					withVersions.ObjectKey:  second,
+					pendingLatest.ObjectKey: committed,
+					withMetadata.ObjectKey:  metadataObject,
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{Objects: []metabase.RawObject{
+				metabase.RawObject(first),
+				metabase.RawObject(second),
+				metabase.RawObject(committed),
+				metabase.RawObject(pending),
+				metabase.RawObject(deletedObject),
+				metabase.RawObject(deleteResult.Markers[0]),
+				metabase.RawObject(metadataObject),
+			}}.Check(ctx, t, db)
+		})
+	})
+}
+
+func TestGetObjectsExactVersion(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		t.Run("invalid request", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsExactVersion{
+				Opts:     metabase.GetObjectsExactVersion{},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "ProjectID missing",
+			}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsExactVersion{
+				Opts: metabase.GetObjectsExactVersion{
+					ProjectID: obj.ProjectID,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "BucketName missing",
+			}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsExactVersion{
+				Opts: metabase.GetObjectsExactVersion{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					Locations:  []metabase.VersionedLocation{{Version: 1}},
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "ObjectKey missing",
+			}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsExactVersion{
+				Opts: metabase.GetObjectsExactVersion{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					Locations:  []metabase.VersionedLocation{{ObjectKey: obj.ObjectKey}},
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "Version invalid: 0",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("no locations", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsExactVersion{
+				Opts: metabase.GetObjectsExactVersion{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+				},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("multiple objects", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			withVersions := obj
+			withVersions.ObjectKey = "with-versions"
+			withVersions.Version = 10
+			first := metabasetest.CreateObjectVersioned(ctx, t, db, withVersions, 0)
+			withVersions.Version = 11
+			withVersions.StreamID = testrand.UUID()
+			second := metabasetest.CreateObjectVersioned(ctx, t, db, withVersions, 0)
+
+			pending := obj
+			pending.ObjectKey = "pending"
+			pending.Version = 1
+			pending.StreamID = testrand.UUID()
+			pendingObject := metabasetest.CreatePendingObject(ctx, t, db, pending, 0)
+
+			encryptedMetadata := testrand.Bytes(1024)
+			encryptedMetadataNonce := testrand.Nonce()
+			encryptedMetadataKey := testrand.Bytes(265)
+
+			withMetadata := obj
+			withMetadata.ObjectKey = "with-metadata"
+			withMetadata.StreamID = testrand.UUID()
+			metadataObject, _ := metabasetest.CreateTestObject{
+				CommitObject: &metabase.CommitObject{
+					ObjectStream:                  withMetadata,
+					EncryptedMetadataNonce:        encryptedMetadataNonce[:],
+					EncryptedMetadata:             encryptedMetadata,
+					EncryptedMetadataEncryptedKey: encryptedMetadataKey,
+					OverrideEncryptedMetadata:     true,
+				},
+			}.Run(ctx, t, db, withMetadata, 0)
+
+			locations := []metabase.VersionedLocation{
+				{ObjectKey: withMetadata.ObjectKey, Version: withMetadata.Version},
+				{ObjectKey: withVersions.ObjectKey, Version: 10},
+				{ObjectKey: withVersions.ObjectKey, Version: 12},
+				{ObjectKey: pending.ObjectKey, Version: pending.Version},
+				{ObjectKey: "missing", Version: 1},
+				{ObjectKey: withVersions.ObjectKey, Version: 11},
+			}
+			notFound := []metabase.VersionedLocation{locations[2], locations[3], locations[4]}
+
+			metadataObjectWithoutMetadata := metadataObject
+			metadataObjectWithoutMetadata.EncryptedMetadataNonce = nil
+			metadataObjectWithoutMetadata.EncryptedMetadata = nil
+			metadataObjectWithoutMetadata.EncryptedMetadataEncryptedKey = nil
+
+			metabasetest.GetObjectsExactVersion{
+				Opts: metabase.GetObjectsExactVersion{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					Locations:  locations,
+				},
+				Result: metabase.GetObjectsExactVersionResult{
+					Objects:  []metabase.Object{metadataObjectWithoutMetadata, first, second},
+					NotFound: notFound,
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.GetObjectsExactVersion{
+				Opts: metabase.GetObjectsExactVersion{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					Locations:  locations,
+
+					IncludeCustomMetadata: true,
+				},
+				Result: metabase.GetObjectsExactVersionResult{
+					Objects:  []metabase.Object{metadataObject, first, second},
+					NotFound: notFound,
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{Objects: []metabase.RawObject{
+				metabase.RawObject(first),
+				metabase.RawObject(second),
+				metabase.RawObject(pendingObject),
+				metabase.RawObject(metadataObject),
+			}}.Check(ctx, t, db)
+		})
+	})
+}
+
+func TestGetObjectByStreamID(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		t.Run("StreamID missing", func(t *testing.T) {
+			_, err := db.GetObjectByStreamID(ctx, uuid.UUID{})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+		})
+
+		t.Run("object missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.CreateObject(ctx, t, db, metabasetest.RandObjectStream(), 0)
+
+			_, err := db.GetObjectByStreamID(ctx, testrand.UUID())
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+
+		t.Run("found", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			committed := metabasetest.CreateObject(ctx, t, db, metabasetest.RandObjectStream(), 2)
+			pending := metabasetest.CreatePendingObject(ctx, t, db, metabasetest.RandObjectStream(), 0)
+
+			for _, expected := range []metabase.Object{committed, pending} {
+				object, err := db.GetObjectByStreamID(ctx, expected.StreamID)
+				require.NoError(t, err)
+				require.Equal(t, expected.ObjectStream, object.ObjectStream)
+				require.Equal(t, expected.Status, object.Status)
+				require.Equal(t, expected.SegmentCount, object.SegmentCount)
+				require.Equal(t, expected.TotalEncryptedSize, object.TotalEncryptedSize)
+			}
+		})
+	})
+}
+
 func TestGetSegmentByPosition(t *testing.T) {
 	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
 		obj := metabasetest.RandObjectStream()
@@ -1865,3 +2203,71 @@ func TestBucketEmpty(t *testing.T) {
 		})
 	})
 }
+
+func TestBucketHasMultipleVersions(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		t.Run("invalid request", func(t *testing.T) {
+			_, err := db.BucketHasMultipleVersions(ctx, uuid.UUID{}, obj.BucketName)
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "ProjectID missing")
+
+			_, err = db.BucketHasMultipleVersions(ctx, obj.ProjectID, "")
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "BucketName missing")
+		})
+
+		t.Run("single versions", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			has, err := db.BucketHasMultipleVersions(ctx, obj.ProjectID, obj.BucketName)
+			require.NoError(t, err)
+			require.False(t, has)
+
+			first := obj
+			first.Version = 1
+			metabasetest.CreateObjectVersioned(ctx, t, db, first, 0)
+
+			// pending objects and delete markers aren't committed versions.
+			pending := obj
+			pending.Version = 2
+			pending.StreamID = testrand.UUID()
+			metabasetest.CreatePendingObject(ctx, t, db, pending, 0)
+
+			_, err = db.DeleteObjectLastCommitted(ctx, metabase.DeleteObjectLastCommitted{
+				ObjectLocation: obj.Location(),
+				Versioned:      true,
+			})
+			require.NoError(t, err)
+
+			other := metabasetest.RandObjectStream()
+			other.ProjectID, other.BucketName = obj.ProjectID, obj.BucketName
+			metabasetest.CreateObject(ctx, t, db, other, 0)
+
+			has, err = db.BucketHasMultipleVersions(ctx, obj.ProjectID, obj.BucketName)
+			require.NoError(t, err)
+			require.False(t, has)
+		})
+
+		t.Run("multiple versions", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			for version := metabase.Version(1); version <= 2; version++ {
+				versioned := obj
+				versioned.Version = version
+				versioned.StreamID = testrand.UUID()
+				metabasetest.CreateObjectVersioned(ctx, t, db, versioned, 0)
+			}
+
+			has, err := db.BucketHasMultipleVersions(ctx, obj.ProjectID, obj.BucketName)
+			require.NoError(t, err)
+			require.True(t, has)
+
+			// other buckets aren't affected.
+			has, err = db.BucketHasMultipleVersions(ctx, obj.ProjectID, obj.BucketName+"-other")
+			require.NoError(t, err)
+			require.False(t, has)
+		})
+	})
+}
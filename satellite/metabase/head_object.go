@@ -0,0 +1,125 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/api/iterator"
+
+	"storj.io/storj/shared/dbutil/spannerutil"
+)
+
+// HeadObjectResult contains the summary of the last committed object version,
+// which is needed to answer a HEAD request.
+type HeadObjectResult struct {
+	Version Version
+	Status  ObjectStatus
+
+	TotalPlainSize     int64
+	TotalEncryptedSize int64
+
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+
+	Retention Retention
+	LegalHold bool
+}
+
+// HeadObject returns the existence, size and lock state of the last committed
+// object version with a single query. ErrObjectNotFound is returned when there's
+// no such version or when it's a delete marker.
+func (db *DB) HeadObject(ctx context.Context, location ObjectLocation) (result HeadObjectResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := location.Verify(); err != nil {
+		return HeadObjectResult{}, err
+	}
+
+	result, err = db.ChooseAdapter(location.ProjectID).HeadObject(ctx, location)
+	if err != nil {
+		return HeadObjectResult{}, err
+	}
+	if result.Status.IsDeleteMarker() {
+		return HeadObjectResult{}, ErrObjectNotFound.Wrap(Error.Wrap(sql.ErrNoRows))
+	}
+	return result, nil
+}
+
+// HeadObject implements Adapter.
+func (p *PostgresAdapter) HeadObject(ctx context.Context, location ObjectLocation) (result HeadObjectResult, err error) {
+	err = p.db.QueryRowContext(ctx, `
+		SELECT
+			version, status,
+			total_plain_size, total_encrypted_size,
+			created_at, expires_at,
+			retention_mode, retain_until
+		FROM objects
+		WHERE
+			(project_id, bucket_name, object_key) = ($1, $2, $3) AND
+			status <> `+statusPending+` AND
+			(expires_at IS NULL OR expires_at > now())
+		ORDER BY version DESC
+		LIMIT 1`,
+		location.ProjectID, []byte(location.BucketName), location.ObjectKey,
+	).Scan(
+		&result.Version, &result.Status,
+		&result.TotalPlainSize, &result.TotalEncryptedSize,
+		&result.CreatedAt, &result.ExpiresAt,
+		lockModeWrapper{retentionMode: &result.Retention.Mode, legalHold: &result.LegalHold},
+		timeWrapper{&result.Retention.RetainUntil},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return HeadObjectResult{}, ErrObjectNotFound.Wrap(Error.Wrap(sql.ErrNoRows))
+		}
+		return HeadObjectResult{}, Error.New("unable to query object: %w", err)
+	}
+	return result, nil
+}
+
+// HeadObject implements Adapter.
+func (s *SpannerAdapter) HeadObject(ctx context.Context, location ObjectLocation) (result HeadObjectResult, err error) {
+	result, err = spannerutil.CollectRow(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				version, status,
+				total_plain_size, total_encrypted_size,
+				created_at, expires_at,
+				retention_mode, retain_until
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				bucket_name = @bucket_name AND
+				object_key = @object_key AND
+				status <> ` + statusPending + ` AND
+				(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			ORDER BY version DESC
+			LIMIT 1`,
+		Params: map[string]interface{}{
+			"project_id":  location.ProjectID,
+			"bucket_name": location.BucketName,
+			"object_key":  location.ObjectKey,
+		},
+	}), func(row *spanner.Row, result *HeadObjectResult) error {
+		return Error.Wrap(row.Columns(
+			&result.Version, &result.Status,
+			&result.TotalPlainSize, &result.TotalEncryptedSize,
+			&result.CreatedAt, &result.ExpiresAt,
+			lockModeWrapper{retentionMode: &result.Retention.Mode, legalHold: &result.LegalHold},
+			timeWrapper{&result.Retention.RetainUntil},
+		))
+	})
+	if err != nil {
+		if errors.Is(err, iterator.Done) {
+			return HeadObjectResult{}, ErrObjectNotFound.Wrap(Error.Wrap(sql.ErrNoRows))
+		}
+		return HeadObjectResult{}, Error.New("unable to query object: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,125 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestHeadObject(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+		location := obj.Location()
+
+		t.Run("invalid location", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			for _, test := range metabasetest.InvalidObjectLocations(location) {
+				_, err := db.HeadObject(ctx, test.ObjectLocation)
+				require.True(t, test.ErrClass.Has(err), test.Name)
+				require.ErrorContains(t, err, test.ErrText, test.Name)
+			}
+		})
+
+		t.Run("missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			_, err := db.HeadObject(ctx, location)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+
+			metabasetest.CreatePendingObject(ctx, t, db, obj, 0)
+
+			_, err = db.HeadObject(ctx, location)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+
+		t.Run("expired", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.CreateExpiredObject(ctx, t, db, obj, 1, time.Now().Add(-time.Hour))
+
+			_, err := db.HeadObject(ctx, location)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+
+		t.Run("committed", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			object := metabasetest.CreateObject(ctx, t, db, obj, 2)
+
+			result, err := db.HeadObject(ctx, location)
+			require.NoError(t, err)
+			require.Equal(t, metabase.HeadObjectResult{
+				Version:            object.Version,
+				Status:             metabase.CommittedUnversioned,
+				TotalPlainSize:     object.TotalPlainSize,
+				TotalEncryptedSize: object.TotalEncryptedSize,
+				CreatedAt:          object.CreatedAt,
+			}, result)
+		})
+
+		t.Run("lock state", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			retention := metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: time.Now().Add(time.Hour).Truncate(time.Microsecond),
+			}
+			object, _ := metabasetest.CreateTestObject{
+				CommitObject: &metabase.CommitObject{
+					ObjectStream: obj,
+					Encryption:   metabasetest.DefaultEncryption,
+					Retention:    retention,
+					LegalHold:    true,
+				},
+			}.Run(ctx, t, db, obj, 1)
+
+			result, err := db.HeadObject(ctx, location)
+			require.NoError(t, err)
+			require.Equal(t, object.Version, result.Version)
+			require.Equal(t, retention.Mode, result.Retention.Mode)
+			require.WithinDuration(t, retention.RetainUntil, result.Retention.RetainUntil, time.Microsecond)
+			require.True(t, result.LegalHold)
+		})
+
+		t.Run("latest version", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.CreateObjectVersioned(ctx, t, db, obj, 1)
+
+			second := obj
+			second.Version = obj.Version + 1
+			object := metabasetest.CreateObjectVersioned(ctx, t, db, second, 2)
+
+			result, err := db.HeadObject(ctx, location)
+			require.NoError(t, err)
+			require.Equal(t, object.Version, result.Version)
+			require.Equal(t, metabase.CommittedVersioned, result.Status)
+			require.Equal(t, object.TotalPlainSize, result.TotalPlainSize)
+		})
+
+		t.Run("delete marker", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.CreateObjectVersioned(ctx, t, db, obj, 1)
+
+			_, err := db.DeleteObjectLastCommitted(ctx, metabase.DeleteObjectLastCommitted{
+				ObjectLocation: location,
+				Versioned:      true,
+			})
+			require.NoError(t, err)
+
+			_, err = db.HeadObject(ctx, location)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+	})
+}
@@ -0,0 +1,207 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
+)
+
+// IterateProjectSegments contains arguments necessary for iterating over all segments of a project.
+type IterateProjectSegments struct {
+	ProjectID uuid.UUID
+	// Cursor is exclusive, iteration continues after the segment at the cursor.
+	Cursor    ProjectSegmentsCursor
+	BatchSize int
+
+	// ExcludeInlineData doesn't return inline data of the segments.
+	ExcludeInlineData bool
+}
+
+// ProjectSegmentsCursor is a cursor used when iterating over segments of a project.
+type ProjectSegmentsCursor struct {
+	StreamID uuid.UUID
+	Position SegmentPosition
+}
+
+// Verify verifies iterate project segments request fields.
+func (opts *IterateProjectSegments) Verify() error {
+	if opts.ProjectID.IsZero() {
+		return ErrInvalidRequest.New("ProjectID missing")
+	}
+	if opts.BatchSize < 0 {
+		return ErrInvalidRequest.New("BatchSize is negative")
+	}
+	return nil
+}
+
+// IterateProjectSegments iterates over all segments of the project ordered by
+// stream ID and position. The segments are fetched in batches, so only a single
+// batch is kept in memory at a time. Iteration can be resumed by using the stream
+// ID and position of the last processed segment as the cursor.
+func (db *DB) IterateProjectSegments(ctx context.Context, opts IterateProjectSegments, fn func(context.Context, Segment) error) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return err
+	}
+
+	loopIteratorBatchSizeLimit.Ensure(&opts.BatchSize)
+
+	adapter := db.ChooseAdapter(opts.ProjectID)
+	for {
+		segments, err := adapter.ListProjectSegments(ctx, opts, db.aliasCache)
+		if err != nil {
+			return err
+		}
+
+		for _, segment := range segments {
+			if err := fn(ctx, segment); err != nil {
+				return err
+			}
+		}
+
+		if len(segments) < opts.BatchSize {
+			return nil
+		}
+
+		last := segments[len(segments)-1]
+		opts.Cursor = ProjectSegmentsCursor{
+			StreamID: last.StreamID,
+			Position: last.Position,
+		}
+	}
+}
+
+// ListProjectSegments lists a batch of project segments after the cursor.
+func (p *PostgresAdapter) ListProjectSegments(ctx context.Context, opts IterateProjectSegments, aliasCache *NodeAliasCache) (segments []Segment, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	inlineData := "segments.inline_data"
+	if opts.ExcludeInlineData {
+		inlineData = "NULL"
+	}
+
+	// segments don't contain the project ID, hence the project's objects are
+	// joined with the segments of their streams.
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT
+			segments.stream_id, segments.position,
+			segments.created_at, segments.expires_at, segments.repaired_at,
+			segments.root_piece_id, segments.encrypted_key_nonce, segments.encrypted_key,
+			segments.encrypted_size, segments.plain_offset, segments.plain_size,
+			segments.encrypted_etag,
+			segments.redundancy,
+			`+inlineData+`, segments.remote_alias_pieces,
+			segments.placement
+		FROM segments
+		JOIN objects ON objects.stream_id = segments.stream_id
+		WHERE
+			objects.project_id = $1 AND
+			(segments.stream_id, segments.position) > ($2, $3)
+		ORDER BY segments.stream_id ASC, segments.position ASC
+		LIMIT $4
+	`, opts.ProjectID, opts.Cursor.StreamID, opts.Cursor.Position, opts.BatchSize)
+
+	err = withRows(rows, err)(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			var segment Segment
+			var aliasPieces AliasPieces
+			err := rows.Scan(
+				&segment.StreamID, &segment.Position,
+				&segment.CreatedAt, &segment.ExpiresAt, &segment.RepairedAt,
+				&segment.RootPieceID, &segment.EncryptedKeyNonce, &segment.EncryptedKey,
+				&segment.EncryptedSize, &segment.PlainOffset, &segment.PlainSize,
+				&segment.EncryptedETag,
+				redundancyScheme{&segment.Redundancy},
+				&segment.InlineData, &aliasPieces,
+				&segment.Placement,
+			)
+			if err != nil {
+				return Error.New("failed to scan segments: %w", err)
+			}
+
+			segment.Pieces, err = aliasCache.ConvertAliasesToPieces(ctx, aliasPieces)
+			if err != nil {
+				return Error.New("failed to convert aliases to pieces: %w", err)
+			}
+
+			segments = append(segments, segment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to list project segments: %w", err)
+	}
+	return segments, nil
+}
+
+// ListProjectSegments lists a batch of project segments after the cursor.
+func (s *SpannerAdapter) ListProjectSegments(ctx context.Context, opts IterateProjectSegments, aliasCache *NodeAliasCache) (segments []Segment, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	inlineData := "segments.inline_data"
+	if opts.ExcludeInlineData {
+		inlineData = "CAST(NULL AS BYTES)"
+	}
+
+	// segments don't contain the project ID, hence the project's objects are
+	// joined with the segments of their streams.
+	segments, err = spannerutil.CollectRows(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				segments.stream_id, segments.position,
+				segments.created_at, segments.expires_at, segments.repaired_at,
+				segments.root_piece_id, segments.encrypted_key_nonce, segments.encrypted_key,
+				segments.encrypted_size, segments.plain_offset, segments.plain_size,
+				segments.encrypted_etag,
+				segments.redundancy,
+				` + inlineData + `, segments.remote_alias_pieces,
+				segments.placement
+			FROM segments
+			JOIN objects ON objects.stream_id = segments.stream_id
+			WHERE
+				objects.project_id = @project_id AND
+				(segments.stream_id > @stream_id OR (segments.stream_id = @stream_id AND segments.position > @position))
+			ORDER BY segments.stream_id ASC, segments.position ASC
+			LIMIT @batch_size
+		`,
+		Params: map[string]interface{}{
+			"project_id": opts.ProjectID,
+			"stream_id":  opts.Cursor.StreamID,
+			"position":   opts.Cursor.Position,
+			"batch_size": int64(opts.BatchSize),
+		},
+	}), func(row *spanner.Row, segment *Segment) error {
+		var aliasPieces AliasPieces
+		err := row.Columns(
+			&segment.StreamID, &segment.Position,
+			&segment.CreatedAt, &segment.ExpiresAt, &segment.RepairedAt,
+			&segment.RootPieceID, &segment.EncryptedKeyNonce, &segment.EncryptedKey,
+			spannerutil.Int(&segment.EncryptedSize), &segment.PlainOffset, spannerutil.Int(&segment.PlainSize),
+			&segment.EncryptedETag,
+			redundancyScheme{&segment.Redundancy},
+			&segment.InlineData, &aliasPieces,
+			&segment.Placement,
+		)
+		if err != nil {
+			return Error.New("failed to read segments: %w", err)
+		}
+
+		segment.Pieces, err = aliasCache.ConvertAliasesToPieces(ctx, aliasPieces)
+		if err != nil {
+			return Error.New("failed to convert aliases to pieces: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to list project segments: %w", err)
+	}
+	return segments, nil
+}
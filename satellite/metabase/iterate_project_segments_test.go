@@ -0,0 +1,154 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/common/uuid"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestIterateProjectSegments(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		collect := func(opts metabase.IterateProjectSegments) (segments []metabase.Segment) {
+			err := db.IterateProjectSegments(ctx, opts, func(ctx context.Context, segment metabase.Segment) error {
+				segments = append(segments, segment)
+				return nil
+			})
+			require.NoError(t, err)
+			return segments
+		}
+
+		t.Run("invalid request", func(t *testing.T) {
+			nop := func(context.Context, metabase.Segment) error { return nil }
+
+			err := db.IterateProjectSegments(ctx, metabase.IterateProjectSegments{}, nop)
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "ProjectID missing")
+
+			err = db.IterateProjectSegments(ctx, metabase.IterateProjectSegments{
+				ProjectID: testrand.UUID(),
+				BatchSize: -1,
+			}, nop)
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "BatchSize is negative")
+		})
+
+		t.Run("empty project", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			require.Empty(t, collect(metabase.IterateProjectSegments{ProjectID: testrand.UUID()}))
+		})
+
+		t.Run("iterate", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID := testrand.UUID()
+
+			var expected []metabase.Segment
+			for i := 0; i < 4; i++ {
+				obj := metabasetest.RandObjectStream()
+				obj.ProjectID = projectID
+				_, segments := metabasetest.CreateTestObject{}.Run(ctx, t, db, obj, byte(i))
+				expected = append(expected, segments...)
+			}
+
+			inline := metabasetest.RandObjectStream()
+			inline.ProjectID = projectID
+			inlineData := testrand.Bytes(100)
+			_, err := db.CommitInlineObject(ctx, metabase.CommitInlineObject{
+				ObjectStream: inline,
+				Encryption:   metabasetest.DefaultEncryption,
+				CommitInlineSegment: metabase.CommitInlineSegment{
+					ObjectStream:      inline,
+					EncryptedKey:      testrand.Bytes(32),
+					EncryptedKeyNonce: testrand.Bytes(32),
+					PlainSize:         512,
+					InlineData:        inlineData,
+				},
+			})
+			require.NoError(t, err)
+			expected = append(expected, metabase.Segment{StreamID: inline.StreamID, InlineData: inlineData})
+
+			// segments of a different project.
+			metabasetest.CreateTestObject{}.Run(ctx, t, db, metabasetest.RandObjectStream(), 3)
+
+			sort.Slice(expected, func(i, k int) bool {
+				if expected[i].StreamID != expected[k].StreamID {
+					return expected[i].StreamID.Less(expected[k].StreamID)
+				}
+				return expected[i].Position.Less(expected[k].Position)
+			})
+
+			requireSegments := func(expected, segments []metabase.Segment, withInlineData bool) {
+				require.Len(t, segments, len(expected))
+				for i, segment := range segments {
+					require.Equal(t, expected[i].StreamID, segment.StreamID)
+					require.Equal(t, expected[i].Position, segment.Position)
+					require.Equal(t, len(expected[i].Pieces), len(segment.Pieces))
+					if withInlineData {
+						require.Equal(t, expected[i].InlineData, segment.InlineData)
+					} else {
+						require.Empty(t, segment.InlineData)
+					}
+				}
+			}
+
+			for _, batchSize := range []int{0, 1, 2, 3, 100} {
+				requireSegments(expected, collect(metabase.IterateProjectSegments{
+					ProjectID: projectID,
+					BatchSize: batchSize,
+				}), true)
+			}
+
+			requireSegments(expected, collect(metabase.IterateProjectSegments{
+				ProjectID:         projectID,
+				BatchSize:         2,
+				ExcludeInlineData: true,
+			}), false)
+
+			// resume after an interrupted iteration.
+			var processed []metabase.Segment
+			errStop := errors.New("stop")
+			err = db.IterateProjectSegments(ctx, metabase.IterateProjectSegments{
+				ProjectID: projectID,
+				BatchSize: 2,
+			}, func(ctx context.Context, segment metabase.Segment) error {
+				if len(processed) == 3 {
+					return errStop
+				}
+				processed = append(processed, segment)
+				return nil
+			})
+			require.ErrorIs(t, err, errStop)
+
+			last := processed[len(processed)-1]
+			processed = append(processed, collect(metabase.IterateProjectSegments{
+				ProjectID: projectID,
+				Cursor: metabase.ProjectSegmentsCursor{
+					StreamID: last.StreamID,
+					Position: last.Position,
+				},
+				BatchSize: 2,
+			})...)
+			requireSegments(expected, processed, true)
+
+			require.Empty(t, collect(metabase.IterateProjectSegments{
+				ProjectID: projectID,
+				Cursor: metabase.ProjectSegmentsCursor{
+					StreamID: uuid.Max(),
+				},
+			}))
+		})
+	})
+}
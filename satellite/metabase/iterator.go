@@ -28,6 +28,7 @@ type objectsIterator struct {
 	recursive             bool
 	includeCustomMetadata bool
 	includeSystemMetadata bool
+	priority              RequestPriority
 
 	curIndex int
 	curRows  tagsql.Rows
@@ -63,6 +64,7 @@ func iterateAllVersionsWithStatusDescending(ctx context.Context, adapter Adapter
 		recursive:             opts.Recursive,
 		includeCustomMetadata: opts.IncludeCustomMetadata,
 		includeSystemMetadata: opts.IncludeSystemMetadata,
+		priority:              opts.Priority,
 
 		curIndex: 0,
 		cursor:   FirstIterateCursor(opts.Recursive, opts.Cursor, opts.Prefix),
@@ -95,6 +97,7 @@ func iterateAllVersionsWithStatusAscending(ctx context.Context, adapter Adapter,
 		recursive:             opts.Recursive,
 		includeCustomMetadata: opts.IncludeCustomMetadata,
 		includeSystemMetadata: opts.IncludeSystemMetadata,
+		priority:              opts.Priority,
 
 		curIndex: 0,
 		cursor:   FirstIterateCursor(opts.Recursive, opts.Cursor, opts.Prefix),
@@ -336,7 +339,7 @@ func (s *SpannerAdapter) doNextQueryAllVersionsWithStatus(ctx context.Context, i
 
 	if it.prefixLimit == "" {
 		querySelectFields := querySelectorFields("object_key", it)
-		rowIterator := s.client.Single().Query(ctx, spanner.Statement{
+		rowIterator := s.client.Single().QueryWithOptions(ctx, spanner.Statement{
 			SQL: `
 				SELECT
 					` + querySelectFields + `
@@ -358,7 +361,7 @@ func (s *SpannerAdapter) doNextQueryAllVersionsWithStatus(ctx context.Context, i
 				"batch_size":     int64(it.batchSize),
 				"next_bucket":    string(nextBucket(it.bucketName)),
 			},
-		})
+		}, it.priority.spannerQueryOptions())
 		return newSpannerRows(rowIterator), nil
 	}
 
@@ -368,7 +371,7 @@ func (s *SpannerAdapter) doNextQueryAllVersionsWithStatus(ctx context.Context, i
 	}
 
 	querySelectFields := querySelectorFields("SUBSTR(object_key, @from_substring)", it)
-	rowIterator := s.client.Single().Query(ctx, spanner.Statement{
+	rowIterator := s.client.Single().QueryWithOptions(ctx, spanner.Statement{
 		SQL: `
 			SELECT
 				` + querySelectFields + `
@@ -395,7 +398,7 @@ func (s *SpannerAdapter) doNextQueryAllVersionsWithStatus(ctx context.Context, i
 			"batch_size":     int64(it.batchSize),
 			"from_substring": int64(fromSubstring),
 		},
-	})
+	}, it.priority.spannerQueryOptions())
 	return newSpannerRows(rowIterator), nil
 }
 
@@ -472,7 +475,7 @@ func (s *SpannerAdapter) doNextQueryAllVersionsWithStatusAscending(ctx context.C
 
 	if it.prefixLimit == "" {
 		querySelectFields := querySelectorFields("object_key", it)
-		rowIterator := s.client.Single().Query(ctx, spanner.Statement{
+		rowIterator := s.client.Single().QueryWithOptions(ctx, spanner.Statement{
 			SQL: `
 				SELECT
 					` + querySelectFields + `
@@ -494,7 +497,7 @@ func (s *SpannerAdapter) doNextQueryAllVersionsWithStatusAscending(ctx context.C
 				"batch_size":     int64(it.batchSize),
 				"next_bucket":    string(nextBucket(it.bucketName)),
 			},
-		})
+		}, it.priority.spannerQueryOptions())
 		return newSpannerRows(rowIterator), nil
 	}
 
@@ -504,7 +507,7 @@ func (s *SpannerAdapter) doNextQueryAllVersionsWithStatusAscending(ctx context.C
 	}
 
 	querySelectFields := querySelectorFields("SUBSTR(object_key, @from_substring)", it)
-	rowIterator := s.client.Single().Query(ctx, spanner.Statement{
+	rowIterator := s.client.Single().QueryWithOptions(ctx, spanner.Statement{
 		SQL: `
 			SELECT
 				` + querySelectFields + `
@@ -531,7 +534,7 @@ func (s *SpannerAdapter) doNextQueryAllVersionsWithStatusAscending(ctx context.C
 			"batch_size":     int64(it.batchSize),
 			"from_substring": int64(fromSubstring),
 		},
-	})
+	}, it.priority.spannerQueryOptions())
 	return newSpannerRows(rowIterator), nil
 }
 
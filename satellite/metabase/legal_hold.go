@@ -0,0 +1,104 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"cloud.google.com/go/spanner"
+)
+
+// ClearLegalHold contains arguments necessary for clearing the legal hold of an object version.
+type ClearLegalHold struct {
+	ObjectStream
+}
+
+// Verify verifies clear legal hold request fields.
+func (opts *ClearLegalHold) Verify() error {
+	return opts.ObjectStream.Verify()
+}
+
+// ClearLegalHold removes the legal hold from a committed object version.
+//
+// The retention that remains on the object version is returned, so that the caller
+// can check whether the object version is still protected from deletion.
+func (db *DB) ClearLegalHold(ctx context.Context, opts ClearLegalHold) (retention Retention, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return Retention{}, err
+	}
+
+	retention, err = db.ChooseAdapter(opts.ProjectID).ClearLegalHold(ctx, opts)
+	if err != nil {
+		return Retention{}, err
+	}
+
+	mon.Meter("object_legal_hold_cleared").Mark(1)
+
+	return retention, nil
+}
+
+// ClearLegalHold removes the legal hold from a committed object version and returns its retention.
+func (p *PostgresAdapter) ClearLegalHold(ctx context.Context, opts ClearLegalHold) (retention Retention, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = p.db.QueryRowContext(ctx, `
+		UPDATE objects SET
+			retention_mode = NULLIF(retention_mode & `+retentionModeMaskSQL+`, 0)
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			stream_id = $5 AND
+			status IN `+statusesCommitted+`
+		RETURNING retention_mode, retain_until
+	`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, opts.StreamID).
+		Scan(lockModeWrapper{retentionMode: &retention.Mode}, timeWrapper{&retention.RetainUntil})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Retention{}, ErrObjectNotFound.Wrap(Error.New("object not found"))
+		}
+		return Retention{}, Error.New("unable to clear legal hold: %w", err)
+	}
+	return retention, nil
+}
+
+// ClearLegalHold removes the legal hold from a committed object version and returns its retention.
+func (s *SpannerAdapter) ClearLegalHold(ctx context.Context, opts ClearLegalHold) (retention Retention, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	found := false
+	_, err = s.client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		found = false
+		return tx.Query(ctx, spanner.Statement{
+			SQL: `
+				UPDATE objects SET
+					retention_mode = NULLIF(retention_mode & ` + retentionModeMaskSQL + `, 0)
+				WHERE
+					(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+					stream_id = @stream_id AND
+					status IN ` + statusesCommitted + `
+				THEN RETURN retention_mode, retain_until
+			`,
+			Params: map[string]interface{}{
+				"project_id":  opts.ProjectID,
+				"bucket_name": opts.BucketName,
+				"object_key":  opts.ObjectKey,
+				"version":     opts.Version,
+				"stream_id":   opts.StreamID,
+			},
+		}).Do(func(row *spanner.Row) error {
+			found = true
+			return Error.Wrap(row.Columns(lockModeWrapper{retentionMode: &retention.Mode}, timeWrapper{&retention.RetainUntil}))
+		})
+	})
+	if err != nil {
+		return Retention{}, Error.New("unable to clear legal hold: %w", err)
+	}
+	if !found {
+		return Retention{}, ErrObjectNotFound.Wrap(Error.New("object not found"))
+	}
+	return retention, nil
+}
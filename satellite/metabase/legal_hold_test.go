@@ -0,0 +1,118 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestClearLegalHold(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+		now := time.Now().Truncate(time.Second)
+
+		t.Run("invalid ObjectStream", func(t *testing.T) {
+			for _, test := range metabasetest.InvalidObjectStreams(obj) {
+				test := test
+				t.Run(test.Name, func(t *testing.T) {
+					metabasetest.ClearLegalHold{
+						Opts: metabase.ClearLegalHold{
+							ObjectStream: test.ObjectStream,
+						},
+						ErrClass: test.ErrClass,
+						ErrText:  test.ErrText,
+					}.Check(ctx, t, db)
+				})
+			}
+		})
+
+		t.Run("object missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.ClearLegalHold{
+				Opts: metabase.ClearLegalHold{
+					ObjectStream: obj,
+				},
+				ErrClass: &metabase.ErrObjectNotFound,
+				ErrText:  "metabase: object not found",
+			}.Check(ctx, t, db)
+
+			pending := metabasetest.CreatePendingObject(ctx, t, db, obj, 0)
+
+			metabasetest.ClearLegalHold{
+				Opts: metabase.ClearLegalHold{
+					ObjectStream: obj,
+				},
+				ErrClass: &metabase.ErrObjectNotFound,
+				ErrText:  "metabase: object not found",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{metabase.RawObject(pending)},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("clear", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			retention := metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(time.Hour),
+			}
+
+			newObject := func(retention metabase.Retention) metabase.RawObject {
+				return metabase.RawObject{
+					ObjectStream: metabasetest.RandObjectStream(),
+					CreatedAt:    now,
+					Status:       metabase.CommittedUnversioned,
+					Encryption:   metabasetest.DefaultEncryption,
+					Retention:    retention,
+					LegalHold:    true,
+				}
+			}
+
+			held := newObject(metabase.Retention{})
+			retained := newObject(retention)
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{held, retained}))
+
+			metabasetest.ClearLegalHold{
+				Opts: metabase.ClearLegalHold{
+					ObjectStream: held.ObjectStream,
+				},
+				Result: metabase.Retention{},
+			}.Check(ctx, t, db)
+
+			// the retention is kept when the legal hold is cleared.
+			metabasetest.ClearLegalHold{
+				Opts: metabase.ClearLegalHold{
+					ObjectStream: retained.ObjectStream,
+				},
+				Result: retention,
+			}.Check(ctx, t, db)
+
+			// clearing is idempotent.
+			metabasetest.ClearLegalHold{
+				Opts: metabase.ClearLegalHold{
+					ObjectStream: retained.ObjectStream,
+				},
+				Result: retention,
+			}.Check(ctx, t, db)
+
+			held.LegalHold = false
+			retained.LegalHold = false
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{held, retained},
+			}.Check(ctx, t, db)
+		})
+	})
+}
@@ -8,6 +8,8 @@ import (
 	"strings"
 	"time"
 
+	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/apiv1/spannerpb"
 	"github.com/zeebo/errs"
 
 	"storj.io/common/storj"
@@ -37,6 +39,13 @@ type ObjectEntry struct {
 	FixedSegmentSize   int32
 
 	Encryption storj.EncryptionParameters
+
+	SystemLabels map[string]string
+
+	Tags map[string]string
+
+	// Placement is the placement of the first segment, see ListObjects.IncludeFirstSegmentPlacement.
+	Placement storj.PlacementConstraint
 }
 
 // StreamVersionID returns byte representation of object stream version id.
@@ -88,6 +97,36 @@ type StreamIDCursor struct {
 	StreamID uuid.UUID
 }
 
+// RequestPriority is the priority of the Spanner requests of a listing.
+// Background jobs can use a lower priority to not compete with latency-sensitive
+// requests. Postgres ignores the priority.
+type RequestPriority byte
+
+const (
+	// RequestPriorityDefault uses the default priority of the Spanner client.
+	RequestPriorityDefault = RequestPriority(0)
+	// RequestPriorityLow uses the LOW Spanner request priority.
+	RequestPriorityLow = RequestPriority(1)
+	// RequestPriorityMedium uses the MEDIUM Spanner request priority.
+	RequestPriorityMedium = RequestPriority(2)
+	// RequestPriorityHigh uses the HIGH Spanner request priority.
+	RequestPriorityHigh = RequestPriority(3)
+)
+
+// spannerQueryOptions returns the Spanner query options for the priority.
+func (priority RequestPriority) spannerQueryOptions() spanner.QueryOptions {
+	switch priority {
+	case RequestPriorityLow:
+		return spanner.QueryOptions{Priority: spannerpb.RequestOptions_PRIORITY_LOW}
+	case RequestPriorityMedium:
+		return spanner.QueryOptions{Priority: spannerpb.RequestOptions_PRIORITY_MEDIUM}
+	case RequestPriorityHigh:
+		return spanner.QueryOptions{Priority: spannerpb.RequestOptions_PRIORITY_HIGH}
+	default:
+		return spanner.QueryOptions{}
+	}
+}
+
 // IterateObjectsWithStatus contains arguments necessary for listing objects in a bucket.
 type IterateObjectsWithStatus struct {
 	ProjectID             uuid.UUID
@@ -99,6 +138,9 @@ type IterateObjectsWithStatus struct {
 	Pending               bool
 	IncludeCustomMetadata bool
 	IncludeSystemMetadata bool
+
+	// Priority is the Spanner request priority of the queries, it's ignored by Postgres.
+	Priority RequestPriority
 }
 
 // IterateObjectsAllVersionsWithStatus iterates through all versions of all objects with specified status.
@@ -130,6 +172,8 @@ func (opts *IterateObjectsWithStatus) Verify() error {
 		return ErrInvalidRequest.New("BucketName missing")
 	case opts.BatchSize < 0:
 		return ErrInvalidRequest.New("BatchSize is negative")
+	case opts.Priority > RequestPriorityHigh:
+		return ErrInvalidRequest.New("Invalid Priority: %d", opts.Priority)
 	}
 	return nil
 }
@@ -141,11 +185,14 @@ func (db *DB) ListObjectsWithIterator(ctx context.Context, opts ListObjects) (re
 	if err := opts.Verify(); err != nil {
 		return ListObjectsResult{}, err
 	}
-	if opts.Pending || opts.AllVersions {
+	if !opts.supportedByIterator() || db.config.ValidateListedEncryption {
 		return ListObjectsResult{}, errs.New("not implemented")
 	}
 
-	ListLimit.Ensure(&opts.Limit)
+	if err := opts.ensureLimit(db.ListObjectsLimit(), db.config.RejectListObjectsOverLimit); err != nil {
+		return ListObjectsResult{}, err
+	}
+	opts.includeImplicitFields()
 
 	err = db.IterateObjectsAllVersionsWithStatus(ctx,
 		IterateObjectsWithStatus{
@@ -163,6 +210,7 @@ func (db *DB) ListObjectsWithIterator(ctx context.Context, opts ListObjects) (re
 			Pending:               false,
 			IncludeCustomMetadata: opts.IncludeCustomMetadata,
 			IncludeSystemMetadata: opts.IncludeSystemMetadata,
+			Priority:              opts.Priority,
 		}, func(ctx context.Context, it ObjectsIterator) error {
 			var previousLatestSet bool
 			var entry, previousLatest ObjectEntry
@@ -189,6 +237,19 @@ func (db *DB) ListObjectsWithIterator(ctx context.Context, opts ListObjects) (re
 					previousLatestSet = true
 					previousLatest = entry
 
+					if opts.excludes(entry) {
+						continue
+					}
+
+					if opts.KeysOnly {
+						entry = ObjectEntry{
+							IsPrefix:  entry.IsPrefix,
+							ObjectKey: entry.ObjectKey,
+							Version:   entry.Version,
+							Status:    entry.Status,
+						}
+					}
+
 					result.Objects = append(result.Objects, entry)
 				}
 			}
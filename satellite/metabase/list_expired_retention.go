@@ -0,0 +1,175 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
+)
+
+// ListExpiredRetentionObjects contains arguments necessary for listing the committed
+// objects of a bucket whose retention period has ended.
+type ListExpiredRetentionObjects struct {
+	ProjectID  uuid.UUID
+	BucketName string
+	// Now is the time against which the retention periods are compared.
+	Now time.Time
+	// Cursor is the object version after which the listing starts.
+	Cursor IterateCursor
+	Limit  int
+}
+
+// ExpiredRetentionObject is an object version whose retention period has ended.
+type ExpiredRetentionObject struct {
+	ObjectStream
+	Retention Retention
+}
+
+// ListExpiredRetentionObjectsResult is the result of ListExpiredRetentionObjects.
+type ListExpiredRetentionObjectsResult struct {
+	Objects []ExpiredRetentionObject
+	// NextCursor should be used as the cursor of the next request, when More is set.
+	NextCursor IterateCursor
+	More       bool
+}
+
+// Verify verifies list expired retention objects request fields.
+func (opts *ListExpiredRetentionObjects) Verify() error {
+	switch {
+	case opts.ProjectID.IsZero():
+		return ErrInvalidRequest.New("ProjectID missing")
+	case opts.BucketName == "":
+		return ErrInvalidRequest.New("BucketName missing")
+	case opts.Now.IsZero():
+		return ErrInvalidRequest.New("Now missing")
+	case opts.Limit < 0:
+		return ErrInvalidRequest.New("Invalid limit: %d", opts.Limit)
+	}
+	return nil
+}
+
+// ListExpiredRetentionObjects lists the committed object versions of a bucket, which have
+// a retention period that ended before opts.Now and which aren't under legal hold.
+// Object versions that never had a retention period aren't listed, nor are the ones whose
+// COMPLIANCE retention period is still in effect.
+//
+// The object versions are walked in primary key order, hence the listing should continue
+// with NextCursor while More is set.
+func (db *DB) ListExpiredRetentionObjects(ctx context.Context, opts ListExpiredRetentionObjects) (result ListExpiredRetentionObjectsResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return ListExpiredRetentionObjectsResult{}, err
+	}
+	ListLimit.Ensure(&opts.Limit)
+
+	result.Objects, err = db.ChooseAdapter(opts.ProjectID).ListExpiredRetentionObjects(ctx, opts)
+	if err != nil {
+		return ListExpiredRetentionObjectsResult{}, err
+	}
+
+	if len(result.Objects) == opts.Limit {
+		last := result.Objects[len(result.Objects)-1]
+		result.NextCursor = IterateCursor{
+			Key:     last.ObjectKey,
+			Version: last.Version,
+		}
+		result.More = true
+	}
+
+	return result, nil
+}
+
+// ListExpiredRetentionObjects lists the object versions whose retention period has ended.
+func (p *PostgresAdapter) ListExpiredRetentionObjects(ctx context.Context, opts ListExpiredRetentionObjects) (objects []ExpiredRetentionObject, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// retain_until < $5 excludes the object versions whose retention is still in effect,
+	// and NULL retain_until, i.e. versions without retention, never matches the comparison.
+	err = withRows(p.db.QueryContext(ctx, `
+		SELECT
+			object_key, version, stream_id,
+			retention_mode, retain_until
+		FROM objects
+		WHERE
+			(project_id, bucket_name) = ($1, $2) AND
+			(object_key, version) > ($3, $4) AND
+			status IN `+statusesCommitted+` AND
+			COALESCE(retention_mode, 0) & `+retentionModeMaskSQL+` <> 0 AND
+			COALESCE(retention_mode, 0) & `+legalHoldFlagSQL+` = 0 AND
+			retain_until < $5
+		ORDER BY project_id, bucket_name, object_key, version
+		LIMIT $6
+	`, opts.ProjectID, []byte(opts.BucketName), opts.Cursor.Key, opts.Cursor.Version, opts.Now, opts.Limit))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			object := ExpiredRetentionObject{ObjectStream: ObjectStream{
+				ProjectID:  opts.ProjectID,
+				BucketName: opts.BucketName,
+			}}
+			if err := rows.Scan(
+				&object.ObjectKey, &object.Version, &object.StreamID,
+				lockModeWrapper{retentionMode: &object.Retention.Mode}, timeWrapper{&object.Retention.RetainUntil},
+			); err != nil {
+				return Error.Wrap(err)
+			}
+			objects = append(objects, object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to list expired retention objects: %w", err)
+	}
+	return objects, nil
+}
+
+// ListExpiredRetentionObjects lists the object versions whose retention period has ended.
+func (s *SpannerAdapter) ListExpiredRetentionObjects(ctx context.Context, opts ListExpiredRetentionObjects) (objects []ExpiredRetentionObject, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// retain_until < @now excludes the object versions whose retention is still in effect,
+	// and NULL retain_until, i.e. versions without retention, never matches the comparison.
+	objects, err = spannerutil.CollectRows(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				object_key, version, stream_id,
+				retention_mode, retain_until
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				bucket_name = @bucket_name AND
+				(object_key > @object_key OR (object_key = @object_key AND version > @version)) AND
+				status IN ` + statusesCommitted + ` AND
+				COALESCE(retention_mode, 0) & ` + retentionModeMaskSQL + ` <> 0 AND
+				COALESCE(retention_mode, 0) & ` + legalHoldFlagSQL + ` = 0 AND
+				retain_until < @now
+			ORDER BY project_id, bucket_name, object_key, version
+			LIMIT @limit
+		`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.BucketName,
+			"object_key":  opts.Cursor.Key,
+			"version":     opts.Cursor.Version,
+			"now":         opts.Now,
+			"limit":       int64(opts.Limit),
+		},
+	}), func(row *spanner.Row, object *ExpiredRetentionObject) error {
+		object.ProjectID = opts.ProjectID
+		object.BucketName = opts.BucketName
+		return Error.Wrap(row.Columns(
+			&object.ObjectKey, &object.Version, &object.StreamID,
+			lockModeWrapper{retentionMode: &object.Retention.Mode}, timeWrapper{&object.Retention.RetainUntil},
+		))
+	})
+	if err != nil {
+		return nil, Error.New("unable to list expired retention objects: %w", err)
+	}
+	return objects, nil
+}
@@ -0,0 +1,160 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestListExpiredRetentionObjects(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+		now := time.Now().Truncate(time.Second)
+
+		t.Run("invalid request", func(t *testing.T) {
+			for _, test := range []struct {
+				opts    metabase.ListExpiredRetentionObjects
+				errText string
+			}{
+				{
+					opts:    metabase.ListExpiredRetentionObjects{BucketName: obj.BucketName, Now: now},
+					errText: "ProjectID missing",
+				},
+				{
+					opts:    metabase.ListExpiredRetentionObjects{ProjectID: obj.ProjectID, Now: now},
+					errText: "BucketName missing",
+				},
+				{
+					opts:    metabase.ListExpiredRetentionObjects{ProjectID: obj.ProjectID, BucketName: obj.BucketName},
+					errText: "Now missing",
+				},
+				{
+					opts:    metabase.ListExpiredRetentionObjects{ProjectID: obj.ProjectID, BucketName: obj.BucketName, Now: now, Limit: -1},
+					errText: "Invalid limit: -1",
+				},
+			} {
+				_, err := db.ListExpiredRetentionObjects(ctx, test.opts)
+				require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+				require.ErrorContains(t, err, test.errText)
+			}
+		})
+
+		t.Run("empty bucket", func(t *testing.T) {
+			result, err := db.ListExpiredRetentionObjects(ctx, metabase.ListExpiredRetentionObjects{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Now:        now,
+			})
+			require.NoError(t, err)
+			require.Empty(t, result.Objects)
+			require.False(t, result.More)
+		})
+
+		t.Run("list", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			expired := metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(-time.Hour),
+			}
+			active := metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(time.Hour),
+			}
+
+			newObject := func(key metabase.ObjectKey, status metabase.ObjectStatus, retention metabase.Retention, legalHold bool) metabase.RawObject {
+				stream := obj
+				stream.ObjectKey = key
+				stream.StreamID = testrand.UUID()
+				return metabase.RawObject{
+					ObjectStream: stream,
+					CreatedAt:    now,
+					Status:       status,
+					Encryption:   metabasetest.DefaultEncryption,
+					Retention:    retention,
+					LegalHold:    legalHold,
+				}
+			}
+
+			expiredA := newObject("a", metabase.CommittedVersioned, expired, false)
+			expiredC := newObject("c", metabase.CommittedUnversioned, expired, false)
+			expiredE := newObject("e", metabase.CommittedVersioned, expired, false)
+
+			activeB := newObject("b", metabase.CommittedVersioned, active, false)
+
+			otherBucket := newObject("b", metabase.CommittedVersioned, expired, false)
+			otherBucket.BucketName = "other-bucket"
+
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{
+				expiredA, expiredC, expiredE, otherBucket,
+				activeB,
+				newObject("d", metabase.CommittedVersioned, expired, true),
+				newObject("f", metabase.CommittedVersioned, metabase.Retention{}, false),
+				newObject("g", metabase.CommittedVersioned, metabase.Retention{}, true),
+				newObject("h", metabase.Pending, expired, false),
+			}))
+
+			requireObjects := func(expected []metabase.RawObject, objects []metabase.ExpiredRetentionObject) {
+				require.Len(t, objects, len(expected))
+				for i, object := range objects {
+					require.Equal(t, expected[i].ObjectStream, object.ObjectStream)
+					require.Equal(t, expected[i].Retention.Mode, object.Retention.Mode)
+					require.WithinDuration(t, expected[i].Retention.RetainUntil, object.Retention.RetainUntil, time.Second)
+				}
+			}
+
+			result, err := db.ListExpiredRetentionObjects(ctx, metabase.ListExpiredRetentionObjects{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Now:        now,
+			})
+			require.NoError(t, err)
+			requireObjects([]metabase.RawObject{expiredA, expiredC, expiredE}, result.Objects)
+			require.False(t, result.More)
+
+			// paginated
+			result, err = db.ListExpiredRetentionObjects(ctx, metabase.ListExpiredRetentionObjects{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Now:        now,
+				Limit:      2,
+			})
+			require.NoError(t, err)
+			requireObjects([]metabase.RawObject{expiredA, expiredC}, result.Objects)
+			require.True(t, result.More)
+			require.Equal(t, metabase.IterateCursor{Key: "c", Version: expiredC.Version}, result.NextCursor)
+
+			result, err = db.ListExpiredRetentionObjects(ctx, metabase.ListExpiredRetentionObjects{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Now:        now,
+				Cursor:     result.NextCursor,
+				Limit:      2,
+			})
+			require.NoError(t, err)
+			requireObjects([]metabase.RawObject{expiredE}, result.Objects)
+			require.False(t, result.More)
+
+			// the active retention lapses later.
+			result, err = db.ListExpiredRetentionObjects(ctx, metabase.ListExpiredRetentionObjects{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Now:        now.Add(2 * time.Hour),
+				Cursor:     metabase.IterateCursor{Key: "a", Version: expiredA.Version},
+				Limit:      1,
+			})
+			require.NoError(t, err)
+			requireObjects([]metabase.RawObject{activeB}, result.Objects)
+		})
+	})
+}
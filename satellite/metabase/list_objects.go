@@ -7,13 +7,20 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"cloud.google.com/go/spanner"
+	"cloud.google.com/go/spanner/apiv1/spannerpb"
 	"github.com/zeebo/errs"
 	"google.golang.org/api/iterator"
 
 	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil"
+	"storj.io/storj/shared/dbutil/pgutil"
 	"storj.io/storj/shared/dbutil/spannerutil"
 	"storj.io/storj/shared/tagsql"
 )
@@ -39,8 +46,94 @@ type ListObjects struct {
 	AllVersions           bool
 	IncludeCustomMetadata bool
 	IncludeSystemMetadata bool
+	IncludeSystemLabels   bool
+	IncludeTags           bool
+
+	// MaxVersionsPerKey limits how many versions of each key are listed,
+	// when AllVersions is set. Zero means no limit.
+	MaxVersionsPerKey int
+
+	// MinTotalEncryptedSize excludes objects with a smaller total encrypted size
+	// from the result. Prefixes are always listed. Setting it implies
+	// IncludeSystemMetadata.
+	MinTotalEncryptedSize int64
+
+	// CreatedAfter and CreatedBefore exclude objects created outside of the time window
+	// from the result, both bounds are exclusive and optional. Like MinTotalEncryptedSize,
+	// the latest version is filtered, so older versions are never listed in its place.
+	// Prefixes are always listed. Setting either implies IncludeSystemMetadata.
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// IncludeSoftDeleted controls whether soft-deleted objects are listed.
+	// By default they are excluded.
+	IncludeSoftDeleted SoftDeletedMode
+
+	// KeysOnly lists only the object keys, versions and statuses of the entries,
+	// which makes enumerating large buckets cheaper. It can't be combined with
+	// the Include options or MinTotalEncryptedSize.
+	KeysOnly bool
+
+	// OrderBy selects the order of the entries, by default they are ordered by key.
+	// Other orderings continue from OrderCursor instead of Cursor, see ListObjectsOrderBy.
+	OrderBy     ListObjectsOrderBy
+	OrderCursor ListObjectsOrderCursor
+
+	// RequeryLimit is the base number of queries a listing may execute. Zero uses
+	// the default of Limit + 10. More queries are allowed for entries that are
+	// skipped without counting towards Limit.
+	RequeryLimit int
+	// RequeryPerDeleteMarker is the number of additional queries allowed per delete
+	// marker hidden from the listing. It's meant for buckets with a high density
+	// of delete markers. Zero doesn't allow additional queries.
+	RequeryPerDeleteMarker int
+
+	// ReturnFullKey returns the full object keys of the entries, including Prefix.
+	// By default Prefix is stripped from the keys. Collapsed prefixes of non-recursive
+	// listings are still determined from the part of the key after Prefix.
+	ReturnFullKey bool
+
+	// IncludeFirstSegmentPlacement returns the placement of the first segment of each
+	// object in ObjectEntry.Placement. Objects without a first segment have a zero placement.
+	// The segment is looked up for every listed object version, which makes the listing
+	// noticeably more expensive, hence it should be used only when the placement is needed.
+	IncludeFirstSegmentPlacement bool
+
+	// SnapshotTime makes the listing read the database as of the given time, so that
+	// all the queries of a listing, and all the pages of a listing when the same time
+	// is passed with every page, see the same objects. Callers should pick the time
+	// once, e.g. when listing the first page. It must be within the version retention
+	// period of the database. Spanner uses an exact staleness read, Cockroach uses
+	// AS OF SYSTEM TIME and Postgres ignores the option.
+	SnapshotTime time.Time
+
+	// Priority is the Spanner request priority of the listing queries, it's ignored by Postgres.
+	Priority RequestPriority
+
+	// validateEncryption makes the scans verify the encryption parameters of the entries,
+	// it's set from Config.ValidateListedEncryption.
+	validateEncryption bool
 }
 
+// SoftDeletedMode controls how ListObjects treats soft-deleted objects.
+//
+// A soft-deleted object is an unversioned object, which was converted into a versioned
+// delete marker when it was overwritten, see CommitObject.SoftDeleteOnOverwrite.
+// Unlike regular delete markers, it keeps its stream and encryption parameters.
+type SoftDeletedMode byte
+
+const (
+	// SoftDeletedExclude excludes soft-deleted objects from the listing.
+	SoftDeletedExclude = SoftDeletedMode(0)
+	// SoftDeletedInclude lists soft-deleted objects as delete markers together with other objects.
+	SoftDeletedInclude = SoftDeletedMode(1)
+	// SoftDeletedOnly lists only soft-deleted objects, it requires AllVersions.
+	SoftDeletedOnly = SoftDeletedMode(2)
+)
+
+// softDeletedCondition matches soft-deleted objects, regular delete markers don't have encryption set.
+const softDeletedCondition = `(status = ` + statusDeleteMarkerVersioned + ` AND encryption <> 0)`
+
 // Verify verifies get object request fields.
 func (opts *ListObjects) Verify() error {
 	switch {
@@ -48,13 +141,101 @@ func (opts *ListObjects) Verify() error {
 		return ErrInvalidRequest.New("ProjectID missing")
 	case opts.BucketName == "":
 		return ErrInvalidRequest.New("BucketName missing")
+	case !utf8.ValidString(opts.BucketName):
+		// Spanner stores bucket names as STRING, while Postgres compares raw bytes,
+		// which would list such bucket names differently.
+		return ErrInvalidRequest.New("BucketName must be valid UTF-8")
 	case opts.Limit < 0:
 		return ErrInvalidRequest.New("Invalid limit: %d", opts.Limit)
+	case opts.MaxVersionsPerKey < 0:
+		return ErrInvalidRequest.New("Invalid MaxVersionsPerKey: %d", opts.MaxVersionsPerKey)
+	case opts.MinTotalEncryptedSize < 0:
+		return ErrInvalidRequest.New("Invalid MinTotalEncryptedSize: %d", opts.MinTotalEncryptedSize)
+	case opts.IncludeSoftDeleted > SoftDeletedOnly:
+		return ErrInvalidRequest.New("Invalid IncludeSoftDeleted: %d", opts.IncludeSoftDeleted)
+	case opts.IncludeSoftDeleted == SoftDeletedOnly && (!opts.AllVersions || opts.Pending):
+		return ErrInvalidRequest.New("listing only soft-deleted objects requires AllVersions")
+	case opts.KeysOnly && (opts.IncludeCustomMetadata || opts.IncludeSystemMetadata || opts.IncludeSystemLabels || opts.IncludeTags || opts.IncludeFirstSegmentPlacement):
+		return ErrInvalidRequest.New("KeysOnly can't be combined with including metadata")
+	case opts.KeysOnly && opts.MinTotalEncryptedSize > 0:
+		return ErrInvalidRequest.New("KeysOnly can't be combined with MinTotalEncryptedSize")
+	case opts.KeysOnly && (opts.CreatedAfter != nil || opts.CreatedBefore != nil):
+		return ErrInvalidRequest.New("KeysOnly can't be combined with CreatedAfter or CreatedBefore")
+	case opts.CreatedAfter != nil && opts.CreatedBefore != nil && !opts.CreatedBefore.After(*opts.CreatedAfter):
+		return ErrInvalidRequest.New("CreatedBefore must be after CreatedAfter")
+	case !opts.cursorVersionInRange():
+		return ErrInvalidRequest.New("Invalid Cursor.Version for %s version order: %d", opts.versionOrderName(), opts.Cursor.Version)
+	case opts.RequeryLimit < 0:
+		return ErrInvalidRequest.New("Invalid RequeryLimit: %d", opts.RequeryLimit)
+	case opts.RequeryPerDeleteMarker < 0:
+		return ErrInvalidRequest.New("Invalid RequeryPerDeleteMarker: %d", opts.RequeryPerDeleteMarker)
+	case opts.Priority > RequestPriorityHigh:
+		return ErrInvalidRequest.New("Invalid Priority: %d", opts.Priority)
 	}
 
+	return opts.verifyOrderBy()
+}
+
+// supportedByIterator returns whether ListObjectsWithIterator supports the options,
+// it only lists the latest committed versions in the key order.
+func (opts *ListObjects) supportedByIterator() bool {
+	return !opts.Pending && !opts.AllVersions && opts.keyOrdered() && !opts.ReturnFullKey &&
+		!opts.IncludeFirstSegmentPlacement && opts.SnapshotTime.IsZero() && !opts.IncludeSystemLabels &&
+		opts.MaxVersionsPerKey == 0 && opts.IncludeSoftDeleted == SoftDeletedExclude && !opts.IncludeTags
+}
+
+// excludes returns whether the entry is excluded from the listing by MinTotalEncryptedSize
+// or the creation time window. Prefixes are never excluded.
+func (opts *ListObjects) excludes(entry ObjectEntry) bool {
+	switch {
+	case entry.IsPrefix:
+		return false
+	case entry.TotalEncryptedSize < opts.MinTotalEncryptedSize:
+		return true
+	case opts.CreatedAfter != nil && !entry.CreatedAt.After(*opts.CreatedAfter):
+		return true
+	case opts.CreatedBefore != nil && !entry.CreatedAt.Before(*opts.CreatedBefore):
+		return true
+	}
+	return false
+}
+
+// filtersInQuery returns whether the queries exclude the entries filtered by
+// MinTotalEncryptedSize and the creation time window, so that they aren't read
+// at all. It's only possible when every version is listed on its own: otherwise
+// an excluded latest version must still hide the older versions, and the entries
+// inside a collapsed prefix must still produce the prefix. The scans filter the
+// entries with excludes in either case.
+func (opts *ListObjects) filtersInQuery() bool {
+	return opts.AllVersions && opts.Recursive &&
+		(opts.MinTotalEncryptedSize > 0 || opts.CreatedAfter != nil || opts.CreatedBefore != nil)
+}
+
+// ensureLimit clamps Limit to maxLimit. When reject is set, a Limit exceeding
+// maxLimit fails instead.
+func (opts *ListObjects) ensureLimit(maxLimit int, reject bool) error {
+	if reject && opts.Limit > maxLimit {
+		return ErrInvalidRequest.New("Limit %d exceeds the maximum %d", opts.Limit, maxLimit)
+	}
+	intLimitRange(maxLimit).Ensure(&opts.Limit)
 	return nil
 }
 
+// spannerReadOnly returns the read-only transaction the Spanner listing queries use.
+func (opts *ListObjects) spannerReadOnly(client *spanner.Client) *spanner.ReadOnlyTransaction {
+	if opts.SnapshotTime.IsZero() {
+		return client.Single()
+	}
+	return client.Single().WithTimestampBound(spanner.ReadTimestamp(opts.SnapshotTime))
+}
+
+// bucketBounds returns the bucket name and the next bucket name, which is the
+// exclusive upper bound of the listing. Both adapters must use the same bounds.
+func (opts *ListObjects) bucketBounds() (bucket, next []byte) {
+	bucket = []byte(opts.BucketName)
+	return bucket, nextBucket(bucket)
+}
+
 // ListObjectsResult result of listing objects.
 type ListObjectsResult struct {
 	Objects []ObjectEntry
@@ -73,297 +254,206 @@ func (db *DB) ListObjects(ctx context.Context, opts ListObjects) (result ListObj
 		return ListObjectsResult{}, err
 	}
 
-	ListLimit.Ensure(&opts.Limit)
-
-	return db.ChooseAdapter(opts.ProjectID).ListObjects(ctx, opts)
+	if err := opts.ensureLimit(db.ListObjectsLimit(), db.config.RejectListObjectsOverLimit); err != nil {
+		return ListObjectsResult{}, err
+	}
+	opts.includeImplicitFields()
+	opts.validateEncryption = db.config.ValidateListedEncryption
+
+	err = db.ChooseAdapter(opts.ProjectID).IterateObjects(ctx, opts, func(entry ObjectEntry) error {
+		if len(result.Objects) >= opts.Limit {
+			// an entry beyond the limit means that there are more entries.
+			result.More = true
+			return errListObjectsPageFull
+		}
+		result.Objects = append(result.Objects, entry)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errListObjectsPageFull) {
+		return ListObjectsResult{}, err
+	}
+	return result, nil
 }
 
-// ListObjects lists objects.
-func (p *PostgresAdapter) ListObjects(ctx context.Context, opts ListObjects) (result ListObjectsResult, err error) {
-	// maxSkipVersionsUntilRequery is the limit on how many versions we query for a single object, until we requery.
-	const maxSkipVersionsUntilRequery = 100
+// errListObjectsPageFull stops the iteration, when ListObjects has collected a full page.
+var errListObjectsPageFull = errs.New("page full")
 
-	// maxSkipPrefixUntilRequery is the limit on how many entries we scan inside a prefix, until we requery.
-	const maxSkipPrefixUntilRequery = 10
-
-	// minQuerySize ensures that we list a more entries, as there's a significant overhead to a single query.
-	const minQuerySize = 100
+// IterateObjects lists objects the same way as ListObjects, however, instead of
+// collecting a page of entries, it calls fn for every entry. The iteration continues
+// until all the entries have been listed or fn returns an error, which is returned as is.
+//
+// opts.Limit doesn't limit the number of entries, it's only used for sizing the queries.
+func (db *DB) IterateObjects(ctx context.Context, opts ListObjects, fn func(ObjectEntry) error) (err error) {
+	defer mon.Task()(&ctx)(&err)
 
-	// requeryLimit is a safety net for invalid implementation.
-	requeryLimit := opts.Limit + 10 // we do some extra queries, but, roughly at most we should have one query per entry
+	if err := opts.Verify(); err != nil {
+		return err
+	}
 
-	// extraSkipEntries to avoid requerying in the common case of !AllVersions.
-	const extraSkipEntries = 10
-	// extraEntriesForMore is the additional entry we need for determining whether there are more entries.
-	const extraEntriesForMore = 1
-	batchSize := opts.Limit + extraEntriesForMore + extraSkipEntries
+	ListLimit.Ensure(&opts.Limit)
+	opts.includeImplicitFields()
+	opts.validateEncryption = db.config.ValidateListedEncryption
 
-	if batchSize < minQuerySize {
-		batchSize = minQuerySize
-	}
+	return db.ChooseAdapter(opts.ProjectID).IterateObjects(ctx, opts, fn)
+}
 
-	// lastEntry is used to keep track of the last entry put into the result.
-	var lastEntry struct {
-		Set bool
+// ExplainListObjects returns the query plan of the first query that ListObjects
+// would execute for opts. It's meant for diagnosing slow listings.
+func (db *DB) ExplainListObjects(ctx context.Context, opts ListObjects) (_ string, err error) {
+	defer mon.Task()(&ctx)(&err)
 
-		ObjectKey ObjectKey
-		Version   Version
-		IsPrefix  bool
+	if err := opts.Verify(); err != nil {
+		return "", err
 	}
 
-	// skipCounter keeps track on how many entries we have skipped either due to
-	// objects of similar version or due to a collapsed non-recursive prefix.
-	type skipCounter struct {
-		Prefix  int
-		Version int
+	if err := opts.ensureLimit(db.ListObjectsLimit(), db.config.RejectListObjectsOverLimit); err != nil {
+		return "", err
 	}
-	var skipCount skipCounter
+	opts.includeImplicitFields()
 
-	cursor := opts.StartCursor()
+	return db.ChooseAdapter(opts.ProjectID).ExplainListObjects(ctx, opts)
+}
 
-	for repeat := 0; repeat < requeryLimit; repeat++ {
-		args := []any{
-			opts.ProjectID, []byte(opts.BucketName),
-			cursor.Key, cursor.Version,
-			batchSize, nextBucket([]byte(opts.BucketName)),
-		}
-		if opts.Prefix != "" {
-			args = append(args, len(opts.Prefix)+1, opts.stopKey())
-		}
+// IterateObjects calls fn for every listed object.
+func (p *PostgresAdapter) IterateObjects(ctx context.Context, opts ListObjects, fn func(ObjectEntry) error) (err error) {
+	if !opts.keyOrdered() {
+		return p.iterateObjectsOrdered(ctx, opts, fn)
+	}
 
-		var objectKey = `object_key`
-		if opts.Prefix != "" {
-			objectKey = `substring(object_key from $7) AS object_key`
-		}
+	state := newListObjectsState(&opts, fn)
 
-		var statusCondition = `status != ` + statusPending
-		if opts.Pending {
-			statusCondition = `status = ` + statusPending
-		}
+	for repeat := 0; repeat < state.requeryLimit; repeat++ {
+		query, args := listObjectsQueryPostgres(p.impl, state)
 
-		rows, err := p.db.QueryContext(ctx, `SELECT
-			`+objectKey+`,
-			version
-			`+opts.selectedFields()+`
-			FROM objects
-			WHERE
-				`+opts.boundaryPostgres()+`
-				AND (project_id, bucket_name) < ($1, $6)
-				AND `+statusCondition+`
-				AND (expires_at IS NULL OR expires_at > now())
-			ORDER BY `+opts.orderBy()+`
-			LIMIT $5
-		`, args...)
+		rows, err := p.db.QueryContext(ctx, query, args...)
 		if errors.Is(err, sql.ErrNoRows) {
-			return result, nil
+			return nil
 		}
 		if err != nil {
-			return result, Error.Wrap(err)
+			return Error.Wrap(err)
 		}
 
-		scannedCount := 0
-		skipAhead := false
-	read_entries:
+		state.startBatch()
 		for rows.Next() {
 			entry, err := scanListObjectsEntryPostgres(rows, &opts)
 			if err != nil {
-				return result, Error.Wrap(errs.Combine(err, rows.Err(), rows.Close()))
+				return Error.Wrap(errs.Combine(err, rows.Err(), rows.Close()))
 			}
-			scannedCount++
-
-			// skip a duplicate prefix entry, which only happens with opts.Recursive
-			// TODO: does this need opts.AllVersions
-			skipPrefix := lastEntry.Set && opts.AllVersions && lastEntry.IsPrefix && entry.IsPrefix && lastEntry.ObjectKey == entry.ObjectKey
-			// skip duplicate object key with other versions, when !opts.AllVersions
-			skipVersion := lastEntry.Set && !opts.AllVersions && lastEntry.IsPrefix == entry.IsPrefix && lastEntry.ObjectKey == entry.ObjectKey
-
-			// we'll need to ensure that when we are iterating only latest objects that we don't
-			// emit an object entry when we start iterating from half-way in versions.
-			var skipCursorAllVersionsDoubleCheck bool
-			if !opts.AllVersions && entryKeyMatchesCursor(opts.Prefix, entry.ObjectKey, opts.Cursor.Key) {
-				if opts.VersionAscending() {
-					skipCursorAllVersionsDoubleCheck = entry.Version <= opts.Cursor.Version
-				} else {
-					skipCursorAllVersionsDoubleCheck = entry.Version >= opts.Cursor.Version
-				}
-			}
-
-			lastEntry.Set = true
-			lastEntry.ObjectKey = entry.ObjectKey
-			lastEntry.Version = entry.Version
-			lastEntry.IsPrefix = entry.IsPrefix
 
-			if skipPrefix || skipVersion || skipCursorAllVersionsDoubleCheck {
-				if skipPrefix {
-					skipCount.Prefix++
-				}
-				if skipVersion {
-					skipCount.Version++
-				}
-
-				if skipCount.Prefix >= maxSkipPrefixUntilRequery || skipCount.Version >= maxSkipVersionsUntilRequery {
-					skipAhead = true
-					skipCount = skipCounter{}
-					// we landed inside a large number of repeated items,
-					// either prefixes or versions, let's requery and skip
-					break read_entries
-				}
-
-				continue
-			}
-
-			skipCount = skipCounter{}
-
-			// We don't want to include delete markers in the output, when we are listing only the latest version.
-			// We still set "lastEntry" so we skip any objects that are beyond the delete marker.
-			if !opts.AllVersions && entry.Status.IsDeleteMarker() {
-				continue
+			requery, err := state.add(entry)
+			if err != nil {
+				return errs.Combine(err, rows.Close())
 			}
-
-			result.Objects = append(result.Objects, entry)
-			if len(result.Objects) >= opts.Limit+1 {
-				result.More = true
-				result.Objects = result.Objects[:opts.Limit]
-				return result, Error.Wrap(errs.Combine(err, rows.Err(), rows.Close()))
+			if requery {
+				break
 			}
 		}
 
 		if err := errs.Combine(rows.Err(), rows.Close()); err != nil {
-			return result, Error.Wrap(err)
+			return Error.Wrap(err)
 		}
 
-		if scannedCount == 0 {
-			result.More = false
-			return result, nil
-		}
-		if !skipAhead && scannedCount < batchSize {
-			result.More = false
-			return result, nil
-		}
-
-		switch {
-		case lastEntry.IsPrefix: // can only be true if recursive listing
-			// skip over the prefix
-			cursor.Key = opts.Prefix + lastEntry.ObjectKey[:len(lastEntry.ObjectKey)-1] + DelimiterNext
-			cursor.Version = opts.FirstVersion()
-
-		case opts.AllVersions:
-			// continue where-ever we left off
-			cursor.Key = opts.Prefix + lastEntry.ObjectKey
-			cursor.Version = lastEntry.Version
-
-		case !opts.AllVersions:
-			// jump to the next object
-			cursor.Key = opts.Prefix + lastEntry.ObjectKey
-			cursor.Version = opts.lastVersion()
+		if !state.nextBatch() {
+			return nil
 		}
 	}
 
-	panic("too many requeries")
+	return state.errTooManyRequeries()
 }
 
-// ListObjects lists objects.
-func (s *SpannerAdapter) ListObjects(ctx context.Context, opts ListObjects) (result ListObjectsResult, err error) {
-	// TODO(spanner): retune all of these for Spanner. Also, can we use a smarter query now
-	// using some feature that wasn't in Cockroach? (e.g. windowed queries).
-
-	// maxSkipVersionsUntilRequery is the limit on how many versions we query for a single object, until we requery.
-	const maxSkipVersionsUntilRequery = 100
+// ExplainListObjects explains the first query of ListObjects.
+func (p *PostgresAdapter) ExplainListObjects(ctx context.Context, opts ListObjects) (_ string, err error) {
+	defer mon.Task()(&ctx)(&err)
 
-	// maxSkipPrefixUntilRequery is the limit on how many entries we scan inside a prefix, until we requery.
-	const maxSkipPrefixUntilRequery = 10
+	if !opts.keyOrdered() {
+		return p.explainListObjectsOrdered(ctx, opts)
+	}
 
-	// minQuerySize ensures that we list a more entries, as there's a significant overhead to a single query.
-	const minQuerySize = 100
+	query, args := listObjectsQueryPostgres(p.impl, newListObjectsState(&opts, nil))
 
-	// requeryLimit is a safety net for invalid implementation.
-	requeryLimit := opts.Limit + 10 // we do some extra queries, but, roughly at most we should have one query per entry
+	explanation, err := pgutil.Explain(ctx, p.db, query, args...)
+	if err != nil {
+		return "", Error.Wrap(err)
+	}
+	return explanation.String(), nil
+}
 
-	// extraSkipEntries to avoid requerying in the common case of !AllVersions.
-	const extraSkipEntries = 10
-	// extraEntriesForMore is the additional entry we need for determining whether there are more entries.
-	const extraEntriesForMore = 1
-	batchSize := opts.Limit + extraEntriesForMore + extraSkipEntries
+// listObjectsQueryPostgres returns the query for the next batch of state.
+func listObjectsQueryPostgres(impl dbutil.Implementation, state *listObjectsState) (query string, args []any) {
+	opts := state.opts
+	bucket, next := opts.bucketBounds()
 
-	if batchSize < minQuerySize {
-		batchSize = minQuerySize
+	args = []any{
+		opts.ProjectID, bucket,
+		state.cursor.Key, state.cursor.Version,
+		state.batchSize, next,
+	}
+	if opts.Prefix != "" {
+		args = append(args, opts.stopKey())
 	}
 
-	// lastEntry is used to keep track of the last entry put into the result.
-	var lastEntry struct {
-		Set bool
-
-		ObjectKey ObjectKey
-		Version   Version
-		IsPrefix  bool
+	var objectKey = `object_key`
+	if opts.strippedPrefix() != "" {
+		args = append(args, len(opts.Prefix)+1)
+		objectKey = `substring(object_key from $8) AS object_key`
 	}
 
-	// skipCounter keeps track on how many entries we have skipped either due to
-	// objects of similar version or due to a collapsed non-recursive prefix.
-	type skipCounter struct {
-		Prefix  int
-		Version int
-	}
-	var skipCount skipCounter
-
-	cursor := opts.StartCursor()
-
-	for repeat := 0; repeat < requeryLimit; repeat++ {
-		args := map[string]any{
-			"project_id":     opts.ProjectID,
-			"bucket_name":    opts.BucketName,
-			"cursor_key":     cursor.Key,
-			"cursor_version": cursor.Version,
-			"limit":          batchSize,
-			"next_bucket":    nextBucket([]byte(opts.BucketName)),
+	var filterCondition string
+	if opts.filtersInQuery() {
+		if opts.MinTotalEncryptedSize > 0 {
+			args = append(args, opts.MinTotalEncryptedSize)
+			filterCondition += ` AND total_encrypted_size >= $` + strconv.Itoa(len(args))
 		}
-		if opts.Prefix != "" {
-			args["prefix_len"] = len(opts.Prefix) + 1
-			args["stop_key"] = opts.stopKey()
+		if opts.CreatedAfter != nil {
+			args = append(args, *opts.CreatedAfter)
+			filterCondition += ` AND created_at > $` + strconv.Itoa(len(args))
 		}
-
-		var objectKey = `object_key`
-		if opts.Prefix != "" {
-			objectKey = `substr(object_key, @prefix_len) AS object_key`
+		if opts.CreatedBefore != nil {
+			args = append(args, *opts.CreatedBefore)
+			filterCondition += ` AND created_at < $` + strconv.Itoa(len(args))
 		}
+	}
 
-		var statusCondition = `status != ` + statusPending
-		if opts.Pending {
-			statusCondition = `status = ` + statusPending
-		}
+	return `SELECT
+		` + objectKey + `,
+		version
+		` + opts.selectedFields() + `
+		FROM objects
+		` + impl.AsOfSystemTime(opts.SnapshotTime) + `
+		WHERE
+			` + opts.boundaryPostgres() + `
+			AND (project_id, bucket_name) < ($1, $6)
+			AND ` + opts.statusCondition() + `
+			AND (expires_at IS NULL OR expires_at > now())
+			` + filterCondition + `
+		ORDER BY ` + opts.orderBy() + `
+		LIMIT $5
+	`, args
+}
 
-		stmt := spanner.Statement{
-			SQL: `
-				SELECT
-					` + objectKey + `,
-					version
-					` + opts.selectedFields() + `
-				FROM objects
-				WHERE
-					` + opts.boundarySpanner() + `
-					AND ((project_id < @project_id) OR (project_id = @project_id AND bucket_name < CAST(@next_bucket AS STRING)))
-					AND ` + statusCondition + `
-					AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
-				ORDER BY ` + opts.orderBy() + `
-				LIMIT @limit
-			`,
-			Params: args,
-		}
+// IterateObjects calls fn for every listed object.
+func (s *SpannerAdapter) IterateObjects(ctx context.Context, opts ListObjects, fn func(ObjectEntry) error) (err error) {
+	// TODO(spanner): retune all of these for Spanner. Also, can we use a smarter query now
+	// using some feature that wasn't in Cockroach? (e.g. windowed queries).
+	if !opts.keyOrdered() {
+		return s.iterateObjectsOrdered(ctx, opts, fn)
+	}
+
+	state := newListObjectsState(&opts, fn)
 
-		scannedCount := 0
-		skipAhead := false
-		done := false
+	for repeat := 0; repeat < state.requeryLimit; repeat++ {
+		stmt := listObjectsStatementSpanner(state)
 
+		var fnErr error
 		err := func() error {
-			rowIterator := s.client.Single().Query(ctx, stmt)
+			rowIterator := opts.spannerReadOnly(s.client).QueryWithOptions(ctx, stmt, opts.Priority.spannerQueryOptions())
 			defer rowIterator.Stop()
 
-		readEntries:
+			state.startBatch()
 			for {
 				row, err := rowIterator.Next()
 				if err != nil {
 					if errors.Is(err, iterator.Done) {
-						done = true
 						return nil
 					}
 					return Error.Wrap(err)
@@ -373,102 +463,373 @@ func (s *SpannerAdapter) ListObjects(ctx context.Context, opts ListObjects) (res
 				if err != nil {
 					return Error.Wrap(err)
 				}
-				scannedCount++
-
-				// skip a duplicate prefix entry, which only happens with opts.Recursive
-				// TODO: does this need opts.AllVersions
-				skipPrefix := lastEntry.Set && opts.AllVersions && lastEntry.IsPrefix && entry.IsPrefix && lastEntry.ObjectKey == entry.ObjectKey
-				// skip duplicate object key with other versions, when !opts.AllVersions
-				skipVersion := lastEntry.Set && !opts.AllVersions && lastEntry.IsPrefix == entry.IsPrefix && lastEntry.ObjectKey == entry.ObjectKey
-
-				// we'll need to ensure that when we are iterating only latest objects that we don't
-				// emit an object entry when we start iterating from half-way in versions.
-				var skipCursorAllVersionsDoubleCheck bool
-				if !opts.AllVersions && entryKeyMatchesCursor(opts.Prefix, entry.ObjectKey, opts.Cursor.Key) {
-					if opts.VersionAscending() {
-						skipCursorAllVersionsDoubleCheck = entry.Version <= opts.Cursor.Version
-					} else {
-						skipCursorAllVersionsDoubleCheck = entry.Version >= opts.Cursor.Version
-					}
+
+				var requery bool
+				requery, fnErr = state.add(entry)
+				if fnErr != nil || requery {
+					return nil
 				}
+			}
+		}()
+		if err != nil {
+			return Error.Wrap(err)
+		}
+		if fnErr != nil {
+			return fnErr
+		}
 
-				lastEntry.Set = true
-				lastEntry.ObjectKey = entry.ObjectKey
-				lastEntry.Version = entry.Version
-				lastEntry.IsPrefix = entry.IsPrefix
+		if !state.nextBatch() {
+			return nil
+		}
+	}
 
-				if skipPrefix || skipVersion || skipCursorAllVersionsDoubleCheck {
-					if skipPrefix {
-						skipCount.Prefix++
-					}
-					if skipVersion {
-						skipCount.Version++
-					}
+	return state.errTooManyRequeries()
+}
 
-					if skipCount.Prefix >= maxSkipPrefixUntilRequery || skipCount.Version >= maxSkipVersionsUntilRequery {
-						skipAhead = true
-						skipCount = skipCounter{}
-						// we landed inside a large number of repeated items,
-						// either prefixes or versions, let's requery and skip
-						break readEntries
-					}
+// ExplainListObjects explains the first query of ListObjects.
+func (s *SpannerAdapter) ExplainListObjects(ctx context.Context, opts ListObjects) (_ string, err error) {
+	defer mon.Task()(&ctx)(&err)
 
-					continue
-				}
+	if !opts.keyOrdered() {
+		return s.explainListObjectsOrdered(ctx, opts)
+	}
 
-				skipCount = skipCounter{}
+	plan, err := s.client.Single().AnalyzeQuery(ctx, listObjectsStatementSpanner(newListObjectsState(&opts, nil)))
+	if err != nil {
+		return "", Error.Wrap(err)
+	}
+	return formatSpannerQueryPlan(plan), nil
+}
 
-				// We don't want to include delete markers in the output, when we are listing only the latest version.
-				// We still set "lastEntry" so we skip any objects that are beyond the delete marker.
-				if !opts.AllVersions && entry.Status.IsDeleteMarker() {
-					continue
-				}
+// listObjectsStatementSpanner returns the statement for the next batch of state.
+func listObjectsStatementSpanner(state *listObjectsState) spanner.Statement {
+	opts := state.opts
+	bucket, next := opts.bucketBounds()
+
+	args := map[string]any{
+		"project_id":     opts.ProjectID,
+		"bucket_name":    string(bucket),
+		"cursor_key":     state.cursor.Key,
+		"cursor_version": state.cursor.Version,
+		"limit":          state.batchSize,
+		"next_bucket":    string(next),
+	}
+	if opts.Prefix != "" {
+		args["stop_key"] = opts.stopKey()
+	}
 
-				result.Objects = append(result.Objects, entry)
-				if len(result.Objects) >= opts.Limit+1 {
-					result.More = true
-					result.Objects = result.Objects[:opts.Limit]
-					done = true
-					return nil
-				}
-			}
-			return nil
-		}()
-		if err != nil {
-			return result, Error.Wrap(err)
+	var objectKey = `object_key`
+	if opts.strippedPrefix() != "" {
+		args["prefix_len"] = len(opts.Prefix) + 1
+		objectKey = `substr(object_key, @prefix_len) AS object_key`
+	}
+
+	var filterCondition string
+	if opts.filtersInQuery() {
+		if opts.MinTotalEncryptedSize > 0 {
+			args["min_total_encrypted_size"] = opts.MinTotalEncryptedSize
+			filterCondition += ` AND total_encrypted_size >= @min_total_encrypted_size`
+		}
+		if opts.CreatedAfter != nil {
+			args["created_after"] = *opts.CreatedAfter
+			filterCondition += ` AND created_at > @created_after`
+		}
+		if opts.CreatedBefore != nil {
+			args["created_before"] = *opts.CreatedBefore
+			filterCondition += ` AND created_at < @created_before`
+		}
+	}
+
+	return spanner.Statement{
+		SQL: `
+			SELECT
+				` + objectKey + `,
+				version
+				` + opts.selectedFields() + `
+			FROM objects
+			WHERE
+				` + opts.boundarySpanner() + `
+				AND ((project_id < @project_id) OR (project_id = @project_id AND bucket_name < CAST(@next_bucket AS STRING)))
+				AND ` + opts.statusCondition() + `
+				AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+				` + filterCondition + `
+			ORDER BY ` + opts.orderBy() + `
+			LIMIT @limit
+		`,
+		Params: args,
+	}
+}
+
+// formatSpannerQueryPlan formats the plan nodes as an indented tree.
+func formatSpannerQueryPlan(plan *spannerpb.QueryPlan) string {
+	nodes := plan.GetPlanNodes()
+	if len(nodes) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	var format func(index int32, depth int)
+	format = func(index int32, depth int) {
+		if index < 0 || int(index) >= len(nodes) {
+			return
+		}
+		node := nodes[index]
+		fmt.Fprintf(&b, "%s%s", strings.Repeat("  ", depth), node.GetDisplayName())
+		if description := node.GetShortRepresentation().GetDescription(); description != "" {
+			fmt.Fprintf(&b, ": %s", description)
 		}
-		if done {
-			return result, nil
+		b.WriteString("\n")
+		for _, child := range node.GetChildLinks() {
+			format(child.GetChildIndex(), depth+1)
+		}
+	}
+	format(0, 0)
+
+	return b.String()
+}
+
+// listObjectsState contains the iteration logic shared between all adapters,
+// which ensures that adapters produce identical results for identical inputs.
+type listObjectsState struct {
+	opts *ListObjects
+
+	// emit is called for every entry in the result.
+	emit func(ObjectEntry) error
+	// emitted is the number of entries passed to emit.
+	emitted int
+
+	// requeryLimit is a safety net for invalid implementation.
+	requeryLimit int
+	// batchSize is the number of entries to query at once.
+	batchSize int
+	// cursor is the position for the next query.
+	cursor ListObjectsCursor
+
+	// lastEntry is used to keep track of the last entry put into the result.
+	lastEntry struct {
+		Set bool
+
+		ObjectKey ObjectKey
+		Version   Version
+		IsPrefix  bool
+	}
+
+	// collapsedPrefix is the full key of the collapsed prefix, which is being
+	// processed by a non-recursive listing. The entries inside it are skipped and
+	// the next query continues past it, see continuePastPrefix.
+	collapsedPrefix ObjectKey
+
+	// skipCount keeps track on how many entries we have skipped either due to
+	// objects of similar version or due to a collapsed non-recursive prefix.
+	skipCount listObjectsSkipCounter
+
+	// keyVersions is the number of versions of lastEntry.ObjectKey, which
+	// count towards opts.MaxVersionsPerKey.
+	keyVersions int
+
+	scannedCount  int
+	filteredCount int
+	skipAhead     bool
+
+	// hiddenDeleteMarkers is the number of delete markers hidden from the result.
+	hiddenDeleteMarkers int
+}
+
+type listObjectsSkipCounter struct {
+	Prefix  int
+	Version int
+}
+
+func newListObjectsState(opts *ListObjects, emit func(ObjectEntry) error) *listObjectsState {
+	// minQuerySize ensures that we list a more entries, as there's a significant overhead to a single query.
+	const minQuerySize = 100
+
+	// extraSkipEntries to avoid requerying in the common case of !AllVersions.
+	const extraSkipEntries = 10
+	// extraEntriesForMore is the additional entry we need for determining whether there are more entries.
+	const extraEntriesForMore = 1
+
+	batchSize := opts.Limit + extraEntriesForMore + extraSkipEntries
+	if batchSize < minQuerySize {
+		batchSize = minQuerySize
+	}
+
+	// we do some extra queries, but, roughly at most we should have one query per entry
+	requeryLimit := opts.Limit + 10
+	if opts.RequeryLimit > 0 {
+		requeryLimit = opts.RequeryLimit
+	}
+
+	return &listObjectsState{
+		opts:            opts,
+		emit:            emit,
+		requeryLimit:    requeryLimit,
+		batchSize:       batchSize,
+		cursor:          opts.StartCursor(),
+		collapsedPrefix: opts.cursorCollapsedPrefix(),
+	}
+}
+
+// startBatch must be called before adding entries from a new query.
+func (state *listObjectsState) startBatch() {
+	state.scannedCount = 0
+	state.filteredCount = 0
+	state.skipAhead = false
+}
+
+// add processes the next entry from the query. It returns requery when the rest
+// of the batch should be skipped, and the error returned by emit, which ends the listing.
+func (state *listObjectsState) add(entry ObjectEntry) (requery bool, err error) {
+	// maxSkipVersionsUntilRequery is the limit on how many versions we query for a single object, until we requery.
+	const maxSkipVersionsUntilRequery = 100
+
+	// maxSkipPrefixUntilRequery is the limit on how many entries we scan inside a prefix, until we requery.
+	const maxSkipPrefixUntilRequery = 10
+
+	opts, lastEntry := state.opts, &state.lastEntry
+
+	state.scannedCount++
+
+	// skip an entry inside the collapsed prefix, which only happens with !opts.Recursive.
+	// This is counted separately from versions, regardless of opts.AllVersions,
+	// so that we jump past a collapsed prefix instead of scanning all of its versions.
+	var skipPrefix bool
+	if entry.IsPrefix {
+		prefix := opts.strippedPrefix() + entry.ObjectKey
+		skipPrefix = state.collapsedPrefix == prefix
+		state.collapsedPrefix = prefix
+	} else {
+		state.collapsedPrefix = ""
+	}
+	sameKey := lastEntry.Set && !lastEntry.IsPrefix && !entry.IsPrefix && lastEntry.ObjectKey == entry.ObjectKey
+	if !sameKey {
+		state.keyVersions = 0
+	}
+	// skip duplicate object key with other versions, when !opts.AllVersions,
+	// or when we have reached opts.MaxVersionsPerKey.
+	skipVersion := sameKey && (!opts.AllVersions || state.versionLimitReached())
+
+	// we'll need to ensure that when we are iterating only latest objects that we don't
+	// emit an object entry when we start iterating from half-way in versions.
+	// Similarly, the versions before the cursor count towards opts.MaxVersionsPerKey.
+	var skipCursorAllVersionsDoubleCheck bool
+	if opts.tracksCursorKeyVersions() && entryKeyMatchesCursor(opts.strippedPrefix(), entry.ObjectKey, opts.Cursor.Key) {
+		if opts.VersionAscending() {
+			skipCursorAllVersionsDoubleCheck = entry.Version <= opts.Cursor.Version
+		} else {
+			skipCursorAllVersionsDoubleCheck = entry.Version >= opts.Cursor.Version
 		}
+	}
+
+	lastEntry.Set = true
+	lastEntry.ObjectKey = entry.ObjectKey
+	lastEntry.Version = entry.Version
+	lastEntry.IsPrefix = entry.IsPrefix
+
+	if !entry.IsPrefix && !skipVersion {
+		state.keyVersions++
+	}
 
-		if scannedCount == 0 {
-			result.More = false
-			return result, nil
+	if skipPrefix || skipVersion || skipCursorAllVersionsDoubleCheck {
+		if skipPrefix {
+			state.skipCount.Prefix++
 		}
-		if !skipAhead && scannedCount < batchSize {
-			result.More = false
-			return result, nil
+		if skipVersion {
+			state.skipCount.Version++
 		}
 
-		switch {
-		case lastEntry.IsPrefix: // can only be true if recursive listing
-			// skip over the prefix
-			cursor.Key = opts.Prefix + lastEntry.ObjectKey[:len(lastEntry.ObjectKey)-1] + DelimiterNext
-			cursor.Version = opts.FirstVersion()
-
-		case opts.AllVersions:
-			// continue where-ever we left off
-			cursor.Key = opts.Prefix + lastEntry.ObjectKey
-			cursor.Version = lastEntry.Version
-
-		case !opts.AllVersions:
-			// jump to the next object
-			cursor.Key = opts.Prefix + lastEntry.ObjectKey
-			cursor.Version = opts.lastVersion()
+		if state.skipCount.Prefix >= maxSkipPrefixUntilRequery || state.skipCount.Version >= maxSkipVersionsUntilRequery {
+			state.skipAhead = true
+			state.skipCount = listObjectsSkipCounter{}
+			// we landed inside a large number of repeated items,
+			// either prefixes or versions, let's requery and skip
+			return true, nil
 		}
+
+		return false, nil
 	}
 
-	panic("too many requeries")
+	state.skipCount = listObjectsSkipCounter{}
+
+	// We don't want to include delete markers in the output, when we are listing only the latest version.
+	// We still set "lastEntry" so we skip any objects that are beyond the delete marker.
+	if !opts.AllVersions && entry.Status.IsDeleteMarker() {
+		state.hiddenDeleteMarkers++
+		state.requeryLimit += opts.RequeryPerDeleteMarker
+		return false, nil
+	}
+
+	// Similarly, objects excluded by opts.MinTotalEncryptedSize or the creation time
+	// window are not included, but they still hide the older versions.
+	if opts.excludes(entry) {
+		state.filteredCount++
+		return false, nil
+	}
+
+	state.emitted++
+	if state.emitted > opts.Limit+1 {
+		// the listing continues past opts.Limit only when the entries are
+		// streamed, so allow a query per additional entry.
+		state.requeryLimit++
+	}
+
+	return false, state.emit(entry)
+}
+
+// nextBatch updates the cursor for the next query and returns false
+// when there are no more entries to query.
+func (state *listObjectsState) nextBatch() bool {
+	opts, lastEntry := state.opts, &state.lastEntry
+
+	if state.scannedCount == 0 {
+		return false
+	}
+	if !state.skipAhead && state.scannedCount < state.batchSize {
+		return false
+	}
+
+	if state.filteredCount > 0 {
+		// filtered entries don't end up in the result, however, they still move
+		// the cursor forward, so they shouldn't count towards the requery limit.
+		state.requeryLimit++
+	}
+
+	switch {
+	case state.collapsedPrefix != "": // can only be true if non-recursive listing
+		state.cursor = opts.continuePastPrefix(state.collapsedPrefix)
+
+	case opts.AllVersions && !state.versionLimitReached():
+		// continue where-ever we left off
+		state.cursor.Key = opts.strippedPrefix() + lastEntry.ObjectKey
+		state.cursor.Version = lastEntry.Version
+
+	default:
+		// jump to the next object
+		state.cursor.Key = opts.strippedPrefix() + lastEntry.ObjectKey
+		state.cursor.Version = opts.lastVersion()
+	}
+
+	return true
+}
+
+// errTooManyRequeries returns the error for a listing, which has exhausted its query budget.
+// It contains the state of the listing, which helps with tuning the requery options.
+func (state *listObjectsState) errTooManyRequeries() error {
+	return Error.New("too many requeries: limit %d, cursor (%x, %d), emitted %d, hidden delete markers %d, batch size %d",
+		state.requeryLimit, []byte(state.cursor.Key), state.cursor.Version,
+		state.emitted, state.hiddenDeleteMarkers, state.batchSize)
+}
+
+// versionLimitReached returns whether all the versions of lastEntry.ObjectKey
+// allowed by opts.MaxVersionsPerKey have been seen.
+func (state *listObjectsState) versionLimitReached() bool {
+	return state.opts.MaxVersionsPerKey > 0 && state.keyVersions >= state.opts.MaxVersionsPerKey
+}
+
+// tracksCursorKeyVersions returns whether the listing needs to start from the first
+// version of the cursor key, instead of the cursor version.
+func (opts *ListObjects) tracksCursorKeyVersions() bool {
+	return !opts.AllVersions || opts.MaxVersionsPerKey > 0
 }
 
 func entryKeyMatchesCursor(prefix, entryKey, cursorKey ObjectKey) bool {
@@ -477,6 +838,26 @@ func entryKeyMatchesCursor(prefix, entryKey, cursorKey ObjectKey) bool {
 		entryKey == cursorKey[len(prefix):]
 }
 
+// strippedPrefix returns the prefix, which is stripped from the object keys of the entries.
+func (opts *ListObjects) strippedPrefix() ObjectKey {
+	if opts.ReturnFullKey {
+		return ""
+	}
+	return opts.Prefix
+}
+
+// collapsedPrefixLength returns the length of the collapsed prefix of a non-recursive
+// listing for a scanned object key, or -1 when the key isn't inside a prefix.
+// The delimiter is only searched after opts.Prefix, even when the key contains it.
+func (opts *ListObjects) collapsedPrefixLength(key ObjectKey) int {
+	offset := len(opts.Prefix) - len(opts.strippedPrefix())
+	i := strings.IndexByte(string(key[offset:]), Delimiter)
+	if i < 0 {
+		return -1
+	}
+	return offset + i + 1
+}
+
 func (opts *ListObjects) stopKey() []byte {
 	if opts.Prefix != "" {
 		return []byte(PrefixLimit(opts.Prefix))
@@ -485,7 +866,7 @@ func (opts *ListObjects) stopKey() []byte {
 }
 
 func (opts *ListObjects) boundaryPostgres() string {
-	const prefixBoundaryCondition = `(project_id, bucket_name, object_key) < ($1, $2, $8)`
+	const prefixBoundaryCondition = `(project_id, bucket_name, object_key) < ($1, $2, $7)`
 
 	if opts.VersionAscending() {
 		const compare = `(project_id, bucket_name, object_key, version) > ($1, $2, $3, $4)`
@@ -560,6 +941,46 @@ func (opts *ListObjects) VersionAscending() bool {
 	return opts.Pending
 }
 
+// versionOrderName returns the name of the version order, used in error messages.
+func (opts *ListObjects) versionOrderName() string {
+	if opts.VersionAscending() {
+		return "ascending"
+	}
+	return "descending"
+}
+
+// cursorVersionInRange checks whether the cursor version is valid for the
+// effective version order. Versions are limited to [0, MaxVersion] in both
+// orders. Additionally, an ascending listing doesn't accept MaxVersion, the
+// first version of the descending order, for a cursor that starts the listing,
+// as it indicates that the cursor was created for the descending order.
+func (opts *ListObjects) cursorVersionInRange() bool {
+	if opts.Cursor.Version < 0 || opts.Cursor.Version > MaxVersion {
+		return false
+	}
+	if opts.VersionAscending() && opts.Cursor.Version == MaxVersion {
+		startsListing := opts.Cursor.Key == "" || opts.Cursor.Key < opts.Prefix
+		return !startsListing
+	}
+	return true
+}
+
+// statusCondition returns the condition for the statuses of the listed objects.
+func (opts *ListObjects) statusCondition() string {
+	if opts.Pending {
+		return `status = ` + statusPending
+	}
+
+	switch opts.IncludeSoftDeleted {
+	case SoftDeletedInclude:
+		return `status != ` + statusPending
+	case SoftDeletedOnly:
+		return softDeletedCondition
+	default:
+		return `status != ` + statusPending + ` AND NOT ` + softDeletedCondition
+	}
+}
+
 func (opts *ListObjects) orderBy() string {
 	if opts.VersionAscending() {
 		return "project_id ASC, bucket_name ASC, object_key ASC, version ASC"
@@ -569,6 +990,12 @@ func (opts *ListObjects) orderBy() string {
 }
 
 func (opts ListObjects) selectedFields() (selectedFields string) {
+	if opts.KeysOnly {
+		// status is still needed for skipping delete markers.
+		return `
+		,status`
+	}
+
 	selectedFields += `
 	,stream_id
 	,status
@@ -591,6 +1018,24 @@ func (opts ListObjects) selectedFields() (selectedFields string) {
 		,encrypted_metadata_encrypted_key`
 	}
 
+	if opts.IncludeSystemLabels {
+		selectedFields += `
+		,system_labels`
+	}
+
+	if opts.IncludeTags {
+		selectedFields += `
+		,object_tags`
+	}
+
+	if opts.IncludeFirstSegmentPlacement {
+		selectedFields += `
+		,COALESCE((
+			SELECT segments.placement FROM segments
+			WHERE segments.stream_id = objects.stream_id AND segments.position = 0
+		), 0)`
+	}
+
 	return selectedFields
 }
 
@@ -616,35 +1061,71 @@ func (opts *ListObjects) StartCursor() ListObjectsCursor {
 		return opts.Cursor
 	}
 
-	keyWithoutPrefix := opts.Cursor.Key[len(opts.Prefix):]
-	if !opts.Recursive {
-		// Check whether we need to skip outside of a prefix.
-		firstDelimiter := strings.IndexByte(string(keyWithoutPrefix), '/')
-		if firstDelimiter >= 0 {
-			firstDelimiter += len(opts.Prefix)
-			return ListObjectsCursor{
-				Key:     opts.Cursor.Key[:firstDelimiter] + DelimiterNext,
-				Version: opts.FirstVersion(),
-			}
-		}
+	if collapsedPrefix := opts.cursorCollapsedPrefix(); collapsedPrefix != "" {
+		// The prefix has been already listed, hence skip all of its entries.
+		return opts.continuePastPrefix(collapsedPrefix)
 	}
 
-	if !opts.AllVersions {
+	if opts.tracksCursorKeyVersions() {
 		// We need to double check whether the latest entry has been already
-		// produced, because we may need to skip it.
+		// produced, because we may need to skip it. With MaxVersionsPerKey
+		// we need to count the versions before the cursor.
 		return ListObjectsCursor{Key: opts.Cursor.Key, Version: opts.FirstVersion()}
 	}
 
 	return opts.Cursor
 }
 
+// cursorCollapsedPrefix returns the full key of the collapsed prefix, which contains
+// the cursor of a non-recursive listing, or "" when the cursor isn't inside one.
+func (opts *ListObjects) cursorCollapsedPrefix() ObjectKey {
+	if opts.Recursive || !strings.HasPrefix(string(opts.Cursor.Key), string(opts.Prefix)) {
+		return ""
+	}
+	keyWithoutPrefix := opts.Cursor.Key[len(opts.Prefix):]
+	firstDelimiter := strings.IndexByte(string(keyWithoutPrefix), Delimiter)
+	if firstDelimiter < 0 {
+		return ""
+	}
+	return opts.Cursor.Key[:len(opts.Prefix)+firstDelimiter+1]
+}
+
+// continuePastPrefix returns the cursor, which continues the listing after all the
+// entries of the collapsed prefix. The prefix ends with the delimiter, hence
+// replacing it with DelimiterNext gives the first key past the prefix.
+func (opts *ListObjects) continuePastPrefix(collapsedPrefix ObjectKey) ListObjectsCursor {
+	return ListObjectsCursor{
+		Key:     collapsedPrefix[:len(collapsedPrefix)-1] + DelimiterNext,
+		Version: opts.FirstVersion(),
+	}
+}
+
+// verifyEncryption checks the encryption parameters of a listed object, when
+// opts.validateEncryption is set.
+func (opts *ListObjects) verifyEncryption(item ObjectEntry) error {
+	if !opts.validateEncryption || opts.KeysOnly {
+		return nil
+	}
+	if err := (encryptionParameters{&item.Encryption}).verify(); err != nil {
+		return fmt.Errorf("invalid encryption parameters of object %q version %d: %w", item.ObjectKey, item.Version, err)
+	}
+	return nil
+}
+
 func scanListObjectsEntryPostgres(rows tagsql.Rows, opts *ListObjects) (item ObjectEntry, err error) {
 	fields := []interface{}{
 		&item.ObjectKey,
 		&item.Version,
-		&item.StreamID,
-		&item.Status,
-		encryptionParameters{&item.Encryption},
+	}
+
+	if opts.KeysOnly {
+		fields = append(fields, &item.Status)
+	} else {
+		fields = append(fields,
+			&item.StreamID,
+			&item.Status,
+			encryptionParameters{&item.Encryption},
+		)
 	}
 
 	if opts.IncludeSystemMetadata {
@@ -666,15 +1147,26 @@ func scanListObjectsEntryPostgres(rows tagsql.Rows, opts *ListObjects) (item Obj
 		)
 	}
 
+	if opts.IncludeSystemLabels {
+		fields = append(fields, systemLabels{&item.SystemLabels})
+	}
+
+	if opts.IncludeTags {
+		fields = append(fields, objectTags{&item.Tags})
+	}
+
+	if opts.IncludeFirstSegmentPlacement {
+		fields = append(fields, &item.Placement)
+	}
+
 	if err := rows.Scan(fields...); err != nil {
 		return item, err
 	}
 
 	if !opts.Recursive {
-		i := strings.IndexByte(string(item.ObjectKey), Delimiter)
-		if i >= 0 {
+		if n := opts.collapsedPrefixLength(item.ObjectKey); n >= 0 {
 			item.IsPrefix = true
-			item.ObjectKey = item.ObjectKey[:i+1]
+			item.ObjectKey = item.ObjectKey[:n]
 		}
 	}
 
@@ -686,15 +1178,26 @@ func scanListObjectsEntryPostgres(rows tagsql.Rows, opts *ListObjects) (item Obj
 		}, nil
 	}
 
+	if err := opts.verifyEncryption(item); err != nil {
+		return item, err
+	}
+
 	return item, nil
 }
 func scanListObjectsEntrySpanner(row *spanner.Row, opts *ListObjects) (item ObjectEntry, err error) {
 	fields := []interface{}{
 		&item.ObjectKey,
 		&item.Version,
-		&item.StreamID,
-		&item.Status,
-		encryptionParameters{&item.Encryption},
+	}
+
+	if opts.KeysOnly {
+		fields = append(fields, &item.Status)
+	} else {
+		fields = append(fields,
+			&item.StreamID,
+			&item.Status,
+			encryptionParameters{&item.Encryption},
+		)
 	}
 
 	if opts.IncludeSystemMetadata {
@@ -716,15 +1219,26 @@ func scanListObjectsEntrySpanner(row *spanner.Row, opts *ListObjects) (item Obje
 		)
 	}
 
+	if opts.IncludeSystemLabels {
+		fields = append(fields, systemLabels{&item.SystemLabels})
+	}
+
+	if opts.IncludeTags {
+		fields = append(fields, objectTags{&item.Tags})
+	}
+
+	if opts.IncludeFirstSegmentPlacement {
+		fields = append(fields, spannerutil.Int(&item.Placement))
+	}
+
 	if err := row.Columns(fields...); err != nil {
 		return item, err
 	}
 
 	if !opts.Recursive {
-		i := strings.IndexByte(string(item.ObjectKey), Delimiter)
-		if i >= 0 {
+		if n := opts.collapsedPrefixLength(item.ObjectKey); n >= 0 {
 			item.IsPrefix = true
-			item.ObjectKey = item.ObjectKey[:i+1]
+			item.ObjectKey = item.ObjectKey[:n]
 		}
 	}
 
@@ -736,5 +1250,9 @@ func scanListObjectsEntrySpanner(row *spanner.Row, opts *ListObjects) (item Obje
 		}, nil
 	}
 
+	if err := opts.verifyEncryption(item); err != nil {
+		return item, err
+	}
+
 	return item, nil
 }
@@ -6,6 +6,7 @@ package metabase_test
 import (
 	"flag"
 	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -31,6 +32,13 @@ func TestListObjects_Exhaustive(t *testing.T) {
 		require.NoError(t, db.TestingBatchInsertObjects(ctx, raw))
 
 		check := func(opts metabase.ListObjects) {
+			if err := opts.Verify(); err != nil {
+				// the naive implementation doesn't verify the request.
+				_, gotErr := db.ListObjects(ctx, opts)
+				require.True(t, metabase.ErrInvalidRequest.Has(gotErr), fmt.Sprintf("%#v", opts))
+				return
+			}
+
 			expResult, expErr := naive.ListObjects(ctx, opts)
 			gotResult, gotErr := db.ListObjects(ctx, opts)
 
@@ -86,6 +94,118 @@ func TestListObjects_Exhaustive(t *testing.T) {
 	})
 }
 
+// TestListObjects_Randomized compares every adapter against the same naive
+// implementation, which ensures that all adapters produce identical results.
+func TestListObjects_Randomized(t *testing.T) {
+	entries := generateRandomizedTestData(rand.New(rand.NewSource(1)))
+	raw := objectEntriesToRawObjects(entries)
+	naive := NewNaiveObjectsDB(entries)
+
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		require.NoError(t, db.TestingBatchInsertObjects(ctx, raw))
+
+		check := func(opts metabase.ListObjects) {
+			if err := opts.Verify(); err != nil {
+				// the naive implementation doesn't verify the request.
+				_, gotErr := db.ListObjects(ctx, opts)
+				require.True(t, metabase.ErrInvalidRequest.Has(gotErr), fmt.Sprintf("%#v", opts))
+				return
+			}
+
+			expResult, expErr := naive.ListObjects(ctx, opts)
+			gotResult, gotErr := db.ListObjects(ctx, opts)
+
+			require.Equal(t, expErr, gotErr, fmt.Sprintf("%#v", opts))
+			require.Equal(t, expResult, gotResult, fmt.Sprintf("%#v", opts))
+		}
+
+		rng := rand.New(rand.NewSource(2))
+
+		var opts metabase.ListObjects
+		opts.ProjectID = uuid.UUID{1}
+		opts.BucketName = "b"
+		for _, opts.Prefix = range []metabase.ObjectKey{"", "a", "a/", "m/"} {
+			for _, opts.AllVersions = range []bool{true, false} {
+				for _, opts.MaxVersionsPerKey = range []int{0, 2} {
+					for _, opts.Recursive = range []bool{true, false} {
+						for _, opts.Limit = range []int{1, 5, 150} {
+							opts.Cursor = metabase.ListObjectsCursor{}
+							check(opts)
+
+							for i := 0; i < 10; i++ {
+								entry := &entries[rng.Intn(len(entries))]
+								opts.Cursor.Key = entry.ObjectKey
+								opts.Cursor.Version = entry.Version - 1 + metabase.Version(rng.Intn(3))
+								check(opts)
+							}
+						}
+					}
+				}
+			}
+		}
+	})
+}
+
+func generateRandomizedTestData(rng *rand.Rand) []metabase.ObjectEntry {
+	alphabet := []byte{0, 'a', 'b', '/', 0xFF}
+	streamID := uuid.UUID{1}
+
+	seen := map[metabase.ObjectKey]bool{}
+	entries := []metabase.ObjectEntry{}
+	for len(seen) < 100 {
+		key := make([]byte, 1+rng.Intn(4))
+		for i := range key {
+			key[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		if seen[metabase.ObjectKey(key)] {
+			continue
+		}
+		seen[metabase.ObjectKey(key)] = true
+
+		versions := 1 + rng.Intn(4)
+		for v := 1; v <= versions; v++ {
+			status := metabase.CommittedVersioned
+			if rng.Intn(3) == 0 {
+				status = metabase.DeleteMarkerVersioned
+			}
+			entries = append(entries, metabase.ObjectEntry{
+				ObjectKey: metabase.ObjectKey(key),
+				Version:   metabase.Version(v),
+				StreamID:  streamID,
+				Status:    status,
+			})
+		}
+	}
+
+	// a long run of keys where the latest version is a delete marker,
+	// which spans multiple query batches without producing any output.
+	for i := 0; i < 150; i++ {
+		key := metabase.ObjectKey(fmt.Sprintf("m/%03d", i))
+		entries = append(entries,
+			metabase.ObjectEntry{
+				ObjectKey: key,
+				Version:   1,
+				StreamID:  streamID,
+				Status:    metabase.CommittedVersioned,
+			},
+			metabase.ObjectEntry{
+				ObjectKey: key,
+				Version:   2,
+				StreamID:  streamID,
+				Status:    metabase.DeleteMarkerVersioned,
+			},
+		)
+	}
+	entries = append(entries, metabase.ObjectEntry{
+		ObjectKey: "m/999",
+		Version:   1,
+		StreamID:  streamID,
+		Status:    metabase.CommittedVersioned,
+	})
+
+	return entries
+}
+
 func objectEntriesToRawObjects(entries []metabase.ObjectEntry) (rs []metabase.RawObject) {
 	rs = make([]metabase.RawObject, len(entries))
 	for i := range rs {
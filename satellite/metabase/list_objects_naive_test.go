@@ -59,6 +59,9 @@ func NewNaiveObjectsDB(rawentries []metabase.ObjectEntry) *NaiveObjectsDB {
 // ListObjects lists objects.
 func (db *NaiveObjectsDB) ListObjects(ctx context.Context, opts metabase.ListObjects) (result metabase.ListObjectsResult, err error) {
 	metabase.ListLimit.Ensure(&opts.Limit)
+	if opts.MinTotalEncryptedSize > 0 || opts.CreatedAfter != nil || opts.CreatedBefore != nil {
+		opts.IncludeSystemMetadata = true
+	}
 
 	entries := db.VersionDesc
 	if opts.Pending {
@@ -66,6 +69,8 @@ func (db *NaiveObjectsDB) ListObjects(ctx context.Context, opts metabase.ListObj
 	}
 
 	var last *metabase.ObjectEntry
+	var versionsKey metabase.ObjectKey
+	var versions int
 	for i := range entries {
 		entry := &entries[i]
 
@@ -80,12 +85,24 @@ func (db *NaiveObjectsDB) ListObjects(ctx context.Context, opts metabase.ListObj
 		// remove opts.Prefix and collapse child key
 		entryKeyWithPrefix, entryKey, entryVersion, isPrefix := calculateEntryKey(&opts, entry)
 
-		// The entry is before our cursor position.
-		if entryExcludedByCursor(&opts, entryKeyWithPrefix, entryVersion, isPrefix) {
+		if opts.Pending != (entry.Status == metabase.Pending) {
 			continue
 		}
 
-		if opts.Pending != (entry.Status == metabase.Pending) {
+		// Only the first opts.MaxVersionsPerKey versions of a key are listed,
+		// including the ones before the cursor.
+		if opts.AllVersions && opts.MaxVersionsPerKey > 0 && !isPrefix {
+			if versionsKey != entry.ObjectKey {
+				versionsKey, versions = entry.ObjectKey, 0
+			}
+			versions++
+			if versions > opts.MaxVersionsPerKey {
+				continue
+			}
+		}
+
+		// The entry is before our cursor position.
+		if entryExcludedByCursor(&opts, entryKeyWithPrefix, entryVersion, isPrefix) {
 			continue
 		}
 
@@ -125,6 +142,12 @@ func (db *NaiveObjectsDB) ListObjects(ctx context.Context, opts metabase.ListObj
 			last = &scoped
 			continue
 		}
+		if !scoped.IsPrefix && (scoped.TotalEncryptedSize < opts.MinTotalEncryptedSize ||
+			opts.CreatedAfter != nil && !scoped.CreatedAt.After(*opts.CreatedAfter) ||
+			opts.CreatedBefore != nil && !scoped.CreatedAt.Before(*opts.CreatedBefore)) {
+			last = &scoped
+			continue
+		}
 		result.Objects = append(result.Objects, scoped)
 		last = &result.Objects[len(result.Objects)-1]
 
@@ -0,0 +1,406 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/zeebo/errs"
+	"google.golang.org/api/iterator"
+
+	"storj.io/storj/shared/dbutil"
+	"storj.io/storj/shared/dbutil/pgutil"
+)
+
+// ListObjectsOrderBy is the order of the entries listed by ListObjects.
+//
+// Orderings other than ListObjectsOrderKeyAsc don't use ListObjects.Cursor,
+// instead the listing continues from ListObjects.OrderCursor, which contains
+// the ordered value of the last listed entry. These orderings implicitly select
+// the system metadata and they require a recursive listing, because collapsing
+// the prefixes relies on the entries being ordered by key.
+type ListObjectsOrderBy byte
+
+const (
+	// ListObjectsOrderKeyAsc lists the entries by object key in ascending order.
+	ListObjectsOrderKeyAsc = ListObjectsOrderBy(0)
+	// ListObjectsOrderSizeDesc lists the largest objects first. Entries of the same
+	// total encrypted size are ordered by object key and version.
+	ListObjectsOrderSizeDesc = ListObjectsOrderBy(1)
+	// ListObjectsOrderCreatedDesc lists the most recently created objects first.
+	// Entries of the same creation time are ordered by object key and version.
+	ListObjectsOrderCreatedDesc = ListObjectsOrderBy(2)
+)
+
+// ListObjectsOrderCursor is the position of a listing with a non-key ordering.
+// The zero value starts from the beginning of the listing.
+type ListObjectsOrderCursor struct {
+	// Key is the full object key of the last entry, including ListObjects.Prefix.
+	Key     ObjectKey
+	Version Version
+
+	// TotalEncryptedSize of the last entry, used by ListObjectsOrderSizeDesc.
+	TotalEncryptedSize int64
+	// CreatedAt of the last entry, used by ListObjectsOrderCreatedDesc.
+	CreatedAt time.Time
+}
+
+// IsZero returns whether the cursor is at the beginning of the listing.
+func (cursor ListObjectsOrderCursor) IsZero() bool {
+	return cursor.Key == "" && cursor.Version == 0 && cursor.TotalEncryptedSize == 0 && cursor.CreatedAt.IsZero()
+}
+
+// NextOrderCursor returns the cursor for continuing a listing with a non-key ordering after entry.
+func (opts *ListObjects) NextOrderCursor(entry ObjectEntry) ListObjectsOrderCursor {
+	return ListObjectsOrderCursor{
+		Key:                opts.strippedPrefix() + entry.ObjectKey,
+		Version:            entry.Version,
+		TotalEncryptedSize: entry.TotalEncryptedSize,
+		CreatedAt:          entry.CreatedAt,
+	}
+}
+
+// keyOrdered returns whether the entries are listed in the key order.
+func (opts *ListObjects) keyOrdered() bool {
+	return opts.OrderBy == ListObjectsOrderKeyAsc
+}
+
+// verifyOrderBy verifies the fields, which depend on opts.OrderBy.
+func (opts *ListObjects) verifyOrderBy() error {
+	switch {
+	case opts.OrderBy > ListObjectsOrderCreatedDesc:
+		return ErrInvalidRequest.New("Invalid OrderBy: %d", opts.OrderBy)
+	case opts.keyOrdered():
+		if !opts.OrderCursor.IsZero() {
+			return ErrInvalidRequest.New("OrderCursor requires a non-key OrderBy")
+		}
+		return nil
+	case !opts.Recursive:
+		return ErrInvalidRequest.New("non-key OrderBy can't be combined with a non-recursive listing")
+	case opts.Pending:
+		return ErrInvalidRequest.New("non-key OrderBy can't be combined with Pending")
+	case opts.MaxVersionsPerKey > 0:
+		return ErrInvalidRequest.New("non-key OrderBy can't be combined with MaxVersionsPerKey")
+	case opts.KeysOnly:
+		return ErrInvalidRequest.New("non-key OrderBy can't be combined with KeysOnly")
+	case opts.Cursor != ListObjectsCursor{}:
+		return ErrInvalidRequest.New("non-key OrderBy requires OrderCursor instead of Cursor")
+	}
+	return nil
+}
+
+// includeImplicitFields selects the fields, which are needed by the other options.
+func (opts *ListObjects) includeImplicitFields() {
+	if opts.MinTotalEncryptedSize > 0 || opts.CreatedAfter != nil || opts.CreatedBefore != nil || !opts.keyOrdered() {
+		opts.IncludeSystemMetadata = true
+	}
+}
+
+// listObjectsOrderedState contains the iteration logic of non-key orderings
+// shared between all adapters.
+type listObjectsOrderedState struct {
+	opts *ListObjects
+
+	// emit is called for every entry in the result.
+	emit func(ObjectEntry) error
+
+	// batchSize is the number of entries to query at once.
+	batchSize int
+	// cursor is the position for the next query.
+	cursor ListObjectsOrderCursor
+	// scannedCount is the number of entries in the current batch.
+	scannedCount int
+}
+
+func newListObjectsOrderedState(opts *ListObjects, emit func(ObjectEntry) error) *listObjectsOrderedState {
+	// minQuerySize ensures that we list a more entries, as there's a significant overhead to a single query.
+	const minQuerySize = 100
+
+	// the additional entry is needed for determining whether there are more entries.
+	batchSize := opts.Limit + 1
+	if batchSize < minQuerySize {
+		batchSize = minQuerySize
+	}
+
+	return &listObjectsOrderedState{
+		opts:      opts,
+		emit:      emit,
+		batchSize: batchSize,
+		cursor:    opts.OrderCursor,
+	}
+}
+
+// startBatch must be called before adding entries from a new query.
+func (state *listObjectsOrderedState) startBatch() {
+	state.scannedCount = 0
+}
+
+// add processes the next entry from the query and returns the error returned by emit,
+// which ends the listing.
+func (state *listObjectsOrderedState) add(entry ObjectEntry) error {
+	opts := state.opts
+
+	state.scannedCount++
+	state.cursor = opts.NextOrderCursor(entry)
+
+	// the older versions are already excluded by the query, however, the latest
+	// version may be a delete marker, which hides the object.
+	if !opts.AllVersions && entry.Status.IsDeleteMarker() {
+		return nil
+	}
+	if opts.excludes(entry) {
+		return nil
+	}
+
+	return state.emit(entry)
+}
+
+// nextBatch returns false when there are no more entries to query.
+func (state *listObjectsOrderedState) nextBatch() bool {
+	return state.scannedCount >= state.batchSize
+}
+
+// iterateObjectsOrdered calls fn for every listed object with a non-key ordering.
+func (p *PostgresAdapter) iterateObjectsOrdered(ctx context.Context, opts ListObjects, fn func(ObjectEntry) error) (err error) {
+	state := newListObjectsOrderedState(&opts, fn)
+
+	for {
+		query, args := listObjectsOrderedQueryPostgres(p.impl, state)
+
+		rows, err := p.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+
+		state.startBatch()
+		for rows.Next() {
+			entry, err := scanListObjectsEntryPostgres(rows, &opts)
+			if err != nil {
+				return Error.Wrap(errs.Combine(err, rows.Err(), rows.Close()))
+			}
+			if err := state.add(entry); err != nil {
+				return errs.Combine(err, rows.Close())
+			}
+		}
+
+		if err := errs.Combine(rows.Err(), rows.Close()); err != nil {
+			return Error.Wrap(err)
+		}
+
+		if !state.nextBatch() {
+			return nil
+		}
+	}
+}
+
+// explainListObjectsOrdered explains the first query of ListObjects with a non-key ordering.
+func (p *PostgresAdapter) explainListObjectsOrdered(ctx context.Context, opts ListObjects) (_ string, err error) {
+	query, args := listObjectsOrderedQueryPostgres(p.impl, newListObjectsOrderedState(&opts, nil))
+
+	explanation, err := pgutil.Explain(ctx, p.db, query, args...)
+	if err != nil {
+		return "", Error.Wrap(err)
+	}
+	return explanation.String(), nil
+}
+
+// listObjectsOrderedQueryPostgres returns the query for the next batch of state.
+func listObjectsOrderedQueryPostgres(impl dbutil.Implementation, state *listObjectsOrderedState) (query string, args []any) {
+	opts := state.opts
+
+	args = []any{
+		opts.ProjectID, []byte(opts.BucketName),
+		state.batchSize,
+	}
+
+	var objectKey = `object_key`
+	var conditions string
+	if opts.Prefix != "" {
+		args = append(args, []byte(opts.Prefix), opts.stopKey())
+		conditions += `
+			AND object_key >= $4 AND object_key < $5`
+	}
+	if opts.strippedPrefix() != "" {
+		args = append(args, len(opts.Prefix)+1)
+		objectKey = `substring(object_key from $` + strconv.Itoa(len(args)) + `) AS object_key`
+	}
+
+	if !opts.AllVersions {
+		// only the latest version of every key is listed. The versions are
+		// hidden by the same entries as in the key ordered listing.
+		conditions += `
+			AND NOT EXISTS (
+				SELECT 1 FROM objects AS newer
+				WHERE
+					(newer.project_id, newer.bucket_name, newer.object_key) = (objects.project_id, objects.bucket_name, objects.object_key)
+					AND newer.version > objects.version
+					AND ` + opts.statusCondition() + `
+					AND (newer.expires_at IS NULL OR newer.expires_at > now())
+			)`
+	}
+
+	var orderValue string
+	var cursorValue any
+	switch opts.OrderBy {
+	case ListObjectsOrderSizeDesc:
+		orderValue, cursorValue = `total_encrypted_size`, state.cursor.TotalEncryptedSize
+	case ListObjectsOrderCreatedDesc:
+		orderValue, cursorValue = `created_at`, state.cursor.CreatedAt
+	}
+	if !state.cursor.IsZero() {
+		value, key, version := "$"+strconv.Itoa(len(args)+1), "$"+strconv.Itoa(len(args)+2), "$"+strconv.Itoa(len(args)+3)
+		args = append(args, cursorValue, []byte(state.cursor.Key), state.cursor.Version)
+		conditions += `
+			AND (` + orderValue + ` < ` + value + ` OR (` + orderValue + ` = ` + value + ` AND
+				(objects.object_key > ` + key + ` OR (objects.object_key = ` + key + ` AND version < ` + version + `))))`
+	}
+
+	return `SELECT
+		` + objectKey + `,
+		version
+		` + opts.selectedFields() + `
+		FROM objects
+		` + impl.AsOfSystemTime(opts.SnapshotTime) + `
+		WHERE
+			(project_id, bucket_name) = ($1, $2)
+			AND ` + opts.statusCondition() + `
+			AND (expires_at IS NULL OR expires_at > now())
+			` + conditions + `
+		ORDER BY ` + orderValue + ` DESC, objects.object_key ASC, version DESC
+		LIMIT $3
+	`, args
+}
+
+// iterateObjectsOrdered calls fn for every listed object with a non-key ordering.
+func (s *SpannerAdapter) iterateObjectsOrdered(ctx context.Context, opts ListObjects, fn func(ObjectEntry) error) (err error) {
+	state := newListObjectsOrderedState(&opts, fn)
+
+	for {
+		stmt := listObjectsOrderedStatementSpanner(state)
+
+		var fnErr error
+		err := func() error {
+			rowIterator := opts.spannerReadOnly(s.client).QueryWithOptions(ctx, stmt, opts.Priority.spannerQueryOptions())
+			defer rowIterator.Stop()
+
+			state.startBatch()
+			for {
+				row, err := rowIterator.Next()
+				if err != nil {
+					if errors.Is(err, iterator.Done) {
+						return nil
+					}
+					return Error.Wrap(err)
+				}
+
+				entry, err := scanListObjectsEntrySpanner(row, &opts)
+				if err != nil {
+					return Error.Wrap(err)
+				}
+
+				if fnErr = state.add(entry); fnErr != nil {
+					return nil
+				}
+			}
+		}()
+		if err != nil {
+			return Error.Wrap(err)
+		}
+		if fnErr != nil {
+			return fnErr
+		}
+
+		if !state.nextBatch() {
+			return nil
+		}
+	}
+}
+
+// explainListObjectsOrdered explains the first query of ListObjects with a non-key ordering.
+func (s *SpannerAdapter) explainListObjectsOrdered(ctx context.Context, opts ListObjects) (_ string, err error) {
+	plan, err := s.client.Single().AnalyzeQuery(ctx, listObjectsOrderedStatementSpanner(newListObjectsOrderedState(&opts, nil)))
+	if err != nil {
+		return "", Error.Wrap(err)
+	}
+	return formatSpannerQueryPlan(plan), nil
+}
+
+// listObjectsOrderedStatementSpanner returns the statement for the next batch of state.
+func listObjectsOrderedStatementSpanner(state *listObjectsOrderedState) spanner.Statement {
+	opts := state.opts
+
+	args := map[string]any{
+		"project_id":  opts.ProjectID,
+		"bucket_name": opts.BucketName,
+		"limit":       state.batchSize,
+	}
+
+	var objectKey = `object_key`
+	var conditions string
+	if opts.Prefix != "" {
+		args["prefix"] = []byte(opts.Prefix)
+		args["stop_key"] = opts.stopKey()
+		conditions += `
+			AND object_key >= @prefix AND object_key < @stop_key`
+	}
+	if opts.strippedPrefix() != "" {
+		args["prefix_len"] = len(opts.Prefix) + 1
+		objectKey = `substr(object_key, @prefix_len) AS object_key`
+	}
+
+	if !opts.AllVersions {
+		// only the latest version of every key is listed. The versions are
+		// hidden by the same entries as in the key ordered listing.
+		conditions += `
+			AND NOT EXISTS (
+				SELECT 1 FROM objects AS newer
+				WHERE
+					newer.project_id = objects.project_id
+					AND newer.bucket_name = objects.bucket_name
+					AND newer.object_key = objects.object_key
+					AND newer.version > objects.version
+					AND ` + opts.statusCondition() + `
+					AND (newer.expires_at IS NULL OR newer.expires_at > CURRENT_TIMESTAMP)
+			)`
+	}
+
+	var orderValue string
+	var cursorValue any
+	switch opts.OrderBy {
+	case ListObjectsOrderSizeDesc:
+		orderValue, cursorValue = `total_encrypted_size`, state.cursor.TotalEncryptedSize
+	case ListObjectsOrderCreatedDesc:
+		orderValue, cursorValue = `created_at`, state.cursor.CreatedAt
+	}
+	if !state.cursor.IsZero() {
+		args["cursor_value"] = cursorValue
+		args["cursor_key"] = []byte(state.cursor.Key)
+		args["cursor_version"] = state.cursor.Version
+		conditions += `
+			AND (` + orderValue + ` < @cursor_value OR (` + orderValue + ` = @cursor_value AND
+				(objects.object_key > @cursor_key OR (objects.object_key = @cursor_key AND version < @cursor_version))))`
+	}
+
+	return spanner.Statement{
+		SQL: `
+			SELECT
+				` + objectKey + `,
+				version
+				` + opts.selectedFields() + `
+			FROM objects
+			WHERE
+				project_id = @project_id AND bucket_name = @bucket_name
+				AND ` + opts.statusCondition() + `
+				AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+				` + conditions + `
+			ORDER BY ` + orderValue + ` DESC, objects.object_key ASC, version DESC
+			LIMIT @limit
+		`,
+		Params: args,
+	}
+}
@@ -4,18 +4,25 @@
 package metabase_test
 
 import (
+	"context"
+	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/spanner/apiv1/spannerpb"
 	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
 
+	"storj.io/common/storj"
 	"storj.io/common/testcontext"
 	"storj.io/common/testrand"
 	"storj.io/common/uuid"
 	"storj.io/storj/satellite/metabase"
 	"storj.io/storj/satellite/metabase/metabasetest"
+	"storj.io/storj/shared/dbutil"
 )
 
 type listObjectsScenario struct {
@@ -78,6 +85,104 @@ func TestListObjects(t *testing.T) {
 			metabasetest.Verify{}.Check(ctx, t, db)
 		})
 
+		t.Run("Invalid MinTotalEncryptedSize", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:             obj.ProjectID,
+					BucketName:            obj.BucketName,
+					MinTotalEncryptedSize: -1,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "Invalid MinTotalEncryptedSize: -1",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("Invalid creation time window", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			now := time.Now()
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:     obj.ProjectID,
+					BucketName:    obj.BucketName,
+					CreatedAfter:  &now,
+					CreatedBefore: &now,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "CreatedBefore must be after CreatedAfter",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("Invalid cursor version", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					Pending:    true,
+					Cursor:     metabase.ListObjectsCursor{Key: "a", Version: -1},
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "Invalid Cursor.Version for ascending version order: -1",
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:   obj.ProjectID,
+					BucketName:  obj.BucketName,
+					AllVersions: true,
+					Cursor:      metabase.ListObjectsCursor{Key: "a", Version: metabase.MaxVersion + 1},
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "Invalid Cursor.Version for descending version order: 9223372036854775744",
+			}.Check(ctx, t, db)
+
+			// a cursor starting a descending listing isn't valid for a pending listing.
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					Pending:    true,
+					Cursor:     metabase.ListObjectsCursor{Version: metabase.MaxVersion},
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "Invalid Cursor.Version for ascending version order: 9223372036854775743",
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					Prefix:     "b/",
+					Pending:    true,
+					Cursor:     metabase.ListObjectsCursor{Key: "a", Version: metabase.MaxVersion},
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "Invalid Cursor.Version for ascending version order: 9223372036854775743",
+			}.Check(ctx, t, db)
+
+			// after the first key it skips the remaining versions of the key.
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:  obj.ProjectID,
+					BucketName: obj.BucketName,
+					Pending:    true,
+					Cursor:     metabase.ListObjectsCursor{Key: "a", Version: metabase.MaxVersion},
+				},
+				Result: metabase.ListObjectsResult{},
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
 		t.Run("no objects", func(t *testing.T) {
 			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
 
@@ -604,7 +709,525 @@ func TestListObjects(t *testing.T) {
 				}.Check(ctx, t, db)
 			}
 		})
-
+
+		t.Run("min total encrypted size", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID, bucketName := uuid.UUID{1}, "bucky"
+
+			objects := map[metabase.ObjectKey]metabase.ObjectEntry{}
+			for key, numberOfSegments := range map[metabase.ObjectKey]byte{
+				"a":   2,
+				"b":   0,
+				"c/1": 0,
+				"c/2": 1,
+				"d/1": 0,
+			} {
+				stream := metabasetest.RandObjectStream()
+				stream.ProjectID, stream.BucketName, stream.ObjectKey = projectID, bucketName, key
+				object := metabasetest.CreateObject(ctx, t, db, stream, numberOfSegments)
+				objects[key] = objectEntryFromRaw(metabase.RawObject(object))
+			}
+
+			for _, allVersions := range []bool{false, true} {
+				metabasetest.ListObjects{
+					Opts: metabase.ListObjects{
+						ProjectID:             projectID,
+						BucketName:            bucketName,
+						Recursive:             true,
+						AllVersions:           allVersions,
+						IncludeCustomMetadata: true,
+						MinTotalEncryptedSize: 1,
+					},
+					Result: metabase.ListObjectsResult{
+						Objects: []metabase.ObjectEntry{
+							objects["a"],
+							objects["c/2"],
+						},
+					},
+				}.Check(ctx, t, db)
+
+				// prefixes don't have a size and are always listed.
+				metabasetest.ListObjects{
+					Opts: metabase.ListObjects{
+						ProjectID:             projectID,
+						BucketName:            bucketName,
+						AllVersions:           allVersions,
+						IncludeCustomMetadata: true,
+						MinTotalEncryptedSize: 1,
+					},
+					Result: metabase.ListObjectsResult{
+						Objects: []metabase.ObjectEntry{
+							objects["a"],
+							prefixEntry("c/"),
+							prefixEntry("d/"),
+						},
+					},
+				}.Check(ctx, t, db)
+
+				metabasetest.ListObjects{
+					Opts: metabase.ListObjects{
+						ProjectID:             projectID,
+						BucketName:            bucketName,
+						AllVersions:           allVersions,
+						IncludeCustomMetadata: true,
+						MinTotalEncryptedSize: 1,
+
+						Prefix: "c/",
+					},
+					Result: metabase.ListObjectsResult{
+						Objects: withoutPrefix("c/",
+							objects["c/2"],
+						),
+					},
+				}.Check(ctx, t, db)
+			}
+		})
+
+		t.Run("min total encrypted size is filtered by the query", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID, bucketName := uuid.UUID{1}, "bucky"
+
+			// more small objects than a single query reads.
+			var objects []metabase.RawObject
+			for i := 0; i < 150; i++ {
+				objects = append(objects, metabase.RawObject{
+					ObjectStream: metabase.ObjectStream{
+						ProjectID:  projectID,
+						BucketName: bucketName,
+						ObjectKey:  metabase.ObjectKey(fmt.Sprintf("a/%03d", i)),
+						Version:    1,
+						StreamID:   testrand.UUID(),
+					},
+					CreatedAt: time.Now(),
+					Status:    metabase.CommittedUnversioned,
+				})
+			}
+			large := metabase.RawObject{
+				ObjectStream: metabase.ObjectStream{
+					ProjectID:  projectID,
+					BucketName: bucketName,
+					ObjectKey:  "b",
+					Version:    1,
+					StreamID:   testrand.UUID(),
+				},
+				CreatedAt:          time.Now(),
+				Status:             metabase.CommittedUnversioned,
+				TotalEncryptedSize: 1024,
+			}
+			objects = append(objects, large)
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, objects))
+
+			result, err := db.ListObjects(ctx, metabase.ListObjects{
+				ProjectID:             projectID,
+				BucketName:            bucketName,
+				Recursive:             true,
+				AllVersions:           true,
+				Limit:                 1,
+				MinTotalEncryptedSize: 1,
+			})
+			require.NoError(t, err)
+			require.Len(t, result.Objects, 1)
+			require.Equal(t, large.ObjectKey, result.Objects[0].ObjectKey)
+			require.False(t, result.More)
+		})
+
+		t.Run("creation time window", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID, bucketName := uuid.UUID{1}, "bucky"
+			createdAt := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+
+			newObject := func(key metabase.ObjectKey, version metabase.Version, created time.Time) metabase.RawObject {
+				return metabase.RawObject{
+					ObjectStream: metabase.ObjectStream{
+						ProjectID:  projectID,
+						BucketName: bucketName,
+						ObjectKey:  key,
+						Version:    version,
+						StreamID:   testrand.UUID(),
+					},
+					CreatedAt:  created,
+					Status:     metabase.CommittedVersioned,
+					Encryption: metabasetest.DefaultEncryption,
+				}
+			}
+
+			var raw []metabase.RawObject
+			for i, key := range []metabase.ObjectKey{"a", "b", "c/1", "c/2", "d"} {
+				raw = append(raw, newObject(key, 1, createdAt.Add(time.Duration(i)*time.Minute)))
+			}
+			// the latest version of "e" is outside of the window,
+			// which hides the older version inside of it.
+			raw = append(raw,
+				newObject("e", 1, createdAt.Add(2*time.Minute)),
+				newObject("e", 2, createdAt.Add(10*time.Minute)),
+			)
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, raw))
+
+			objects := map[metabase.ObjectKey]metabase.ObjectEntry{}
+			for _, object := range raw[:5] {
+				objects[object.ObjectKey] = objectEntryFromRaw(object)
+			}
+
+			after, before := createdAt, createdAt.Add(4*time.Minute)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:     projectID,
+					BucketName:    bucketName,
+					Recursive:     true,
+					CreatedAfter:  &after,
+					CreatedBefore: &before,
+				},
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objects["b"],
+						objects["c/1"],
+						objects["c/2"],
+					},
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:    projectID,
+					BucketName:   bucketName,
+					CreatedAfter: &after,
+				},
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objects["b"],
+						prefixEntry("c/"),
+						objects["d"],
+						objectEntryFromRaw(raw[6]),
+					},
+				},
+			}.Check(ctx, t, db)
+
+			// the window composes with the prefix.
+			cAfter := createdAt.Add(2 * time.Minute)
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:     projectID,
+					BucketName:    bucketName,
+					Prefix:        "c/",
+					CreatedAfter:  &cAfter,
+					CreatedBefore: &before,
+				},
+				Result: metabase.ListObjectsResult{
+					Objects: withoutPrefix("c/",
+						objects["c/2"],
+					),
+				},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("creation time window filters most entries", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID, bucketName := uuid.UUID{1}, "bucky"
+			createdAt := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+
+			var raw []metabase.RawObject
+			for i := 0; i < 250; i++ {
+				created := createdAt
+				if i%100 == 99 {
+					created = createdAt.Add(time.Minute)
+				}
+				raw = append(raw, metabase.RawObject{
+					ObjectStream: metabase.ObjectStream{
+						ProjectID:  projectID,
+						BucketName: bucketName,
+						ObjectKey:  metabase.ObjectKey(fmt.Sprintf("%03d", i)),
+						Version:    1,
+						StreamID:   testrand.UUID(),
+					},
+					CreatedAt:  created,
+					Status:     metabase.CommittedUnversioned,
+					Encryption: metabasetest.DefaultEncryption,
+				})
+			}
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, raw))
+
+			// the filtered entries span multiple queries, however, they
+			// shouldn't exhaust the requery limit.
+			after := createdAt
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:    projectID,
+					BucketName:   bucketName,
+					Recursive:    true,
+					Limit:        5,
+					RequeryLimit: 1,
+					CreatedAfter: &after,
+				},
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objectEntryFromRaw(raw[99]),
+						objectEntryFromRaw(raw[199]),
+					},
+				},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("keys only", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID, bucketName := uuid.UUID{1}, "bucky"
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:             projectID,
+					BucketName:            bucketName,
+					KeysOnly:              true,
+					IncludeSystemMetadata: true,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "KeysOnly can't be combined with including metadata",
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:             projectID,
+					BucketName:            bucketName,
+					KeysOnly:              true,
+					MinTotalEncryptedSize: 1,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "KeysOnly can't be combined with MinTotalEncryptedSize",
+			}.Check(ctx, t, db)
+
+			now := time.Now()
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:    projectID,
+					BucketName:   bucketName,
+					KeysOnly:     true,
+					CreatedAfter: &now,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "KeysOnly can't be combined with CreatedAfter or CreatedBefore",
+			}.Check(ctx, t, db)
+
+			objects := map[metabase.ObjectKey]metabase.ObjectEntry{}
+			for _, key := range []metabase.ObjectKey{"a", "b/1", "b/2", "c"} {
+				stream := metabasetest.RandObjectStream()
+				stream.ProjectID, stream.BucketName, stream.ObjectKey = projectID, bucketName, key
+				object := metabasetest.CreateObject(ctx, t, db, stream, 1)
+				objects[key] = metabase.ObjectEntry{
+					ObjectKey: object.ObjectKey,
+					Version:   object.Version,
+					Status:    object.Status,
+				}
+			}
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:  projectID,
+					BucketName: bucketName,
+					Recursive:  true,
+					KeysOnly:   true,
+				},
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objects["a"],
+						objects["b/1"],
+						objects["b/2"],
+						objects["c"],
+					},
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:  projectID,
+					BucketName: bucketName,
+					KeysOnly:   true,
+				},
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objects["a"],
+						prefixEntry("b/"),
+						objects["c"],
+					},
+				},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("soft deleted", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID, bucketName := uuid.UUID{1}, "bucky"
+
+			a := metabasetest.RandObjectStream()
+			a.ProjectID, a.BucketName, a.ObjectKey, a.Version = projectID, bucketName, "a", 1
+			softDeleted := metabasetest.CreateObject(ctx, t, db, a, 0)
+
+			a.Version, a.StreamID = 2, testrand.UUID()
+			metabasetest.CreatePendingObject(ctx, t, db, a, 0)
+			overwrite := metabasetest.CommitObject{
+				Opts: metabase.CommitObject{
+					ObjectStream:          a,
+					SoftDeleteOnOverwrite: true,
+				},
+			}.Check(ctx, t, db)
+			softDeleted.Status = metabase.DeleteMarkerVersioned
+
+			b := metabasetest.RandObjectStream()
+			b.ProjectID, b.BucketName, b.ObjectKey, b.Version = projectID, bucketName, "b", 1
+			versioned := metabasetest.CreateObjectVersioned(ctx, t, db, b, 0)
+
+			b.Version, b.StreamID = 2, testrand.UUID()
+			deleteMarker := metabase.RawObject{
+				ObjectStream: b,
+				CreatedAt:    time.Now().Truncate(time.Second),
+				Status:       metabase.DeleteMarkerVersioned,
+			}
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{deleteMarker}))
+
+			list := func(mode metabase.SoftDeletedMode, allVersions bool) metabase.ListObjects {
+				return metabase.ListObjects{
+					ProjectID:             projectID,
+					BucketName:            bucketName,
+					Recursive:             true,
+					AllVersions:           allVersions,
+					IncludeCustomMetadata: true,
+					IncludeSystemMetadata: true,
+					IncludeSoftDeleted:    mode,
+				}
+			}
+
+			metabasetest.ListObjects{
+				Opts:     list(metabase.SoftDeletedOnly, false),
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "listing only soft-deleted objects requires AllVersions",
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: list(metabase.SoftDeletedExclude, true),
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objectEntryFromRaw(metabase.RawObject(overwrite)),
+						objectEntryFromRaw(deleteMarker),
+						objectEntryFromRaw(metabase.RawObject(versioned)),
+					},
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: list(metabase.SoftDeletedInclude, true),
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objectEntryFromRaw(metabase.RawObject(overwrite)),
+						objectEntryFromRaw(metabase.RawObject(softDeleted)),
+						objectEntryFromRaw(deleteMarker),
+						objectEntryFromRaw(metabase.RawObject(versioned)),
+					},
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: list(metabase.SoftDeletedOnly, true),
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objectEntryFromRaw(metabase.RawObject(softDeleted)),
+					},
+				},
+			}.Check(ctx, t, db)
+
+			// the soft-deleted object is never the latest version,
+			// hence it doesn't change what's visible.
+			for _, mode := range []metabase.SoftDeletedMode{metabase.SoftDeletedExclude, metabase.SoftDeletedInclude} {
+				metabasetest.ListObjects{
+					Opts: list(mode, false),
+					Result: metabase.ListObjectsResult{
+						Objects: []metabase.ObjectEntry{
+							objectEntryFromRaw(metabase.RawObject(overwrite)),
+						},
+					},
+				}.Check(ctx, t, db)
+			}
+		})
+	})
+}
+
+func TestExplainListObjects(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		_, err := db.ExplainListObjects(ctx, metabase.ListObjects{})
+		require.True(t, metabase.ErrInvalidRequest.Has(err))
+
+		for _, recursive := range []bool{false, true} {
+			explanation, err := db.ExplainListObjects(ctx, metabase.ListObjects{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Prefix:     "a/",
+				Recursive:  recursive,
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, explanation)
+		}
+	})
+}
+
+func TestIterateObjects(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		projectID, bucketName := uuid.UUID{1}, "bucky"
+
+		err := db.IterateObjects(ctx, metabase.ListObjects{}, func(metabase.ObjectEntry) error {
+			return nil
+		})
+		require.True(t, metabase.ErrInvalidRequest.Has(err))
+
+		var keys []metabase.ObjectKey
+		for i := 0; i < 250; i++ {
+			keys = append(keys, metabase.ObjectKey("a/"+strconv.Itoa(i)))
+		}
+		keys = append(keys, "b/1", "b/2", "c")
+		createObjectsWithKeys(ctx, t, db, projectID, bucketName, keys)
+
+		for _, recursive := range []bool{false, true} {
+			opts := metabase.ListObjects{
+				ProjectID:  projectID,
+				BucketName: bucketName,
+				Recursive:  recursive,
+				Limit:      3,
+			}
+
+			// the entries are not limited to a single page.
+			var iterated []metabase.ObjectEntry
+			err := db.IterateObjects(ctx, opts, func(entry metabase.ObjectEntry) error {
+				iterated = append(iterated, entry)
+				return nil
+			})
+			require.NoError(t, err)
+
+			opts.Limit = int(metabase.ListLimit)
+			listed, err := db.ListObjects(ctx, opts)
+			require.NoError(t, err)
+			require.False(t, listed.More)
+			require.Equal(t, listed.Objects, iterated)
+		}
+
+		// the error of the callback stops the iteration.
+		errStop := errs.New("stop")
+		count := 0
+		err = db.IterateObjects(ctx, metabase.ListObjects{
+			ProjectID:  projectID,
+			BucketName: bucketName,
+			Recursive:  true,
+		}, func(entry metabase.ObjectEntry) error {
+			count++
+			if count == 10 {
+				return errStop
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, errStop)
+		require.Equal(t, 10, count)
 	})
 }
 
@@ -764,6 +1387,78 @@ func TestListObjectsSkipCursor(t *testing.T) {
 			}
 		})
 
+		t.Run("deep prefix versions", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			const objectsPerPrefix = 3
+			const versionsPerObject = 50
+
+			prefixes := []metabase.ObjectKey{"a/", "b/", "c/", "d/"}
+
+			var objects []metabase.RawObject
+			for _, prefix := range prefixes {
+				for k := 0; k < objectsPerPrefix; k++ {
+					for v := 0; v < versionsPerObject; v++ {
+						objects = append(objects, metabase.RawObject{
+							ObjectStream: metabase.ObjectStream{
+								ProjectID:  projectID,
+								BucketName: bucketName,
+								ObjectKey:  prefix + metabase.ObjectKey(strconv.Itoa(k)),
+								Version:    metabase.Version(v + 1),
+								StreamID:   testrand.UUID(),
+							},
+							CreatedAt: time.Now(),
+							Status:    metabase.CommittedVersioned,
+						})
+					}
+				}
+			}
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, objects))
+
+			for _, allVersions := range []bool{false, true} {
+				var listed []metabase.ObjectEntry
+				cursor := metabase.ListObjectsCursor{}
+				for {
+					result, err := db.ListObjects(ctx, metabase.ListObjects{
+						ProjectID:   projectID,
+						BucketName:  bucketName,
+						Recursive:   false,
+						Cursor:      cursor,
+						AllVersions: allVersions,
+						Limit:       1,
+					})
+					require.NoError(t, err)
+					listed = append(listed, result.Objects...)
+					if !result.More {
+						break
+					}
+					last := result.Objects[len(result.Objects)-1]
+					cursor = metabase.ListObjectsCursor{Key: last.ObjectKey, Version: last.Version}
+				}
+
+				expected := make([]metabase.ObjectEntry, 0, len(prefixes))
+				for _, prefix := range prefixes {
+					expected = append(expected, prefixEntry(prefix))
+				}
+				require.Equal(t, expected, listed, "allVersions=%v", allVersions)
+
+				// a cursor in the middle of the versions of a prefix continues past the prefix.
+				result, err := db.ListObjects(ctx, metabase.ListObjects{
+					ProjectID:   projectID,
+					BucketName:  bucketName,
+					Recursive:   false,
+					Cursor:      metabase.ListObjectsCursor{Key: "b/1", Version: versionsPerObject / 2},
+					AllVersions: allVersions,
+					Limit:       1,
+				})
+				require.NoError(t, err)
+				require.Equal(t, metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{prefixEntry("c/")},
+					More:    true,
+				}, result, "allVersions=%v", allVersions)
+			}
+		})
+
 		t.Run("batch-size", func(t *testing.T) {
 			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
 
@@ -2401,6 +3096,84 @@ func TestListObjectsVersioned(t *testing.T) {
 			}.Check(ctx, t, db)
 
 		})
+
+		t.Run("max versions per key", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID, bucketName := uuid.UUID{1}, "b1"
+
+			a := metabasetest.RandObjectStream()
+			a.ProjectID, a.BucketName, a.ObjectKey = projectID, bucketName, "a"
+			b := a
+			b.ObjectKey = "b"
+
+			var versionsA, versionsB []metabase.Object
+			for i := 0; i < 3; i++ {
+				a.Version = metabase.Version(i + 1)
+				a.StreamID = testrand.UUID()
+				versionsA = append(versionsA, metabasetest.CreateObjectVersioned(ctx, t, db, a, 0))
+
+				b.Version = metabase.Version(i + 1)
+				b.StreamID = testrand.UUID()
+				versionsB = append(versionsB, metabasetest.CreateObjectVersioned(ctx, t, db, b, 0))
+			}
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:         projectID,
+					BucketName:        bucketName,
+					Recursive:         true,
+					AllVersions:       true,
+					MaxVersionsPerKey: -1,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "Invalid MaxVersionsPerKey: -1",
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:             projectID,
+					BucketName:            bucketName,
+					Recursive:             true,
+					AllVersions:           true,
+					MaxVersionsPerKey:     2,
+					IncludeCustomMetadata: true,
+					IncludeSystemMetadata: true,
+				},
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objectEntryFromRaw(metabase.RawObject(versionsA[2])),
+						objectEntryFromRaw(metabase.RawObject(versionsA[1])),
+						objectEntryFromRaw(metabase.RawObject(versionsB[2])),
+						objectEntryFromRaw(metabase.RawObject(versionsB[1])),
+					},
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:             projectID,
+					BucketName:            bucketName,
+					Recursive:             true,
+					AllVersions:           true,
+					MaxVersionsPerKey:     2,
+					IncludeCustomMetadata: true,
+					IncludeSystemMetadata: true,
+
+					Cursor: metabase.ListObjectsCursor{
+						Key:     "a",
+						Version: 3,
+					},
+				},
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						objectEntryFromRaw(metabase.RawObject(versionsA[1])),
+						objectEntryFromRaw(metabase.RawObject(versionsB[2])),
+						objectEntryFromRaw(metabase.RawObject(versionsB[1])),
+					},
+				},
+			}.Check(ctx, t, db)
+		})
 	})
 }
 
@@ -2490,3 +3263,664 @@ func TestListObjects_Stress(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestListObjectsBucketBoundaries(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		projectID := testrand.UUID()
+
+		t.Run("invalid bucket name", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:  projectID,
+					BucketName: "bucket\xff",
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "BucketName must be valid UTF-8",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("neighbouring buckets", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			// bucket names, which sort immediately before or after each other.
+			bucketNames := []string{"bucke", "bucket", "bucket0", "bucketa", "bucketé", "bucketéa"}
+
+			objects := map[string]metabase.Object{}
+			for _, bucketName := range bucketNames {
+				obj := metabasetest.RandObjectStream()
+				obj.ProjectID = projectID
+				obj.BucketName = bucketName
+				obj.ObjectKey = "key"
+				objects[bucketName] = metabasetest.CreateObject(ctx, t, db, obj, 0)
+			}
+
+			for _, bucketName := range bucketNames {
+				for _, allVersions := range []bool{false, true} {
+					metabasetest.ListObjects{
+						Opts: metabase.ListObjects{
+							ProjectID:             projectID,
+							BucketName:            bucketName,
+							Recursive:             true,
+							AllVersions:           allVersions,
+							IncludeCustomMetadata: true,
+							IncludeSystemMetadata: true,
+						},
+						Result: metabase.ListObjectsResult{
+							Objects: []metabase.ObjectEntry{
+								objectEntryFromRaw(metabase.RawObject(objects[bucketName])),
+							},
+						},
+					}.Check(ctx, t, db)
+				}
+			}
+		})
+	})
+}
+
+func TestListObjectsLimit(t *testing.T) {
+	for _, reject := range []bool{false, true} {
+		metabasetest.RunWithConfig(t, metabase.Config{
+			ApplicationName:            "metabase-tests",
+			ListObjectsLimit:           2,
+			RejectListObjectsOverLimit: reject,
+		}, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			require.Equal(t, 2, db.ListObjectsLimit())
+
+			projectID, bucketName := testrand.UUID(), "bucky"
+			createObjectsWithKeys(ctx, t, db, projectID, bucketName, []metabase.ObjectKey{"a", "b", "c"})
+
+			list := func(limit int) (metabase.ListObjectsResult, error) {
+				return db.ListObjects(ctx, metabase.ListObjects{
+					ProjectID:  projectID,
+					BucketName: bucketName,
+					Recursive:  true,
+					Limit:      limit,
+				})
+			}
+
+			result, err := list(2)
+			require.NoError(t, err)
+			require.Len(t, result.Objects, 2)
+			require.True(t, result.More)
+
+			// zero limit means the maximum.
+			result, err = list(0)
+			require.NoError(t, err)
+			require.Len(t, result.Objects, 2)
+
+			result, err = list(3)
+			if reject {
+				require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+				require.ErrorContains(t, err, "Limit 3 exceeds the maximum 2")
+			} else {
+				require.NoError(t, err)
+				require.Len(t, result.Objects, 2)
+				require.True(t, result.More)
+			}
+		})
+	}
+}
+
+func TestListObjectsValidateEncryption(t *testing.T) {
+	for _, validate := range []bool{false, true} {
+		metabasetest.RunWithConfig(t, metabase.Config{
+			ApplicationName:          "metabase-tests",
+			ValidateListedEncryption: validate,
+		}, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID, bucketName := testrand.UUID(), "bucky"
+			newObject := func(key metabase.ObjectKey, status metabase.ObjectStatus, encryption storj.EncryptionParameters) metabase.RawObject {
+				return metabase.RawObject{
+					ObjectStream: metabase.ObjectStream{
+						ProjectID:  projectID,
+						BucketName: bucketName,
+						ObjectKey:  key,
+						Version:    1,
+						StreamID:   testrand.UUID(),
+					},
+					CreatedAt:  time.Now(),
+					Status:     status,
+					Encryption: encryption,
+				}
+			}
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{
+				newObject("a", metabase.CommittedVersioned, metabasetest.DefaultEncryption),
+				newObject("b", metabase.DeleteMarkerVersioned, storj.EncryptionParameters{}),
+				newObject("c", metabase.CommittedVersioned, storj.EncryptionParameters{CipherSuite: 9, BlockSize: 1}),
+			}))
+
+			list := func(prefix metabase.ObjectKey) (metabase.ListObjectsResult, error) {
+				return db.ListObjects(ctx, metabase.ListObjects{
+					ProjectID:   projectID,
+					BucketName:  bucketName,
+					Prefix:      prefix,
+					Recursive:   true,
+					AllVersions: true,
+					Limit:       10,
+				})
+			}
+
+			result, err := list("")
+			if validate {
+				require.ErrorContains(t, err, `invalid encryption parameters of object "c" version 1: unknown cipher suite`)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, result.Objects, 3)
+			}
+
+			// delete markers don't have encryption parameters.
+			result, err = list("b")
+			require.NoError(t, err)
+			require.Len(t, result.Objects, 1)
+		})
+	}
+}
+
+func TestListObjectsWithIteratorUnsupported(t *testing.T) {
+	for _, validate := range []bool{false, true} {
+		metabasetest.RunWithConfig(t, metabase.Config{
+			ApplicationName:          "metabase-tests",
+			ValidateListedEncryption: validate,
+		}, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			list := func(update func(opts *metabase.ListObjects)) error {
+				opts := metabase.ListObjects{
+					ProjectID:  testrand.UUID(),
+					BucketName: "bucky",
+					Limit:      10,
+				}
+				update(&opts)
+				_, err := db.ListObjectsWithIterator(ctx, opts)
+				return err
+			}
+
+			err := list(func(opts *metabase.ListObjects) {})
+			if validate {
+				require.ErrorContains(t, err, "not implemented")
+				return
+			}
+			require.NoError(t, err)
+
+			for _, tt := range []struct {
+				name   string
+				update func(opts *metabase.ListObjects)
+			}{
+				{"Pending", func(opts *metabase.ListObjects) { opts.Pending = true }},
+				{"AllVersions", func(opts *metabase.ListObjects) { opts.AllVersions = true }},
+				{"OrderBy", func(opts *metabase.ListObjects) {
+					opts.Recursive = true
+					opts.OrderBy = metabase.ListObjectsOrderSizeDesc
+				}},
+				{"ReturnFullKey", func(opts *metabase.ListObjects) { opts.ReturnFullKey = true }},
+				{"IncludeFirstSegmentPlacement", func(opts *metabase.ListObjects) { opts.IncludeFirstSegmentPlacement = true }},
+				{"SnapshotTime", func(opts *metabase.ListObjects) { opts.SnapshotTime = time.Now() }},
+				{"IncludeSystemLabels", func(opts *metabase.ListObjects) { opts.IncludeSystemLabels = true }},
+				{"MaxVersionsPerKey", func(opts *metabase.ListObjects) { opts.MaxVersionsPerKey = 1 }},
+				{"IncludeSoftDeleted", func(opts *metabase.ListObjects) { opts.IncludeSoftDeleted = metabase.SoftDeletedInclude }},
+				{"IncludeTags", func(opts *metabase.ListObjects) { opts.IncludeTags = true }},
+			} {
+				t.Run(tt.name, func(t *testing.T) {
+					require.ErrorContains(t, list(tt.update), "not implemented")
+				})
+			}
+		})
+	}
+}
+
+func TestListObjectsOrderBy(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		projectID, bucketName := testrand.UUID(), "bucky"
+
+		t.Run("invalid", func(t *testing.T) {
+			for _, test := range []struct {
+				opts    metabase.ListObjects
+				errText string
+			}{
+				{
+					opts:    metabase.ListObjects{OrderBy: 3, Recursive: true},
+					errText: "Invalid OrderBy: 3",
+				},
+				{
+					opts:    metabase.ListObjects{OrderCursor: metabase.ListObjectsOrderCursor{Key: "a"}, Recursive: true},
+					errText: "OrderCursor requires a non-key OrderBy",
+				},
+				{
+					opts:    metabase.ListObjects{OrderBy: metabase.ListObjectsOrderSizeDesc},
+					errText: "non-key OrderBy can't be combined with a non-recursive listing",
+				},
+				{
+					opts:    metabase.ListObjects{OrderBy: metabase.ListObjectsOrderCreatedDesc, Recursive: true, Pending: true},
+					errText: "non-key OrderBy can't be combined with Pending",
+				},
+				{
+					opts:    metabase.ListObjects{OrderBy: metabase.ListObjectsOrderSizeDesc, Recursive: true, AllVersions: true, MaxVersionsPerKey: 1},
+					errText: "non-key OrderBy can't be combined with MaxVersionsPerKey",
+				},
+				{
+					opts:    metabase.ListObjects{OrderBy: metabase.ListObjectsOrderSizeDesc, Recursive: true, KeysOnly: true},
+					errText: "non-key OrderBy can't be combined with KeysOnly",
+				},
+				{
+					opts:    metabase.ListObjects{OrderBy: metabase.ListObjectsOrderSizeDesc, Recursive: true, Cursor: metabase.ListObjectsCursor{Key: "a"}},
+					errText: "non-key OrderBy requires OrderCursor instead of Cursor",
+				},
+			} {
+				test.opts.ProjectID, test.opts.BucketName = projectID, bucketName
+				_, err := db.ListObjects(ctx, test.opts)
+				require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+				require.ErrorContains(t, err, test.errText)
+			}
+		})
+
+		t.Run("ordered", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			create := func(key metabase.ObjectKey, version metabase.Version, segments byte) metabase.Object {
+				obj := metabasetest.RandObjectStream()
+				obj.ProjectID, obj.BucketName, obj.ObjectKey, obj.Version = projectID, bucketName, key, version
+				return metabasetest.CreateObjectVersioned(ctx, t, db, obj, segments)
+			}
+
+			create("p/a", 1, 1)
+			b := create("p/b", 1, 3)
+			create("p/c", 1, 2)
+			create("p/d", 1, 3)
+			create("p/e", 1, 5)
+			create("p/e", 2, 1)
+			// outside of the prefix.
+			create("q", 1, 4)
+
+			type listed struct {
+				Key     metabase.ObjectKey
+				Version metabase.Version
+			}
+			list := func(orderBy metabase.ListObjectsOrderBy, allVersions bool, limit int) (entries []metabase.ObjectEntry) {
+				opts := metabase.ListObjects{
+					ProjectID:   projectID,
+					BucketName:  bucketName,
+					Prefix:      "p/",
+					Recursive:   true,
+					AllVersions: allVersions,
+					OrderBy:     orderBy,
+					Limit:       limit,
+				}
+				for {
+					result, err := db.ListObjects(ctx, opts)
+					require.NoError(t, err)
+					entries = append(entries, result.Objects...)
+					if !result.More {
+						return entries
+					}
+					require.Len(t, result.Objects, limit)
+					opts.OrderCursor = opts.NextOrderCursor(result.Objects[len(result.Objects)-1])
+				}
+			}
+			keys := func(entries []metabase.ObjectEntry) (result []listed) {
+				for _, entry := range entries {
+					result = append(result, listed{entry.ObjectKey, entry.Version})
+				}
+				return result
+			}
+
+			for _, limit := range []int{1, 2, 10} {
+				entries := list(metabase.ListObjectsOrderSizeDesc, false, limit)
+				require.Equal(t, []listed{{"b", 1}, {"d", 1}, {"c", 1}, {"a", 1}, {"e", 2}}, keys(entries), "limit %d", limit)
+				// system metadata is implicitly selected.
+				require.Equal(t, b.TotalEncryptedSize, entries[0].TotalEncryptedSize)
+				require.False(t, entries[0].CreatedAt.IsZero())
+
+				require.Equal(t,
+					[]listed{{"e", 1}, {"b", 1}, {"d", 1}, {"c", 1}, {"a", 1}, {"e", 2}},
+					keys(list(metabase.ListObjectsOrderSizeDesc, true, limit)), "limit %d", limit)
+
+				// the objects may have the same creation time, which orders them by key.
+				entries = list(metabase.ListObjectsOrderCreatedDesc, false, limit)
+				require.Len(t, entries, 5, "limit %d", limit)
+				for i := 1; i < len(entries); i++ {
+					prev, next := entries[i-1], entries[i]
+					require.True(t, prev.CreatedAt.After(next.CreatedAt) ||
+						prev.CreatedAt.Equal(next.CreatedAt) && prev.ObjectKey < next.ObjectKey, "limit %d", limit)
+				}
+				require.ElementsMatch(t, []listed{{"a", 1}, {"b", 1}, {"c", 1}, {"d", 1}, {"e", 2}}, keys(entries))
+			}
+		})
+	})
+}
+
+func TestListObjectsReturnFullKey(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		projectID, bucketName := testrand.UUID(), "bucky"
+
+		var keys []metabase.ObjectKey
+		for i := 0; i < 30; i++ {
+			keys = append(keys, metabase.ObjectKey("a/x/"+strconv.Itoa(i)))
+		}
+		keys = append(keys, "a/y", "a/z/1", "a/z/2", "b/1", "c")
+		createObjectsWithKeys(ctx, t, db, projectID, bucketName, keys)
+
+		listAll := func(opts metabase.ListObjects) (entries []metabase.ObjectEntry) {
+			for {
+				result, err := db.ListObjects(ctx, opts)
+				require.NoError(t, err)
+				entries = append(entries, result.Objects...)
+				if !result.More {
+					return entries
+				}
+				last := result.Objects[len(result.Objects)-1]
+				opts.Cursor = metabase.ListObjectsCursor{Key: last.ObjectKey, Version: last.Version}
+				if !opts.ReturnFullKey {
+					opts.Cursor.Key = opts.Prefix + last.ObjectKey
+				}
+			}
+		}
+
+		for _, recursive := range []bool{false, true} {
+			for _, limit := range []int{1, 2, 1000} {
+				opts := metabase.ListObjects{
+					ProjectID:  projectID,
+					BucketName: bucketName,
+					Prefix:     "a/",
+					Recursive:  recursive,
+					Limit:      limit,
+				}
+				stripped := listAll(opts)
+
+				opts.ReturnFullKey = true
+				full := listAll(opts)
+
+				require.Len(t, full, len(stripped))
+				for i := range stripped {
+					expected := stripped[i]
+					expected.ObjectKey = opts.Prefix + expected.ObjectKey
+					require.Equal(t, expected, full[i])
+				}
+			}
+
+			result, err := db.ListObjects(ctx, metabase.ListObjects{
+				ProjectID:     projectID,
+				BucketName:    bucketName,
+				Prefix:        "a/",
+				Recursive:     recursive,
+				ReturnFullKey: true,
+			})
+			require.NoError(t, err)
+			if recursive {
+				require.Len(t, result.Objects, 33)
+			} else {
+				require.Len(t, result.Objects, 3)
+				require.Equal(t, metabase.ObjectKey("a/x/"), result.Objects[0].ObjectKey)
+				require.True(t, result.Objects[0].IsPrefix)
+				require.Equal(t, metabase.ObjectKey("a/y"), result.Objects[1].ObjectKey)
+				require.False(t, result.Objects[1].IsPrefix)
+				require.Equal(t, metabase.ObjectKey("a/z/"), result.Objects[2].ObjectKey)
+				require.True(t, result.Objects[2].IsPrefix)
+			}
+		}
+
+		// the other orderings return the full key as well.
+		result, err := db.ListObjects(ctx, metabase.ListObjects{
+			ProjectID:     projectID,
+			BucketName:    bucketName,
+			Prefix:        "a/z/",
+			Recursive:     true,
+			OrderBy:       metabase.ListObjectsOrderCreatedDesc,
+			ReturnFullKey: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Objects, 2)
+		for _, entry := range result.Objects {
+			require.True(t, strings.HasPrefix(string(entry.ObjectKey), "a/z/"), entry.ObjectKey)
+		}
+	})
+}
+
+func TestListObjectsRequeryLimit(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		projectID, bucketName := testrand.UUID(), "bucky"
+
+		iterate := func(opts metabase.ListObjects) (count int, err error) {
+			opts.ProjectID, opts.BucketName = projectID, bucketName
+			opts.Recursive = true
+			opts.Limit = 1
+			err = db.IterateObjects(ctx, opts, func(metabase.ObjectEntry) error {
+				count++
+				return nil
+			})
+			return count, err
+		}
+
+		_, err := iterate(metabase.ListObjects{RequeryLimit: -1})
+		require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+		_, err = iterate(metabase.ListObjects{RequeryPerDeleteMarker: -1})
+		require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+
+		now := time.Now()
+		var objects []metabase.RawObject
+		for i := 0; i < 150; i++ {
+			object := metabase.RawObject{
+				ObjectStream: metabase.ObjectStream{
+					ProjectID:  projectID,
+					BucketName: bucketName,
+					ObjectKey:  metabase.ObjectKey(fmt.Sprintf("%03d", i)),
+					Version:    1,
+					StreamID:   testrand.UUID(),
+				},
+				CreatedAt:  now,
+				Status:     metabase.CommittedVersioned,
+				Encryption: metabasetest.DefaultEncryption,
+			}
+			// the first entries are hidden, hence they don't allow additional queries.
+			if i < 140 {
+				object.Status = metabase.DeleteMarkerVersioned
+				object.Encryption = storj.EncryptionParameters{}
+			}
+			objects = append(objects, object)
+		}
+		require.NoError(t, db.TestingBatchInsertObjects(ctx, objects))
+
+		// a single query isn't enough for listing all the entries.
+		_, err = iterate(metabase.ListObjects{RequeryLimit: 1})
+		require.Error(t, err)
+		require.ErrorContains(t, err, "too many requeries: limit 1")
+		require.ErrorContains(t, err, "hidden delete markers 100")
+
+		count, err := iterate(metabase.ListObjects{RequeryLimit: 2})
+		require.NoError(t, err)
+		require.Equal(t, 10, count)
+
+		// the hidden delete markers allow additional queries.
+		count, err = iterate(metabase.ListObjects{RequeryLimit: 1, RequeryPerDeleteMarker: 1})
+		require.NoError(t, err)
+		require.Equal(t, 10, count)
+	})
+}
+
+func TestListObjectsFirstSegmentPlacement(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		projectID, bucketName := testrand.UUID(), "bucky"
+
+		newObject := func(key metabase.ObjectKey) metabase.RawObject {
+			return metabase.RawObject{
+				ObjectStream: metabase.ObjectStream{
+					ProjectID:  projectID,
+					BucketName: bucketName,
+					ObjectKey:  key,
+					Version:    1,
+					StreamID:   testrand.UUID(),
+				},
+				CreatedAt:  time.Now(),
+				Status:     metabase.CommittedUnversioned,
+				Encryption: metabasetest.DefaultEncryption,
+			}
+		}
+
+		placed := newObject("a")
+		placed.SegmentCount = 2
+		unsegmented := newObject("b")
+		missingFirst := newObject("c")
+		missingFirst.SegmentCount = 1
+		require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{placed, unsegmented, missingFirst}))
+
+		first := metabasetest.DefaultRawSegment(placed.ObjectStream, metabase.SegmentPosition{Index: 0})
+		first.Placement = 5
+		second := metabasetest.DefaultRawSegment(placed.ObjectStream, metabase.SegmentPosition{Index: 1})
+		second.Placement = 7
+		other := metabasetest.DefaultRawSegment(missingFirst.ObjectStream, metabase.SegmentPosition{Index: 1})
+		other.Placement = 7
+		require.NoError(t, db.TestingBatchInsertSegments(ctx, []metabase.RawSegment{first, second, other}))
+
+		_, err := db.ListObjects(ctx, metabase.ListObjects{
+			ProjectID:                    projectID,
+			BucketName:                   bucketName,
+			KeysOnly:                     true,
+			IncludeFirstSegmentPlacement: true,
+		})
+		require.True(t, metabase.ErrInvalidRequest.Has(err))
+
+		for _, orderBy := range []metabase.ListObjectsOrderBy{metabase.ListObjectsOrderKeyAsc, metabase.ListObjectsOrderCreatedDesc} {
+			result, err := db.ListObjects(ctx, metabase.ListObjects{
+				ProjectID:                    projectID,
+				BucketName:                   bucketName,
+				Recursive:                    true,
+				OrderBy:                      orderBy,
+				IncludeFirstSegmentPlacement: true,
+			})
+			require.NoError(t, err)
+
+			placements := map[metabase.ObjectKey]storj.PlacementConstraint{}
+			for _, entry := range result.Objects {
+				placements[entry.ObjectKey] = entry.Placement
+			}
+			require.Equal(t, map[metabase.ObjectKey]storj.PlacementConstraint{
+				"a": 5,
+				"b": 0,
+				"c": 0,
+			}, placements)
+		}
+
+		// the placement isn't returned by default.
+		result, err := db.ListObjects(ctx, metabase.ListObjects{
+			ProjectID:  projectID,
+			BucketName: bucketName,
+			Recursive:  true,
+		})
+		require.NoError(t, err)
+		require.Len(t, result.Objects, 3)
+		require.Zero(t, result.Objects[0].Placement)
+
+		metabasetest.DeleteAll{}.Check(ctx, t, db)
+	})
+}
+
+func TestListObjectsPriority(t *testing.T) {
+	for priority, expected := range map[metabase.RequestPriority]spannerpb.RequestOptions_Priority{
+		metabase.RequestPriorityDefault: spannerpb.RequestOptions_PRIORITY_UNSPECIFIED,
+		metabase.RequestPriorityLow:     spannerpb.RequestOptions_PRIORITY_LOW,
+		metabase.RequestPriorityMedium:  spannerpb.RequestOptions_PRIORITY_MEDIUM,
+		metabase.RequestPriorityHigh:    spannerpb.RequestOptions_PRIORITY_HIGH,
+	} {
+		require.Equal(t, expected, metabase.SpannerQueryOptions(priority).Priority)
+	}
+
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		projectID, bucketName := testrand.UUID(), "bucky"
+		createObjectsWithKeys(ctx, t, db, projectID, bucketName, []metabase.ObjectKey{"a", "b/1", "c"})
+
+		_, err := db.ListObjects(ctx, metabase.ListObjects{
+			ProjectID:  projectID,
+			BucketName: bucketName,
+			Priority:   metabase.RequestPriorityHigh + 1,
+		})
+		require.True(t, metabase.ErrInvalidRequest.Has(err))
+
+		for _, orderBy := range []metabase.ListObjectsOrderBy{metabase.ListObjectsOrderKeyAsc, metabase.ListObjectsOrderCreatedDesc} {
+			result, err := db.ListObjects(ctx, metabase.ListObjects{
+				ProjectID:  projectID,
+				BucketName: bucketName,
+				Recursive:  true,
+				OrderBy:    orderBy,
+				Priority:   metabase.RequestPriorityLow,
+			})
+			require.NoError(t, err)
+			require.Len(t, result.Objects, 3)
+		}
+
+		var count int
+		err = db.IterateObjectsAllVersionsWithStatus(ctx, metabase.IterateObjectsWithStatus{
+			ProjectID:  projectID,
+			BucketName: bucketName,
+			Recursive:  true,
+			BatchSize:  1,
+			Priority:   metabase.RequestPriorityLow,
+		}, func(ctx context.Context, it metabase.ObjectsIterator) error {
+			var entry metabase.ObjectEntry
+			for it.Next(ctx, &entry) {
+				count++
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+
+		metabasetest.DeleteAll{}.Check(ctx, t, db)
+	})
+}
+
+func TestListObjectsSnapshotTime(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		projectID, bucketName := testrand.UUID(), "bucky"
+		createObjectsWithKeys(ctx, t, db, projectID, bucketName, []metabase.ObjectKey{"a", "c"})
+
+		// make sure that the snapshot is strictly between the commits.
+		time.Sleep(100 * time.Millisecond)
+		snapshotTime := time.Now()
+		time.Sleep(100 * time.Millisecond)
+
+		createObjectsWithKeys(ctx, t, db, projectID, bucketName, []metabase.ObjectKey{"b", "d"})
+
+		expected := []metabase.ObjectKey{"a", "c"}
+		if db.Implementation() == dbutil.Postgres {
+			// Postgres ignores the snapshot time.
+			expected = []metabase.ObjectKey{"a", "b", "c", "d"}
+		}
+
+		for _, orderBy := range []metabase.ListObjectsOrderBy{metabase.ListObjectsOrderKeyAsc, metabase.ListObjectsOrderCreatedDesc} {
+			opts := metabase.ListObjects{
+				ProjectID:    projectID,
+				BucketName:   bucketName,
+				Recursive:    true,
+				Limit:        1,
+				OrderBy:      orderBy,
+				SnapshotTime: snapshotTime,
+			}
+
+			var keys []metabase.ObjectKey
+			for {
+				result, err := db.ListObjects(ctx, opts)
+				require.NoError(t, err)
+				for _, entry := range result.Objects {
+					keys = append(keys, entry.ObjectKey)
+				}
+				if !result.More {
+					break
+				}
+				last := result.Objects[len(result.Objects)-1]
+				if orderBy == metabase.ListObjectsOrderKeyAsc {
+					opts.Cursor = metabase.ListObjectsCursor{Key: last.ObjectKey, Version: last.Version}
+				} else {
+					opts.OrderCursor = opts.NextOrderCursor(last)
+				}
+			}
+			sort.Slice(keys, func(i, k int) bool { return keys[i] < keys[k] })
+			require.Equal(t, expected, keys)
+		}
+
+		metabasetest.DeleteAll{}.Check(ctx, t, db)
+	})
+}
@@ -0,0 +1,155 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
+)
+
+// ListOrphanedSegmentStreams contains arguments necessary for listing streams,
+// which have segments, but no object.
+type ListOrphanedSegmentStreams struct {
+	// CursorStreamID is the stream ID after which the examination starts.
+	CursorStreamID uuid.UUID
+	// Limit is the number of distinct segment streams examined per adapter.
+	Limit int
+
+	AsOfSystemTime     time.Time
+	AsOfSystemInterval time.Duration
+}
+
+// ListOrphanedSegmentStreamsResult is the result of ListOrphanedSegmentStreams.
+type ListOrphanedSegmentStreamsResult struct {
+	// StreamIDs are the orphaned streams ordered by stream ID.
+	StreamIDs []uuid.UUID
+	// NextCursor should be used as the cursor of the next request.
+	// It's zero when all the streams have been examined.
+	NextCursor uuid.UUID
+}
+
+// segmentStream is a distinct stream in the segments table.
+type segmentStream struct {
+	StreamID uuid.UUID
+	Orphaned bool
+}
+
+// ListOrphanedSegmentStreams lists stream IDs present in segments, but absent from objects.
+//
+// Every call examines at most Limit distinct streams following the cursor, hence the result
+// may contain fewer stream IDs than Limit, even when there are more orphaned streams.
+// The streams are walked using the primary key of segments, however checking whether
+// an object exists for them requires scanning the objects table, so the calls should be
+// spaced out and preferably use AsOfSystemTime.
+func (db *DB) ListOrphanedSegmentStreams(ctx context.Context, opts ListOrphanedSegmentStreams) (result ListOrphanedSegmentStreamsResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if opts.Limit <= 0 {
+		return ListOrphanedSegmentStreamsResult{}, ErrInvalidRequest.New("invalid limit: %d", opts.Limit)
+	}
+	ListVerifyLimit.Ensure(&opts.Limit)
+
+	var orphaned []uuid.UUID
+	for _, adapter := range db.adapters {
+		streams, err := adapter.ListOrphanedSegmentStreams(ctx, opts)
+		if err != nil {
+			return ListOrphanedSegmentStreamsResult{}, Error.Wrap(err)
+		}
+
+		// adapters are examined independently, so the next cursor must not
+		// skip any streams that haven't been examined in another adapter.
+		if len(streams) == opts.Limit {
+			last := streams[len(streams)-1].StreamID
+			if result.NextCursor.IsZero() || last.Less(result.NextCursor) {
+				result.NextCursor = last
+			}
+		}
+
+		for _, stream := range streams {
+			if stream.Orphaned {
+				orphaned = append(orphaned, stream.StreamID)
+			}
+		}
+	}
+
+	for _, streamID := range orphaned {
+		if !result.NextCursor.IsZero() && result.NextCursor.Less(streamID) {
+			continue
+		}
+		result.StreamIDs = append(result.StreamIDs, streamID)
+	}
+	uuid.SortAscending(result.StreamIDs)
+
+	return result, nil
+}
+
+// ListOrphanedSegmentStreams lists distinct segment streams after the cursor and whether they have an object.
+func (p *PostgresAdapter) ListOrphanedSegmentStreams(ctx context.Context, opts ListOrphanedSegmentStreams) (streams []segmentStream, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = withRows(p.db.QueryContext(ctx, `
+		SELECT
+			candidates.stream_id,
+			NOT EXISTS (SELECT 1 FROM objects WHERE objects.stream_id = candidates.stream_id)
+		FROM (
+			SELECT DISTINCT stream_id
+			FROM segments
+			WHERE stream_id > $1
+			ORDER BY stream_id ASC
+			LIMIT $2
+		) AS candidates
+		`+LimitedAsOfSystemTime(p.impl, time.Now(), opts.AsOfSystemTime, opts.AsOfSystemInterval)+`
+		ORDER BY candidates.stream_id ASC
+	`, opts.CursorStreamID, opts.Limit))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			var stream segmentStream
+			if err := rows.Scan(&stream.StreamID, &stream.Orphaned); err != nil {
+				return Error.Wrap(err)
+			}
+			streams = append(streams, stream)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to list orphaned segment streams: %w", err)
+	}
+	return streams, nil
+}
+
+// ListOrphanedSegmentStreams lists distinct segment streams after the cursor and whether they have an object.
+func (s *SpannerAdapter) ListOrphanedSegmentStreams(ctx context.Context, opts ListOrphanedSegmentStreams) (streams []segmentStream, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	streams, err = spannerutil.CollectRows(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				candidates.stream_id,
+				NOT EXISTS (SELECT 1 FROM objects WHERE objects.stream_id = candidates.stream_id)
+			FROM (
+				SELECT DISTINCT stream_id
+				FROM segments
+				WHERE stream_id > @stream_id
+				ORDER BY stream_id ASC
+				LIMIT @limit
+			) AS candidates
+			ORDER BY candidates.stream_id ASC
+		`,
+		Params: map[string]interface{}{
+			"stream_id": opts.CursorStreamID,
+			"limit":     int64(opts.Limit),
+		},
+	}), func(row *spanner.Row, stream *segmentStream) error {
+		return Error.Wrap(row.Columns(&stream.StreamID, &stream.Orphaned))
+	})
+	if err != nil {
+		return nil, Error.New("unable to list orphaned segment streams: %w", err)
+	}
+	return streams, nil
+}
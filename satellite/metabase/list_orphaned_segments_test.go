@@ -0,0 +1,76 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/uuid"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestListOrphanedSegmentStreams(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		t.Run("invalid limit", func(t *testing.T) {
+			_, err := db.ListOrphanedSegmentStreams(ctx, metabase.ListOrphanedSegmentStreams{
+				Limit: -1,
+			})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+		})
+
+		t.Run("no segments", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			result, err := db.ListOrphanedSegmentStreams(ctx, metabase.ListOrphanedSegmentStreams{
+				Limit: 10,
+			})
+			require.NoError(t, err)
+			require.Empty(t, result.StreamIDs)
+			require.True(t, result.NextCursor.IsZero())
+		})
+
+		t.Run("orphaned", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			// segments of pending and committed objects aren't orphaned.
+			metabasetest.CreateObject(ctx, t, db, metabasetest.RandObjectStream(), 2)
+			metabasetest.CreatePendingObject(ctx, t, db, metabasetest.RandObjectStream(), 2)
+
+			var expected []uuid.UUID
+			var segments []metabase.RawSegment
+			for i := 0; i < 5; i++ {
+				obj := metabasetest.RandObjectStream()
+				expected = append(expected, obj.StreamID)
+				segments = append(segments,
+					metabasetest.DefaultRawSegment(obj, metabase.SegmentPosition{Index: 0}),
+					metabasetest.DefaultRawSegment(obj, metabase.SegmentPosition{Index: 1}),
+				)
+			}
+			require.NoError(t, db.TestingBatchInsertSegments(ctx, segments))
+			uuid.SortAscending(expected)
+
+			for _, limit := range []int{1, 2, 3, 10} {
+				var orphaned []uuid.UUID
+				var cursor uuid.UUID
+				for {
+					result, err := db.ListOrphanedSegmentStreams(ctx, metabase.ListOrphanedSegmentStreams{
+						CursorStreamID: cursor,
+						Limit:          limit,
+					})
+					require.NoError(t, err)
+					orphaned = append(orphaned, result.StreamIDs...)
+					if result.NextCursor.IsZero() {
+						break
+					}
+					cursor = result.NextCursor
+				}
+				require.Equal(t, expected, orphaned, "limit %d", limit)
+			}
+		})
+	})
+}
@@ -65,7 +65,7 @@ func (p *PostgresAdapter) ListSegments(ctx context.Context, opts ListSegments, a
 				position, created_at, expires_at, root_piece_id,
 				encrypted_key_nonce, encrypted_key, encrypted_size,
 				plain_offset, plain_size, encrypted_etag, redundancy,
-				inline_data, remote_alias_pieces, placement
+				inline_data, remote_alias_pieces, placement, is_manifest
 			FROM segments
 			WHERE
 				stream_id = $1 AND
@@ -79,7 +79,7 @@ func (p *PostgresAdapter) ListSegments(ctx context.Context, opts ListSegments, a
 				position, created_at, expires_at, root_piece_id,
 				encrypted_key_nonce, encrypted_key, encrypted_size,
 				plain_offset, plain_size, encrypted_etag, redundancy,
-				inline_data, remote_alias_pieces, placement
+				inline_data, remote_alias_pieces, placement, is_manifest
 			FROM segments
 			WHERE
 				stream_id = $1 AND
@@ -102,7 +102,7 @@ func (p *PostgresAdapter) ListSegments(ctx context.Context, opts ListSegments, a
 				&segment.EncryptedETag,
 				redundancyScheme{&segment.Redundancy},
 				&segment.InlineData, &aliasPieces,
-				&segment.Placement,
+				&segment.Placement, &segment.IsManifest,
 			)
 			if err != nil {
 				return Error.New("failed to scan segments: %w", err)
@@ -143,7 +143,7 @@ func (s *SpannerAdapter) ListSegments(ctx context.Context, opts ListSegments, al
 					position, created_at, expires_at, root_piece_id,
 					encrypted_key_nonce, encrypted_key, encrypted_size,
 					plain_offset, plain_size, encrypted_etag, redundancy,
-					inline_data, remote_alias_pieces, placement
+					inline_data, remote_alias_pieces, placement, is_manifest
 				FROM segments
 				WHERE
 					stream_id = @stream_id AND
@@ -164,7 +164,7 @@ func (s *SpannerAdapter) ListSegments(ctx context.Context, opts ListSegments, al
 					position, created_at, expires_at, root_piece_id,
 					encrypted_key_nonce, encrypted_key, encrypted_size,
 					plain_offset, plain_size, encrypted_etag, redundancy,
-					inline_data, remote_alias_pieces, placement
+					inline_data, remote_alias_pieces, placement, is_manifest
 				FROM segments
 				WHERE
 					stream_id = @stream_id AND
@@ -196,7 +196,7 @@ func (s *SpannerAdapter) ListSegments(ctx context.Context, opts ListSegments, al
 				&segment.EncryptedETag,
 				redundancyScheme{&segment.Redundancy},
 				&segment.InlineData, &aliasPieces,
-				spannerutil.Int(&segment.Placement),
+				spannerutil.Int(&segment.Placement), &segment.IsManifest,
 			)
 			if err != nil {
 				return Error.New("failed to read segments: %w", err)
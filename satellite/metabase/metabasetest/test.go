@@ -230,6 +230,19 @@ func (step UpdateSegmentPieces) Check(ctx *testcontext.Context, t testing.TB, db
 	checkError(t, err, step.ErrClass, step.ErrText)
 }
 
+// UpdateSegmentRedundancy is for testing metabase.UpdateSegmentRedundancy.
+type UpdateSegmentRedundancy struct {
+	Opts     metabase.UpdateSegmentRedundancy
+	ErrClass *errs.Class
+	ErrText  string
+}
+
+// Check runs the test.
+func (step UpdateSegmentRedundancy) Check(ctx *testcontext.Context, t testing.TB, db *metabase.DB) {
+	err := db.UpdateSegmentRedundancy(ctx, step.Opts)
+	checkError(t, err, step.ErrClass, step.ErrText)
+}
+
 // GetObjectExactVersion is for testing metabase.GetObjectExactVersion.
 type GetObjectExactVersion struct {
 	Opts     metabase.GetObjectExactVersion
@@ -263,6 +276,91 @@ func (step GetObjectLastCommitted) Check(ctx *testcontext.Context, t testing.TB,
 	require.Zero(t, diff)
 }
 
+// ReopenObject is for testing metabase.ReopenObject.
+type ReopenObject struct {
+	Opts     metabase.ReopenObject
+	Result   metabase.Object
+	ErrClass *errs.Class
+	ErrText  string
+}
+
+// Check runs the test.
+func (step ReopenObject) Check(ctx *testcontext.Context, t testing.TB, db *metabase.DB) metabase.Object {
+	result, err := db.ReopenObject(ctx, step.Opts)
+	checkError(t, err, step.ErrClass, step.ErrText)
+	diff := cmp.Diff(step.Result, result, cmpopts.EquateApproxTime(5*time.Second))
+	require.Zero(t, diff)
+	return result
+}
+
+// SetObjectsRetention is for testing metabase.SetObjectsRetention.
+type SetObjectsRetention struct {
+	ProjectID  uuid.UUID
+	BucketName string
+	Locations  []metabase.VersionedLocation
+	Retention  metabase.Retention
+
+	Result   []metabase.SetObjectsRetentionResult
+	ErrClass *errs.Class
+	ErrText  string
+}
+
+// Check runs the test.
+func (step SetObjectsRetention) Check(ctx *testcontext.Context, t testing.TB, db *metabase.DB) {
+	result, err := db.SetObjectsRetention(ctx, step.ProjectID, step.BucketName, step.Locations, step.Retention)
+	checkError(t, err, step.ErrClass, step.ErrText)
+	diff := cmp.Diff(step.Result, result)
+	require.Zero(t, diff)
+}
+
+// ClearLegalHold is for testing metabase.ClearLegalHold.
+type ClearLegalHold struct {
+	Opts     metabase.ClearLegalHold
+	Result   metabase.Retention
+	ErrClass *errs.Class
+	ErrText  string
+}
+
+// Check runs the test.
+func (step ClearLegalHold) Check(ctx *testcontext.Context, t testing.TB, db *metabase.DB) {
+	result, err := db.ClearLegalHold(ctx, step.Opts)
+	checkError(t, err, step.ErrClass, step.ErrText)
+	diff := cmp.Diff(step.Result, result, cmpopts.EquateApproxTime(5*time.Second))
+	require.Zero(t, diff)
+}
+
+// GetObjectsLastCommitted is for testing metabase.GetObjectsLastCommitted.
+type GetObjectsLastCommitted struct {
+	Opts     metabase.GetObjectsLastCommitted
+	Result   map[metabase.ObjectKey]metabase.Object
+	ErrClass *errs.Class
+	ErrText  string
+}
+
+// Check runs the test.
+func (step GetObjectsLastCommitted) Check(ctx *testcontext.Context, t testing.TB, db *metabase.DB) {
+	result, err := db.GetObjectsLastCommitted(ctx, step.Opts)
+	checkError(t, err, step.ErrClass, step.ErrText)
+	diff := cmp.Diff(step.Result, result, cmpopts.EquateApproxTime(5*time.Second), cmpopts.EquateEmpty())
+	require.Zero(t, diff)
+}
+
+// GetObjectsExactVersion is for testing metabase.GetObjectsExactVersion.
+type GetObjectsExactVersion struct {
+	Opts     metabase.GetObjectsExactVersion
+	Result   metabase.GetObjectsExactVersionResult
+	ErrClass *errs.Class
+	ErrText  string
+}
+
+// Check runs the test.
+func (step GetObjectsExactVersion) Check(ctx *testcontext.Context, t testing.TB, db *metabase.DB) {
+	result, err := db.GetObjectsExactVersion(ctx, step.Opts)
+	checkError(t, err, step.ErrClass, step.ErrText)
+	diff := cmp.Diff(step.Result, result, cmpopts.EquateApproxTime(5*time.Second), cmpopts.EquateEmpty())
+	require.Zero(t, diff)
+}
+
 // GetSegmentByPosition is for testing metabase.GetSegmentByPosition.
 type GetSegmentByPosition struct {
 	Opts     metabase.GetSegmentByPosition
@@ -402,6 +500,23 @@ func (step GetStreamPieceCountByNodeID) Check(ctx *testcontext.Context, t testin
 	require.Zero(t, diff)
 }
 
+// VerifyObjectTotals is for testing metabase.VerifyObjectTotals.
+type VerifyObjectTotals struct {
+	Opts     metabase.VerifyObjectTotals
+	Result   metabase.ObjectTotals
+	ErrClass *errs.Class
+	ErrText  string
+}
+
+// Check runs the test.
+func (step VerifyObjectTotals) Check(ctx *testcontext.Context, t testing.TB, db *metabase.DB) {
+	result, err := db.VerifyObjectTotals(ctx, step.Opts)
+	checkError(t, err, step.ErrClass, step.ErrText)
+
+	diff := cmp.Diff(step.Result, result)
+	require.Zero(t, diff)
+}
+
 // IterateLoopSegments is for testing metabase.IterateLoopSegments.
 type IterateLoopSegments struct {
 	Opts     metabase.IterateLoopSegments
@@ -820,3 +935,20 @@ func (step CollectBucketTallies) Check(ctx *testcontext.Context, t testing.TB, d
 	diff := cmp.Diff(step.Result, result, DefaultTimeDiff(), cmpopts.EquateEmpty())
 	require.Zero(t, diff)
 }
+
+// CountObjectsPerBucket is for testing metabase.CountObjectsPerBucket.
+type CountObjectsPerBucket struct {
+	Opts     metabase.CountObjectsPerBucket
+	Result   map[string]metabase.BucketObjectCounts
+	ErrClass *errs.Class
+	ErrText  string
+}
+
+// Check runs the test.
+func (step CountObjectsPerBucket) Check(ctx *testcontext.Context, t testing.TB, db *metabase.DB) {
+	result, err := db.CountObjectsPerBucket(ctx, step.Opts)
+	checkError(t, err, step.ErrClass, step.ErrText)
+
+	diff := cmp.Diff(step.Result, result, cmpopts.EquateEmpty())
+	require.Zero(t, diff)
+}
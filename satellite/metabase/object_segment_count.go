@@ -0,0 +1,212 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
+)
+
+// ObjectSegmentCount contains the segment count stored on an object and
+// the number of its segments.
+type ObjectSegmentCount struct {
+	ObjectStream
+
+	StoredSegmentCount int32
+	ActualSegmentCount int32
+
+	// Mismatch is true when the stored segment count differs from the actual one.
+	Mismatch bool
+}
+
+// VerifyObjectSegmentCount compares the segment count stored on a committed object
+// with the number of its segments. Nothing is modified in the database.
+func (db *DB) VerifyObjectSegmentCount(ctx context.Context, obj ObjectStream) (count ObjectSegmentCount, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := obj.Verify(); err != nil {
+		return ObjectSegmentCount{}, err
+	}
+
+	totals, err := db.ChooseAdapter(obj.ProjectID).VerifyObjectTotals(ctx, VerifyObjectTotals{ObjectStream: obj})
+	if err != nil {
+		return ObjectSegmentCount{}, err
+	}
+
+	count = ObjectSegmentCount{
+		ObjectStream:       obj,
+		StoredSegmentCount: totals.StoredSegmentCount,
+		ActualSegmentCount: totals.ComputedSegmentCount,
+		Mismatch:           totals.StoredSegmentCount != totals.ComputedSegmentCount,
+	}
+	if count.Mismatch {
+		mon.Meter("object_segment_count_mismatch").Mark(1)
+	}
+
+	return count, nil
+}
+
+// ListObjectSegmentCountMismatches contains arguments necessary for scanning a project
+// for objects with a mismatching segment count.
+type ListObjectSegmentCountMismatches struct {
+	ProjectID uuid.UUID
+	// Cursor is the object version after which the examination starts.
+	Cursor ObjectSegmentCountCursor
+	// Limit is the number of committed objects examined.
+	Limit int
+
+	AsOfSystemTime     time.Time
+	AsOfSystemInterval time.Duration
+}
+
+// ObjectSegmentCountCursor is a position within a project.
+type ObjectSegmentCountCursor struct {
+	BucketName string
+	ObjectKey  ObjectKey
+	Version    Version
+}
+
+// ListObjectSegmentCountMismatchesResult is the result of ListObjectSegmentCountMismatches.
+type ListObjectSegmentCountMismatchesResult struct {
+	// Objects are the examined objects with a mismatching segment count.
+	Objects []ObjectSegmentCount
+	// NextCursor should be used as the cursor of the next request, when More is set.
+	NextCursor ObjectSegmentCountCursor
+	More       bool
+}
+
+// Verify verifies list object segment count mismatches request fields.
+func (opts *ListObjectSegmentCountMismatches) Verify() error {
+	switch {
+	case opts.ProjectID.IsZero():
+		return ErrInvalidRequest.New("ProjectID missing")
+	case opts.Limit <= 0:
+		return ErrInvalidRequest.New("invalid limit: %d", opts.Limit)
+	}
+	return nil
+}
+
+// ListObjectSegmentCountMismatches examines up to Limit committed objects of a project following
+// the cursor and returns the ones whose stored segment count differs from the number of segments.
+//
+// The result may contain fewer objects than Limit, even when there are more mismatches,
+// hence the scan should continue while More is set. Counting the segments requires
+// a lookup per object, so the calls should be spaced out and preferably use AsOfSystemTime.
+func (db *DB) ListObjectSegmentCountMismatches(ctx context.Context, opts ListObjectSegmentCountMismatches) (result ListObjectSegmentCountMismatchesResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return ListObjectSegmentCountMismatchesResult{}, err
+	}
+	ListVerifyLimit.Ensure(&opts.Limit)
+
+	counts, err := db.ChooseAdapter(opts.ProjectID).ListObjectSegmentCounts(ctx, opts)
+	if err != nil {
+		return ListObjectSegmentCountMismatchesResult{}, Error.Wrap(err)
+	}
+
+	if len(counts) == opts.Limit {
+		last := counts[len(counts)-1]
+		result.NextCursor = ObjectSegmentCountCursor{
+			BucketName: last.BucketName,
+			ObjectKey:  last.ObjectKey,
+			Version:    last.Version,
+		}
+		result.More = true
+	}
+
+	for _, count := range counts {
+		count.Mismatch = count.StoredSegmentCount != count.ActualSegmentCount
+		if count.Mismatch {
+			result.Objects = append(result.Objects, count)
+		}
+	}
+	mon.Meter("object_segment_count_mismatch").Mark(len(result.Objects))
+
+	return result, nil
+}
+
+// ListObjectSegmentCounts lists the stored and actual segment counts of committed objects after the cursor.
+func (p *PostgresAdapter) ListObjectSegmentCounts(ctx context.Context, opts ListObjectSegmentCountMismatches) (counts []ObjectSegmentCount, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = withRows(p.db.QueryContext(ctx, `
+		SELECT
+			bucket_name, object_key, version, stream_id,
+			segment_count,
+			(SELECT COUNT(*) FROM segments WHERE segments.stream_id = objects.stream_id)
+		FROM objects
+		`+LimitedAsOfSystemTime(p.impl, time.Now(), opts.AsOfSystemTime, opts.AsOfSystemInterval)+`
+		WHERE
+			project_id = $1 AND
+			(bucket_name, object_key, version) > ($2, $3, $4) AND
+			status IN `+statusesCommitted+`
+		ORDER BY project_id, bucket_name, object_key, version
+		LIMIT $5
+	`, opts.ProjectID, []byte(opts.Cursor.BucketName), opts.Cursor.ObjectKey, opts.Cursor.Version, opts.Limit))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			count := ObjectSegmentCount{ObjectStream: ObjectStream{ProjectID: opts.ProjectID}}
+			if err := rows.Scan(
+				&count.BucketName, &count.ObjectKey, &count.Version, &count.StreamID,
+				&count.StoredSegmentCount, &count.ActualSegmentCount,
+			); err != nil {
+				return Error.Wrap(err)
+			}
+			counts = append(counts, count)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to list object segment counts: %w", err)
+	}
+	return counts, nil
+}
+
+// ListObjectSegmentCounts lists the stored and actual segment counts of committed objects after the cursor.
+func (s *SpannerAdapter) ListObjectSegmentCounts(ctx context.Context, opts ListObjectSegmentCountMismatches) (counts []ObjectSegmentCount, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	counts, err = spannerutil.CollectRows(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				bucket_name, object_key, version, stream_id,
+				segment_count,
+				(SELECT COUNT(*) FROM segments WHERE segments.stream_id = objects.stream_id)
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				(
+					bucket_name > @bucket_name OR
+					(bucket_name = @bucket_name AND object_key > @object_key) OR
+					(bucket_name = @bucket_name AND object_key = @object_key AND version > @version)
+				) AND
+				status IN ` + statusesCommitted + `
+			ORDER BY project_id, bucket_name, object_key, version
+			LIMIT @limit
+		`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.Cursor.BucketName,
+			"object_key":  opts.Cursor.ObjectKey,
+			"version":     opts.Cursor.Version,
+			"limit":       int64(opts.Limit),
+		},
+	}), func(row *spanner.Row, count *ObjectSegmentCount) error {
+		count.ProjectID = opts.ProjectID
+		return Error.Wrap(row.Columns(
+			&count.BucketName, &count.ObjectKey, &count.Version, &count.StreamID,
+			spannerutil.Int(&count.StoredSegmentCount), spannerutil.Int(&count.ActualSegmentCount),
+		))
+	})
+	if err != nil {
+		return nil, Error.New("unable to list object segment counts: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,169 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestVerifyObjectSegmentCount(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		t.Run("invalid ObjectStream", func(t *testing.T) {
+			for _, test := range metabasetest.InvalidObjectStreams(obj) {
+				test := test
+				t.Run(test.Name, func(t *testing.T) {
+					_, err := db.VerifyObjectSegmentCount(ctx, test.ObjectStream)
+					require.True(t, test.ErrClass.Has(err), err)
+					require.ErrorContains(t, err, test.ErrText)
+				})
+			}
+		})
+
+		t.Run("object missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			_, err := db.VerifyObjectSegmentCount(ctx, obj)
+			require.True(t, metabase.ErrObjectNotFound.Has(err), err)
+
+			metabasetest.CreatePendingObject(ctx, t, db, obj, 1)
+
+			_, err = db.VerifyObjectSegmentCount(ctx, obj)
+			require.True(t, metabase.ErrObjectNotFound.Has(err), err)
+		})
+
+		t.Run("matching", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.CreateObject(ctx, t, db, obj, 2)
+
+			count, err := db.VerifyObjectSegmentCount(ctx, obj)
+			require.NoError(t, err)
+			require.Equal(t, metabase.ObjectSegmentCount{
+				ObjectStream:       obj,
+				StoredSegmentCount: 2,
+				ActualSegmentCount: 2,
+			}, count)
+		})
+
+		t.Run("mismatching", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{{
+				ObjectStream: obj,
+				CreatedAt:    time.Now(),
+				Status:       metabase.CommittedUnversioned,
+				SegmentCount: 3,
+				Encryption:   metabasetest.DefaultEncryption,
+			}}))
+			require.NoError(t, db.TestingBatchInsertSegments(ctx, []metabase.RawSegment{
+				metabasetest.DefaultRawSegment(obj, metabase.SegmentPosition{Index: 0}),
+			}))
+
+			count, err := db.VerifyObjectSegmentCount(ctx, obj)
+			require.NoError(t, err)
+			require.Equal(t, metabase.ObjectSegmentCount{
+				ObjectStream:       obj,
+				StoredSegmentCount: 3,
+				ActualSegmentCount: 1,
+				Mismatch:           true,
+			}, count)
+		})
+	})
+}
+
+func TestListObjectSegmentCountMismatches(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		projectID := testrand.UUID()
+
+		t.Run("invalid request", func(t *testing.T) {
+			_, err := db.ListObjectSegmentCountMismatches(ctx, metabase.ListObjectSegmentCountMismatches{
+				Limit: 10,
+			})
+			require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+			require.ErrorContains(t, err, "ProjectID missing")
+
+			_, err = db.ListObjectSegmentCountMismatches(ctx, metabase.ListObjectSegmentCountMismatches{
+				ProjectID: projectID,
+			})
+			require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+			require.ErrorContains(t, err, "invalid limit: 0")
+		})
+
+		t.Run("scan project", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			newStream := func(bucketName string, key string) metabase.ObjectStream {
+				obj := metabasetest.RandObjectStream()
+				obj.ProjectID = projectID
+				obj.BucketName = bucketName
+				obj.ObjectKey = metabase.ObjectKey(key)
+				return obj
+			}
+
+			// objects of other projects and pending objects aren't examined.
+			metabasetest.CreateObject(ctx, t, db, metabasetest.RandObjectStream(), 1)
+			metabasetest.CreatePendingObject(ctx, t, db, newStream("bucket-a", "pending"), 2)
+
+			var rawObjects []metabase.RawObject
+			var rawSegments []metabase.RawSegment
+			var expected []metabase.ObjectSegmentCount
+			for i, bucketName := range []string{"bucket-a", "bucket-b"} {
+				for k := 0; k < 3; k++ {
+					obj := newStream(bucketName, "key"+strconv.Itoa(k))
+					if k == 1 {
+						metabasetest.CreateObject(ctx, t, db, obj, 2)
+						continue
+					}
+
+					rawObjects = append(rawObjects, metabase.RawObject{
+						ObjectStream: obj,
+						CreatedAt:    time.Now(),
+						Status:       metabase.CommittedUnversioned,
+						SegmentCount: int32(i + 2),
+						Encryption:   metabasetest.DefaultEncryption,
+					})
+					rawSegments = append(rawSegments, metabasetest.DefaultRawSegment(obj, metabase.SegmentPosition{Index: 0}))
+					expected = append(expected, metabase.ObjectSegmentCount{
+						ObjectStream:       obj,
+						StoredSegmentCount: int32(i + 2),
+						ActualSegmentCount: 1,
+						Mismatch:           true,
+					})
+				}
+			}
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, rawObjects))
+			require.NoError(t, db.TestingBatchInsertSegments(ctx, rawSegments))
+
+			for _, limit := range []int{1, 2, 5, 10} {
+				var mismatches []metabase.ObjectSegmentCount
+				var cursor metabase.ObjectSegmentCountCursor
+				for {
+					result, err := db.ListObjectSegmentCountMismatches(ctx, metabase.ListObjectSegmentCountMismatches{
+						ProjectID: projectID,
+						Cursor:    cursor,
+						Limit:     limit,
+					})
+					require.NoError(t, err)
+					mismatches = append(mismatches, result.Objects...)
+					if !result.More {
+						break
+					}
+					cursor = result.NextCursor
+				}
+				require.Equal(t, expected, mismatches, "limit %d", limit)
+			}
+		})
+	})
+}
@@ -0,0 +1,214 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"unicode/utf8"
+
+	"cloud.google.com/go/spanner"
+)
+
+// SetObjectTags contains arguments necessary for setting the tags of an object version.
+type SetObjectTags struct {
+	ObjectLocation
+	Version Version
+
+	// Tags replace the existing tags of the object version.
+	// Empty tags remove the existing tags.
+	Tags map[string]string
+}
+
+// Verify verifies set object tags request fields.
+func (opts *SetObjectTags) Verify() error {
+	if err := opts.ObjectLocation.Verify(); err != nil {
+		return err
+	}
+	if opts.Version <= 0 {
+		return ErrInvalidRequest.New("Version invalid: %v", opts.Version)
+	}
+	return verifyObjectTags(opts.Tags)
+}
+
+// verifyObjectTags checks that tags satisfy the S3 limits.
+func verifyObjectTags(tags map[string]string) error {
+	if len(tags) > MaxObjectTags {
+		return ErrInvalidRequest.New("too many tags: %d, maximum allowed: %d", len(tags), MaxObjectTags)
+	}
+	for key, value := range tags {
+		switch {
+		case key == "":
+			return ErrInvalidRequest.New("tag key missing")
+		case !utf8.ValidString(key) || !utf8.ValidString(value):
+			return ErrInvalidRequest.New("tag must be valid UTF-8")
+		case utf8.RuneCountInString(key) > MaxObjectTagKeyLength:
+			return ErrInvalidRequest.New("tag key is too long: %d characters, maximum allowed: %d", utf8.RuneCountInString(key), MaxObjectTagKeyLength)
+		case utf8.RuneCountInString(value) > MaxObjectTagValueLength:
+			return ErrInvalidRequest.New("tag value is too long: %d characters, maximum allowed: %d", utf8.RuneCountInString(value), MaxObjectTagValueLength)
+		}
+	}
+	return nil
+}
+
+// SetObjectTags replaces the tags of a committed object version.
+//
+// Tags belong to a single object version, committing a new version doesn't
+// carry over the tags of the previous versions.
+func (db *DB) SetObjectTags(ctx context.Context, opts SetObjectTags) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return err
+	}
+
+	err = db.ChooseAdapter(opts.ProjectID).SetObjectTags(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	mon.Meter("object_tags_set").Mark(1)
+
+	return nil
+}
+
+// SetObjectTags replaces the tags of a committed object version.
+func (p *PostgresAdapter) SetObjectTags(ctx context.Context, opts SetObjectTags) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := p.db.ExecContext(ctx, `
+		UPDATE objects SET
+			object_tags = $5::JSONB
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			status IN `+statusesCommitted+`
+	`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, objectTags{&opts.Tags})
+	if err != nil {
+		return Error.New("unable to set object tags: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return Error.New("unable to set object tags: %w", err)
+	}
+	if affected == 0 {
+		return ErrObjectNotFound.Wrap(Error.New("object not found"))
+	}
+	return nil
+}
+
+// SetObjectTags replaces the tags of a committed object version.
+func (s *SpannerAdapter) SetObjectTags(ctx context.Context, opts SetObjectTags) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var affected int64
+	_, err = s.client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		affected, err = tx.Update(ctx, spanner.Statement{
+			SQL: `
+				UPDATE objects SET
+					object_tags = @object_tags
+				WHERE
+					(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+					status IN ` + statusesCommitted + `
+			`,
+			Params: map[string]interface{}{
+				"project_id":  opts.ProjectID,
+				"bucket_name": opts.BucketName,
+				"object_key":  opts.ObjectKey,
+				"version":     opts.Version,
+				"object_tags": objectTags{&opts.Tags},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return Error.New("unable to set object tags: %w", err)
+	}
+	if affected == 0 {
+		return ErrObjectNotFound.Wrap(Error.New("object not found"))
+	}
+	return nil
+}
+
+// GetObjectTags contains arguments necessary for fetching the tags of an object version.
+type GetObjectTags struct {
+	ObjectLocation
+	Version Version
+}
+
+// Verify verifies get object tags request fields.
+func (opts *GetObjectTags) Verify() error {
+	if err := opts.ObjectLocation.Verify(); err != nil {
+		return err
+	}
+	if opts.Version <= 0 {
+		return ErrInvalidRequest.New("Version invalid: %v", opts.Version)
+	}
+	return nil
+}
+
+// GetObjectTags returns the tags of a committed object version.
+// An object version without tags returns nil tags.
+func (db *DB) GetObjectTags(ctx context.Context, opts GetObjectTags) (tags map[string]string, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return nil, err
+	}
+
+	return db.ChooseAdapter(opts.ProjectID).GetObjectTags(ctx, opts)
+}
+
+// GetObjectTags returns the tags of a committed object version.
+func (p *PostgresAdapter) GetObjectTags(ctx context.Context, opts GetObjectTags) (tags map[string]string, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = p.db.QueryRowContext(ctx, `
+		SELECT object_tags
+		FROM objects
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			status IN `+statusesCommitted+`
+	`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version).Scan(objectTags{&tags})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrObjectNotFound.Wrap(Error.New("object not found"))
+		}
+		return nil, Error.New("unable to query object tags: %w", err)
+	}
+	return tags, nil
+}
+
+// GetObjectTags returns the tags of a committed object version.
+func (s *SpannerAdapter) GetObjectTags(ctx context.Context, opts GetObjectTags) (tags map[string]string, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	found := false
+	err = s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT object_tags
+			FROM objects
+			WHERE
+				(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+				status IN ` + statusesCommitted + `
+		`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.BucketName,
+			"object_key":  opts.ObjectKey,
+			"version":     opts.Version,
+		},
+	}).Do(func(row *spanner.Row) error {
+		found = true
+		return Error.Wrap(row.Columns(objectTags{&tags}))
+	})
+	if err != nil {
+		return nil, Error.New("unable to query object tags: %w", err)
+	}
+	if !found {
+		return nil, ErrObjectNotFound.Wrap(Error.New("object not found"))
+	}
+	return tags, nil
+}
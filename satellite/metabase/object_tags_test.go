@@ -0,0 +1,176 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestObjectTags(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		t.Run("invalid request", func(t *testing.T) {
+			tooMany := map[string]string{}
+			for i := 0; i <= metabase.MaxObjectTags; i++ {
+				tooMany["key"+strconv.Itoa(i)] = "value"
+			}
+
+			for _, test := range []struct {
+				tags    map[string]string
+				version metabase.Version
+				errText string
+			}{
+				{version: 0, errText: "Version invalid: 0"},
+				{tags: tooMany, version: obj.Version, errText: "too many tags: 11, maximum allowed: 10"},
+				{tags: map[string]string{"": "value"}, version: obj.Version, errText: "tag key missing"},
+				{tags: map[string]string{"key\xff": "value"}, version: obj.Version, errText: "tag must be valid UTF-8"},
+				{
+					tags:    map[string]string{strings.Repeat("é", metabase.MaxObjectTagKeyLength+1): "value"},
+					version: obj.Version,
+					errText: "tag key is too long: 129 characters, maximum allowed: 128",
+				},
+				{
+					tags:    map[string]string{"key": strings.Repeat("é", metabase.MaxObjectTagValueLength+1)},
+					version: obj.Version,
+					errText: "tag value is too long: 257 characters, maximum allowed: 256",
+				},
+			} {
+				err := db.SetObjectTags(ctx, metabase.SetObjectTags{
+					ObjectLocation: obj.Location(),
+					Version:        test.version,
+					Tags:           test.tags,
+				})
+				require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+				require.ErrorContains(t, err, test.errText)
+			}
+
+			_, err := db.GetObjectTags(ctx, metabase.GetObjectTags{
+				ObjectLocation: obj.Location(),
+			})
+			require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+			require.ErrorContains(t, err, "Version invalid: 0")
+		})
+
+		t.Run("object missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			err := db.SetObjectTags(ctx, metabase.SetObjectTags{
+				ObjectLocation: obj.Location(),
+				Version:        obj.Version,
+				Tags:           map[string]string{"key": "value"},
+			})
+			require.True(t, metabase.ErrObjectNotFound.Has(err), err)
+
+			// pending objects can't be tagged.
+			pending := metabasetest.CreatePendingObject(ctx, t, db, obj, 0)
+
+			err = db.SetObjectTags(ctx, metabase.SetObjectTags{
+				ObjectLocation: obj.Location(),
+				Version:        obj.Version,
+				Tags:           map[string]string{"key": "value"},
+			})
+			require.True(t, metabase.ErrObjectNotFound.Has(err), err)
+
+			_, err = db.GetObjectTags(ctx, metabase.GetObjectTags{
+				ObjectLocation: obj.Location(),
+				Version:        obj.Version,
+			})
+			require.True(t, metabase.ErrObjectNotFound.Has(err), err)
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{metabase.RawObject(pending)},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("set and get", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			first := metabasetest.CreateObjectVersioned(ctx, t, db, obj, 0)
+
+			tags, err := db.GetObjectTags(ctx, metabase.GetObjectTags{
+				ObjectLocation: first.Location(),
+				Version:        first.Version,
+			})
+			require.NoError(t, err)
+			require.Nil(t, tags)
+
+			firstTags := map[string]string{"project": "alpha", "owner": "ops"}
+			require.NoError(t, db.SetObjectTags(ctx, metabase.SetObjectTags{
+				ObjectLocation: first.Location(),
+				Version:        first.Version,
+				Tags:           firstTags,
+			}))
+
+			// tags are per version.
+			next := obj
+			next.Version = first.Version + 1
+			second := metabasetest.CreateObjectVersioned(ctx, t, db, next, 0)
+
+			tags, err = db.GetObjectTags(ctx, metabase.GetObjectTags{
+				ObjectLocation: second.Location(),
+				Version:        second.Version,
+			})
+			require.NoError(t, err)
+			require.Nil(t, tags)
+
+			tags, err = db.GetObjectTags(ctx, metabase.GetObjectTags{
+				ObjectLocation: first.Location(),
+				Version:        first.Version,
+			})
+			require.NoError(t, err)
+			require.Equal(t, firstTags, tags)
+
+			metabasetest.ListObjects{
+				Opts: metabase.ListObjects{
+					ProjectID:   obj.ProjectID,
+					BucketName:  obj.BucketName,
+					Recursive:   true,
+					AllVersions: true,
+					IncludeTags: true,
+				},
+				Result: metabase.ListObjectsResult{
+					Objects: []metabase.ObjectEntry{
+						{
+							ObjectKey:  second.ObjectKey,
+							Version:    second.Version,
+							StreamID:   second.StreamID,
+							Status:     metabase.CommittedVersioned,
+							Encryption: metabasetest.DefaultEncryption,
+						},
+						{
+							ObjectKey:  first.ObjectKey,
+							Version:    first.Version,
+							StreamID:   first.StreamID,
+							Status:     metabase.CommittedVersioned,
+							Encryption: metabasetest.DefaultEncryption,
+							Tags:       firstTags,
+						},
+					},
+				},
+			}.Check(ctx, t, db)
+
+			// empty tags remove the tags.
+			require.NoError(t, db.SetObjectTags(ctx, metabase.SetObjectTags{
+				ObjectLocation: first.Location(),
+				Version:        first.Version,
+			}))
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{
+					metabase.RawObject(first),
+					metabase.RawObject(second),
+				},
+			}.Check(ctx, t, db)
+		})
+	})
+}
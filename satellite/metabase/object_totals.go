@@ -0,0 +1,144 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/storj/shared/dbutil/spannerutil"
+)
+
+// VerifyObjectTotals contains arguments necessary for verifying object totals.
+type VerifyObjectTotals struct {
+	ObjectStream
+}
+
+// Verify verifies verify object totals request fields.
+func (opts *VerifyObjectTotals) Verify() error {
+	return opts.ObjectStream.Verify()
+}
+
+// ObjectTotals contains the totals stored on an object and the totals
+// computed from its segments.
+type ObjectTotals struct {
+	StoredSegmentCount       int32
+	StoredTotalPlainSize     int64
+	StoredTotalEncryptedSize int64
+
+	ComputedSegmentCount       int32
+	ComputedTotalPlainSize     int64
+	ComputedTotalEncryptedSize int64
+
+	// Mismatch is true when any of the stored totals differs from the computed one.
+	Mismatch bool
+}
+
+// VerifyObjectTotals recomputes the segment count, plain size and encrypted size of a committed
+// object from its segments and compares them with the values stored on the object.
+// Nothing is modified in the database.
+func (db *DB) VerifyObjectTotals(ctx context.Context, opts VerifyObjectTotals) (totals ObjectTotals, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return ObjectTotals{}, err
+	}
+
+	totals, err = db.ChooseAdapter(opts.ProjectID).VerifyObjectTotals(ctx, opts)
+	if err != nil {
+		return ObjectTotals{}, err
+	}
+
+	totals.Mismatch = totals.StoredSegmentCount != totals.ComputedSegmentCount ||
+		totals.StoredTotalPlainSize != totals.ComputedTotalPlainSize ||
+		totals.StoredTotalEncryptedSize != totals.ComputedTotalEncryptedSize
+	if totals.Mismatch {
+		mon.Meter("object_totals_mismatch").Mark(1)
+	}
+
+	return totals, nil
+}
+
+// VerifyObjectTotals returns the stored and computed totals of a committed object.
+func (p *PostgresAdapter) VerifyObjectTotals(ctx context.Context, opts VerifyObjectTotals) (totals ObjectTotals, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = p.db.QueryRowContext(ctx, `
+		SELECT
+			objects.segment_count, objects.total_plain_size, objects.total_encrypted_size,
+			computed.segment_count, computed.total_plain_size, computed.total_encrypted_size
+		FROM objects, (
+			SELECT
+				COUNT(*)                          AS segment_count,
+				COALESCE(SUM(plain_size), 0)     AS total_plain_size,
+				COALESCE(SUM(encrypted_size), 0) AS total_encrypted_size
+			FROM segments
+			WHERE stream_id = $5
+		) AS computed
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			stream_id = $5 AND
+			status IN `+statusesCommitted+`
+	`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, opts.StreamID).
+		Scan(
+			&totals.StoredSegmentCount, &totals.StoredTotalPlainSize, &totals.StoredTotalEncryptedSize,
+			&totals.ComputedSegmentCount, &totals.ComputedTotalPlainSize, &totals.ComputedTotalEncryptedSize,
+		)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ObjectTotals{}, ErrObjectNotFound.Wrap(Error.New("object not found"))
+		}
+		return ObjectTotals{}, Error.New("unable to query object totals: %w", err)
+	}
+	return totals, nil
+}
+
+// VerifyObjectTotals returns the stored and computed totals of a committed object.
+func (s *SpannerAdapter) VerifyObjectTotals(ctx context.Context, opts VerifyObjectTotals) (totals ObjectTotals, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	found := false
+	err = s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				objects.segment_count, objects.total_plain_size, objects.total_encrypted_size,
+				computed.segment_count, computed.total_plain_size, computed.total_encrypted_size
+			FROM objects, (
+				SELECT
+					COUNT(*)                          AS segment_count,
+					COALESCE(SUM(plain_size), 0)     AS total_plain_size,
+					COALESCE(SUM(encrypted_size), 0) AS total_encrypted_size
+				FROM segments
+				WHERE stream_id = @stream_id
+			) AS computed
+			WHERE
+				(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+				stream_id = @stream_id AND
+				status IN ` + statusesCommitted + `
+		`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.BucketName,
+			"object_key":  opts.ObjectKey,
+			"version":     opts.Version,
+			"stream_id":   opts.StreamID,
+		},
+	}).Do(func(row *spanner.Row) error {
+		found = true
+		return Error.Wrap(row.Columns(
+			spannerutil.Int(&totals.StoredSegmentCount), &totals.StoredTotalPlainSize, &totals.StoredTotalEncryptedSize,
+			spannerutil.Int(&totals.ComputedSegmentCount), &totals.ComputedTotalPlainSize, &totals.ComputedTotalEncryptedSize,
+		))
+	})
+	if err != nil {
+		return ObjectTotals{}, Error.New("unable to query object totals: %w", err)
+	}
+	if !found {
+		return ObjectTotals{}, ErrObjectNotFound.Wrap(Error.New("object not found"))
+	}
+	return totals, nil
+}
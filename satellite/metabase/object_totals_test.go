@@ -0,0 +1,115 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestVerifyObjectTotals(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		t.Run("invalid ObjectStream", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			for _, test := range metabasetest.InvalidObjectStreams(obj) {
+				test := test
+				t.Run(test.Name, func(t *testing.T) {
+					metabasetest.VerifyObjectTotals{
+						Opts: metabase.VerifyObjectTotals{
+							ObjectStream: test.ObjectStream,
+						},
+						ErrClass: test.ErrClass,
+						ErrText:  test.ErrText,
+					}.Check(ctx, t, db)
+				})
+			}
+		})
+
+		t.Run("object missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.VerifyObjectTotals{
+				Opts: metabase.VerifyObjectTotals{
+					ObjectStream: obj,
+				},
+				ErrClass: &metabase.ErrObjectNotFound,
+				ErrText:  "metabase: object not found",
+			}.Check(ctx, t, db)
+
+			metabasetest.CreatePendingObject(ctx, t, db, obj, 1)
+
+			metabasetest.VerifyObjectTotals{
+				Opts: metabase.VerifyObjectTotals{
+					ObjectStream: obj,
+				},
+				ErrClass: &metabase.ErrObjectNotFound,
+				ErrText:  "metabase: object not found",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("matching totals", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			object, _ := metabasetest.CreateTestObject{}.Run(ctx, t, db, obj, 2)
+
+			metabasetest.VerifyObjectTotals{
+				Opts: metabase.VerifyObjectTotals{
+					ObjectStream: obj,
+				},
+				Result: metabase.ObjectTotals{
+					StoredSegmentCount:       2,
+					StoredTotalPlainSize:     object.TotalPlainSize,
+					StoredTotalEncryptedSize: object.TotalEncryptedSize,
+
+					ComputedSegmentCount:       2,
+					ComputedTotalPlainSize:     object.TotalPlainSize,
+					ComputedTotalEncryptedSize: object.TotalEncryptedSize,
+				},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("mismatching totals", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			rawObject := metabase.RawObject{
+				ObjectStream: obj,
+				CreatedAt:    time.Now(),
+				Status:       metabase.CommittedUnversioned,
+
+				SegmentCount:       1,
+				TotalPlainSize:     100,
+				TotalEncryptedSize: 200,
+
+				Encryption: metabasetest.DefaultEncryption,
+			}
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{rawObject}))
+
+			metabasetest.VerifyObjectTotals{
+				Opts: metabase.VerifyObjectTotals{
+					ObjectStream: obj,
+				},
+				Result: metabase.ObjectTotals{
+					StoredSegmentCount:       1,
+					StoredTotalPlainSize:     100,
+					StoredTotalEncryptedSize: 200,
+
+					Mismatch: true,
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{rawObject},
+			}.Check(ctx, t, db)
+		})
+	})
+}
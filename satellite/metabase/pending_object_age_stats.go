@@ -0,0 +1,94 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/spannerutil"
+)
+
+// PendingObjectAgeStats contains the number of pending objects of a project
+// bucketed by their age.
+type PendingObjectAgeStats struct {
+	// LessThanHour is the number of pending objects created within the last hour.
+	LessThanHour int64
+	// LessThanDay is the number of pending objects created between one hour and a day ago.
+	LessThanDay int64
+	// MoreThanDay is the number of pending objects created more than a day ago.
+	MoreThanDay int64
+}
+
+// Total returns the total number of pending objects.
+func (stats PendingObjectAgeStats) Total() int64 {
+	return stats.LessThanHour + stats.LessThanDay + stats.MoreThanDay
+}
+
+// PendingObjectAgeStats returns the distribution of the pending object ages in
+// the project, based on their creation time. It's meant for reporting, e.g. to
+// spot stuck multipart uploads and to decide the zombie deletion period of the
+// project. The zombie deletion deadline of the objects isn't taken into account.
+func (db *DB) PendingObjectAgeStats(ctx context.Context, projectID uuid.UUID) (stats PendingObjectAgeStats, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if projectID.IsZero() {
+		return PendingObjectAgeStats{}, ErrInvalidRequest.New("ProjectID missing")
+	}
+
+	return db.ChooseAdapter(projectID).PendingObjectAgeStats(ctx, projectID, db.nowFn())
+}
+
+// PendingObjectAgeStats returns the distribution of the pending object ages in the project.
+func (p *PostgresAdapter) PendingObjectAgeStats(ctx context.Context, projectID uuid.UUID, now time.Time) (stats PendingObjectAgeStats, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = p.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN created_at > $2 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN created_at <= $2 AND created_at > $3 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN created_at <= $3 THEN 1 ELSE 0 END), 0)
+		FROM objects
+		WHERE
+			project_id = $1 AND
+			status = `+statusPending+`
+	`, projectID, now.Add(-time.Hour), now.Add(-24*time.Hour)).
+		Scan(&stats.LessThanHour, &stats.LessThanDay, &stats.MoreThanDay)
+	if err != nil {
+		return PendingObjectAgeStats{}, Error.New("unable to query pending object ages: %w", err)
+	}
+	return stats, nil
+}
+
+// PendingObjectAgeStats returns the distribution of the pending object ages in the project.
+func (s *SpannerAdapter) PendingObjectAgeStats(ctx context.Context, projectID uuid.UUID, now time.Time) (stats PendingObjectAgeStats, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	stats, err = spannerutil.CollectRow(s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				COALESCE(SUM(CASE WHEN created_at > @hour_ago THEN 1 ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN created_at <= @hour_ago AND created_at > @day_ago THEN 1 ELSE 0 END), 0),
+				COALESCE(SUM(CASE WHEN created_at <= @day_ago THEN 1 ELSE 0 END), 0)
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				status = ` + statusPending + `
+		`,
+		Params: map[string]interface{}{
+			"project_id": projectID,
+			"hour_ago":   now.Add(-time.Hour),
+			"day_ago":    now.Add(-24 * time.Hour),
+		},
+	}), func(row *spanner.Row, stats *PendingObjectAgeStats) error {
+		return Error.Wrap(row.Columns(&stats.LessThanHour, &stats.LessThanDay, &stats.MoreThanDay))
+	})
+	if err != nil {
+		return PendingObjectAgeStats{}, Error.New("unable to query pending object ages: %w", err)
+	}
+	return stats, nil
+}
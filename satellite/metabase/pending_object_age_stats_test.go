@@ -0,0 +1,71 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/common/uuid"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestPendingObjectAgeStats(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		now := time.Now()
+
+		t.Run("invalid request", func(t *testing.T) {
+			_, err := db.PendingObjectAgeStats(ctx, uuid.UUID{})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "ProjectID missing")
+		})
+
+		t.Run("empty project", func(t *testing.T) {
+			stats, err := db.PendingObjectAgeStats(ctx, testrand.UUID())
+			require.NoError(t, err)
+			require.Zero(t, stats)
+		})
+
+		t.Run("ages", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			projectID := testrand.UUID()
+			newObject := func(projectID uuid.UUID, status metabase.ObjectStatus, createdAt time.Time) metabase.RawObject {
+				obj := metabasetest.RandObjectStream()
+				obj.ProjectID = projectID
+				return metabase.RawObject{
+					ObjectStream: obj,
+					CreatedAt:    createdAt,
+					Status:       status,
+					Encryption:   metabasetest.DefaultEncryption,
+				}
+			}
+
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{
+				newObject(projectID, metabase.Pending, now.Add(-time.Minute)),
+				newObject(projectID, metabase.Pending, now.Add(-2*time.Hour)),
+				newObject(projectID, metabase.Pending, now.Add(-3*time.Hour)),
+				newObject(projectID, metabase.Pending, now.Add(-48*time.Hour)),
+				// committed objects aren't counted.
+				newObject(projectID, metabase.CommittedUnversioned, now.Add(-48*time.Hour)),
+				// different project.
+				newObject(testrand.UUID(), metabase.Pending, now.Add(-time.Minute)),
+			}))
+
+			stats, err := db.PendingObjectAgeStats(ctx, projectID)
+			require.NoError(t, err)
+			require.Equal(t, metabase.PendingObjectAgeStats{
+				LessThanHour: 1,
+				LessThanDay:  2,
+				MoreThanDay:  1,
+			}, stats)
+			require.EqualValues(t, 4, stats.Total())
+		})
+	})
+}
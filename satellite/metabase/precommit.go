@@ -9,19 +9,25 @@ import (
 	"errors"
 
 	"cloud.google.com/go/spanner"
+	"github.com/spacemonkeygo/monkit/v3"
 	"go.uber.org/zap"
 	"google.golang.org/api/iterator"
 
+	"storj.io/common/storj"
 	"storj.io/common/uuid"
 	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
 )
 
 type precommitTransactionAdapter interface {
 	precommitQueryHighest(ctx context.Context, loc ObjectLocation) (highest Version, err error)
 	precommitQueryHighestAndUnversioned(ctx context.Context, loc ObjectLocation) (highest Version, unversionedExists bool, err error)
+	precommitQueryLastCommittedExists(ctx context.Context, loc ObjectLocation) (exists bool, err error)
 	precommitDeleteUnversioned(ctx context.Context, loc ObjectLocation) (result PrecommitConstraintResult, err error)
 	precommitDeleteUnversionedWithSQLCheck(ctx context.Context, loc ObjectLocation) (result PrecommitConstraintResult, err error)
 	precommitDeleteUnversionedWithVersionCheck(ctx context.Context, loc ObjectLocation) (result PrecommitConstraintResult, err error)
+	precommitSoftDeleteUnversioned(ctx context.Context, loc ObjectLocation) (result PrecommitConstraintResult, err error)
+	precommitQueryUnversionedSegments(ctx context.Context, loc ObjectLocation) (segments []orphanedAliasSegment, err error)
 }
 
 // PrecommitConstraint is arguments to ensure that a single unversioned object or delete marker exists in the
@@ -32,9 +38,41 @@ type PrecommitConstraint struct {
 	Versioned      bool
 	DisallowDelete bool
 
+	// RequireExisting requires that a committed object, which is not a delete marker,
+	// already exists at the location.
+	RequireExisting bool
+
+	// SoftDelete converts the unversioned object into a versioned delete marker,
+	// which keeps its segments, instead of deleting it. An unversioned delete marker
+	// is still deleted. The segments stay until the delete marker is deleted explicitly.
+	SoftDelete bool
+
+	// CollectOrphans collects the remote segments of the deleted unversioned object,
+	// so that their pieces can be deleted without waiting for garbage collection.
+	CollectOrphans bool
+
 	PrecommitDeleteMode int
 }
 
+// OrphanedSegment is a remote segment, which was deleted together with its object,
+// and whose pieces are still stored on the nodes.
+type OrphanedSegment struct {
+	StreamID uuid.UUID
+	Position SegmentPosition
+
+	RootPieceID storj.PieceID
+	Pieces      Pieces
+}
+
+// orphanedAliasSegment is an OrphanedSegment before converting the node aliases.
+type orphanedAliasSegment struct {
+	StreamID uuid.UUID
+	Position SegmentPosition
+
+	RootPieceID storj.PieceID
+	AliasPieces AliasPieces
+}
+
 // PrecommitConstraintResult returns the result of enforcing precommit constraint.
 type PrecommitConstraintResult struct {
 	Deleted []Object
@@ -43,6 +81,12 @@ type PrecommitConstraintResult struct {
 	DeletedObjectCount int
 	// DeletedSegmentCount returns how many segments were deleted.
 	DeletedSegmentCount int
+	// SoftDeletedObjectCount returns how many objects were converted into delete markers.
+	SoftDeletedObjectCount int
+
+	// Orphaned contains the remote segments of the deleted objects.
+	// It's populated only when CollectOrphans is set.
+	Orphaned []OrphanedSegment
 
 	// HighestVersion returns tha highest version that was present in the table.
 	// It returns 0 if there was none.
@@ -63,6 +107,18 @@ var PrecommitDeleteModes = []int{
 func (r *PrecommitConstraintResult) submitMetrics() {
 	mon.Meter("object_delete").Mark(r.DeletedObjectCount)
 	mon.Meter("segment_delete").Mark(r.DeletedSegmentCount)
+	mon.Meter("object_soft_delete").Mark(r.SoftDeletedObjectCount)
+}
+
+// Conditions reported by the commit_precondition_failed meter.
+const (
+	preconditionRequireExisting = "require_existing"
+	preconditionDisallowDelete  = "disallow_delete"
+)
+
+// submitPreconditionFailed records that a commit was rejected due to condition.
+func submitPreconditionFailed(condition string) {
+	mon.Meter("commit_precondition_failed", monkit.NewSeriesTag("condition", condition)).Mark(1)
 }
 
 // PrecommitConstraint ensures that only a single uncommitted object exists at the specified location.
@@ -73,6 +129,17 @@ func (db *DB) PrecommitConstraint(ctx context.Context, opts PrecommitConstraint,
 		return result, Error.Wrap(err)
 	}
 
+	if opts.RequireExisting {
+		exists, err := adapter.precommitQueryLastCommittedExists(ctx, opts.Location)
+		if err != nil {
+			return PrecommitConstraintResult{}, Error.Wrap(err)
+		}
+		if !exists {
+			submitPreconditionFailed(preconditionRequireExisting)
+			return PrecommitConstraintResult{}, ErrFailedPrecondition.New("object does not exist")
+		}
+	}
+
 	if opts.Versioned {
 		highest, err := adapter.precommitQueryHighest(ctx, opts.Location)
 		if err != nil {
@@ -89,21 +156,78 @@ func (db *DB) PrecommitConstraint(ctx context.Context, opts PrecommitConstraint,
 		}
 		result.HighestVersion = highest
 		if unversionedExists {
+			submitPreconditionFailed(preconditionDisallowDelete)
 			return PrecommitConstraintResult{}, ErrPermissionDenied.New("no permissions to delete existing object")
 		}
 		return result, nil
 	}
 
+	if opts.SoftDelete {
+		return adapter.precommitSoftDeleteUnversioned(ctx, opts.Location)
+	}
+
+	// the segments have to be queried before they are deleted.
+	var orphaned []orphanedAliasSegment
+	if opts.CollectOrphans {
+		orphaned, err = adapter.precommitQueryUnversionedSegments(ctx, opts.Location)
+		if err != nil {
+			return PrecommitConstraintResult{}, Error.Wrap(err)
+		}
+	}
+
 	switch opts.PrecommitDeleteMode {
 	case defaultUnversionedPrecommitMode:
-		return adapter.precommitDeleteUnversioned(ctx, opts.Location)
+		result, err = adapter.precommitDeleteUnversioned(ctx, opts.Location)
 	case withPrecheckSQLUnversionedPrecommitMode:
-		return adapter.precommitDeleteUnversionedWithSQLCheck(ctx, opts.Location)
+		result, err = adapter.precommitDeleteUnversionedWithSQLCheck(ctx, opts.Location)
 	case withVersionPrecheckUnversionedPrecommitMode:
-		return adapter.precommitDeleteUnversionedWithVersionCheck(ctx, opts.Location)
+		result, err = adapter.precommitDeleteUnversionedWithVersionCheck(ctx, opts.Location)
 	default:
 		return PrecommitConstraintResult{}, Error.New("Invalid precommit delete mode version: %d", opts.PrecommitDeleteMode)
 	}
+	if err != nil {
+		return PrecommitConstraintResult{}, err
+	}
+
+	if opts.CollectOrphans {
+		result.Orphaned, err = db.convertOrphanedSegments(ctx, result.Deleted, orphaned)
+		if err != nil {
+			return PrecommitConstraintResult{}, Error.Wrap(err)
+		}
+	}
+	return result, nil
+}
+
+// convertOrphanedSegments converts the segments belonging to the deleted objects into OrphanedSegment.
+func (db *DB) convertOrphanedSegments(ctx context.Context, deleted []Object, segments []orphanedAliasSegment) (orphaned []OrphanedSegment, err error) {
+	isDeleted := func(streamID uuid.UUID) bool {
+		for _, object := range deleted {
+			if object.StreamID == streamID {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, segment := range segments {
+		// inline segments don't have any pieces to delete.
+		if len(segment.AliasPieces) == 0 || !isDeleted(segment.StreamID) {
+			continue
+		}
+
+		pieces, err := db.aliasCache.ConvertAliasesToPieces(ctx, segment.AliasPieces)
+		if err != nil {
+			return nil, err
+		}
+
+		orphaned = append(orphaned, OrphanedSegment{
+			StreamID:    segment.StreamID,
+			Position:    segment.Position,
+			RootPieceID: segment.RootPieceID,
+			Pieces:      pieces,
+		})
+	}
+	return orphaned, nil
 }
 
 // precommitQueryHighest queries the highest version for a given object.
@@ -233,6 +357,63 @@ func (stx *spannerTransactionAdapter) precommitQueryHighestAndUnversioned(ctx co
 	return highest, unversionedExists, nil
 }
 
+// precommitQueryLastCommittedExists queries whether the last committed version of the object exists
+// and is not a delete marker.
+func (ptx *postgresTransactionAdapter) precommitQueryLastCommittedExists(ctx context.Context, loc ObjectLocation) (exists bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var status ObjectStatus
+	err = ptx.tx.QueryRowContext(ctx, `
+		SELECT status
+		FROM objects
+		WHERE
+			(project_id, bucket_name, object_key) = ($1, $2, $3) AND
+			status <> `+statusPending+` AND
+			(expires_at IS NULL OR expires_at > now())
+		ORDER BY version DESC
+		LIMIT 1
+	`, loc.ProjectID, []byte(loc.BucketName), loc.ObjectKey).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, Error.Wrap(err)
+	}
+
+	return !status.IsDeleteMarker(), nil
+}
+
+func (stx *spannerTransactionAdapter) precommitQueryLastCommittedExists(ctx context.Context, loc ObjectLocation) (exists bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	status, err := spannerutil.CollectRow(stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT status
+			FROM objects
+			WHERE
+				(project_id, bucket_name, object_key) = (@project_id, @bucket_name, @object_key) AND
+				status <> ` + statusPending + ` AND
+				(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			ORDER BY version DESC
+			LIMIT 1
+		`,
+		Params: map[string]interface{}{
+			"project_id":  loc.ProjectID,
+			"bucket_name": loc.BucketName,
+			"object_key":  loc.ObjectKey,
+		},
+	}), func(row *spanner.Row, status *ObjectStatus) error {
+		return Error.Wrap(row.Columns(status))
+	})
+	if err != nil {
+		if errors.Is(err, iterator.Done) {
+			return false, nil
+		}
+		return false, Error.Wrap(err)
+	}
+	return !status.IsDeleteMarker(), nil
+}
+
 // precommitDeleteUnversioned deletes the unversioned object at loc and also returns the highest version.
 func (ptx *postgresTransactionAdapter) precommitDeleteUnversioned(ctx context.Context, loc ObjectLocation) (result PrecommitConstraintResult, err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -672,6 +853,162 @@ func (stx *spannerTransactionAdapter) precommitDeleteUnversioned(ctx context.Con
 	return result, Error.Wrap(err)
 }
 
+// precommitSoftDeleteUnversioned converts the committed unversioned object at loc into a versioned
+// delete marker and also returns the highest version. An unversioned delete marker is deleted,
+// as it has nothing to keep.
+//
+// The segments of the converted object are kept, nothing removes them automatically. They are
+// deleted only when the versioned delete marker is deleted explicitly.
+func (ptx *postgresTransactionAdapter) precommitSoftDeleteUnversioned(ctx context.Context, loc ObjectLocation) (result PrecommitConstraintResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = ptx.tx.QueryRowContext(ctx, `
+		WITH highest_object AS (
+			SELECT version
+			FROM objects
+			WHERE (project_id, bucket_name, object_key) = ($1, $2, $3)
+			ORDER BY version DESC
+			LIMIT 1
+		), soft_deleted_objects AS (
+			UPDATE objects
+			SET status = `+statusDeleteMarkerVersioned+`
+			WHERE
+				(project_id, bucket_name, object_key) = ($1, $2, $3)
+				AND status = `+statusCommittedUnversioned+`
+			RETURNING version
+		), deleted_delete_markers AS (
+			DELETE FROM objects
+			WHERE
+				(project_id, bucket_name, object_key) = ($1, $2, $3)
+				AND status = `+statusDeleteMarkerUnversioned+`
+			RETURNING version
+		)
+		SELECT
+			(SELECT count(*) FROM soft_deleted_objects),
+			(SELECT count(*) FROM deleted_delete_markers),
+			coalesce((SELECT version FROM highest_object), 0)
+	`, loc.ProjectID, []byte(loc.BucketName), loc.ObjectKey).
+		Scan(&result.SoftDeletedObjectCount, &result.DeletedObjectCount, &result.HighestVersion)
+	if err != nil {
+		return PrecommitConstraintResult{}, Error.Wrap(err)
+	}
+	return result, nil
+}
+
+func (stx *spannerTransactionAdapter) precommitSoftDeleteUnversioned(ctx context.Context, loc ObjectLocation) (result PrecommitConstraintResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result.HighestVersion, err = stx.precommitQueryHighest(ctx, loc)
+	if err != nil {
+		return PrecommitConstraintResult{}, Error.Wrap(err)
+	}
+
+	rowCount, err := stx.tx.Update(ctx, spanner.Statement{
+		SQL: `
+			UPDATE objects
+			SET status = ` + statusDeleteMarkerVersioned + `
+			WHERE
+				project_id      = @project_id
+				AND bucket_name = @bucket_name
+				AND object_key  = @object_key
+				AND status      = ` + statusCommittedUnversioned + `
+		`,
+		Params: map[string]any{
+			"project_id":  loc.ProjectID,
+			"bucket_name": loc.BucketName,
+			"object_key":  loc.ObjectKey,
+		},
+	})
+	if err != nil {
+		return PrecommitConstraintResult{}, Error.Wrap(err)
+	}
+	result.SoftDeletedObjectCount = int(rowCount)
+
+	// delete markers don't have segments, hence there's nothing to keep.
+	rowCount, err = stx.tx.Update(ctx, spanner.Statement{
+		SQL: `
+			DELETE FROM objects
+			WHERE
+				project_id      = @project_id
+				AND bucket_name = @bucket_name
+				AND object_key  = @object_key
+				AND status      = ` + statusDeleteMarkerUnversioned + `
+		`,
+		Params: map[string]any{
+			"project_id":  loc.ProjectID,
+			"bucket_name": loc.BucketName,
+			"object_key":  loc.ObjectKey,
+		},
+	})
+	if err != nil {
+		return PrecommitConstraintResult{}, Error.Wrap(err)
+	}
+	result.DeletedObjectCount = int(rowCount)
+
+	return result, nil
+}
+
+// precommitQueryUnversionedSegments queries the segments of the unversioned objects and delete markers.
+func (ptx *postgresTransactionAdapter) precommitQueryUnversionedSegments(ctx context.Context, loc ObjectLocation) (segments []orphanedAliasSegment, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = withRows(ptx.tx.QueryContext(ctx, `
+		SELECT stream_id, position, root_piece_id, remote_alias_pieces
+		FROM segments
+		WHERE stream_id IN (
+			SELECT stream_id
+			FROM objects
+			WHERE
+				(project_id, bucket_name, object_key) = ($1, $2, $3) AND
+				status IN `+statusesUnversioned+`
+		)
+		ORDER BY stream_id, position
+	`, loc.ProjectID, []byte(loc.BucketName), loc.ObjectKey))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			var segment orphanedAliasSegment
+			if err := rows.Scan(&segment.StreamID, &segment.Position, &segment.RootPieceID, &segment.AliasPieces); err != nil {
+				return Error.Wrap(err)
+			}
+			segments = append(segments, segment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to query segments: %w", err)
+	}
+	return segments, nil
+}
+
+func (stx *spannerTransactionAdapter) precommitQueryUnversionedSegments(ctx context.Context, loc ObjectLocation) (segments []orphanedAliasSegment, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	segments, err = spannerutil.CollectRows(stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT stream_id, position, root_piece_id, remote_alias_pieces
+			FROM segments
+			WHERE stream_id IN (
+				SELECT stream_id
+				FROM objects
+				WHERE
+					(project_id, bucket_name, object_key) = (@project_id, @bucket_name, @object_key) AND
+					status IN ` + statusesUnversioned + `
+			)
+			ORDER BY stream_id, position
+		`,
+		Params: map[string]interface{}{
+			"project_id":  loc.ProjectID,
+			"bucket_name": loc.BucketName,
+			"object_key":  loc.ObjectKey,
+		},
+	}), func(row *spanner.Row, segment *orphanedAliasSegment) error {
+		return Error.Wrap(row.Columns(&segment.StreamID, &segment.Position, &segment.RootPieceID, &segment.AliasPieces))
+	})
+	if err != nil {
+		return nil, Error.New("unable to query segments: %w", err)
+	}
+	return segments, nil
+}
+
 // PrecommitConstraintWithNonPendingResult contains the result for enforcing precommit constraint.
 type PrecommitConstraintWithNonPendingResult struct {
 	Deleted []Object
@@ -0,0 +1,239 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/pgutil"
+	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
+)
+
+type pruneVersionsTransactionAdapter interface {
+	pruneQueryVersions(ctx context.Context, loc ObjectLocation, maxVersions int) (versions []versionForPrune, err error)
+	pruneDeleteVersions(ctx context.Context, loc ObjectLocation, versions []Version) (deleted []Object, segments []orphanedAliasSegment, err error)
+}
+
+// pruneVersionsLimit is the maximum number of excess versions queried by a single
+// pruning. Commits add a single version at a time, hence any remaining excess is
+// pruned by the following commits.
+const pruneVersionsLimit = 1000
+
+// versionForPrune is a non-pending object version considered for pruning.
+type versionForPrune struct {
+	Version Version
+	Locked  bool
+}
+
+// pruneObjectVersions deletes the oldest non-pending versions at the location, such that
+// at most maxVersions of them remain. Versions under Object Lock are kept, even when they
+// are over the limit. It returns the deleted versions and their remote segments.
+func (db *DB) pruneObjectVersions(ctx context.Context, adapter pruneVersionsTransactionAdapter, loc ObjectLocation, maxVersions int) (pruned []Object, orphaned []OrphanedSegment, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	versions, err := adapter.pruneQueryVersions(ctx, loc, maxVersions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var excess []Version
+	for _, version := range versions {
+		if !version.Locked {
+			excess = append(excess, version.Version)
+		}
+	}
+	if len(excess) == 0 {
+		return nil, nil, nil
+	}
+
+	pruned, segments, err := adapter.pruneDeleteVersions(ctx, loc, excess)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	orphaned, err = db.convertOrphanedSegments(ctx, pruned, segments)
+	if err != nil {
+		return nil, nil, Error.Wrap(err)
+	}
+
+	mon.Meter("object_version_pruned").Mark(len(pruned))
+	mon.Meter("segment_delete").Mark(len(segments))
+	return pruned, orphaned, nil
+}
+
+// pruneQueryVersions returns the non-pending versions at the location, which come after
+// the newest maxVersions, newest first. The kept versions aren't read at all.
+func (ptx *postgresTransactionAdapter) pruneQueryVersions(ctx context.Context, loc ObjectLocation, maxVersions int) (versions []versionForPrune, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = withRows(ptx.tx.QueryContext(ctx, `
+		SELECT
+			version,
+			(COALESCE(retention_mode, 0) & `+retentionModeMaskSQL+` <> 0 AND COALESCE(retain_until > now(), false)) OR
+			COALESCE(retention_mode, 0) & `+legalHoldFlagSQL+` <> 0
+		FROM objects
+		WHERE
+			(project_id, bucket_name, object_key) = ($1, $2, $3) AND
+			status <> `+statusPending+`
+		ORDER BY version DESC
+		LIMIT $5 OFFSET $4
+	`, loc.ProjectID, []byte(loc.BucketName), loc.ObjectKey, maxVersions, pruneVersionsLimit))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			var version versionForPrune
+			if err := rows.Scan(&version.Version, &version.Locked); err != nil {
+				return Error.Wrap(err)
+			}
+			versions = append(versions, version)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to query object versions: %w", err)
+	}
+	return versions, nil
+}
+
+func (stx *spannerTransactionAdapter) pruneQueryVersions(ctx context.Context, loc ObjectLocation, maxVersions int) (versions []versionForPrune, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	versions, err = spannerutil.CollectRows(stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				version,
+				(COALESCE(retention_mode, 0) & ` + retentionModeMaskSQL + ` <> 0 AND COALESCE(retain_until > CURRENT_TIMESTAMP, FALSE)) OR
+				COALESCE(retention_mode, 0) & ` + legalHoldFlagSQL + ` <> 0
+			FROM objects
+			WHERE
+				(project_id, bucket_name, object_key) = (@project_id, @bucket_name, @object_key) AND
+				status <> ` + statusPending + `
+			ORDER BY version DESC
+			LIMIT @limit OFFSET @offset
+		`,
+		Params: map[string]interface{}{
+			"project_id":  loc.ProjectID,
+			"bucket_name": loc.BucketName,
+			"object_key":  loc.ObjectKey,
+			"offset":      int64(maxVersions),
+			"limit":       int64(pruneVersionsLimit),
+		},
+	}), func(row *spanner.Row, version *versionForPrune) error {
+		return Error.Wrap(row.Columns(&version.Version, &version.Locked))
+	})
+	if err != nil {
+		return nil, Error.New("unable to query object versions: %w", err)
+	}
+	return versions, nil
+}
+
+// pruneDeleteVersions deletes the specified versions and their segments.
+func (ptx *postgresTransactionAdapter) pruneDeleteVersions(ctx context.Context, loc ObjectLocation, versions []Version) (deleted []Object, segments []orphanedAliasSegment, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rawVersions := make([]int64, len(versions))
+	for i, version := range versions {
+		rawVersions[i] = int64(version)
+	}
+
+	err = withRows(ptx.tx.QueryContext(ctx, `
+		DELETE FROM objects
+		WHERE
+			(project_id, bucket_name, object_key) = ($1, $2, $3) AND
+			version = ANY($4) AND
+			status <> `+statusPending+`
+		RETURNING
+			version, stream_id, created_at, expires_at, status, segment_count, encrypted_metadata_nonce,
+			encrypted_metadata, encrypted_metadata_encrypted_key, total_plain_size, total_encrypted_size,
+			fixed_segment_size, encryption
+	`, loc.ProjectID, []byte(loc.BucketName), loc.ObjectKey, pgutil.Int8Array(rawVersions)))(func(rows tagsql.Rows) error {
+		deleted, err = scanObjectDeletionPostgres(ctx, loc, rows)
+		return err
+	})
+	if err != nil {
+		return nil, nil, Error.New("unable to delete object versions: %w", err)
+	}
+	if len(deleted) == 0 {
+		return nil, nil, nil
+	}
+
+	streamIDs := make([]uuid.UUID, len(deleted))
+	for i, object := range deleted {
+		streamIDs[i] = object.StreamID
+	}
+
+	err = withRows(ptx.tx.QueryContext(ctx, `
+		DELETE FROM segments
+		WHERE stream_id = ANY($1)
+		RETURNING stream_id, position, root_piece_id, remote_alias_pieces
+	`, pgutil.UUIDArray(streamIDs)))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			var segment orphanedAliasSegment
+			if err := rows.Scan(&segment.StreamID, &segment.Position, &segment.RootPieceID, &segment.AliasPieces); err != nil {
+				return Error.Wrap(err)
+			}
+			segments = append(segments, segment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, Error.New("unable to delete segments: %w", err)
+	}
+	return deleted, segments, nil
+}
+
+func (stx *spannerTransactionAdapter) pruneDeleteVersions(ctx context.Context, loc ObjectLocation, versions []Version) (deleted []Object, segments []orphanedAliasSegment, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rawVersions := make([]int64, len(versions))
+	for i, version := range versions {
+		rawVersions[i] = int64(version)
+	}
+
+	deleted, err = collectDeletedObjectsSpanner(ctx, loc, stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			DELETE FROM objects
+			WHERE
+				(project_id, bucket_name, object_key) = (@project_id, @bucket_name, @object_key) AND
+				version IN UNNEST(@versions) AND
+				status <> ` + statusPending + `
+			THEN RETURN ` + collectDeletedObjectsSpannerFields,
+		Params: map[string]interface{}{
+			"project_id":  loc.ProjectID,
+			"bucket_name": loc.BucketName,
+			"object_key":  loc.ObjectKey,
+			"versions":    rawVersions,
+		},
+	}))
+	if err != nil {
+		return nil, nil, Error.New("unable to delete object versions: %w", err)
+	}
+	if len(deleted) == 0 {
+		return nil, nil, nil
+	}
+
+	streamIDs := make([][]byte, len(deleted))
+	for i, object := range deleted {
+		streamIDs[i] = object.StreamID.Bytes()
+	}
+
+	segments, err = spannerutil.CollectRows(stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			DELETE FROM segments
+			WHERE stream_id IN UNNEST(@stream_ids)
+			THEN RETURN stream_id, position, root_piece_id, remote_alias_pieces
+		`,
+		Params: map[string]interface{}{
+			"stream_ids": streamIDs,
+		},
+	}), func(row *spanner.Row, segment *orphanedAliasSegment) error {
+		return Error.Wrap(row.Columns(&segment.StreamID, &segment.Position, &segment.RootPieceID, &segment.AliasPieces))
+	})
+	if err != nil {
+		return nil, nil, Error.New("unable to delete segments: %w", err)
+	}
+	return deleted, segments, nil
+}
@@ -46,6 +46,20 @@ type RawObject struct {
 	// This is as a safeguard against objects that failed to upload and the client has not indicated
 	// whether they want to continue uploading or delete the already uploaded data.
 	ZombieDeletionDeadline *time.Time
+
+	// SystemLabels are server-side labels, which are stored outside of the encrypted metadata.
+	SystemLabels map[string]string
+
+	// ComputedETag is the S3 compatible ETag computed by the gateway.
+	ComputedETag []byte
+
+	// Tags are the S3 compatible tags of the object version.
+	Tags map[string]string
+
+	// Retention is the Object Lock retention configuration of the object version.
+	Retention Retention
+	// LegalHold indicates whether the object version is under legal hold.
+	LegalHold bool
 }
 
 // RawSegment defines the full segment that is stored in the database. It should be rarely used directly.
@@ -74,6 +88,9 @@ type RawSegment struct {
 	Pieces     Pieces
 
 	Placement storj.PlacementConstraint
+
+	// IsManifest marks the segment, which contains the index of the object.
+	IsManifest bool
 }
 
 // RawCopy contains a copy that is stored in the database.
@@ -171,7 +188,11 @@ func (p *PostgresAdapter) TestingGetAllObjects(ctx context.Context) (_ []RawObje
 			encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
 			total_plain_size, total_encrypted_size, fixed_segment_size,
 			encryption,
-			zombie_deletion_deadline
+			zombie_deletion_deadline,
+			system_labels,
+			retention_mode, retain_until,
+			computed_etag,
+			object_tags
 		FROM objects
 		ORDER BY project_id ASC, bucket_name ASC, object_key ASC, version ASC
 	`)
@@ -204,6 +225,10 @@ func (p *PostgresAdapter) TestingGetAllObjects(ctx context.Context) (_ []RawObje
 
 			encryptionParameters{&obj.Encryption},
 			&obj.ZombieDeletionDeadline,
+			systemLabels{&obj.SystemLabels},
+			lockModeWrapper{retentionMode: &obj.Retention.Mode, legalHold: &obj.LegalHold}, timeWrapper{&obj.Retention.RetainUntil},
+			&obj.ComputedETag,
+			objectTags{&obj.Tags},
 		)
 		if err != nil {
 			return nil, Error.New("testingGetAllObjects scan failed: %w", err)
@@ -231,7 +256,11 @@ func (s *SpannerAdapter) TestingGetAllObjects(ctx context.Context) (_ []RawObjec
 				encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
 				total_plain_size, total_encrypted_size, fixed_segment_size,
 				encryption,
-				zombie_deletion_deadline
+				zombie_deletion_deadline,
+				system_labels,
+				retention_mode, retain_until,
+				computed_etag,
+				object_tags
 			FROM objects
 			ORDER BY project_id ASC, bucket_name ASC, object_key ASC, version ASC
 		`,
@@ -259,6 +288,10 @@ func (s *SpannerAdapter) TestingGetAllObjects(ctx context.Context) (_ []RawObjec
 
 			encryptionParameters{&obj.Encryption},
 			&obj.ZombieDeletionDeadline,
+			systemLabels{&obj.SystemLabels},
+			lockModeWrapper{retentionMode: &obj.Retention.Mode, legalHold: &obj.LegalHold}, timeWrapper{&obj.Retention.RetainUntil},
+			&obj.ComputedETag,
+			objectTags{&obj.Tags},
 		))
 	})
 }
@@ -402,6 +435,11 @@ func (ctr *copyFromRawObjects) Columns() []string {
 
 		"encryption",
 		"zombie_deletion_deadline",
+		"system_labels",
+		"retention_mode",
+		"retain_until",
+		"computed_etag",
+		"object_tags",
 	}
 }
 
@@ -430,6 +468,11 @@ func (ctr *copyFromRawObjects) Values() ([]any, error) {
 
 		encryptionParameters{&obj.Encryption},
 		obj.ZombieDeletionDeadline,
+		systemLabels{&obj.SystemLabels},
+		lockModeWrapper{retentionMode: &obj.Retention.Mode, legalHold: &obj.LegalHold},
+		timeWrapper{&obj.Retention.RetainUntil},
+		obj.ComputedETag,
+		objectTags{&obj.Tags},
 	}, nil
 }
 
@@ -450,7 +493,7 @@ func (p *PostgresAdapter) TestingGetAllSegments(ctx context.Context, aliasCache
 			encrypted_etag,
 			redundancy,
 			inline_data, remote_alias_pieces,
-			placement
+			placement, is_manifest
 		FROM segments
 		ORDER BY stream_id ASC, position ASC
 	`)
@@ -484,6 +527,7 @@ func (p *PostgresAdapter) TestingGetAllSegments(ctx context.Context, aliasCache
 			&seg.InlineData,
 			&aliasPieces,
 			&seg.Placement,
+			&seg.IsManifest,
 		)
 		if err != nil {
 			return nil, Error.New("testingGetAllSegments scan failed: %w", err)
@@ -517,7 +561,7 @@ func (s *SpannerAdapter) TestingGetAllSegments(ctx context.Context, aliasCache *
 			encrypted_etag,
 			redundancy,
 			inline_data, remote_alias_pieces,
-			placement
+			placement, is_manifest
 		FROM segments
 		ORDER BY stream_id ASC, position ASC
 	`}), func(row *spanner.Row, segment *RawSegment) error {
@@ -531,7 +575,7 @@ func (s *SpannerAdapter) TestingGetAllSegments(ctx context.Context, aliasCache *
 			&segment.EncryptedETag,
 			redundancyScheme{&segment.Redundancy},
 			&segment.InlineData, &aliasPieces,
-			&segment.Placement,
+			&segment.Placement, &segment.IsManifest,
 		)
 		if err != nil {
 			return Error.Wrap(err)
@@ -614,6 +658,7 @@ var rawSegmentColumns = []string{
 	"inline_data",
 	"remote_alias_pieces",
 	"placement",
+	"is_manifest",
 }
 
 type copyFromRawSegments struct {
@@ -669,6 +714,7 @@ func (ctr *copyFromRawSegments) Values() ([]any, error) {
 		obj.InlineData,
 		aliasPieces,
 		obj.Placement,
+		obj.IsManifest,
 	)
 	return ctr.row, nil
 }
@@ -706,6 +752,7 @@ func (s *SpannerAdapter) TestingBatchInsertSegments(ctx context.Context, aliasCa
 			segment.InlineData,
 			aliasPieces,
 			int64(segment.Placement),
+			segment.IsManifest,
 		)
 
 		mutations[i] = spanner.InsertOrUpdate("segments", rawSegmentColumns, vals)
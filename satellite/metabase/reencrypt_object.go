@@ -0,0 +1,145 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/api/iterator"
+
+	"storj.io/common/storj"
+	"storj.io/storj/shared/dbutil/spannerutil"
+)
+
+// verifyReencryptObjectParameters verifies the arguments of ReencryptObjectParameters.
+func verifyReencryptObjectParameters(obj ObjectStream, encryption storj.EncryptionParameters) error {
+	if err := obj.Verify(); err != nil {
+		return err
+	}
+	if encryption.CipherSuite == storj.EncUnspecified {
+		return ErrInvalidRequest.New("Encryption missing")
+	}
+	if encryption.BlockSize <= 0 {
+		return ErrInvalidRequest.New("Encryption.BlockSize is negative or zero")
+	}
+	return nil
+}
+
+// ReencryptObjectParameters replaces the encryption parameters of a committed object
+// and returns the updated object.
+//
+// Segments don't store the encryption parameters, they are always derived from
+// the object, hence updating the object row changes them for all the segments at once.
+// The segment encrypted keys are not modified, re-encrypting them is up to the caller.
+func (db *DB) ReencryptObjectParameters(ctx context.Context, obj ObjectStream, encryption storj.EncryptionParameters) (object Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := verifyReencryptObjectParameters(obj, encryption); err != nil {
+		return Object{}, err
+	}
+
+	object, err = db.ChooseAdapter(obj.ProjectID).ReencryptObjectParameters(ctx, obj, encryption)
+	if err != nil {
+		return Object{}, err
+	}
+
+	mon.Meter("object_reencrypt").Mark(1)
+
+	return object, nil
+}
+
+// ReencryptObjectParameters replaces the encryption parameters of a committed object.
+func (p *PostgresAdapter) ReencryptObjectParameters(ctx context.Context, obj ObjectStream, encryption storj.EncryptionParameters) (object Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = p.db.QueryRowContext(ctx, `
+		UPDATE objects SET
+			encryption = $6
+		WHERE
+			(project_id, bucket_name, object_key, version, stream_id) = ($1, $2, $3, $4, $5) AND
+			status IN `+statusesCommitted+`
+		RETURNING
+			status,
+			created_at, expires_at,
+			segment_count,
+			encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
+			total_plain_size, total_encrypted_size, fixed_segment_size,
+			encryption,
+			computed_etag
+	`, obj.ProjectID, []byte(obj.BucketName), obj.ObjectKey, obj.Version, obj.StreamID,
+		encryptionParameters{&encryption},
+	).Scan(
+		&object.Status,
+		&object.CreatedAt, &object.ExpiresAt,
+		&object.SegmentCount,
+		&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
+		&object.TotalPlainSize, &object.TotalEncryptedSize, &object.FixedSegmentSize,
+		encryptionParameters{&object.Encryption},
+		&object.ComputedETag,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Object{}, ErrObjectNotFound.Wrap(Error.New("object not found"))
+		}
+		return Object{}, Error.New("unable to update object encryption: %w", err)
+	}
+
+	object.ObjectStream = obj
+	return object, nil
+}
+
+// ReencryptObjectParameters replaces the encryption parameters of a committed object.
+func (s *SpannerAdapter) ReencryptObjectParameters(ctx context.Context, obj ObjectStream, encryption storj.EncryptionParameters) (object Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	_, err = s.client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		object, err = spannerutil.CollectRow(tx.Query(ctx, spanner.Statement{
+			SQL: `
+				UPDATE objects SET
+					encryption = @encryption
+				WHERE
+					(project_id, bucket_name, object_key, version, stream_id) = (@project_id, @bucket_name, @object_key, @version, @stream_id) AND
+					status IN ` + statusesCommitted + `
+				THEN RETURN
+					status,
+					created_at, expires_at,
+					segment_count,
+					encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
+					total_plain_size, total_encrypted_size, fixed_segment_size,
+					encryption,
+					computed_etag
+			`,
+			Params: map[string]interface{}{
+				"project_id":  obj.ProjectID,
+				"bucket_name": obj.BucketName,
+				"object_key":  obj.ObjectKey,
+				"version":     obj.Version,
+				"stream_id":   obj.StreamID,
+				"encryption":  encryptionParameters{&encryption},
+			},
+		}), func(row *spanner.Row, object *Object) error {
+			object.ObjectStream = obj
+			return Error.Wrap(row.Columns(
+				&object.Status,
+				&object.CreatedAt, &object.ExpiresAt,
+				spannerutil.Int(&object.SegmentCount),
+				&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
+				&object.TotalPlainSize, &object.TotalEncryptedSize, spannerutil.Int(&object.FixedSegmentSize),
+				encryptionParameters{&object.Encryption},
+				&object.ComputedETag,
+			))
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, iterator.Done) {
+			return Object{}, ErrObjectNotFound.Wrap(Error.New("object not found"))
+		}
+		return Object{}, Error.New("unable to update object encryption: %w", err)
+	}
+	return object, nil
+}
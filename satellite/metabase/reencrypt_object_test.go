@@ -0,0 +1,98 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestReencryptObjectParameters(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		newEncryption := storj.EncryptionParameters{
+			CipherSuite: storj.EncAESGCM,
+			BlockSize:   1024,
+		}
+
+		t.Run("invalid request", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			for _, test := range metabasetest.InvalidObjectStreams(metabasetest.RandObjectStream()) {
+				_, err := db.ReencryptObjectParameters(ctx, test.ObjectStream, newEncryption)
+				require.True(t, test.ErrClass.Has(err), test.Name)
+				require.EqualError(t, err, test.ErrClass.New(test.ErrText).Error(), test.Name)
+			}
+
+			_, err := db.ReencryptObjectParameters(ctx, metabasetest.RandObjectStream(), storj.EncryptionParameters{})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "Encryption missing")
+
+			_, err = db.ReencryptObjectParameters(ctx, metabasetest.RandObjectStream(), storj.EncryptionParameters{
+				CipherSuite: storj.EncAESGCM,
+				BlockSize:   0,
+			})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "Encryption.BlockSize is negative or zero")
+		})
+
+		t.Run("missing object", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			_, err := db.ReencryptObjectParameters(ctx, metabasetest.RandObjectStream(), newEncryption)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+
+		t.Run("pending object", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			pending := metabasetest.CreatePendingObject(ctx, t, db, metabasetest.RandObjectStream(), 0)
+
+			_, err := db.ReencryptObjectParameters(ctx, pending.ObjectStream, newEncryption)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+
+		t.Run("different stream", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			object := metabasetest.CreateObject(ctx, t, db, metabasetest.RandObjectStream(), 1)
+
+			stream := object.ObjectStream
+			stream.StreamID = testrand.UUID()
+			_, err := db.ReencryptObjectParameters(ctx, stream, newEncryption)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+
+		t.Run("reencrypt", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			object, segments := metabasetest.CreateTestObject{}.Run(ctx, t, db, metabasetest.RandObjectStream(), 2)
+			other, otherSegments := metabasetest.CreateTestObject{}.Run(ctx, t, db, metabasetest.RandObjectStream(), 1)
+
+			updated, err := db.ReencryptObjectParameters(ctx, object.ObjectStream, newEncryption)
+			require.NoError(t, err)
+			require.Equal(t, object.ObjectStream, updated.ObjectStream)
+			require.Equal(t, newEncryption, updated.Encryption)
+			require.Equal(t, object.SegmentCount, updated.SegmentCount)
+			require.Equal(t, object.TotalEncryptedSize, updated.TotalEncryptedSize)
+
+			expected := object
+			expected.Encryption = newEncryption
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{
+					metabase.RawObject(expected),
+					metabase.RawObject(other),
+				},
+				Segments: append(metabasetest.SegmentsToRaw(segments), metabasetest.SegmentsToRaw(otherSegments)...),
+			}.Check(ctx, t, db)
+		})
+	})
+}
@@ -0,0 +1,237 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+type reopenObjectTransactionAdapter interface {
+	getObjectForReopen(ctx context.Context, opts ReopenObject) (object Object, locked bool, err error)
+	reopenObject(ctx context.Context, opts ReopenObject) (affected int64, err error)
+}
+
+// ReopenObject contains arguments necessary for reopening a committed object.
+type ReopenObject struct {
+	ObjectStream
+
+	ZombieDeletionDeadline *time.Time
+}
+
+// Verify verifies reopen object fields.
+func (opts *ReopenObject) Verify() error {
+	if err := opts.ObjectStream.Verify(); err != nil {
+		return err
+	}
+	if opts.Version <= 0 {
+		return ErrInvalidRequest.New("Version invalid: %v", opts.Version)
+	}
+	return nil
+}
+
+// ReopenObject converts a committed unversioned object back to a pending object,
+// so that additional segments can be uploaded before committing it again.
+// Versioned objects, delete markers and objects under retention are not reopened.
+func (db *DB) ReopenObject(ctx context.Context, opts ReopenObject) (object Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return Object{}, err
+	}
+
+	if opts.ZombieDeletionDeadline == nil {
+		deadline := time.Now().Add(db.config.zombieDeletionPeriod(opts.ProjectID))
+		opts.ZombieDeletionDeadline = &deadline
+	}
+
+	err = db.ChooseAdapter(opts.ProjectID).WithTx(ctx, func(ctx context.Context, adapter TransactionAdapter) error {
+		var locked bool
+		object, locked, err = adapter.getObjectForReopen(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case object.Status == Pending:
+			return ErrFailedPrecondition.New("object is already pending")
+		case object.Status == CommittedVersioned:
+			return ErrFailedPrecondition.New("versioned object cannot be reopened")
+		case object.Status.IsDeleteMarker():
+			return ErrObjectNotFound.New("object not found")
+		case object.Status != CommittedUnversioned:
+			return Error.New("unexpected object status: %v", object.Status)
+		}
+
+		if locked {
+			return ErrFailedPrecondition.New("object is locked")
+		}
+
+		affected, err := adapter.reopenObject(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if affected != 1 {
+			return ErrObjectNotFound.New("object was changed during reopen")
+		}
+		return nil
+	})
+	if err != nil {
+		return Object{}, err
+	}
+
+	object.ObjectStream = opts.ObjectStream
+	object.Status = Pending
+	object.SegmentCount = 0
+	object.TotalPlainSize = 0
+	object.TotalEncryptedSize = 0
+	object.FixedSegmentSize = 0
+	object.ZombieDeletionDeadline = opts.ZombieDeletionDeadline
+
+	mon.Meter("object_reopen").Mark(1)
+
+	return object, nil
+}
+
+func (ptx *postgresTransactionAdapter) getObjectForReopen(ctx context.Context, opts ReopenObject) (object Object, locked bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = ptx.tx.QueryRowContext(ctx, `
+		SELECT
+			status,
+			created_at, expires_at,
+			encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
+			encryption,
+			(COALESCE(retention_mode, 0) & `+retentionModeMaskSQL+` <> 0 AND COALESCE(retain_until > now(), false)) OR
+			COALESCE(retention_mode, 0) & `+legalHoldFlagSQL+` <> 0
+		FROM objects
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			stream_id = $5
+	`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, opts.StreamID).
+		Scan(
+			&object.Status,
+			&object.CreatedAt, &object.ExpiresAt,
+			&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
+			encryptionParameters{&object.Encryption},
+			&locked,
+		)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Object{}, false, ErrObjectNotFound.New("object not found")
+		}
+		return Object{}, false, Error.New("unable to query object: %w", err)
+	}
+	return object, locked, nil
+}
+
+func (stx *spannerTransactionAdapter) getObjectForReopen(ctx context.Context, opts ReopenObject) (object Object, locked bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	found := false
+	err = stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				status,
+				created_at, expires_at,
+				encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
+				encryption,
+				(COALESCE(retention_mode, 0) & ` + retentionModeMaskSQL + ` <> 0 AND COALESCE(retain_until > CURRENT_TIMESTAMP, FALSE)) OR
+				COALESCE(retention_mode, 0) & ` + legalHoldFlagSQL + ` <> 0
+			FROM objects
+			WHERE
+				(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+				stream_id = @stream_id
+		`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.BucketName,
+			"object_key":  opts.ObjectKey,
+			"version":     opts.Version,
+			"stream_id":   opts.StreamID,
+		},
+	}).Do(func(row *spanner.Row) error {
+		found = true
+		return Error.Wrap(row.Columns(
+			&object.Status,
+			&object.CreatedAt, &object.ExpiresAt,
+			&object.EncryptedMetadataNonce, &object.EncryptedMetadata, &object.EncryptedMetadataEncryptedKey,
+			encryptionParameters{&object.Encryption},
+			&locked,
+		))
+	})
+	if err != nil {
+		return Object{}, false, Error.New("unable to query object: %w", err)
+	}
+	if !found {
+		return Object{}, false, ErrObjectNotFound.New("object not found")
+	}
+	return object, locked, nil
+}
+
+func (ptx *postgresTransactionAdapter) reopenObject(ctx context.Context, opts ReopenObject) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := ptx.tx.ExecContext(ctx, `
+		UPDATE objects SET
+			status = `+statusPending+`,
+			segment_count = 0,
+			total_plain_size = 0,
+			total_encrypted_size = 0,
+			fixed_segment_size = 0,
+			zombie_deletion_deadline = $6
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			stream_id = $5 AND
+			status = `+statusCommittedUnversioned+`
+	`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, opts.StreamID,
+		opts.ZombieDeletionDeadline)
+	if err != nil {
+		return 0, Error.New("unable to reopen object: %w", err)
+	}
+
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return 0, Error.New("unable to get number of affected objects: %w", err)
+	}
+	return affected, nil
+}
+
+func (stx *spannerTransactionAdapter) reopenObject(ctx context.Context, opts ReopenObject) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// Unlike move, none of the updated columns is part of the primary key,
+	// so the object can be updated in place.
+	affected, err = stx.tx.Update(ctx, spanner.Statement{
+		SQL: `
+			UPDATE objects SET
+				status = ` + statusPending + `,
+				segment_count = 0,
+				total_plain_size = 0,
+				total_encrypted_size = 0,
+				fixed_segment_size = 0,
+				zombie_deletion_deadline = @zombie_deletion_deadline
+			WHERE
+				(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+				stream_id = @stream_id AND
+				status = ` + statusCommittedUnversioned + `
+		`,
+		Params: map[string]interface{}{
+			"project_id":               opts.ProjectID,
+			"bucket_name":              opts.BucketName,
+			"object_key":               opts.ObjectKey,
+			"version":                  opts.Version,
+			"stream_id":                opts.StreamID,
+			"zombie_deletion_deadline": opts.ZombieDeletionDeadline,
+		},
+	})
+	if err != nil {
+		return 0, Error.New("unable to reopen object: %w", err)
+	}
+	return affected, nil
+}
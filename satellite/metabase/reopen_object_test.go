@@ -0,0 +1,197 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestReopenObject(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		for _, test := range metabasetest.InvalidObjectStreams(obj) {
+			test := test
+			t.Run(test.Name, func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+				metabasetest.ReopenObject{
+					Opts: metabase.ReopenObject{
+						ObjectStream: test.ObjectStream,
+					},
+					ErrClass: test.ErrClass,
+					ErrText:  test.ErrText,
+				}.Check(ctx, t, db)
+				metabasetest.Verify{}.Check(ctx, t, db)
+			})
+		}
+
+		t.Run("Version invalid", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			stream := obj
+			stream.Version = 0
+			metabasetest.ReopenObject{
+				Opts: metabase.ReopenObject{
+					ObjectStream: stream,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "Version invalid: 0",
+			}.Check(ctx, t, db)
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("object missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.ReopenObject{
+				Opts: metabase.ReopenObject{
+					ObjectStream: obj,
+				},
+				ErrClass: &metabase.ErrObjectNotFound,
+				ErrText:  "object not found",
+			}.Check(ctx, t, db)
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("pending object", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			pending := metabasetest.BeginObjectExactVersion{
+				Opts: metabase.BeginObjectExactVersion{
+					ObjectStream: obj,
+					Encryption:   metabasetest.DefaultEncryption,
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.ReopenObject{
+				Opts: metabase.ReopenObject{
+					ObjectStream: obj,
+				},
+				ErrClass: &metabase.ErrFailedPrecondition,
+				ErrText:  "object is already pending",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{metabase.RawObject(pending)},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("versioned object", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			object := metabasetest.CreateObjectVersioned(ctx, t, db, obj, 1)
+
+			metabasetest.ReopenObject{
+				Opts: metabase.ReopenObject{
+					ObjectStream: obj,
+				},
+				ErrClass: &metabase.ErrFailedPrecondition,
+				ErrText:  "versioned object cannot be reopened",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{
+				Objects:  []metabase.RawObject{metabase.RawObject(object)},
+				Segments: []metabase.RawSegment{metabasetest.DefaultRawSegment(obj, metabase.SegmentPosition{})},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("different stream", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			object := metabasetest.CreateObject(ctx, t, db, obj, 0)
+
+			stream := obj
+			stream.StreamID = testrand.UUID()
+			metabasetest.ReopenObject{
+				Opts: metabase.ReopenObject{
+					ObjectStream: stream,
+				},
+				ErrClass: &metabase.ErrObjectNotFound,
+				ErrText:  "object not found",
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{metabase.RawObject(object)},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("reopen and commit again", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			now := time.Now()
+			zombieDeadline := now.Add(time.Hour)
+
+			metabasetest.CreateObject(ctx, t, db, obj, 1)
+
+			metabasetest.ReopenObject{
+				Opts: metabase.ReopenObject{
+					ObjectStream:           obj,
+					ZombieDeletionDeadline: &zombieDeadline,
+				},
+				Result: metabase.Object{
+					ObjectStream:           obj,
+					CreatedAt:              now,
+					Status:                 metabase.Pending,
+					Encryption:             metabasetest.DefaultEncryption,
+					ZombieDeletionDeadline: &zombieDeadline,
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{
+					{
+						ObjectStream:           obj,
+						CreatedAt:              now,
+						Status:                 metabase.Pending,
+						Encryption:             metabasetest.DefaultEncryption,
+						ZombieDeletionDeadline: &zombieDeadline,
+					},
+				},
+				Segments: []metabase.RawSegment{metabasetest.DefaultRawSegment(obj, metabase.SegmentPosition{})},
+			}.Check(ctx, t, db)
+
+			metabasetest.CommitSegment{
+				Opts: metabase.CommitSegment{
+					ObjectStream: obj,
+					Position:     metabase.SegmentPosition{Index: 1},
+					RootPieceID:  storj.PieceID{1},
+					Pieces:       metabase.Pieces{{Number: 0, StorageNode: storj.NodeID{2}}},
+
+					EncryptedKey:      []byte{3},
+					EncryptedKeyNonce: []byte{4},
+					EncryptedETag:     []byte{5},
+
+					EncryptedSize: 1024,
+					PlainSize:     512,
+					PlainOffset:   512,
+					Redundancy:    metabasetest.DefaultRedundancy,
+				},
+			}.Check(ctx, t, db)
+
+			secondSegment := metabasetest.DefaultRawSegment(obj, metabase.SegmentPosition{Index: 1})
+			secondSegment.PlainOffset = 512
+
+			object := metabasetest.CommitObject{
+				Opts: metabase.CommitObject{
+					ObjectStream: obj,
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{metabase.RawObject(object)},
+				Segments: []metabase.RawSegment{
+					metabasetest.DefaultRawSegment(obj, metabase.SegmentPosition{}),
+					secondSegment,
+				},
+			}.Check(ctx, t, db)
+		})
+	})
+}
@@ -0,0 +1,226 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"cloud.google.com/go/spanner"
+)
+
+type restoreObjectVersionTransactionAdapter interface {
+	getObjectForRestore(ctx context.Context, loc ObjectLocation, version Version) (status ObjectStatus, locked bool, err error)
+	restoreObjectVersion(ctx context.Context, loc ObjectLocation, version, nextVersion Version) (affected int64, err error)
+}
+
+// RestoreObjectVersion makes the specified committed version of an object the latest
+// one, e.g. for restoring a previous version in a versioned bucket.
+//
+// The object version is re-committed as the highest version of the object, i.e. the
+// same row is moved to a new version number. It keeps its StreamID and segments,
+// hence nothing is duplicated, however it's no longer available under the old version.
+// Versions under retention or legal hold can't be restored, because they must keep
+// their version. Restoring the latest version doesn't change it.
+func (db *DB) RestoreObjectVersion(ctx context.Context, loc ObjectLocation, version Version) (object Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := loc.Verify(); err != nil {
+		return Object{}, err
+	}
+	if version <= 0 {
+		return Object{}, ErrInvalidRequest.New("Version invalid: %v", version)
+	}
+
+	nextVersion := version
+	err = db.ChooseAdapter(loc.ProjectID).WithTx(ctx, func(ctx context.Context, adapter TransactionAdapter) error {
+		status, locked, err := adapter.getObjectForRestore(ctx, loc, version)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case status == Pending:
+			return ErrObjectNotFound.New("object not found")
+		case status.IsDeleteMarker():
+			return ErrFailedPrecondition.New("delete marker cannot be restored")
+		case status == CommittedUnversioned:
+			return ErrFailedPrecondition.New("unversioned object cannot be restored")
+		case status != CommittedVersioned:
+			return Error.New("unexpected object status: %v", status)
+		}
+
+		if locked {
+			return ErrFailedPrecondition.New("object is locked")
+		}
+
+		highest, err := adapter.precommitQueryHighest(ctx, loc)
+		if err != nil {
+			return err
+		}
+		if highest == version {
+			return nil
+		}
+		nextVersion = highest + 1
+
+		affected, err := adapter.restoreObjectVersion(ctx, loc, version, nextVersion)
+		if err != nil {
+			return err
+		}
+		if affected != 1 {
+			return ErrObjectNotFound.New("object was changed during restore")
+		}
+		return nil
+	})
+	if err != nil {
+		return Object{}, err
+	}
+
+	mon.Meter("object_restore_version").Mark(1)
+
+	return db.GetObjectExactVersion(ctx, GetObjectExactVersion{
+		ObjectLocation: loc,
+		Version:        nextVersion,
+	})
+}
+
+func (ptx *postgresTransactionAdapter) getObjectForRestore(ctx context.Context, loc ObjectLocation, version Version) (status ObjectStatus, locked bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = ptx.tx.QueryRowContext(ctx, `
+		SELECT
+			status,
+			(COALESCE(retention_mode, 0) & `+retentionModeMaskSQL+` <> 0 AND COALESCE(retain_until > now(), false)) OR
+			COALESCE(retention_mode, 0) & `+legalHoldFlagSQL+` <> 0
+		FROM objects
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			(expires_at IS NULL OR expires_at > now())
+	`, loc.ProjectID, []byte(loc.BucketName), loc.ObjectKey, version).Scan(&status, &locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, ErrObjectNotFound.New("object not found")
+		}
+		return 0, false, Error.New("unable to query object: %w", err)
+	}
+	return status, locked, nil
+}
+
+func (stx *spannerTransactionAdapter) getObjectForRestore(ctx context.Context, loc ObjectLocation, version Version) (status ObjectStatus, locked bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	found := false
+	err = stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				status,
+				(COALESCE(retention_mode, 0) & ` + retentionModeMaskSQL + ` <> 0 AND COALESCE(retain_until > CURRENT_TIMESTAMP, FALSE)) OR
+				COALESCE(retention_mode, 0) & ` + legalHoldFlagSQL + ` <> 0
+			FROM objects
+			WHERE
+				(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+				(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		`,
+		Params: map[string]interface{}{
+			"project_id":  loc.ProjectID,
+			"bucket_name": loc.BucketName,
+			"object_key":  loc.ObjectKey,
+			"version":     version,
+		},
+	}).Do(func(row *spanner.Row) error {
+		found = true
+		return Error.Wrap(row.Columns(&status, &locked))
+	})
+	if err != nil {
+		return 0, false, Error.New("unable to query object: %w", err)
+	}
+	if !found {
+		return 0, false, ErrObjectNotFound.New("object not found")
+	}
+	return status, locked, nil
+}
+
+func (ptx *postgresTransactionAdapter) restoreObjectVersion(ctx context.Context, loc ObjectLocation, version, nextVersion Version) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := ptx.tx.ExecContext(ctx, `
+		UPDATE objects SET
+			version = $5
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			status = `+statusCommittedVersioned+`
+	`, loc.ProjectID, []byte(loc.BucketName), loc.ObjectKey, version, nextVersion)
+	if err != nil {
+		return 0, Error.New("unable to restore object version: %w", err)
+	}
+
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return 0, Error.New("unable to get number of affected objects: %w", err)
+	}
+	return affected, nil
+}
+
+func (stx *spannerTransactionAdapter) restoreObjectVersion(ctx context.Context, loc ObjectLocation, version, nextVersion Version) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// version is part of the primary key, which can't be updated in Spanner,
+	// hence the row is copied to the new version and the old row is deleted.
+	params := map[string]interface{}{
+		"project_id":   loc.ProjectID,
+		"bucket_name":  loc.BucketName,
+		"object_key":   loc.ObjectKey,
+		"version":      version,
+		"next_version": nextVersion,
+	}
+
+	affecteds, err := stx.tx.BatchUpdate(ctx, []spanner.Statement{
+		{
+			SQL: `
+				INSERT INTO objects (
+					project_id, bucket_name, object_key, version, stream_id,
+					created_at, expires_at,
+					status, segment_count,
+					encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
+					total_plain_size, total_encrypted_size, fixed_segment_size,
+					encryption, zombie_deletion_deadline,
+					system_labels,
+					retention_mode, retain_until,
+					computed_etag,
+					object_tags
+				)
+				SELECT
+					project_id, bucket_name, object_key, @next_version, stream_id,
+					created_at, expires_at,
+					status, segment_count,
+					encrypted_metadata_nonce, encrypted_metadata, encrypted_metadata_encrypted_key,
+					total_plain_size, total_encrypted_size, fixed_segment_size,
+					encryption, zombie_deletion_deadline,
+					system_labels,
+					retention_mode, retain_until,
+					computed_etag,
+					object_tags
+				FROM objects
+				WHERE
+					(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+					status = ` + statusCommittedVersioned + `
+			`,
+			Params: params,
+		},
+		{
+			SQL: `
+				DELETE FROM objects
+				WHERE
+					(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+					status = ` + statusCommittedVersioned + `
+			`,
+			Params: params,
+		},
+	})
+	if err != nil {
+		return 0, Error.New("unable to restore object version: %w", err)
+	}
+	return affecteds[0], nil
+}
@@ -0,0 +1,153 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestRestoreObjectVersion(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		now := time.Now()
+
+		newRawObject := func(status metabase.ObjectStatus, retention metabase.Retention, legalHold bool) metabase.RawObject {
+			return metabase.RawObject{
+				ObjectStream: metabasetest.RandObjectStream(),
+				CreatedAt:    now,
+				Status:       status,
+				Encryption:   metabasetest.DefaultEncryption,
+				Retention:    retention,
+				LegalHold:    legalHold,
+			}
+		}
+
+		t.Run("invalid request", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := metabasetest.RandObjectStream()
+			loc := obj.Location()
+
+			invalid := loc
+			invalid.ProjectID = testrand.UUID()
+			invalid.BucketName = ""
+			_, err := db.RestoreObjectVersion(ctx, invalid, 1)
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "BucketName missing")
+
+			_, err = db.RestoreObjectVersion(ctx, loc, 0)
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "Version invalid: 0")
+		})
+
+		t.Run("missing object", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := metabasetest.RandObjectStream()
+			_, err := db.RestoreObjectVersion(ctx, obj.Location(), obj.Version)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+
+		t.Run("pending object", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			pending := metabasetest.CreatePendingObject(ctx, t, db, metabasetest.RandObjectStream(), 0)
+
+			_, err := db.RestoreObjectVersion(ctx, pending.Location(), pending.Version)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+
+		t.Run("not restorable", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			deleteMarker := newRawObject(metabase.DeleteMarkerVersioned, metabase.Retention{}, false)
+			deleteMarker.Encryption = storj.EncryptionParameters{}
+			unversioned := newRawObject(metabase.CommittedUnversioned, metabase.Retention{}, false)
+			retained := newRawObject(metabase.CommittedVersioned, metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(time.Hour),
+			}, false)
+			legalHold := newRawObject(metabase.CommittedVersioned, metabase.Retention{}, true)
+
+			objects := []metabase.RawObject{deleteMarker, unversioned, retained, legalHold}
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, objects))
+
+			for _, test := range []struct {
+				object  metabase.RawObject
+				errText string
+			}{
+				{deleteMarker, "delete marker cannot be restored"},
+				{unversioned, "unversioned object cannot be restored"},
+				{retained, "object is locked"},
+				{legalHold, "object is locked"},
+			} {
+				_, err := db.RestoreObjectVersion(ctx, test.object.Location(), test.object.Version)
+				require.True(t, metabase.ErrFailedPrecondition.Has(err), test.errText)
+				require.ErrorContains(t, err, test.errText)
+			}
+
+			metabasetest.Verify{Objects: objects}.Check(ctx, t, db)
+		})
+
+		t.Run("restore", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := metabasetest.RandObjectStream()
+
+			first := obj
+			first.Version = 1
+			firstObject, firstSegments := metabasetest.CreateTestObject{
+				CommitObject: &metabase.CommitObject{
+					ObjectStream: first,
+					Versioned:    true,
+				},
+			}.Run(ctx, t, db, first, 2)
+
+			second := obj
+			second.Version = 2
+			second.StreamID = testrand.UUID()
+			secondObject, secondSegments := metabasetest.CreateTestObject{
+				CommitObject: &metabase.CommitObject{
+					ObjectStream: second,
+					Versioned:    true,
+				},
+			}.Run(ctx, t, db, second, 1)
+
+			restored, err := db.RestoreObjectVersion(ctx, obj.Location(), first.Version)
+			require.NoError(t, err)
+			require.Equal(t, metabase.Version(3), restored.Version)
+			require.Equal(t, first.StreamID, restored.StreamID)
+			require.Equal(t, firstObject.SegmentCount, restored.SegmentCount)
+
+			expected := firstObject
+			expected.Version = 3
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{
+					metabase.RawObject(secondObject),
+					metabase.RawObject(expected),
+				},
+				Segments: append(metabasetest.SegmentsToRaw(firstSegments), metabasetest.SegmentsToRaw(secondSegments)...),
+			}.Check(ctx, t, db)
+
+			// restoring the latest version doesn't change it.
+			latest, err := db.RestoreObjectVersion(ctx, obj.Location(), expected.Version)
+			require.NoError(t, err)
+			require.Equal(t, metabase.Version(3), latest.Version)
+			require.Equal(t, first.StreamID, latest.StreamID)
+
+			// the old version doesn't exist anymore.
+			_, err = db.RestoreObjectVersion(ctx, obj.Location(), first.Version)
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+	})
+}
@@ -0,0 +1,452 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/storj"
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/pgutil"
+	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
+)
+
+// SetObjectsRetentionLimit is the maximum number of object versions
+// that can be updated with a single SetObjectsRetention call.
+const SetObjectsRetentionLimit = 1000
+
+// Layout of the retention_mode column.
+const (
+	// retentionModeMask selects the retention mode.
+	retentionModeMask = 0b11
+	// legalHoldFlag is set when the object version is under legal hold.
+	legalHoldFlag = 0b100
+
+	retentionModeMaskSQL = "3"
+	legalHoldFlagSQL     = "4"
+)
+
+type setObjectsRetentionTransactionAdapter interface {
+	getObjectsForRetention(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation) (objects []objectForRetention, err error)
+	setObjectsRetention(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation, retention Retention) (affected int64, err error)
+}
+
+// Retention represents the Object Lock retention configuration of an object version.
+type Retention struct {
+	Mode        storj.RetentionMode
+	RetainUntil time.Time
+}
+
+// Enabled returns whether the retention configuration is enabled.
+func (r Retention) Enabled() bool {
+	return r.Mode != storj.NoRetention
+}
+
+// Active returns whether the retention configuration is enabled and
+// hasn't expired as of the given time.
+func (r Retention) Active(now time.Time) bool {
+	return r.Enabled() && now.Before(r.RetainUntil)
+}
+
+// Verify verifies retention fields. An enabled retention must end after now.
+func (r Retention) Verify(now time.Time) error {
+	switch r.Mode {
+	case storj.ComplianceMode:
+		if r.RetainUntil.IsZero() {
+			return ErrInvalidRequest.New("RetainUntil must be set if retention mode is set")
+		}
+		if !r.RetainUntil.After(now) {
+			return ErrInvalidRequest.New("RetainUntil must be in the future: %s", r.RetainUntil.Format(time.RFC3339Nano))
+		}
+	case storj.NoRetention:
+		if !r.RetainUntil.IsZero() {
+			return ErrInvalidRequest.New("RetainUntil must not be set if retention mode is not set")
+		}
+	default:
+		return ErrInvalidRequest.New("invalid retention mode: %d", r.Mode)
+	}
+	return nil
+}
+
+// VersionedLocation is the location of an object version within a bucket.
+type VersionedLocation struct {
+	ObjectKey ObjectKey
+	Version   Version
+}
+
+// SetRetentionOutcome describes what happened to a single object version
+// during SetObjectsRetention.
+type SetRetentionOutcome int
+
+const (
+	// RetentionUpdated means that the retention of the object version was set.
+	RetentionUpdated SetRetentionOutcome = iota
+	// RetentionSkippedLonger means that the object version already has an active
+	// retention, which the requested retention would shorten or remove.
+	RetentionSkippedLonger
+	// RetentionSkippedExpiring means that the object version has an expiration time,
+	// which can't be combined with a retention.
+	RetentionSkippedExpiring
+	// RetentionSkippedNotFound means that there's no committed object version,
+	// which isn't a delete marker, at the location.
+	RetentionSkippedNotFound
+)
+
+// String returns a string representation of the outcome.
+func (outcome SetRetentionOutcome) String() string {
+	switch outcome {
+	case RetentionUpdated:
+		return "updated"
+	case RetentionSkippedLonger:
+		return "skipped longer retention"
+	case RetentionSkippedExpiring:
+		return "skipped expiring object"
+	case RetentionSkippedNotFound:
+		return "skipped missing object"
+	default:
+		return "unknown"
+	}
+}
+
+// SetObjectsRetentionResult contains the outcome of SetObjectsRetention
+// for a single object version.
+type SetObjectsRetentionResult struct {
+	VersionedLocation
+	Outcome SetRetentionOutcome
+}
+
+type objectForRetention struct {
+	VersionedLocation
+	Status    ObjectStatus
+	ExpiresAt *time.Time
+	Retention Retention
+}
+
+// SetObjectsRetention sets the retention of many object versions in a single transaction.
+//
+// An active retention is never shortened or removed, such object versions are skipped
+// instead and so are missing objects, delete markers and objects with an expiration time.
+// The results are in the same order as the locations.
+func (db *DB) SetObjectsRetention(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation, retention Retention) (results []SetObjectsRetentionResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	switch {
+	case projectID.IsZero():
+		return nil, ErrInvalidRequest.New("ProjectID missing")
+	case bucketName == "":
+		return nil, ErrInvalidRequest.New("BucketName missing")
+	case len(locations) > SetObjectsRetentionLimit:
+		return nil, ErrInvalidRequest.New("too many object versions: %d, maximum allowed: %d", len(locations), SetObjectsRetentionLimit)
+	}
+
+	seen := make(map[VersionedLocation]struct{}, len(locations))
+	for _, loc := range locations {
+		if loc.ObjectKey == "" {
+			return nil, ErrInvalidRequest.New("ObjectKey missing")
+		}
+		if loc.Version <= 0 {
+			return nil, ErrInvalidRequest.New("Version invalid: %v", loc.Version)
+		}
+		if _, ok := seen[loc]; ok {
+			return nil, ErrInvalidRequest.New("duplicate object version: %q %v", loc.ObjectKey, loc.Version)
+		}
+		seen[loc] = struct{}{}
+	}
+
+	now := db.nowFn()
+	if err := retention.Verify(now); err != nil {
+		return nil, err
+	}
+
+	if len(locations) == 0 {
+		return nil, nil
+	}
+
+	err = db.ChooseAdapter(projectID).WithTx(ctx, func(ctx context.Context, adapter TransactionAdapter) error {
+		objects, err := adapter.getObjectsForRetention(ctx, projectID, bucketName, locations)
+		if err != nil {
+			return err
+		}
+
+		found := make(map[VersionedLocation]objectForRetention, len(objects))
+		for _, object := range objects {
+			found[object.VersionedLocation] = object
+		}
+
+		results = make([]SetObjectsRetentionResult, len(locations))
+		var update []VersionedLocation
+		for i, loc := range locations {
+			results[i] = SetObjectsRetentionResult{
+				VersionedLocation: loc,
+				Outcome:           retentionOutcome(found[loc], loc, retention, now),
+			}
+			if results[i].Outcome == RetentionUpdated {
+				update = append(update, loc)
+			}
+		}
+
+		if len(update) == 0 {
+			return nil
+		}
+
+		affected, err := adapter.setObjectsRetention(ctx, projectID, bucketName, update, retention)
+		if err != nil {
+			return err
+		}
+		if affected != int64(len(update)) {
+			return ErrObjectNotFound.New("objects were changed during update")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		if result.Outcome == RetentionUpdated {
+			mon.Meter("object_retention_set").Mark(1)
+		} else {
+			mon.Meter("object_retention_skipped").Mark(1)
+		}
+	}
+
+	return results, nil
+}
+
+// retentionOutcome decides whether the retention of object can be set.
+// A missing object is represented by a zero object.
+func retentionOutcome(object objectForRetention, loc VersionedLocation, retention Retention, now time.Time) SetRetentionOutcome {
+	switch {
+	case object.VersionedLocation != loc:
+		return RetentionSkippedNotFound
+	case object.Status != CommittedUnversioned && object.Status != CommittedVersioned:
+		return RetentionSkippedNotFound
+	case object.ExpiresAt != nil && retention.Enabled():
+		return RetentionSkippedExpiring
+	case object.Retention.Active(now) && (!retention.Enabled() || retention.RetainUntil.Before(object.Retention.RetainUntil)):
+		return RetentionSkippedLonger
+	default:
+		return RetentionUpdated
+	}
+}
+
+func (ptx *postgresTransactionAdapter) getObjectsForRetention(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation) (objects []objectForRetention, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	objectKeys, versions := splitVersionedLocations(locations)
+
+	err = withRows(ptx.tx.QueryContext(ctx, `
+		SELECT
+			object_key, version, status, expires_at,
+			retention_mode, retain_until
+		FROM objects
+		WHERE
+			(project_id, bucket_name) = ($1, $2) AND
+			(object_key, version) IN (SELECT unnest($3::BYTEA[]), unnest($4::INT8[]))
+		FOR UPDATE
+	`, projectID, []byte(bucketName), pgutil.ByteaArray(objectKeys), pgutil.Int8Array(versions),
+	))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			var object objectForRetention
+			err := rows.Scan(
+				&object.ObjectKey, &object.Version, &object.Status, &object.ExpiresAt,
+				lockModeWrapper{retentionMode: &object.Retention.Mode}, timeWrapper{&object.Retention.RetainUntil},
+			)
+			if err != nil {
+				return Error.New("unable to scan object: %w", err)
+			}
+			objects = append(objects, object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to query objects: %w", err)
+	}
+	return objects, nil
+}
+
+func (stx *spannerTransactionAdapter) getObjectsForRetention(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation) (objects []objectForRetention, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	objects, err = spannerutil.CollectRows(stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				object_key, version, status, expires_at,
+				retention_mode, retain_until
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				bucket_name = @bucket_name AND
+				STRUCT<ObjectKey BYTES, Version INT64>(object_key, version) IN UNNEST(@locations)
+		`,
+		Params: map[string]interface{}{
+			"project_id":  projectID,
+			"bucket_name": bucketName,
+			"locations":   spannerVersionedLocations(locations),
+		},
+	}), func(row *spanner.Row, object *objectForRetention) error {
+		return Error.Wrap(row.Columns(
+			&object.ObjectKey, &object.Version, &object.Status, &object.ExpiresAt,
+			lockModeWrapper{retentionMode: &object.Retention.Mode}, timeWrapper{&object.Retention.RetainUntil},
+		))
+	})
+	if err != nil {
+		return nil, Error.New("unable to query objects: %w", err)
+	}
+	return objects, nil
+}
+
+func (ptx *postgresTransactionAdapter) setObjectsRetention(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation, retention Retention) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	objectKeys, versions := splitVersionedLocations(locations)
+
+	// the legal hold is kept as is.
+	result, err := ptx.tx.ExecContext(ctx, `
+		UPDATE objects SET
+			retention_mode = NULLIF((COALESCE(retention_mode, 0) & `+legalHoldFlagSQL+`) | $5, 0),
+			retain_until = $6
+		WHERE
+			(project_id, bucket_name) = ($1, $2) AND
+			(object_key, version) IN (SELECT unnest($3::BYTEA[]), unnest($4::INT8[]))
+	`, projectID, []byte(bucketName), pgutil.ByteaArray(objectKeys), pgutil.Int8Array(versions),
+		int64(retention.Mode), timeWrapper{&retention.RetainUntil})
+	if err != nil {
+		return 0, Error.New("unable to set object retention: %w", err)
+	}
+
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return 0, Error.New("unable to get number of affected objects: %w", err)
+	}
+	return affected, nil
+}
+
+func (stx *spannerTransactionAdapter) setObjectsRetention(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation, retention Retention) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// the legal hold is kept as is.
+	affected, err = stx.tx.Update(ctx, spanner.Statement{
+		SQL: `
+			UPDATE objects SET
+				retention_mode = NULLIF((COALESCE(retention_mode, 0) & ` + legalHoldFlagSQL + `) | @retention_mode, 0),
+				retain_until = @retain_until
+			WHERE
+				project_id = @project_id AND
+				bucket_name = @bucket_name AND
+				STRUCT<ObjectKey BYTES, Version INT64>(object_key, version) IN UNNEST(@locations)
+		`,
+		Params: map[string]interface{}{
+			"project_id":     projectID,
+			"bucket_name":    bucketName,
+			"locations":      spannerVersionedLocations(locations),
+			"retention_mode": int64(retention.Mode),
+			"retain_until":   timeWrapper{&retention.RetainUntil},
+		},
+	})
+	if err != nil {
+		return 0, Error.New("unable to set object retention: %w", err)
+	}
+	return affected, nil
+}
+
+func splitVersionedLocations(locations []VersionedLocation) (objectKeys [][]byte, versions []int64) {
+	objectKeys = make([][]byte, len(locations))
+	versions = make([]int64, len(locations))
+	for i, loc := range locations {
+		objectKeys[i] = []byte(loc.ObjectKey)
+		versions[i] = int64(loc.Version)
+	}
+	return objectKeys, versions
+}
+
+func spannerVersionedLocations(locations []VersionedLocation) []spannerVersionedLocation {
+	result := make([]spannerVersionedLocation, len(locations))
+	for i, loc := range locations {
+		result[i] = spannerVersionedLocation{
+			ObjectKey: []byte(loc.ObjectKey),
+			Version:   int64(loc.Version),
+		}
+	}
+	return result
+}
+
+type spannerVersionedLocation struct {
+	ObjectKey []byte
+	Version   int64
+}
+
+// GetObjectRetention returns the retention and the legal hold of a committed object version,
+// without fetching the whole object.
+func (db *DB) GetObjectRetention(ctx context.Context, loc ObjectLocation, version Version) (retention Retention, legalHold bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := loc.Verify(); err != nil {
+		return Retention{}, false, err
+	}
+	if version <= 0 {
+		return Retention{}, false, ErrInvalidRequest.New("Version invalid: %v", version)
+	}
+
+	return db.ChooseAdapter(loc.ProjectID).GetObjectRetention(ctx, loc, version)
+}
+
+// GetObjectRetention returns the retention and the legal hold of a committed object version.
+func (p *PostgresAdapter) GetObjectRetention(ctx context.Context, loc ObjectLocation, version Version) (retention Retention, legalHold bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = p.db.QueryRowContext(ctx, `
+		SELECT retention_mode, retain_until
+		FROM objects
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			status IN `+statusesCommitted+`
+	`, loc.ProjectID, []byte(loc.BucketName), loc.ObjectKey, version).
+		Scan(lockModeWrapper{retentionMode: &retention.Mode, legalHold: &legalHold}, timeWrapper{&retention.RetainUntil})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Retention{}, false, ErrObjectNotFound.Wrap(Error.New("object not found"))
+		}
+		return Retention{}, false, Error.New("unable to query object retention: %w", err)
+	}
+	return retention, legalHold, nil
+}
+
+// GetObjectRetention returns the retention and the legal hold of a committed object version.
+func (s *SpannerAdapter) GetObjectRetention(ctx context.Context, loc ObjectLocation, version Version) (retention Retention, legalHold bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	found := false
+	err = s.client.Single().Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT retention_mode, retain_until
+			FROM objects
+			WHERE
+				(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+				status IN ` + statusesCommitted + `
+		`,
+		Params: map[string]interface{}{
+			"project_id":  loc.ProjectID,
+			"bucket_name": loc.BucketName,
+			"object_key":  loc.ObjectKey,
+			"version":     version,
+		},
+	}).Do(func(row *spanner.Row) error {
+		found = true
+		return Error.Wrap(row.Columns(lockModeWrapper{retentionMode: &retention.Mode, legalHold: &legalHold}, timeWrapper{&retention.RetainUntil}))
+	})
+	if err != nil {
+		return Retention{}, false, Error.New("unable to query object retention: %w", err)
+	}
+	if !found {
+		return Retention{}, false, ErrObjectNotFound.Wrap(Error.New("object not found"))
+	}
+	return retention, legalHold, nil
+}
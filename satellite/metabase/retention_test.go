@@ -0,0 +1,371 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/uuid"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestRetention_Verify(t *testing.T) {
+	now := time.Now()
+
+	for _, test := range []struct {
+		name      string
+		retention metabase.Retention
+		errText   string
+	}{
+		{
+			name: "no retention",
+		},
+		{
+			name:      "RetainUntil without mode",
+			retention: metabase.Retention{RetainUntil: now.Add(time.Hour)},
+			errText:   "RetainUntil must not be set if retention mode is not set",
+		},
+		{
+			name:      "RetainUntil missing",
+			retention: metabase.Retention{Mode: storj.ComplianceMode},
+			errText:   "RetainUntil must be set if retention mode is set",
+		},
+		{
+			name:      "RetainUntil in the past",
+			retention: metabase.Retention{Mode: storj.ComplianceMode, RetainUntil: now.Add(-time.Nanosecond)},
+			errText:   "RetainUntil must be in the future: " + now.Add(-time.Nanosecond).Format(time.RFC3339Nano),
+		},
+		{
+			name:      "RetainUntil now",
+			retention: metabase.Retention{Mode: storj.ComplianceMode, RetainUntil: now},
+			errText:   "RetainUntil must be in the future: " + now.Format(time.RFC3339Nano),
+		},
+		{
+			name:      "RetainUntil in the future",
+			retention: metabase.Retention{Mode: storj.ComplianceMode, RetainUntil: now.Add(time.Nanosecond)},
+		},
+		{
+			name:      "invalid retention mode",
+			retention: metabase.Retention{Mode: 5, RetainUntil: now.Add(time.Hour)},
+			errText:   "invalid retention mode: 5",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.retention.Verify(now)
+			if test.errText == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.EqualError(t, err, metabase.ErrInvalidRequest.New("%s", test.errText).Error())
+		})
+	}
+}
+
+func TestSetObjectsRetention(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		now := time.Now().Truncate(time.Second)
+		retention := metabase.Retention{
+			Mode:        storj.ComplianceMode,
+			RetainUntil: now.Add(time.Hour),
+		}
+
+		for _, test := range []struct {
+			name       string
+			projectID  uuid.UUID
+			bucketName string
+			locations  []metabase.VersionedLocation
+			retention  metabase.Retention
+			errText    string
+		}{
+			{
+				name:       "ProjectID missing",
+				bucketName: obj.BucketName,
+				retention:  retention,
+				errText:    "ProjectID missing",
+			},
+			{
+				name:      "BucketName missing",
+				projectID: obj.ProjectID,
+				retention: retention,
+				errText:   "BucketName missing",
+			},
+			{
+				name:       "ObjectKey missing",
+				projectID:  obj.ProjectID,
+				bucketName: obj.BucketName,
+				locations:  []metabase.VersionedLocation{{Version: 1}},
+				retention:  retention,
+				errText:    "ObjectKey missing",
+			},
+			{
+				name:       "Version invalid",
+				projectID:  obj.ProjectID,
+				bucketName: obj.BucketName,
+				locations:  []metabase.VersionedLocation{{ObjectKey: obj.ObjectKey}},
+				retention:  retention,
+				errText:    "Version invalid: 0",
+			},
+			{
+				name:       "duplicate object version",
+				projectID:  obj.ProjectID,
+				bucketName: obj.BucketName,
+				locations: []metabase.VersionedLocation{
+					{ObjectKey: "a", Version: 1},
+					{ObjectKey: "a", Version: 1},
+				},
+				retention: retention,
+				errText:   `duplicate object version: "a" 1`,
+			},
+			{
+				name:       "RetainUntil missing",
+				projectID:  obj.ProjectID,
+				bucketName: obj.BucketName,
+				retention:  metabase.Retention{Mode: storj.ComplianceMode},
+				errText:    "RetainUntil must be set if retention mode is set",
+			},
+			{
+				name:       "invalid retention mode",
+				projectID:  obj.ProjectID,
+				bucketName: obj.BucketName,
+				retention:  metabase.Retention{Mode: 5, RetainUntil: now},
+				errText:    "invalid retention mode: 5",
+			},
+			{
+				name:       "too many object versions",
+				projectID:  obj.ProjectID,
+				bucketName: obj.BucketName,
+				locations:  make([]metabase.VersionedLocation, metabase.SetObjectsRetentionLimit+1),
+				retention:  retention,
+				errText:    "too many object versions: 1001, maximum allowed: 1000",
+			},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+				metabasetest.SetObjectsRetention{
+					ProjectID:  test.projectID,
+					BucketName: test.bucketName,
+					Locations:  test.locations,
+					Retention:  test.retention,
+					ErrClass:   &metabase.ErrInvalidRequest,
+					ErrText:    test.errText,
+				}.Check(ctx, t, db)
+
+				metabasetest.Verify{}.Check(ctx, t, db)
+			})
+		}
+
+		t.Run("RetainUntil in the past", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+			defer db.TestingSetNow(time.Now)
+
+			object := metabase.RawObject(metabasetest.CreateObject(ctx, t, db, obj, 0))
+			location := metabase.VersionedLocation{ObjectKey: object.ObjectKey, Version: object.Version}
+
+			db.TestingSetNow(func() time.Time { return retention.RetainUntil })
+
+			metabasetest.SetObjectsRetention{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Locations:  []metabase.VersionedLocation{location},
+				Retention:  retention,
+				ErrClass:   &metabase.ErrInvalidRequest,
+				ErrText:    "RetainUntil must be in the future: " + retention.RetainUntil.Format(time.RFC3339Nano),
+			}.Check(ctx, t, db)
+
+			db.TestingSetNow(func() time.Time { return retention.RetainUntil.Add(-time.Second) })
+
+			metabasetest.SetObjectsRetention{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Locations:  []metabase.VersionedLocation{location},
+				Retention:  retention,
+				Result: []metabase.SetObjectsRetentionResult{
+					{VersionedLocation: location, Outcome: metabase.RetentionUpdated},
+				},
+			}.Check(ctx, t, db)
+
+			object.Retention = retention
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{object},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("no locations", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.SetObjectsRetention{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Retention:  retention,
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{}.Check(ctx, t, db)
+		})
+
+		t.Run("set", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			newStream := func(key metabase.ObjectKey) metabase.ObjectStream {
+				stream := metabasetest.RandObjectStream()
+				stream.ProjectID, stream.BucketName, stream.ObjectKey = obj.ProjectID, obj.BucketName, key
+				return stream
+			}
+
+			unversioned := metabase.RawObject(metabasetest.CreateObject(ctx, t, db, newStream("unversioned"), 0))
+			versioned := metabase.RawObject(metabasetest.CreateObjectVersioned(ctx, t, db, newStream("versioned"), 0))
+			pending := metabase.RawObject(metabasetest.CreatePendingObject(ctx, t, db, newStream("pending"), 0))
+			expiring := metabase.RawObject(metabasetest.CreateExpiredObject(ctx, t, db, newStream("expiring"), 0, now.Add(48*time.Hour)))
+
+			location := func(object metabase.RawObject) metabase.VersionedLocation {
+				return metabase.VersionedLocation{ObjectKey: object.ObjectKey, Version: object.Version}
+			}
+			missing := metabase.VersionedLocation{ObjectKey: "missing", Version: 1}
+
+			metabasetest.SetObjectsRetention{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Locations: []metabase.VersionedLocation{
+					location(unversioned), location(versioned), location(pending), location(expiring), missing,
+				},
+				Retention: retention,
+				Result: []metabase.SetObjectsRetentionResult{
+					{VersionedLocation: location(unversioned), Outcome: metabase.RetentionUpdated},
+					{VersionedLocation: location(versioned), Outcome: metabase.RetentionUpdated},
+					{VersionedLocation: location(pending), Outcome: metabase.RetentionSkippedNotFound},
+					{VersionedLocation: location(expiring), Outcome: metabase.RetentionSkippedExpiring},
+					{VersionedLocation: missing, Outcome: metabase.RetentionSkippedNotFound},
+				},
+			}.Check(ctx, t, db)
+
+			unversioned.Retention = retention
+			versioned.Retention = retention
+
+			// an active retention is neither shortened nor removed.
+			longer := metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(2 * time.Hour),
+			}
+			metabasetest.SetObjectsRetention{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Locations:  []metabase.VersionedLocation{location(unversioned)},
+				Retention:  longer,
+				Result: []metabase.SetObjectsRetentionResult{
+					{VersionedLocation: location(unversioned), Outcome: metabase.RetentionUpdated},
+				},
+			}.Check(ctx, t, db)
+
+			unversioned.Retention = longer
+
+			metabasetest.SetObjectsRetention{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Locations:  []metabase.VersionedLocation{location(unversioned), location(versioned)},
+				Retention:  retention,
+				Result: []metabase.SetObjectsRetentionResult{
+					{VersionedLocation: location(unversioned), Outcome: metabase.RetentionSkippedLonger},
+					{VersionedLocation: location(versioned), Outcome: metabase.RetentionUpdated},
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.SetObjectsRetention{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Locations:  []metabase.VersionedLocation{location(unversioned), location(expiring)},
+				Retention:  metabase.Retention{},
+				Result: []metabase.SetObjectsRetentionResult{
+					{VersionedLocation: location(unversioned), Outcome: metabase.RetentionSkippedLonger},
+					{VersionedLocation: location(expiring), Outcome: metabase.RetentionUpdated},
+				},
+			}.Check(ctx, t, db)
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{expiring, pending, unversioned, versioned},
+			}.Check(ctx, t, db)
+		})
+	})
+}
+
+func TestGetObjectRetention(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+		now := time.Now().Truncate(time.Second)
+
+		t.Run("invalid request", func(t *testing.T) {
+			_, _, err := db.GetObjectRetention(ctx, metabase.ObjectLocation{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+			}, obj.Version)
+			require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+			require.ErrorContains(t, err, "ObjectKey missing")
+
+			_, _, err = db.GetObjectRetention(ctx, obj.Location(), 0)
+			require.True(t, metabase.ErrInvalidRequest.Has(err), err)
+			require.ErrorContains(t, err, "Version invalid: 0")
+		})
+
+		t.Run("object missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			_, _, err := db.GetObjectRetention(ctx, obj.Location(), obj.Version)
+			require.True(t, metabase.ErrObjectNotFound.Has(err), err)
+
+			metabasetest.CreatePendingObject(ctx, t, db, obj, 0)
+
+			_, _, err = db.GetObjectRetention(ctx, obj.Location(), obj.Version)
+			require.True(t, metabase.ErrObjectNotFound.Has(err), err)
+		})
+
+		t.Run("get", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			retention := metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(time.Hour),
+			}
+
+			newObject := func(retention metabase.Retention, legalHold bool) metabase.RawObject {
+				return metabase.RawObject{
+					ObjectStream: metabasetest.RandObjectStream(),
+					CreatedAt:    now,
+					Status:       metabase.CommittedVersioned,
+					Encryption:   metabasetest.DefaultEncryption,
+					Retention:    retention,
+					LegalHold:    legalHold,
+				}
+			}
+
+			unprotected := newObject(metabase.Retention{}, false)
+			held := newObject(metabase.Retention{}, true)
+			retained := newObject(retention, false)
+			both := newObject(retention, true)
+
+			objects := []metabase.RawObject{unprotected, held, retained, both}
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, objects))
+
+			for _, object := range objects {
+				gotRetention, gotLegalHold, err := db.GetObjectRetention(ctx, object.Location(), object.Version)
+				require.NoError(t, err)
+				require.Equal(t, object.Retention.Mode, gotRetention.Mode)
+				require.WithinDuration(t, object.Retention.RetainUntil, gotRetention.RetainUntil, time.Second)
+				require.Equal(t, object.LegalHold, gotLegalHold)
+			}
+
+			metabasetest.Verify{
+				Objects: objects,
+			}.Check(ctx, t, db)
+		})
+	})
+}
@@ -0,0 +1,284 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+
+	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil/pgutil"
+	"storj.io/storj/shared/dbutil/spannerutil"
+	"storj.io/storj/shared/tagsql"
+)
+
+const setExpirationBatchSizeLimit = intLimitRange(1000)
+
+// SetExpirationByPrefix contains arguments necessary for setting the expiration
+// of all committed objects under a prefix.
+type SetExpirationByPrefix struct {
+	ProjectID  uuid.UUID
+	BucketName string
+	Prefix     ObjectKey
+	ExpiresAt  time.Time
+
+	// Cursor is the object version after which the update starts. It allows
+	// resuming an interrupted update with the cursor of the returned result.
+	Cursor    IterateCursor
+	BatchSize int
+}
+
+// SetExpirationByPrefixResult is the result of SetExpirationByPrefix.
+type SetExpirationByPrefixResult struct {
+	// Updated is the number of object versions whose expiration was set.
+	Updated int64
+	// SkippedLocked is the number of object versions that were skipped,
+	// because they are under retention or legal hold.
+	SkippedLocked int64
+	// Cursor is the last processed object version.
+	Cursor IterateCursor
+}
+
+type objectForExpiration struct {
+	VersionedLocation
+	Retention Retention
+	LegalHold bool
+}
+
+type setExpirationTransactionAdapter interface {
+	getObjectsForExpiration(ctx context.Context, opts SetExpirationByPrefix) ([]objectForExpiration, error)
+	setObjectsExpiration(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation, expiresAt time.Time) (affected int64, err error)
+}
+
+// Verify verifies set expiration by prefix request fields.
+func (opts *SetExpirationByPrefix) Verify() error {
+	switch {
+	case opts.ProjectID.IsZero():
+		return ErrInvalidRequest.New("ProjectID missing")
+	case opts.BucketName == "":
+		return ErrInvalidRequest.New("BucketName missing")
+	case opts.ExpiresAt.IsZero():
+		return ErrInvalidRequest.New("ExpiresAt missing")
+	case opts.BatchSize < 0:
+		return ErrInvalidRequest.New("BatchSize is negative")
+	}
+	return nil
+}
+
+// SetExpirationByPrefix sets the expiration of all committed object versions under the prefix.
+// Object versions under an active retention or legal hold are skipped and counted in the result.
+// The expiration of the segments isn't changed, they are deleted together with the object.
+//
+// The update performs in batches, each in its own transaction, so in case of error while
+// processing, this method will return the result up to the moment when the error occurred.
+// The update can be resumed by using the returned cursor.
+func (db *DB) SetExpirationByPrefix(ctx context.Context, opts SetExpirationByPrefix) (result SetExpirationByPrefixResult, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return SetExpirationByPrefixResult{}, err
+	}
+
+	setExpirationBatchSizeLimit.Ensure(&opts.BatchSize)
+
+	if LessObjectKey(opts.Cursor.Key, opts.Prefix) {
+		opts.Cursor = IterateCursor{Key: opts.Prefix}
+	}
+	result.Cursor = opts.Cursor
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		var batchSize int
+		var updated, skipped int64
+		err := db.ChooseAdapter(opts.ProjectID).WithTx(ctx, func(ctx context.Context, adapter TransactionAdapter) error {
+			updated, skipped = 0, 0
+
+			objects, err := adapter.getObjectsForExpiration(ctx, opts)
+			if err != nil {
+				return err
+			}
+			batchSize = len(objects)
+
+			now := db.nowFn()
+			var update []VersionedLocation
+			for _, object := range objects {
+				if object.Retention.Active(now) || object.LegalHold {
+					skipped++
+					continue
+				}
+				update = append(update, object.VersionedLocation)
+			}
+
+			if len(update) > 0 {
+				updated, err = adapter.setObjectsExpiration(ctx, opts.ProjectID, opts.BucketName, update, opts.ExpiresAt)
+				if err != nil {
+					return err
+				}
+				if updated != int64(len(update)) {
+					return ErrObjectNotFound.New("objects were changed during update")
+				}
+			}
+
+			if batchSize > 0 {
+				last := objects[batchSize-1]
+				opts.Cursor = IterateCursor{Key: last.ObjectKey, Version: last.Version}
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+
+		mon.Meter("object_expiration_set").Mark64(updated)
+		mon.Meter("object_expiration_skipped_locked").Mark64(skipped)
+
+		result.Updated += updated
+		result.SkippedLocked += skipped
+		result.Cursor = opts.Cursor
+
+		if batchSize < opts.BatchSize {
+			return result, nil
+		}
+	}
+}
+
+func (ptx *postgresTransactionAdapter) getObjectsForExpiration(ctx context.Context, opts SetExpirationByPrefix) (objects []objectForExpiration, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	prefixLimit := ""
+	args := []any{opts.ProjectID, []byte(opts.BucketName), opts.Cursor.Key, opts.Cursor.Version, opts.BatchSize}
+	if opts.Prefix != "" {
+		prefixLimit = "object_key < $6 AND"
+		args = append(args, PrefixLimit(opts.Prefix))
+	}
+
+	err = withRows(ptx.tx.QueryContext(ctx, `
+		SELECT
+			object_key, version,
+			retention_mode, retain_until
+		FROM objects
+		WHERE
+			(project_id, bucket_name) = ($1, $2) AND
+			(object_key, version) > ($3, $4) AND
+			`+prefixLimit+`
+			status IN `+statusesCommitted+`
+		ORDER BY project_id, bucket_name, object_key, version
+		LIMIT $5
+		FOR UPDATE
+	`, args...))(func(rows tagsql.Rows) error {
+		for rows.Next() {
+			var object objectForExpiration
+			err := rows.Scan(
+				&object.ObjectKey, &object.Version,
+				lockModeWrapper{retentionMode: &object.Retention.Mode, legalHold: &object.LegalHold}, timeWrapper{&object.Retention.RetainUntil},
+			)
+			if err != nil {
+				return Error.New("unable to scan object: %w", err)
+			}
+			objects = append(objects, object)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Error.New("unable to query objects: %w", err)
+	}
+	return objects, nil
+}
+
+func (stx *spannerTransactionAdapter) getObjectsForExpiration(ctx context.Context, opts SetExpirationByPrefix) (objects []objectForExpiration, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	prefixLimit := ""
+	params := map[string]interface{}{
+		"project_id":  opts.ProjectID,
+		"bucket_name": opts.BucketName,
+		"object_key":  opts.Cursor.Key,
+		"version":     opts.Cursor.Version,
+		"batch_size":  int64(opts.BatchSize),
+	}
+	if opts.Prefix != "" {
+		prefixLimit = "object_key < @prefix_limit AND"
+		params["prefix_limit"] = PrefixLimit(opts.Prefix)
+	}
+
+	objects, err = spannerutil.CollectRows(stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT
+				object_key, version,
+				retention_mode, retain_until
+			FROM objects
+			WHERE
+				project_id = @project_id AND
+				bucket_name = @bucket_name AND
+				(object_key > @object_key OR (object_key = @object_key AND version > @version)) AND
+				` + prefixLimit + `
+				status IN ` + statusesCommitted + `
+			ORDER BY project_id, bucket_name, object_key, version
+			LIMIT @batch_size
+		`,
+		Params: params,
+	}), func(row *spanner.Row, object *objectForExpiration) error {
+		return Error.Wrap(row.Columns(
+			&object.ObjectKey, &object.Version,
+			lockModeWrapper{retentionMode: &object.Retention.Mode, legalHold: &object.LegalHold}, timeWrapper{&object.Retention.RetainUntil},
+		))
+	})
+	if err != nil {
+		return nil, Error.New("unable to query objects: %w", err)
+	}
+	return objects, nil
+}
+
+func (ptx *postgresTransactionAdapter) setObjectsExpiration(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation, expiresAt time.Time) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	objectKeys, versions := splitVersionedLocations(locations)
+
+	result, err := ptx.tx.ExecContext(ctx, `
+		UPDATE objects SET
+			expires_at = $5
+		WHERE
+			(project_id, bucket_name) = ($1, $2) AND
+			(object_key, version) IN (SELECT unnest($3::BYTEA[]), unnest($4::INT8[]))
+	`, projectID, []byte(bucketName), pgutil.ByteaArray(objectKeys), pgutil.Int8Array(versions), expiresAt)
+	if err != nil {
+		return 0, Error.New("unable to set object expiration: %w", err)
+	}
+
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return 0, Error.New("unable to get number of affected objects: %w", err)
+	}
+	return affected, nil
+}
+
+func (stx *spannerTransactionAdapter) setObjectsExpiration(ctx context.Context, projectID uuid.UUID, bucketName string, locations []VersionedLocation, expiresAt time.Time) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	affected, err = stx.tx.Update(ctx, spanner.Statement{
+		SQL: `
+			UPDATE objects SET
+				expires_at = @expires_at
+			WHERE
+				project_id = @project_id AND
+				bucket_name = @bucket_name AND
+				STRUCT<ObjectKey BYTES, Version INT64>(object_key, version) IN UNNEST(@locations)
+		`,
+		Params: map[string]interface{}{
+			"project_id":  projectID,
+			"bucket_name": bucketName,
+			"locations":   spannerVersionedLocations(locations),
+			"expires_at":  expiresAt,
+		},
+	})
+	if err != nil {
+		return 0, Error.New("unable to set object expiration: %w", err)
+	}
+	return affected, nil
+}
@@ -0,0 +1,134 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestSetExpirationByPrefix(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+		now := time.Now()
+		expiresAt := now.Add(24 * time.Hour)
+
+		t.Run("invalid request", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			for _, test := range []struct {
+				opts    metabase.SetExpirationByPrefix
+				errText string
+			}{
+				{metabase.SetExpirationByPrefix{}, "ProjectID missing"},
+				{metabase.SetExpirationByPrefix{ProjectID: obj.ProjectID}, "BucketName missing"},
+				{metabase.SetExpirationByPrefix{ProjectID: obj.ProjectID, BucketName: obj.BucketName}, "ExpiresAt missing"},
+				{metabase.SetExpirationByPrefix{ProjectID: obj.ProjectID, BucketName: obj.BucketName, ExpiresAt: expiresAt, BatchSize: -1}, "BatchSize is negative"},
+			} {
+				_, err := db.SetExpirationByPrefix(ctx, test.opts)
+				require.True(t, metabase.ErrInvalidRequest.Has(err), test.errText)
+				require.ErrorContains(t, err, test.errText)
+			}
+		})
+
+		t.Run("empty bucket", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			result, err := db.SetExpirationByPrefix(ctx, metabase.SetExpirationByPrefix{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Prefix:     "a/",
+				ExpiresAt:  expiresAt,
+			})
+			require.NoError(t, err)
+			require.Zero(t, result.Updated)
+			require.Zero(t, result.SkippedLocked)
+		})
+
+		t.Run("set expiration", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			newObject := func(key metabase.ObjectKey, status metabase.ObjectStatus) metabase.RawObject {
+				stream := obj
+				stream.ObjectKey = key
+				stream.StreamID = testrand.UUID()
+				return metabase.RawObject{
+					ObjectStream: stream,
+					CreatedAt:    now,
+					Status:       status,
+					Encryption:   metabasetest.DefaultEncryption,
+				}
+			}
+
+			a1 := newObject("a/1", metabase.CommittedUnversioned)
+			a2 := newObject("a/2", metabase.CommittedVersioned)
+			a3 := newObject("a/3", metabase.CommittedVersioned)
+			a5 := newObject("a/5", metabase.CommittedVersioned)
+
+			retained := newObject("a/4", metabase.CommittedVersioned)
+			retained.Retention = metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(time.Hour),
+			}
+			legalHold := newObject("a/6", metabase.CommittedVersioned)
+			legalHold.LegalHold = true
+
+			pending := newObject("a/7", metabase.Pending)
+			outside := newObject("b/1", metabase.CommittedVersioned)
+			before := newObject("a", metabase.CommittedVersioned)
+
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{
+				a1, a2, a3, retained, a5, legalHold, pending, outside, before,
+			}))
+
+			// resume after a/2.
+			result, err := db.SetExpirationByPrefix(ctx, metabase.SetExpirationByPrefix{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Prefix:     "a/",
+				ExpiresAt:  expiresAt,
+				Cursor:     metabase.IterateCursor{Key: a2.ObjectKey, Version: a2.Version},
+				BatchSize:  2,
+			})
+			require.NoError(t, err)
+			require.Equal(t, metabase.SetExpirationByPrefixResult{
+				Updated:       2,
+				SkippedLocked: 2,
+				Cursor:        metabase.IterateCursor{Key: legalHold.ObjectKey, Version: legalHold.Version},
+			}, result)
+
+			result, err = db.SetExpirationByPrefix(ctx, metabase.SetExpirationByPrefix{
+				ProjectID:  obj.ProjectID,
+				BucketName: obj.BucketName,
+				Prefix:     "a/",
+				ExpiresAt:  expiresAt,
+				BatchSize:  2,
+			})
+			require.NoError(t, err)
+			require.Equal(t, metabase.SetExpirationByPrefixResult{
+				Updated:       4,
+				SkippedLocked: 2,
+				Cursor:        metabase.IterateCursor{Key: legalHold.ObjectKey, Version: legalHold.Version},
+			}, result)
+
+			for _, object := range []*metabase.RawObject{&a1, &a2, &a3, &a5} {
+				object.ExpiresAt = &expiresAt
+			}
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{
+					a1, a2, a3, retained, a5, legalHold, pending, outside, before,
+				},
+			}.Check(ctx, t, db)
+		})
+	})
+}
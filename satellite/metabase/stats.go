@@ -7,7 +7,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"sync"
 	"time"
+
+	"github.com/spacemonkeygo/monkit/v3"
 )
 
 const statsUpToDateThreshold = 8 * time.Hour
@@ -139,3 +142,108 @@ func (c *CockroachAdapter) UpdateTableStats(ctx context.Context) error {
 func (s *SpannerAdapter) UpdateTableStats(ctx context.Context) error {
 	return nil
 }
+
+// Stats contains connection pool statistics of the metabase adapters.
+type Stats struct {
+	Adapters []PoolStats
+}
+
+// PoolStats contains connection pool statistics of a single adapter.
+//
+// For Postgres and Cockroach the values describe the database/sql connection pool.
+// For Spanner only MaxOpen is set to the configured limit of the session pool,
+// because the spanner client doesn't expose the state of its session pool.
+type PoolStats struct {
+	// Adapter is the name of the adapter.
+	Adapter string
+
+	// MaxOpen is the maximum number of open connections or sessions, zero means unlimited.
+	MaxOpen int
+	// Open is the number of established connections or sessions.
+	Open int
+	// InUse is the number of connections or sessions currently in use.
+	InUse int
+	// Idle is the number of idle connections or sessions.
+	Idle int
+
+	// WaitCount is the total number of times a connection had to be waited for.
+	WaitCount int64
+	// WaitDuration is the total time spent waiting for a connection.
+	WaitDuration time.Duration
+}
+
+// Stats returns connection pool statistics for each of the adapters.
+func (db *DB) Stats() Stats {
+	var stats Stats
+	for _, adapter := range db.adapters {
+		stats.Adapters = append(stats.Adapters, adapter.PoolStats())
+	}
+	return stats
+}
+
+// PoolStats implements Adapter.
+func (p *PostgresAdapter) PoolStats() PoolStats {
+	stats := p.db.Stats()
+	return PoolStats{
+		Adapter:      p.Name(),
+		MaxOpen:      stats.MaxOpenConnections,
+		Open:         stats.OpenConnections,
+		InUse:        stats.InUse,
+		Idle:         stats.Idle,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: stats.WaitDuration,
+	}
+}
+
+// PoolStats implements Adapter.
+func (c *CockroachAdapter) PoolStats() PoolStats {
+	stats := c.PostgresAdapter.PoolStats()
+	stats.Adapter = c.Name()
+	return stats
+}
+
+// PoolStats implements Adapter.
+//
+// The spanner client doesn't expose the state of its session pool, hence only
+// the configured limit is reported.
+func (s *SpannerAdapter) PoolStats() PoolStats {
+	return PoolStats{
+		Adapter: s.Name(),
+		MaxOpen: int(s.sessionPool.MaxOpened),
+	}
+}
+
+// poolStatsDBs contains the open databases, whose pool stats are exported to monkit.
+var poolStatsDBs = struct {
+	once sync.Once
+	mu   sync.Mutex
+	dbs  map[*DB]struct{}
+}{dbs: map[*DB]struct{}{}}
+
+// registerPoolStats exports the pool stats of db as metabase_pool_stats until it's
+// unregistered. The monkit source is chained only once, because it can't be removed.
+func registerPoolStats(db *DB) {
+	poolStatsDBs.once.Do(func() {
+		mon.Chain(monkit.StatSourceFunc(func(cb func(key monkit.SeriesKey, field string, val float64)) {
+			poolStatsDBs.mu.Lock()
+			defer poolStatsDBs.mu.Unlock()
+
+			for db := range poolStatsDBs.dbs {
+				for _, stats := range db.Stats().Adapters {
+					monkit.StatSourceFromStruct(monkit.NewSeriesKey("metabase_pool_stats").WithTag("adapter", stats.Adapter), stats).Stats(cb)
+				}
+			}
+		}))
+	})
+
+	poolStatsDBs.mu.Lock()
+	defer poolStatsDBs.mu.Unlock()
+	poolStatsDBs.dbs[db] = struct{}{}
+}
+
+// unregisterPoolStats stops exporting the pool stats of db.
+func unregisterPoolStats(db *DB) {
+	poolStatsDBs.mu.Lock()
+	defer poolStatsDBs.mu.Unlock()
+	delete(poolStatsDBs.dbs, db)
+}
@@ -115,3 +115,24 @@ func TestGetTableStats(t *testing.T) {
 		}
 	})
 }
+
+func TestStats(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		require.NoError(t, db.Ping(ctx))
+
+		stats := db.Stats()
+		require.Len(t, stats.Adapters, 1)
+
+		pool := stats.Adapters[0]
+		switch db.Implementation() {
+		case dbutil.Postgres:
+			require.Equal(t, "postgres", pool.Adapter)
+		case dbutil.Cockroach:
+			require.Equal(t, "cockroach", pool.Adapter)
+		case dbutil.Spanner:
+			require.Equal(t, "spanner", pool.Adapter)
+		}
+		require.GreaterOrEqual(t, pool.Open, pool.InUse+pool.Idle)
+		require.GreaterOrEqual(t, pool.MaxOpen, 0)
+	})
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+// TransitionToExpiry contains arguments necessary for replacing the lapsed retention
+// of an object version with an expiration.
+type TransitionToExpiry struct {
+	ObjectStream
+	ExpiresAt time.Time
+}
+
+type transitionToExpiryTransactionAdapter interface {
+	getObjectLockForUpdate(ctx context.Context, opts ObjectStream) (retention Retention, legalHold bool, err error)
+	transitionToExpiry(ctx context.Context, opts TransitionToExpiry) (affected int64, err error)
+}
+
+// Verify verifies transition to expiry request fields.
+func (opts *TransitionToExpiry) Verify() error {
+	if err := opts.ObjectStream.Verify(); err != nil {
+		return err
+	}
+	if opts.ExpiresAt.IsZero() {
+		return ErrInvalidRequest.New("ExpiresAt missing")
+	}
+	return nil
+}
+
+// TransitionToExpiry clears the retention of a committed object version and sets its
+// expiration in a single transaction, so that there's no window in which the object
+// version is neither protected nor expiring. It fails with ErrObjectLock when the
+// retention of the object version is still active or it's under legal hold.
+func (db *DB) TransitionToExpiry(ctx context.Context, opts TransitionToExpiry) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return err
+	}
+
+	err = db.ChooseAdapter(opts.ProjectID).WithTx(ctx, func(ctx context.Context, adapter TransactionAdapter) error {
+		retention, legalHold, err := adapter.getObjectLockForUpdate(ctx, opts.ObjectStream)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case legalHold:
+			return ErrObjectLock.New("object is under legal hold")
+		case retention.Active(db.nowFn()):
+			return ErrObjectLock.New("object is under retention until %v", retention.RetainUntil)
+		}
+
+		affected, err := adapter.transitionToExpiry(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if affected != 1 {
+			return ErrObjectNotFound.New("object was changed during update")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	mon.Meter("object_transition_to_expiry").Mark(1)
+
+	return nil
+}
+
+func (ptx *postgresTransactionAdapter) getObjectLockForUpdate(ctx context.Context, opts ObjectStream) (retention Retention, legalHold bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	err = ptx.tx.QueryRowContext(ctx, `
+		SELECT retention_mode, retain_until
+		FROM objects
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			stream_id = $5 AND
+			status IN `+statusesCommitted+`
+		FOR UPDATE
+	`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, opts.StreamID).
+		Scan(lockModeWrapper{retentionMode: &retention.Mode, legalHold: &legalHold}, timeWrapper{&retention.RetainUntil})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Retention{}, false, ErrObjectNotFound.Wrap(Error.New("object not found"))
+		}
+		return Retention{}, false, Error.New("unable to query object retention: %w", err)
+	}
+	return retention, legalHold, nil
+}
+
+func (stx *spannerTransactionAdapter) getObjectLockForUpdate(ctx context.Context, opts ObjectStream) (retention Retention, legalHold bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	found := false
+	err = stx.tx.Query(ctx, spanner.Statement{
+		SQL: `
+			SELECT retention_mode, retain_until
+			FROM objects
+			WHERE
+				(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+				stream_id = @stream_id AND
+				status IN ` + statusesCommitted + `
+		`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.BucketName,
+			"object_key":  opts.ObjectKey,
+			"version":     opts.Version,
+			"stream_id":   opts.StreamID,
+		},
+	}).Do(func(row *spanner.Row) error {
+		found = true
+		return Error.Wrap(row.Columns(lockModeWrapper{retentionMode: &retention.Mode, legalHold: &legalHold}, timeWrapper{&retention.RetainUntil}))
+	})
+	if err != nil {
+		return Retention{}, false, Error.New("unable to query object retention: %w", err)
+	}
+	if !found {
+		return Retention{}, false, ErrObjectNotFound.Wrap(Error.New("object not found"))
+	}
+	return retention, legalHold, nil
+}
+
+func (ptx *postgresTransactionAdapter) transitionToExpiry(ctx context.Context, opts TransitionToExpiry) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := ptx.tx.ExecContext(ctx, `
+		UPDATE objects SET
+			retention_mode = NULL,
+			retain_until = NULL,
+			expires_at = $6
+		WHERE
+			(project_id, bucket_name, object_key, version) = ($1, $2, $3, $4) AND
+			stream_id = $5 AND
+			status IN `+statusesCommitted+`
+	`, opts.ProjectID, []byte(opts.BucketName), opts.ObjectKey, opts.Version, opts.StreamID, opts.ExpiresAt)
+	if err != nil {
+		return 0, Error.New("unable to transition object to expiry: %w", err)
+	}
+
+	affected, err = result.RowsAffected()
+	if err != nil {
+		return 0, Error.New("unable to get number of affected objects: %w", err)
+	}
+	return affected, nil
+}
+
+func (stx *spannerTransactionAdapter) transitionToExpiry(ctx context.Context, opts TransitionToExpiry) (affected int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	affected, err = stx.tx.Update(ctx, spanner.Statement{
+		SQL: `
+			UPDATE objects SET
+				retention_mode = NULL,
+				retain_until = NULL,
+				expires_at = @expires_at
+			WHERE
+				(project_id, bucket_name, object_key, version) = (@project_id, @bucket_name, @object_key, @version) AND
+				stream_id = @stream_id AND
+				status IN ` + statusesCommitted + `
+		`,
+		Params: map[string]interface{}{
+			"project_id":  opts.ProjectID,
+			"bucket_name": opts.BucketName,
+			"object_key":  opts.ObjectKey,
+			"version":     opts.Version,
+			"stream_id":   opts.StreamID,
+			"expires_at":  opts.ExpiresAt,
+		},
+	})
+	if err != nil {
+		return 0, Error.New("unable to transition object to expiry: %w", err)
+	}
+	return affected, nil
+}
@@ -0,0 +1,137 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package metabase_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/satellite/metabase"
+	"storj.io/storj/satellite/metabase/metabasetest"
+)
+
+func TestTransitionToExpiry(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		now := time.Now()
+		expiresAt := now.Add(time.Hour)
+
+		newObject := func(retention metabase.Retention, legalHold bool) metabase.RawObject {
+			return metabase.RawObject{
+				ObjectStream: metabasetest.RandObjectStream(),
+				CreatedAt:    now,
+				Status:       metabase.CommittedVersioned,
+				Encryption:   metabasetest.DefaultEncryption,
+				Retention:    retention,
+				LegalHold:    legalHold,
+			}
+		}
+
+		t.Run("invalid request", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			for _, test := range metabasetest.InvalidObjectStreams(metabasetest.RandObjectStream()) {
+				err := db.TransitionToExpiry(ctx, metabase.TransitionToExpiry{
+					ObjectStream: test.ObjectStream,
+					ExpiresAt:    expiresAt,
+				})
+				require.True(t, test.ErrClass.Has(err), test.Name)
+				require.EqualError(t, err, test.ErrClass.New(test.ErrText).Error(), test.Name)
+			}
+
+			err := db.TransitionToExpiry(ctx, metabase.TransitionToExpiry{
+				ObjectStream: metabasetest.RandObjectStream(),
+			})
+			require.True(t, metabase.ErrInvalidRequest.Has(err))
+			require.ErrorContains(t, err, "ExpiresAt missing")
+		})
+
+		t.Run("missing object", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			err := db.TransitionToExpiry(ctx, metabase.TransitionToExpiry{
+				ObjectStream: metabasetest.RandObjectStream(),
+				ExpiresAt:    expiresAt,
+			})
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+
+			pending := metabasetest.CreatePendingObject(ctx, t, db, metabasetest.RandObjectStream(), 0)
+			err = db.TransitionToExpiry(ctx, metabase.TransitionToExpiry{
+				ObjectStream: pending.ObjectStream,
+				ExpiresAt:    expiresAt,
+			})
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+
+			object := metabasetest.CreateObject(ctx, t, db, metabasetest.RandObjectStream(), 0)
+			stream := object.ObjectStream
+			stream.StreamID = testrand.UUID()
+			err = db.TransitionToExpiry(ctx, metabase.TransitionToExpiry{
+				ObjectStream: stream,
+				ExpiresAt:    expiresAt,
+			})
+			require.True(t, metabase.ErrObjectNotFound.Has(err))
+		})
+
+		t.Run("protected", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			retained := newObject(metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(time.Hour),
+			}, false)
+			legalHold := newObject(metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(-time.Hour),
+			}, true)
+
+			objects := []metabase.RawObject{retained, legalHold}
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, objects))
+
+			for _, object := range objects {
+				err := db.TransitionToExpiry(ctx, metabase.TransitionToExpiry{
+					ObjectStream: object.ObjectStream,
+					ExpiresAt:    expiresAt,
+				})
+				require.True(t, metabase.ErrObjectLock.Has(err))
+			}
+
+			metabasetest.Verify{Objects: objects}.Check(ctx, t, db)
+		})
+
+		t.Run("transition", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			lapsed := newObject(metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(-time.Hour),
+			}, false)
+			unprotected := newObject(metabase.Retention{}, false)
+			other := newObject(metabase.Retention{
+				Mode:        storj.ComplianceMode,
+				RetainUntil: now.Add(-time.Hour),
+			}, false)
+
+			require.NoError(t, db.TestingBatchInsertObjects(ctx, []metabase.RawObject{lapsed, unprotected, other}))
+
+			for _, object := range []*metabase.RawObject{&lapsed, &unprotected} {
+				err := db.TransitionToExpiry(ctx, metabase.TransitionToExpiry{
+					ObjectStream: object.ObjectStream,
+					ExpiresAt:    expiresAt,
+				})
+				require.NoError(t, err)
+
+				object.Retention = metabase.Retention{}
+				object.ExpiresAt = &expiresAt
+			}
+
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{lapsed, unprotected, other},
+			}.Check(ctx, t, db)
+		})
+	})
+}
@@ -5,6 +5,7 @@ package metabase
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"time"
@@ -15,6 +16,7 @@ import (
 
 	"storj.io/common/storj"
 	"storj.io/common/uuid"
+	"storj.io/storj/shared/dbutil"
 	"storj.io/storj/shared/dbutil/spannerutil"
 )
 
@@ -31,6 +33,12 @@ type UpdateSegmentPieces struct {
 	Position SegmentPosition
 
 	OldPieces Pieces
+	// OldPiecesHash is compared against the hash of the current remote_alias_pieces
+	// instead of comparing full OldPieces (optional). See DB.PiecesHash.
+	//
+	// The hash isn't stored, the database computes it from remote_alias_pieces for
+	// every update, hence it only saves sending OldPieces with the request.
+	OldPiecesHash []byte
 
 	NewRedundancy storj.RedundancyScheme
 	NewPieces     Pieces
@@ -47,7 +55,11 @@ func (db *DB) UpdateSegmentPieces(ctx context.Context, opts UpdateSegmentPieces)
 		return ErrInvalidRequest.New("StreamID missing")
 	}
 
-	if err := opts.OldPieces.Verify(); err != nil {
+	if opts.OldPiecesHash != nil {
+		if len(opts.OldPiecesHash) != sha256.Size {
+			return ErrInvalidRequest.New("OldPiecesHash has invalid length: %d", len(opts.OldPiecesHash))
+		}
+	} else if err := opts.OldPieces.Verify(); err != nil {
 		if ErrInvalidRequest.Has(err) {
 			return ErrInvalidRequest.New("OldPieces: %v", errs.Unwrap(err))
 		}
@@ -71,9 +83,12 @@ func (db *DB) UpdateSegmentPieces(ctx context.Context, opts UpdateSegmentPieces)
 		return err
 	}
 
-	oldPieces, err := db.aliasCache.EnsurePiecesToAliases(ctx, opts.OldPieces)
-	if err != nil {
-		return Error.New("unable to convert pieces to aliases: %w", err)
+	var oldPieces AliasPieces
+	if opts.OldPiecesHash == nil {
+		oldPieces, err = db.aliasCache.EnsurePiecesToAliases(ctx, opts.OldPieces)
+		if err != nil {
+			return Error.New("unable to convert pieces to aliases: %w", err)
+		}
 	}
 
 	newPieces, err := db.aliasCache.EnsurePiecesToAliases(ctx, opts.NewPieces)
@@ -108,29 +123,54 @@ func (db *DB) UpdateSegmentPieces(ctx context.Context, opts UpdateSegmentPieces)
 	return nil
 }
 
+// PiecesHash returns the hash of pieces, which can be used as UpdateSegmentPieces.OldPiecesHash.
+// It's the SHA-256 of the encoded alias pieces, which every adapter computes the same way
+// in the update query.
+func (db *DB) PiecesHash(ctx context.Context, pieces Pieces) (_ []byte, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	aliasPieces, err := db.aliasCache.EnsurePiecesToAliases(ctx, pieces)
+	if err != nil {
+		return nil, Error.New("unable to convert pieces to aliases: %w", err)
+	}
+	return aliasPieces.Hash()
+}
+
 // UpdateSegmentPieces updates pieces for specified segment, if pieces matches oldPieces.
 func (p *PostgresAdapter) UpdateSegmentPieces(ctx context.Context, opts UpdateSegmentPieces, oldPieces, newPieces AliasPieces) (resultPieces AliasPieces, err error) {
 	updateRepairAt := !opts.NewRepairedAt.IsZero()
 
+	oldPiecesMatch := `remote_alias_pieces = $3`
+	var oldPiecesArg any = oldPieces
+	if opts.OldPiecesHash != nil {
+		oldPiecesArg = opts.OldPiecesHash
+		if p.impl == dbutil.Cockroach {
+			// CockroachDB returns sha256 as a hex encoded string.
+			oldPiecesMatch = `decode(sha256(remote_alias_pieces), 'hex') = $3`
+		} else {
+			oldPiecesMatch = `sha256(remote_alias_pieces) = $3`
+		}
+	}
+
 	err = p.db.QueryRowContext(ctx, `
 		UPDATE segments SET
 			remote_alias_pieces = CASE
-				WHEN remote_alias_pieces = $3 THEN $4
+				WHEN `+oldPiecesMatch+` THEN $4
 				ELSE remote_alias_pieces
 			END,
 			redundancy = CASE
-				WHEN remote_alias_pieces = $3 THEN $5
+				WHEN `+oldPiecesMatch+` THEN $5
 				ELSE redundancy
 			END,
 			repaired_at = CASE
-				WHEN remote_alias_pieces = $3 AND $7 = true THEN $6
+				WHEN `+oldPiecesMatch+` AND $7 = true THEN $6
 				ELSE repaired_at
 			END
 		WHERE
 			stream_id     = $1 AND
 			position      = $2
 		RETURNING remote_alias_pieces
-		`, opts.StreamID, opts.Position, oldPieces, newPieces, redundancyScheme{&opts.NewRedundancy}, opts.NewRepairedAt, updateRepairAt).
+		`, opts.StreamID, opts.Position, oldPiecesArg, newPieces, redundancyScheme{&opts.NewRedundancy}, opts.NewRepairedAt, updateRepairAt).
 		Scan(&resultPieces)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -145,20 +185,27 @@ func (p *PostgresAdapter) UpdateSegmentPieces(ctx context.Context, opts UpdateSe
 func (s *SpannerAdapter) UpdateSegmentPieces(ctx context.Context, opts UpdateSegmentPieces, oldPieces, newPieces AliasPieces) (resultPieces AliasPieces, err error) {
 	updateRepairAt := !opts.NewRepairedAt.IsZero()
 
+	oldPiecesMatch := `remote_alias_pieces = @old_pieces`
+	var oldPiecesArg any = oldPieces
+	if opts.OldPiecesHash != nil {
+		oldPiecesMatch = `SHA256(remote_alias_pieces) = @old_pieces`
+		oldPiecesArg = opts.OldPiecesHash
+	}
+
 	_, err = s.client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
 		resultPieces, err = spannerutil.CollectRow(tx.Query(ctx, spanner.Statement{
 			SQL: `
 				UPDATE segments SET
 					remote_alias_pieces = CASE
-						WHEN remote_alias_pieces = @old_pieces THEN @new_pieces
+						WHEN ` + oldPiecesMatch + ` THEN @new_pieces
 						ELSE remote_alias_pieces
 					END,
 					redundancy = CASE
-						WHEN remote_alias_pieces = @old_pieces THEN @redundancy
+						WHEN ` + oldPiecesMatch + ` THEN @redundancy
 						ELSE redundancy
 					END,
 					repaired_at = CASE
-						WHEN remote_alias_pieces = @old_pieces AND @update_repaired_at = true THEN @new_repaired_at
+						WHEN ` + oldPiecesMatch + ` AND @update_repaired_at = true THEN @new_repaired_at
 						ELSE repaired_at
 					END
 				WHERE
@@ -169,7 +216,7 @@ func (s *SpannerAdapter) UpdateSegmentPieces(ctx context.Context, opts UpdateSeg
 			Params: map[string]any{
 				"stream_id":          opts.StreamID,
 				"position":           opts.Position,
-				"old_pieces":         oldPieces,
+				"old_pieces":         oldPiecesArg,
 				"new_pieces":         newPieces,
 				"redundancy":         redundancyScheme{&opts.NewRedundancy},
 				"new_repaired_at":    opts.NewRepairedAt,
@@ -197,3 +244,157 @@ func (s *SpannerAdapter) UpdateSegmentPieces(ctx context.Context, opts UpdateSeg
 	}
 	return resultPieces, nil
 }
+
+// UpdateSegmentRedundancy contains arguments necessary for updating the redundancy
+// scheme of a segment, without replacing its pieces.
+type UpdateSegmentRedundancy struct {
+	// Name of the database adapter to use for this segment. If empty (""), check all adapters
+	// until the segment is found.
+	DBAdapterName string
+
+	StreamID uuid.UUID
+	Position SegmentPosition
+
+	// Pieces must match the current pieces of the segment, they are not modified.
+	Pieces Pieces
+
+	NewRedundancy storj.RedundancyScheme
+}
+
+// Verify verifies update segment redundancy request fields.
+func (opts *UpdateSegmentRedundancy) Verify() error {
+	if opts.StreamID.IsZero() {
+		return ErrInvalidRequest.New("StreamID missing")
+	}
+
+	if err := opts.Pieces.Verify(); err != nil {
+		if ErrInvalidRequest.Has(err) {
+			return ErrInvalidRequest.New("Pieces: %v", errs.Unwrap(err))
+		}
+		return err
+	}
+
+	if opts.NewRedundancy.IsZero() {
+		return ErrInvalidRequest.New("NewRedundancy zero")
+	}
+
+	// the pieces are kept, so they must be valid for the new redundancy scheme.
+	if len(opts.Pieces) < int(opts.NewRedundancy.RepairShares) {
+		return ErrInvalidRequest.New("number of pieces is less than new redundancy repair shares value")
+	}
+	for _, piece := range opts.Pieces {
+		if int(piece.Number) >= int(opts.NewRedundancy.TotalShares) {
+			return ErrInvalidRequest.New("piece number %d exceeds new redundancy total shares value", piece.Number)
+		}
+	}
+
+	return nil
+}
+
+// UpdateSegmentRedundancy updates the redundancy scheme of the specified segment.
+// If provided pieces won't match current database state update will fail.
+func (db *DB) UpdateSegmentRedundancy(ctx context.Context, opts UpdateSegmentRedundancy) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err := opts.Verify(); err != nil {
+		return err
+	}
+
+	pieces, err := db.aliasCache.EnsurePiecesToAliases(ctx, opts.Pieces)
+	if err != nil {
+		return Error.New("unable to convert pieces to aliases: %w", err)
+	}
+
+	var resultPieces AliasPieces
+	for _, adapter := range db.adapters {
+		if opts.DBAdapterName == "" || opts.DBAdapterName == adapter.Name() {
+			resultPieces, err = adapter.UpdateSegmentRedundancy(ctx, opts, pieces)
+			if err != nil {
+				if ErrSegmentNotFound.Has(err) {
+					continue
+				}
+				return err
+			}
+			// segment was found
+			break
+		}
+	}
+	if resultPieces == nil {
+		return ErrSegmentNotFound.New("segment missing")
+	}
+
+	if !EqualAliasPieces(pieces, resultPieces) {
+		return ErrValueChanged.New("segment remote_alias_pieces field was changed")
+	}
+
+	mon.Meter("segment_redundancy_update").Mark(1)
+
+	return nil
+}
+
+// UpdateSegmentRedundancy updates redundancy for specified segment, if pieces matches the current pieces.
+func (p *PostgresAdapter) UpdateSegmentRedundancy(ctx context.Context, opts UpdateSegmentRedundancy, pieces AliasPieces) (resultPieces AliasPieces, err error) {
+	err = p.db.QueryRowContext(ctx, `
+		UPDATE segments SET
+			redundancy = CASE
+				WHEN remote_alias_pieces = $3 THEN $4
+				ELSE redundancy
+			END
+		WHERE
+			stream_id     = $1 AND
+			position      = $2
+		RETURNING remote_alias_pieces
+		`, opts.StreamID, opts.Position, pieces, redundancyScheme{&opts.NewRedundancy}).
+		Scan(&resultPieces)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSegmentNotFound.New("segment missing")
+		}
+		return nil, Error.New("unable to update segment redundancy: %w", err)
+	}
+	return resultPieces, nil
+}
+
+// UpdateSegmentRedundancy updates redundancy for specified segment, if pieces matches the current pieces.
+func (s *SpannerAdapter) UpdateSegmentRedundancy(ctx context.Context, opts UpdateSegmentRedundancy, pieces AliasPieces) (resultPieces AliasPieces, err error) {
+	_, err = s.client.ReadWriteTransaction(ctx, func(ctx context.Context, tx *spanner.ReadWriteTransaction) error {
+		resultPieces, err = spannerutil.CollectRow(tx.Query(ctx, spanner.Statement{
+			SQL: `
+				UPDATE segments SET
+					redundancy = CASE
+						WHEN remote_alias_pieces = @pieces THEN @redundancy
+						ELSE redundancy
+					END
+				WHERE
+					stream_id     = @stream_id AND
+					position      = @position
+				THEN RETURN remote_alias_pieces
+			`,
+			Params: map[string]any{
+				"stream_id":  opts.StreamID,
+				"position":   opts.Position,
+				"pieces":     pieces,
+				"redundancy": redundancyScheme{&opts.NewRedundancy},
+			},
+		}), func(row *spanner.Row, item *AliasPieces) error {
+			err = row.Columns(item)
+			if err != nil {
+				return Error.New("unable to decode result pieces: %w", err)
+			}
+			return nil
+		})
+
+		if err != nil {
+			if errors.Is(err, iterator.Done) {
+				return ErrSegmentNotFound.New("segment missing")
+			}
+			return Error.New("unable to update segment redundancy: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	return resultPieces, nil
+}
@@ -339,6 +339,136 @@ func TestUpdateSegmentPieces(t *testing.T) {
 			}.Check(ctx, t, db)
 		})
 
+		t.Run("OldPiecesHash invalid length", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.UpdateSegmentPieces{
+				Opts: metabase.UpdateSegmentPieces{
+					StreamID:      obj.StreamID,
+					OldPiecesHash: []byte{1, 2, 3},
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "OldPiecesHash has invalid length: 3",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("segment pieces hash was changed", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			obj := metabasetest.CreateObject(ctx, t, db, obj, 1)
+
+			oldPiecesHash, err := db.PiecesHash(ctx, validPieces)
+			require.NoError(t, err)
+
+			metabasetest.UpdateSegmentPieces{
+				Opts: metabase.UpdateSegmentPieces{
+					StreamID:      obj.StreamID,
+					Position:      metabase.SegmentPosition{Index: 0},
+					OldPiecesHash: oldPiecesHash,
+					NewRedundancy: metabasetest.DefaultRedundancy,
+					NewPieces: metabase.Pieces{
+						metabase.Piece{
+							Number:      1,
+							StorageNode: testrand.NodeID(),
+						},
+					},
+				},
+				ErrClass: &metabase.ErrValueChanged,
+				ErrText:  "segment remote_alias_pieces field was changed",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("update pieces using hash", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			object := metabasetest.CreateObject(ctx, t, db, obj, 1)
+
+			segment, err := db.GetSegmentByPosition(ctx, metabase.GetSegmentByPosition{
+				StreamID: object.StreamID,
+				Position: metabase.SegmentPosition{Index: 0},
+			})
+			require.NoError(t, err)
+
+			oldPiecesHash, err := db.PiecesHash(ctx, segment.Pieces)
+			require.NoError(t, err)
+
+			expectedPieces := metabase.Pieces{
+				metabase.Piece{
+					Number:      1,
+					StorageNode: testrand.NodeID(),
+				},
+				metabase.Piece{
+					Number:      2,
+					StorageNode: testrand.NodeID(),
+				},
+			}
+
+			metabasetest.UpdateSegmentPieces{
+				Opts: metabase.UpdateSegmentPieces{
+					StreamID:      obj.StreamID,
+					Position:      metabase.SegmentPosition{Index: 0},
+					OldPiecesHash: oldPiecesHash,
+					NewRedundancy: metabasetest.DefaultRedundancy,
+					NewPieces:     expectedPieces,
+				},
+			}.Check(ctx, t, db)
+
+			expectedSegment := segment
+			expectedSegment.Pieces = expectedPieces
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{
+					metabase.RawObject(object),
+				},
+				Segments: []metabase.RawSegment{
+					metabase.RawSegment(expectedSegment),
+				},
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("update pieces using hash of many pieces", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			// the encoded pieces contain bytes above 0x7f, which must be hashed
+			// the same way by every database.
+			var pieces metabase.Pieces
+			for number := uint16(100); number < 130; number++ {
+				pieces = append(pieces, metabase.Piece{Number: number, StorageNode: testrand.NodeID()})
+			}
+
+			object := metabasetest.CreateObject(ctx, t, db, obj, 0)
+			segment := metabasetest.DefaultRawSegment(object.ObjectStream, metabase.SegmentPosition{Index: 0})
+			segment.Pieces = pieces
+			require.NoError(t, db.TestingBatchInsertSegments(ctx, []metabase.RawSegment{segment}))
+
+			oldPiecesHash, err := db.PiecesHash(ctx, pieces)
+			require.NoError(t, err)
+
+			expectedPieces := metabase.Pieces{
+				metabase.Piece{
+					Number:      1,
+					StorageNode: testrand.NodeID(),
+				},
+			}
+
+			metabasetest.UpdateSegmentPieces{
+				Opts: metabase.UpdateSegmentPieces{
+					StreamID:      object.StreamID,
+					Position:      metabase.SegmentPosition{Index: 0},
+					OldPiecesHash: oldPiecesHash,
+					NewRedundancy: metabasetest.DefaultRedundancy,
+					NewPieces:     expectedPieces,
+				},
+			}.Check(ctx, t, db)
+
+			segment.Pieces = expectedPieces
+			metabasetest.Verify{
+				Objects: []metabase.RawObject{
+					metabase.RawObject(object),
+				},
+				Segments: []metabase.RawSegment{segment},
+			}.Check(ctx, t, db)
+		})
+
 		t.Run("update pieces and repair at", func(t *testing.T) {
 			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
 
@@ -417,3 +547,142 @@ func TestUpdateSegmentPieces(t *testing.T) {
 		})
 	})
 }
+
+func TestUpdateSegmentRedundancy(t *testing.T) {
+	metabasetest.Run(t, func(ctx *testcontext.Context, t *testing.T, db *metabase.DB) {
+		obj := metabasetest.RandObjectStream()
+
+		validPieces := metabase.Pieces{{Number: 0, StorageNode: storj.NodeID{2}}}
+
+		newRedundancy := storj.RedundancyScheme{
+			Algorithm:      storj.ReedSolomon,
+			ShareSize:      2048,
+			RequiredShares: 1,
+			RepairShares:   1,
+			OptimalShares:  2,
+			TotalShares:    4,
+		}
+
+		t.Run("StreamID missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.UpdateSegmentRedundancy{
+				Opts:     metabase.UpdateSegmentRedundancy{},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "StreamID missing",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("Pieces missing", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.UpdateSegmentRedundancy{
+				Opts: metabase.UpdateSegmentRedundancy{
+					StreamID: obj.StreamID,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "Pieces: pieces missing",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("NewRedundancy zero", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.UpdateSegmentRedundancy{
+				Opts: metabase.UpdateSegmentRedundancy{
+					StreamID: obj.StreamID,
+					Pieces:   validPieces,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "NewRedundancy zero",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("Pieces vs NewRedundancy", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			redundancy := newRedundancy
+			redundancy.RepairShares = 2
+			metabasetest.UpdateSegmentRedundancy{
+				Opts: metabase.UpdateSegmentRedundancy{
+					StreamID:      obj.StreamID,
+					Pieces:        validPieces,
+					NewRedundancy: redundancy,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "number of pieces is less than new redundancy repair shares value",
+			}.Check(ctx, t, db)
+
+			metabasetest.UpdateSegmentRedundancy{
+				Opts: metabase.UpdateSegmentRedundancy{
+					StreamID:      obj.StreamID,
+					Pieces:        metabase.Pieces{{Number: 4, StorageNode: testrand.NodeID()}},
+					NewRedundancy: newRedundancy,
+				},
+				ErrClass: &metabase.ErrInvalidRequest,
+				ErrText:  "piece number 4 exceeds new redundancy total shares value",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("segment not found", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			metabasetest.UpdateSegmentRedundancy{
+				Opts: metabase.UpdateSegmentRedundancy{
+					StreamID:      obj.StreamID,
+					Position:      metabase.SegmentPosition{Index: 1},
+					Pieces:        validPieces,
+					NewRedundancy: newRedundancy,
+				},
+				ErrClass: &metabase.ErrSegmentNotFound,
+				ErrText:  "segment missing",
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("segment pieces column was changed", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			object, segments := metabasetest.CreateTestObject{}.Run(ctx, t, db, obj, 1)
+
+			metabasetest.UpdateSegmentRedundancy{
+				Opts: metabase.UpdateSegmentRedundancy{
+					StreamID:      obj.StreamID,
+					Position:      metabase.SegmentPosition{Index: 0},
+					Pieces:        metabase.Pieces{{Number: 1, StorageNode: testrand.NodeID()}},
+					NewRedundancy: newRedundancy,
+				},
+				ErrClass: &metabase.ErrValueChanged,
+				ErrText:  "segment remote_alias_pieces field was changed",
+			}.Check(ctx, t, db)
+
+			// verify that original pieces and redundancy did not change
+			metabasetest.Verify{
+				Objects:  []metabase.RawObject{metabase.RawObject(object)},
+				Segments: metabasetest.SegmentsToRaw(segments),
+			}.Check(ctx, t, db)
+		})
+
+		t.Run("update redundancy", func(t *testing.T) {
+			defer metabasetest.DeleteAll{}.Check(ctx, t, db)
+
+			object, segments := metabasetest.CreateTestObject{}.Run(ctx, t, db, obj, 2)
+
+			metabasetest.UpdateSegmentRedundancy{
+				Opts: metabase.UpdateSegmentRedundancy{
+					StreamID:      obj.StreamID,
+					Position:      segments[1].Position,
+					Pieces:        segments[1].Pieces,
+					NewRedundancy: newRedundancy,
+				},
+			}.Check(ctx, t, db)
+
+			expectedSegments := metabasetest.SegmentsToRaw(segments)
+			expectedSegments[1].Redundancy = newRedundancy
+
+			metabasetest.Verify{
+				Objects:  []metabase.RawObject{metabase.RawObject(object)},
+				Segments: expectedSegments,
+			}.Check(ctx, t, db)
+		})
+	})
+}
@@ -179,6 +179,15 @@ type Config struct {
 
 	NodeAliasCacheFullRefresh bool `help:"node alias cache does a full refresh when a value is missing" default:"false"`
 
+	RejectControlCharactersInKeys bool `help:"reject object keys containing control characters on upload" default:"false"`
+	ValidateSegmentSize           bool `help:"reject segments whose encrypted size and pieces are inconsistent with the redundancy scheme" default:"false"`
+
+	ListObjectsLimit           int  `help:"maximum number of objects listed by a single request, zero means the default maximum of 1000" default:"0"`
+	RejectListObjectsOverLimit bool `help:"reject listing requests with a limit above the maximum, instead of lowering the limit" default:"false"`
+	ValidateListedEncryption   bool `help:"fail listing requests when a listed object has invalid encryption parameters" default:"false"`
+
+	MaxVersionsPerKey int `help:"maximum number of versions kept per object key, the oldest versions are deleted on commit; zero means unlimited" default:"0"`
+
 	UseBucketLevelObjectVersioning bool `help:"enable the use of bucket level object versioning" default:"false"`
 	// flag to simplify testing by enabling bucket level versioning feature only for specific projects
 	UseBucketLevelObjectVersioningProjects []string `help:"list of projects which will have UseBucketLevelObjectVersioning feature flag enabled" default:"" hidden:"true"`
@@ -196,13 +205,19 @@ type Config struct {
 // Metabase constructs Metabase configuration based on Metainfo configuration with specific application name.
 func (c Config) Metabase(applicationName string) metabase.Config {
 	return metabase.Config{
-		ApplicationName:            applicationName,
-		MinPartSize:                c.MinPartSize,
-		MaxNumberOfParts:           c.MaxNumberOfParts,
-		ServerSideCopy:             c.ServerSideCopy,
-		NodeAliasCacheFullRefresh:  c.NodeAliasCacheFullRefresh,
-		TestingCommitSegmentMode:   c.TestCommitSegmentMode,
-		TestingPrecommitDeleteMode: c.TestingPrecommitDeleteMode,
+		ApplicationName:               applicationName,
+		MinPartSize:                   c.MinPartSize,
+		MaxNumberOfParts:              c.MaxNumberOfParts,
+		ServerSideCopy:                c.ServerSideCopy,
+		NodeAliasCacheFullRefresh:     c.NodeAliasCacheFullRefresh,
+		RejectControlCharactersInKeys: c.RejectControlCharactersInKeys,
+		ValidateSegmentSize:           c.ValidateSegmentSize,
+		ListObjectsLimit:              c.ListObjectsLimit,
+		RejectListObjectsOverLimit:    c.RejectListObjectsOverLimit,
+		ValidateListedEncryption:      c.ValidateListedEncryption,
+		MaxVersionsPerKey:             c.MaxVersionsPerKey,
+		TestingCommitSegmentMode:      c.TestCommitSegmentMode,
+		TestingPrecommitDeleteMode:    c.TestingPrecommitDeleteMode,
 	}
 }
 
@@ -373,7 +373,7 @@ func (endpoint *Endpoint) ConvertMetabaseErr(err error) error {
 		message = strings.TrimPrefix(message, ": ")
 		// uplink expects a message that starts with the specified prefix
 		return rpcstatus.Error(rpcstatus.NotFound, "segment not found: "+message)
-	case metabase.ErrInvalidRequest.Has(err):
+	case metabase.ErrInvalidRequest.Has(err), metabase.ErrEncryptionMissing.Has(err):
 		return rpcstatus.Error(rpcstatus.InvalidArgument, err.Error())
 	case metabase.ErrFailedPrecondition.Has(err):
 		return rpcstatus.Error(rpcstatus.FailedPrecondition, err.Error())
@@ -381,7 +381,7 @@ func (endpoint *Endpoint) ConvertMetabaseErr(err error) error {
 		return rpcstatus.Error(rpcstatus.AlreadyExists, err.Error())
 	case metabase.ErrPendingObjectMissing.Has(err):
 		return rpcstatus.Error(rpcstatus.NotFound, err.Error())
-	case metabase.ErrPermissionDenied.Has(err):
+	case metabase.ErrPermissionDenied.Has(err), metabase.ErrObjectLock.Has(err):
 		return rpcstatus.Error(rpcstatus.PermissionDenied, err.Error())
 	default:
 		endpoint.log.Error("internal", zap.Error(err))
@@ -32,6 +32,14 @@ type Accounts interface {
 	// RemoveTaxID removes a tax ID from a user and returns the updated billing information.
 	RemoveTaxID(ctx context.Context, userID uuid.UUID, id string) (*BillingInformation, error)
 
+	// SetAutomaticTax enables or disables calculating the tax of the invoices of a user from
+	// the billing address and returns the updated billing information. Enabling requires an
+	// address, which is complete enough for the tax calculation.
+	SetAutomaticTax(ctx context.Context, userID uuid.UUID, enabled bool) (*BillingInformation, error)
+
+	// ClearInvoiceCustomFields removes all invoice custom fields of a user and returns the updated billing information.
+	ClearInvoiceCustomFields(ctx context.Context, userID uuid.UUID) (*BillingInformation, error)
+
 	// GetBillingInformation gets the billing information for a user.
 	GetBillingInformation(ctx context.Context, userID uuid.UUID) (*BillingInformation, error)
 
@@ -44,15 +52,36 @@ type Accounts interface {
 	// GetPackageInfo returns the package plan and time of purchase for a user.
 	GetPackageInfo(ctx context.Context, userID uuid.UUID) (packagePlan *string, purchaseTime *time.Time, err error)
 
+	// SetBillingParent makes the project charges of the user be invoiced to the parent customer.
+	SetBillingParent(ctx context.Context, userID uuid.UUID, parentCustomerID string) error
+
+	// ClearBillingParent makes the project charges of the user be invoiced to the user's own customer again.
+	ClearBillingParent(ctx context.Context, userID uuid.UUID) error
+
+	// ExportBillingData returns all the billing data held about a user.
+	ExportBillingData(ctx context.Context, userID uuid.UUID) (*BillingDataExport, error)
+
 	// Balances exposes functionality to manage account balances.
 	Balances() Balances
 
 	// ProjectCharges returns how much money current user will be charged for each project.
-	ProjectCharges(ctx context.Context, userID uuid.UUID, since, before time.Time) (ProjectChargesResponse, error)
+	// The charges are computed with the prices of the snapshot, or with the current prices when it's nil.
+	ProjectCharges(ctx context.Context, userID uuid.UUID, since, before time.Time, prices *PriceSnapshot) (ProjectChargesResponse, error)
+
+	// ProjectChargesDetailed returns the project charges split into calendar month billing periods,
+	// marking whether the charges of each period are finalized or still in progress.
+	ProjectChargesDetailed(ctx context.Context, userID uuid.UUID, since, before time.Time, prices *PriceSnapshot) (ProjectChargesDetailedResponse, error)
+
+	// EstimateUpcomingInvoice returns an estimate of the next invoice of the user,
+	// based on the usage of the current billing period so far.
+	EstimateUpcomingInvoice(ctx context.Context, userID uuid.UUID) (UpcomingInvoiceEstimate, error)
 
 	// GetProjectUsagePriceModel returns the project usage price model for a partner name.
 	GetProjectUsagePriceModel(partner string) ProjectUsagePriceModel
 
+	// GetPriceSnapshot returns a snapshot of the current project usage prices.
+	GetPriceSnapshot() PriceSnapshot
+
 	// CheckProjectInvoicingStatus returns error if for the given project there are outstanding project records and/or usage
 	// which have not been applied/invoiced yet (meaning sent over to stripe).
 	CheckProjectInvoicingStatus(ctx context.Context, projectID uuid.UUID) error
@@ -3,6 +3,8 @@
 
 package payments
 
+import "time"
+
 // BillingAddress contains a user's custom billing address.
 type BillingAddress struct {
 	Name       string     `json:"name"`
@@ -25,4 +27,42 @@ type TaxID struct {
 type BillingInformation struct {
 	Address *BillingAddress `json:"address"`
 	TaxIDs  []TaxID         `json:"taxIDs"`
+	// AutomaticTax is set when the tax of the invoices is calculated from the address
+	// by the payment provider, instead of from the tax IDs.
+	AutomaticTax bool `json:"automaticTax"`
+}
+
+// BillingDataExport contains all the billing data held about a user,
+// e.g. for answering a data subject access request.
+type BillingDataExport struct {
+	Address          *BillingAddress `json:"address"`
+	TaxIDs           []TaxID         `json:"taxIDs"`
+	InvoiceReference string          `json:"invoiceReference"`
+
+	PackagePlan        *string    `json:"packagePlan"`
+	PackagePurchasedAt *time.Time `json:"packagePurchasedAt"`
+
+	CreditCards []ExportedCreditCard `json:"creditCards"`
+	Charges     []ExportedCharge     `json:"charges"`
+
+	// CustomerMissing is set when the payment provider has no customer for the user,
+	// in which case only the locally stored data is exported.
+	CustomerMissing bool `json:"customerMissing"`
+}
+
+// ExportedCreditCard is the summary of a credit card in BillingDataExport.
+type ExportedCreditCard struct {
+	Brand     string `json:"brand"`
+	LastFour  string `json:"lastFour"`
+	ExpMonth  int    `json:"expMonth"`
+	ExpYear   int    `json:"expYear"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+// ExportedCharge is the summary of a credit card charge in BillingDataExport.
+type ExportedCharge struct {
+	Amount    int64     `json:"amount"`
+	Brand     string    `json:"brand"`
+	LastFour  string    `json:"lastFour"`
+	CreatedAt time.Time `json:"createdAt"`
 }
@@ -25,12 +25,17 @@ var (
 type Coupons interface {
 	// GetByUserID returns the coupon applied to the specified user.
 	GetByUserID(ctx context.Context, userID uuid.UUID) (*Coupon, error)
+	// GetApplied returns the coupon applied to the specified user and how much of it remains.
+	// AppliedCoupon.Applied is false when the user has no coupon.
+	GetApplied(ctx context.Context, userID uuid.UUID) (AppliedCoupon, error)
 	// ApplyFreeTierCoupon applies the free tier coupon to the specified user.
 	ApplyFreeTierCoupon(ctx context.Context, userID uuid.UUID) (*Coupon, error)
 	// ApplyCoupon applies coupon to user based on coupon ID.
 	ApplyCoupon(ctx context.Context, userID uuid.UUID, couponID string) (*Coupon, error)
 	// ApplyCouponCode attempts to apply a coupon code to the user.
 	ApplyCouponCode(ctx context.Context, userID uuid.UUID, couponCode string) (*Coupon, error)
+	// ApplyPromoCode resolves a promotion code to a coupon and applies it to the user.
+	ApplyPromoCode(ctx context.Context, userID uuid.UUID, promoCode string) (*Coupon, error)
 }
 
 // Coupon describes a discount to the payment account of a user.
@@ -45,6 +50,19 @@ type Coupon struct {
 	Duration   CouponDuration `json:"duration"`
 }
 
+// AppliedCoupon describes the coupon applied to a user and how much of it remains.
+type AppliedCoupon struct {
+	Coupon
+	// Applied is false when the user has no coupon, in which case the other fields are empty.
+	Applied bool `json:"applied"`
+	// RemainingMonths is the number of billing periods a repeating coupon is still applied for.
+	// It's nil for coupons, which aren't limited to a number of months.
+	RemainingMonths *int64 `json:"remainingMonths"`
+	// RemainingRedemptions is how many more times the coupon can be redeemed across all users.
+	// It's nil for coupons without a redemption limit.
+	RemainingRedemptions *int64 `json:"remainingRedemptions"`
+}
+
 // CouponDuration represents how many billing periods a coupon is applied.
 type CouponDuration string
 
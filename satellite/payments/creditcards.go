@@ -34,6 +34,9 @@ type CreditCards interface {
 	// MakeDefault makes a credit card default payment method.
 	// this credit card should be attached to account before make it default.
 	MakeDefault(ctx context.Context, userID uuid.UUID, cardID string) error
+
+	// GetDefault returns the card info of the default payment method of payment account.
+	GetDefault(ctx context.Context, userID uuid.UUID) (CardInfo, error)
 }
 
 // CreditCard holds all public information about credit card.
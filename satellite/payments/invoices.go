@@ -7,6 +7,7 @@ import (
 	"context"
 	"time"
 
+	"storj.io/common/currency"
 	"storj.io/common/uuid"
 )
 
@@ -49,6 +50,13 @@ type Invoices interface {
 	AttemptPayOverdueInvoicesWithTokens(ctx context.Context, userID uuid.UUID) (err error)
 	// Delete a draft invoice.
 	Delete(ctx context.Context, id string) (inv *Invoice, err error)
+	// TotalOwed returns the remaining amount of all open and uncollectible invoices of a user.
+	TotalOwed(ctx context.Context, userID uuid.UUID) (currency.Amount, error)
+	// AddManualLineItem adds a pending invoice item to the user's upcoming invoice.
+	// A negative amount creates a credit.
+	AddManualLineItem(ctx context.Context, userID uuid.UUID, amount currency.Amount, description string) error
+	// AddDefaultInvoiceReference sets the reference shown on all future invoices of the user.
+	AddDefaultInvoiceReference(ctx context.Context, userID uuid.UUID, reference string) error
 }
 
 // Invoice holds all public information about invoice.
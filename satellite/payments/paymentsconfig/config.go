@@ -32,7 +32,7 @@ type Config struct {
 	Storjscan           storjscan.Config
 	UsagePrice          ProjectUsagePrice
 	BonusRate           int64                      `help:"amount of percents that user will earn as bonus credits by depositing in STORJ tokens" default:"10"`
-	UsagePriceOverrides ProjectUsagePriceOverrides `help:"semicolon-separated usage price overrides in the format partner:storage,egress,segment,egress_discount_ratio. The egress discount ratio is the ratio of free egress per unit-month of storage"`
+	UsagePriceOverrides ProjectUsagePriceOverrides `help:"semicolon-separated usage price overrides in the format partner:storage,egress,segment[,egress_discount_ratio]. The egress discount ratio is the ratio of free egress per unit-month of storage, the default ratio is used when it's omitted"`
 	PackagePlans        PackagePlans               `help:"semicolon-separated partner package plans in the format partner:price,credit. Price and credit are in cents USD."`
 }
 
@@ -74,6 +74,9 @@ var _ pflag.Value = (*ProjectUsagePriceOverrides)(nil)
 // ProjectUsagePriceOverrides represents a mapping between partners and project usage price overrides.
 type ProjectUsagePriceOverrides struct {
 	overrideMap map[string]ProjectUsagePrice
+	// defaultEgressDiscount contains the partners whose overrides don't specify
+	// the egress discount ratio, hence they use the default one.
+	defaultEgressDiscount map[string]bool
 }
 
 // Type returns the type of the pflag.Value.
@@ -87,8 +90,10 @@ func (p *ProjectUsagePriceOverrides) String() string {
 	var s strings.Builder
 	left := len(p.overrideMap)
 	for partner, prices := range p.overrideMap {
-		egressDiscount := strconv.FormatFloat(prices.EgressDiscountRatio, 'f', -1, 64)
-		s.WriteString(fmt.Sprintf("%s:%s,%s,%s,%s", partner, prices.StorageTB, prices.EgressTB, prices.Segment, egressDiscount))
+		s.WriteString(fmt.Sprintf("%s:%s,%s,%s", partner, prices.StorageTB, prices.EgressTB, prices.Segment))
+		if !p.defaultEgressDiscount[partner] {
+			s.WriteString("," + strconv.FormatFloat(prices.EgressDiscountRatio, 'f', -1, 64))
+		}
 		left--
 		if left > 0 {
 			s.WriteRune(';')
@@ -100,6 +105,7 @@ func (p *ProjectUsagePriceOverrides) String() string {
 // Set sets the list of price overrides to the parsed string.
 func (p *ProjectUsagePriceOverrides) Set(s string) error {
 	overrideMap := make(map[string]ProjectUsagePrice)
+	defaultEgressDiscount := make(map[string]bool)
 	for _, overrideStr := range strings.Split(s, ";") {
 		if overrideStr == "" {
 			continue
@@ -117,8 +123,8 @@ func (p *ProjectUsagePriceOverrides) Set(s string) error {
 
 		valuesStr := info[1]
 		values := strings.Split(valuesStr, ",")
-		if len(values) != 4 {
-			return Error.New("Invalid values (expected format storage,egress,segment,egress_discount_ratio, got %s)", valuesStr)
+		if len(values) != 3 && len(values) != 4 {
+			return Error.New("Invalid values (expected format storage,egress,segment[,egress_discount_ratio], got %s)", valuesStr)
 		}
 
 		for i := 0; i < 3; i++ {
@@ -127,9 +133,15 @@ func (p *ProjectUsagePriceOverrides) Set(s string) error {
 			}
 		}
 
-		egressDiscount, err := strconv.ParseFloat(values[3], 64)
-		if err != nil {
-			return Error.New("Invalid egress discount ratio '%s' (%s)", values[3], err)
+		var egressDiscount float64
+		if len(values) == 4 {
+			var err error
+			egressDiscount, err = strconv.ParseFloat(values[3], 64)
+			if err != nil {
+				return Error.New("Invalid egress discount ratio '%s' (%s)", values[3], err)
+			}
+		} else {
+			defaultEgressDiscount[info[0]] = true
 		}
 
 		overrideMap[info[0]] = ProjectUsagePrice{
@@ -140,18 +152,24 @@ func (p *ProjectUsagePriceOverrides) Set(s string) error {
 		}
 	}
 	p.overrideMap = overrideMap
+	p.defaultEgressDiscount = defaultEgressDiscount
 	return nil
 }
 
 // SetMap sets the internal mapping between partners and project usage prices.
 func (p *ProjectUsagePriceOverrides) SetMap(overrides map[string]ProjectUsagePrice) {
 	p.overrideMap = overrides
+	p.defaultEgressDiscount = nil
 }
 
 // ToModels returns the price overrides represented as a mapping between partners and project usage price models.
-func (p ProjectUsagePriceOverrides) ToModels() (map[string]payments.ProjectUsagePriceModel, error) {
+// Overrides that don't specify the egress discount ratio use the ratio of the defaults.
+func (p ProjectUsagePriceOverrides) ToModels(defaults ProjectUsagePrice) (map[string]payments.ProjectUsagePriceModel, error) {
 	models := make(map[string]payments.ProjectUsagePriceModel)
 	for partner, prices := range p.overrideMap {
+		if p.defaultEgressDiscount[partner] {
+			prices.EgressDiscountRatio = defaults.EgressDiscountRatio
+		}
 		model, err := prices.ToModel()
 		if err != nil {
 			return nil, err
@@ -19,6 +19,13 @@ import (
 func TestProjectUsagePriceOverrides(t *testing.T) {
 	type Prices map[string]payments.ProjectUsagePriceModel
 
+	defaults := paymentsconfig.ProjectUsagePrice{
+		StorageTB:           "4",
+		EgressTB:            "7",
+		Segment:             "0.0000088",
+		EgressDiscountRatio: 1.5,
+	}
+
 	cases := []struct {
 		testID        string
 		configValue   string
@@ -49,6 +56,20 @@ func TestProjectUsagePriceOverrides(t *testing.T) {
 					EgressDiscountRatio: 4,
 				},
 			},
+		}, {
+			testID:      "default egress discount ratio",
+			configValue: "partner:1,2,3",
+			expectedModel: Prices{
+				"partner": payments.ProjectUsagePriceModel{
+					StorageMBMonthCents: decimal.NewFromInt(1).Shift(-4),
+					EgressMBCents:       decimal.NewFromInt(2).Shift(-4),
+					SegmentMonthCents:   decimal.NewFromInt(3).Shift(2),
+					EgressDiscountRatio: 1.5,
+				},
+			},
+		}, {
+			testID:      "invalid egress discount ratio",
+			configValue: "partner:1,2,3,a",
 		}, {
 			testID:      "too many values",
 			configValue: "partner:1,2,3,4,5",
@@ -57,7 +78,7 @@ func TestProjectUsagePriceOverrides(t *testing.T) {
 			configValue: "partner:0.0.1,2,3,4",
 		}, {
 			testID:      "multiple price overrides",
-			configValue: "partner1:1,2,3,4;partner2:5,6,7,8",
+			configValue: "partner1:1,2,3,4;partner2:5,6,7,8;partner3:9,10,11",
 			expectedModel: Prices{
 				"partner1": payments.ProjectUsagePriceModel{
 					StorageMBMonthCents: decimal.NewFromInt(1).Shift(-4),
@@ -71,6 +92,12 @@ func TestProjectUsagePriceOverrides(t *testing.T) {
 					SegmentMonthCents:   decimal.NewFromInt(7).Shift(2),
 					EgressDiscountRatio: 8,
 				},
+				"partner3": payments.ProjectUsagePriceModel{
+					StorageMBMonthCents: decimal.NewFromInt(9).Shift(-4),
+					EgressMBCents:       decimal.NewFromInt(10).Shift(-4),
+					SegmentMonthCents:   decimal.NewFromInt(11).Shift(2),
+					EgressDiscountRatio: 1.5,
+				},
 			},
 		},
 	}
@@ -90,7 +117,7 @@ func TestProjectUsagePriceOverrides(t *testing.T) {
 			sort.Strings(strParts)
 			require.Equal(t, c.configValue, strings.Join(strParts, ";"))
 
-			models, err := price.ToModels()
+			models, err := price.ToModels(defaults)
 			require.NoError(t, err)
 			require.Len(t, models, len(c.expectedModel))
 			for partner, price := range c.expectedModel {
@@ -4,6 +4,9 @@
 package payments
 
 import (
+	"strings"
+	"time"
+
 	"github.com/shopspring/decimal"
 
 	"storj.io/common/uuid"
@@ -20,6 +23,11 @@ type ProjectCharge struct {
 	EgressMBCents int64 `json:"egressPrice"`
 	// SegmentMonthCents is how many cents we should pay for objects count.
 	SegmentMonthCents int64 `json:"segmentPrice"`
+
+	// FreeEgressBytes is how many bytes of egress were discounted and are free of charge.
+	// ProjectUsage.Egress contains only the charged egress, so the original egress is
+	// the sum of both.
+	FreeEgressBytes int64 `json:"freeEgress"`
 }
 
 // ProjectChargesResponse represents a collection of project usage charges grouped by project ID and partner name.
@@ -29,6 +37,44 @@ type ProjectCharge struct {
 // with a particular project-partner combination.
 type ProjectChargesResponse map[uuid.UUID]map[string]ProjectCharge
 
+// ProjectChargesDetailedResponse contains the project charges split into billing periods, ordered by time.
+type ProjectChargesDetailedResponse []ProjectChargesPeriod
+
+// ProjectChargesPeriod contains the project charges of a single billing period.
+type ProjectChargesPeriod struct {
+	Since  time.Time `json:"since"`
+	Before time.Time `json:"before"`
+
+	// Projects maps project public IDs to their charges in the period.
+	Projects map[uuid.UUID]PeriodProjectCharges `json:"projects"`
+}
+
+// PeriodProjectCharges contains the charges of a project in a billing period.
+type PeriodProjectCharges struct {
+	// Finalized is true when the billing period has closed and the project usage
+	// has been invoiced. Otherwise the charges are an estimate of the usage so far.
+	Finalized bool `json:"finalized"`
+	// Charges maps partner names to the charges of the project.
+	Charges map[string]ProjectCharge `json:"charges"`
+}
+
+// UpcomingInvoiceEstimate is a breakdown of the estimated next invoice of a user.
+//
+// The estimate only contains the usage charges. It leaves out everything, which is
+// applied when the invoice is created: the package credit and the rest of the user
+// balance, coupons and taxes. Hence it isn't the amount due of the invoice.
+type UpcomingInvoiceEstimate struct {
+	// Since and Before are the bounds of the estimated billing period.
+	Since  time.Time `json:"since"`
+	Before time.Time `json:"before"`
+
+	// UsageCents is the sum of the usage charges of all the projects owned by the user.
+	UsageCents int64 `json:"usageCents"`
+	// PackagePlan is the package plan of the user, if any. The package credit
+	// is part of the user balance, hence it isn't deducted from the usage charges.
+	PackagePlan *string `json:"packagePlan,omitempty"`
+}
+
 // ProjectUsagePriceModel represents price model for project usage.
 type ProjectUsagePriceModel struct {
 	StorageMBMonthCents decimal.Decimal `json:"storageMBMonthCents"`
@@ -36,3 +82,25 @@ type ProjectUsagePriceModel struct {
 	SegmentMonthCents   decimal.Decimal `json:"segmentMonthCents"`
 	EgressDiscountRatio float64         `json:"egressDiscountRatio"`
 }
+
+// PriceSnapshot is a frozen copy of the project usage prices. It allows computing
+// charges for a past period with the prices that were in effect at that time.
+type PriceSnapshot struct {
+	UsagePrices         ProjectUsagePriceModel            `json:"usagePrices"`
+	UsagePriceOverrides map[string]ProjectUsagePriceModel `json:"usagePriceOverrides"`
+}
+
+// GetProjectUsagePriceModel returns the project usage price model of the snapshot for a partner name.
+func (snapshot *PriceSnapshot) GetProjectUsagePriceModel(partner string) ProjectUsagePriceModel {
+	if override, ok := snapshot.UsagePriceOverrides[NormalizePartnerName(partner)]; ok {
+		return override
+	}
+	return snapshot.UsagePrices
+}
+
+// NormalizePartnerName returns the canonical form of a partner name, which is used
+// for matching partner names and as the key of price overrides. Partner names are
+// case-insensitive and surrounding whitespace is ignored.
+func NormalizePartnerName(partner string) string {
+	return strings.ToLower(strings.TrimSpace(partner))
+}
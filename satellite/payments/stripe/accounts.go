@@ -6,6 +6,7 @@ package stripe
 import (
 	"context"
 	"errors"
+	"strconv"
 	"time"
 
 	"github.com/stripe/stripe-go/v75"
@@ -19,6 +20,13 @@ import (
 // ErrInvalidTaxID is returned when a tax ID value is invalid.
 var ErrInvalidTaxID = errs.Class("Invalid tax ID value")
 
+// ErrIncompleteTaxAddress is returned when automatic tax is enabled for a customer,
+// whose billing address isn't complete enough for calculating the tax.
+var ErrIncompleteTaxAddress = errs.Class("Incomplete billing address for automatic tax")
+
+// automaticTaxMetadataKey is the customer metadata key, which stores whether automatic tax is enabled.
+const automaticTaxMetadataKey = "automatic_tax"
+
 // ensures that accounts implements payments.Accounts.
 var _ payments.Accounts = (*accounts)(nil)
 
@@ -74,16 +82,11 @@ func (accounts *accounts) Setup(ctx context.Context, userID uuid.UUID, email str
 		return couponType, Error.Wrap(accounts.service.db.Customers().Insert(ctx, userID, customer.ID))
 	}
 
-	promoCodeIter := accounts.service.stripeClient.PromoCodes().List(&stripe.PromotionCodeListParams{
-		ListParams: stripe.ListParams{Context: ctx},
-		Code:       stripe.String(signupPromoCode),
-	})
-
-	var promoCode *stripe.PromotionCode
-
-	if promoCodeIter.Next() {
-		promoCode = promoCodeIter.PromotionCode()
-	} else {
+	promoCode, err := accounts.service.findPromoCode(ctx, signupPromoCode)
+	if err != nil {
+		return couponType, err
+	}
+	if promoCode == nil {
 		couponType = payments.NoCoupon
 	}
 
@@ -140,6 +143,18 @@ func (accounts *accounts) SaveBillingAddress(ctx context.Context, userID uuid.UU
 		return nil, Error.Wrap(err)
 	}
 
+	customer, err := accounts.service.stripeClient.Customers().Get(customerID, &stripe.CustomerParams{
+		Params: stripe.Params{Context: ctx},
+	})
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	if automaticTaxEnabled(customer) {
+		if err := verifyAutomaticTaxAddress(&address); err != nil {
+			return nil, Error.Wrap(err)
+		}
+	}
+
 	customerParams := &stripe.CustomerParams{
 		Params: stripe.Params{
 			Context: ctx,
@@ -156,7 +171,7 @@ func (accounts *accounts) SaveBillingAddress(ctx context.Context, userID uuid.UU
 	}
 	customerParams.AddExpand("tax_ids")
 
-	customer, err := accounts.service.stripeClient.Customers().Update(customerID, customerParams)
+	customer, err = accounts.service.stripeClient.Customers().Update(customerID, customerParams)
 	if err != nil {
 		stripeErr := &stripe.Error{}
 		if errors.As(err, &stripeErr) {
@@ -243,6 +258,93 @@ func (accounts *accounts) RemoveTaxID(ctx context.Context, userID uuid.UUID, id
 	return accounts.unpackBillingInformation(*customer)
 }
 
+// SetAutomaticTax enables or disables calculating the tax of the invoices of a user from the
+// billing address and returns the updated billing information. The setting is stored in the
+// customer metadata and applied when the invoices are created.
+func (accounts *accounts) SetAutomaticTax(ctx context.Context, userID uuid.UUID, enabled bool) (_ *payments.BillingInformation, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	customerID, err := accounts.service.db.Customers().GetCustomerID(ctx, userID)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	if enabled {
+		info, err := accounts.GetBillingInformation(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyAutomaticTaxAddress(info.Address); err != nil {
+			return nil, Error.Wrap(err)
+		}
+	}
+
+	params := &stripe.CustomerParams{
+		Params: stripe.Params{Context: ctx},
+	}
+	params.AddMetadata(automaticTaxMetadataKey, strconv.FormatBool(enabled))
+	params.AddExpand("tax_ids")
+
+	customer, err := accounts.service.stripeClient.Customers().Update(customerID, params)
+	if err != nil {
+		stripeErr := &stripe.Error{}
+		if errors.As(err, &stripeErr) {
+			err = errs.Wrap(errors.New(stripeErr.Msg))
+		}
+		return nil, Error.Wrap(err)
+	}
+
+	return accounts.unpackBillingInformation(*customer)
+}
+
+// automaticTaxEnabled returns whether automatic tax is enabled for the customer.
+func automaticTaxEnabled(customer *stripe.Customer) bool {
+	return customer.Metadata[automaticTaxMetadataKey] == "true"
+}
+
+// verifyAutomaticTaxAddress checks that the address is complete enough for Stripe to
+// calculate the tax. The US and Canada require a postal code, other countries only
+// require the country.
+func verifyAutomaticTaxAddress(address *payments.BillingAddress) error {
+	switch {
+	case address == nil || address.Country.Code == "":
+		return ErrIncompleteTaxAddress.New("country missing")
+	case (address.Country.Code == "US" || address.Country.Code == "CA") && address.PostalCode == "":
+		return ErrIncompleteTaxAddress.New("postal code is required in %s", address.Country.Code)
+	}
+	return nil
+}
+
+// ClearInvoiceCustomFields removes all invoice custom fields of a user and returns the updated billing information.
+// Use Invoices.AddDefaultInvoiceReference with an empty reference to remove only the reference.
+func (accounts *accounts) ClearInvoiceCustomFields(ctx context.Context, userID uuid.UUID) (_ *payments.BillingInformation, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	customerID, err := accounts.service.db.Customers().GetCustomerID(ctx, userID)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	params := &stripe.CustomerParams{
+		Params: stripe.Params{Context: ctx},
+	}
+	// Stripe unsets the custom fields when they are sent as an empty string,
+	// an empty CustomFields slice isn't sent at all.
+	params.AddExtra("invoice_settings[custom_fields]", "")
+	params.AddExpand("tax_ids")
+
+	customer, err := accounts.service.stripeClient.Customers().Update(customerID, params)
+	if err != nil {
+		stripeErr := &stripe.Error{}
+		if errors.As(err, &stripeErr) {
+			err = errs.Wrap(errors.New(stripeErr.Msg))
+		}
+		return nil, Error.Wrap(err)
+	}
+
+	return accounts.unpackBillingInformation(*customer)
+}
+
 // GetBillingInformation gets the billing information for a user.
 func (accounts *accounts) GetBillingInformation(ctx context.Context, userID uuid.UUID) (info *payments.BillingInformation, err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -274,7 +376,7 @@ func (accounts *accounts) unpackBillingInformation(customer stripe.Customer) (in
 	hasNoAddress := customer.Address == nil || customer.Address == (&stripe.Address{})
 	hasNoTaxInfo := customer.TaxIDs == nil || len(customer.TaxIDs.Data) == 0
 	if hasNoAddress && hasNoTaxInfo {
-		return &payments.BillingInformation{}, nil
+		return &payments.BillingInformation{AutomaticTax: automaticTaxEnabled(&customer)}, nil
 	}
 
 	var address *payments.BillingAddress
@@ -322,8 +424,9 @@ func (accounts *accounts) unpackBillingInformation(customer stripe.Customer) (in
 	}
 
 	return &payments.BillingInformation{
-		Address: address,
-		TaxIDs:  taxIDs,
+		Address:      address,
+		TaxIDs:       taxIDs,
+		AutomaticTax: automaticTaxEnabled(&customer),
 	}, nil
 }
 
@@ -351,10 +454,132 @@ func (accounts *accounts) GetPackageInfo(ctx context.Context, userID uuid.UUID)
 	return
 }
 
+// SetBillingParent makes the project charges of the user be invoiced to the parent customer,
+// which allows consolidating the invoices of multiple users.
+func (accounts *accounts) SetBillingParent(ctx context.Context, userID uuid.UUID, parentCustomerID string) (err error) {
+	defer mon.Task()(&ctx, userID)(&err)
+
+	if parentCustomerID == "" {
+		return Error.New("parent customer ID missing")
+	}
+
+	cusID, err := accounts.service.db.Customers().GetCustomerID(ctx, userID)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if cusID == parentCustomerID {
+		return Error.New("customer can't be its own billing parent")
+	}
+
+	_, err = accounts.service.stripeClient.Customers().Get(parentCustomerID, &stripe.CustomerParams{
+		Params: stripe.Params{Context: ctx},
+	})
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	_, err = accounts.service.db.Customers().UpdateBillingCustomerID(ctx, userID, &parentCustomerID)
+	return Error.Wrap(err)
+}
+
+// ClearBillingParent makes the project charges of the user be invoiced to the user's own customer again.
+func (accounts *accounts) ClearBillingParent(ctx context.Context, userID uuid.UUID) (err error) {
+	defer mon.Task()(&ctx, userID)(&err)
+
+	_, err = accounts.service.db.Customers().UpdateBillingCustomerID(ctx, userID, nil)
+	return Error.Wrap(err)
+}
+
+// ExportBillingData returns all the billing data held about a user.
+// Only the locally stored data is returned, when the user has no Stripe customer.
+func (accounts *accounts) ExportBillingData(ctx context.Context, userID uuid.UUID) (export *payments.BillingDataExport, err error) {
+	defer mon.Task()(&ctx, userID)(&err)
+
+	export = &payments.BillingDataExport{}
+
+	customerID, err := accounts.service.db.Customers().GetCustomerID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNoCustomer) {
+			export.CustomerMissing = true
+			return export, nil
+		}
+		return nil, Error.Wrap(err)
+	}
+
+	export.PackagePlan, export.PackagePurchasedAt, err = accounts.service.db.Customers().GetPackageInfo(ctx, userID)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	params := &stripe.CustomerParams{
+		Params: stripe.Params{Context: ctx},
+	}
+	params.AddExpand("tax_ids")
+	customer, err := accounts.service.stripeClient.Customers().Get(customerID, params)
+	if err != nil {
+		stripeErr := &stripe.Error{}
+		if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodeResourceMissing {
+			export.CustomerMissing = true
+			return export, nil
+		}
+		return nil, Error.Wrap(err)
+	}
+
+	info, err := accounts.unpackBillingInformation(*customer)
+	if err != nil {
+		return nil, err
+	}
+	export.Address = info.Address
+	export.TaxIDs = info.TaxIDs
+
+	if customer.InvoiceSettings != nil {
+		for _, field := range customer.InvoiceSettings.CustomFields {
+			if field.Name == invoiceReferenceField {
+				export.InvoiceReference = field.Value
+			}
+		}
+	}
+
+	cards, err := accounts.CreditCards().List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, card := range cards {
+		export.CreditCards = append(export.CreditCards, payments.ExportedCreditCard{
+			Brand:     card.Brand,
+			LastFour:  card.Last4,
+			ExpMonth:  card.ExpMonth,
+			ExpYear:   card.ExpYear,
+			IsDefault: card.IsDefault,
+		})
+	}
+
+	charges, err := accounts.Charges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, charge := range charges {
+		export.Charges = append(export.Charges, payments.ExportedCharge{
+			Amount:    charge.Amount,
+			Brand:     charge.CardInfo.Brand,
+			LastFour:  charge.CardInfo.LastFour,
+			CreatedAt: charge.CreatedAt,
+		})
+	}
+
+	return export, nil
+}
+
 // ProjectCharges returns how much money current user will be charged for each project.
-func (accounts *accounts) ProjectCharges(ctx context.Context, userID uuid.UUID, since, before time.Time) (charges payments.ProjectChargesResponse, err error) {
+// The charges are computed with the prices of the snapshot, or with the current prices when it's nil.
+func (accounts *accounts) ProjectCharges(ctx context.Context, userID uuid.UUID, since, before time.Time, prices *payments.PriceSnapshot) (charges payments.ProjectChargesResponse, err error) {
 	defer mon.Task()(&ctx, userID, since, before)(&err)
 
+	if prices == nil {
+		current := accounts.GetPriceSnapshot()
+		prices = &current
+	}
+
 	charges = make(payments.ProjectChargesResponse)
 
 	projects, err := accounts.service.projectsDB.GetOwn(ctx, userID)
@@ -370,9 +595,20 @@ func (accounts *accounts) ProjectCharges(ctx context.Context, userID uuid.UUID,
 
 		partnerCharges := make(map[string]payments.ProjectCharge)
 
+		if accounts.service.includeZeroUsage {
+			// seed every known partner, so that the layout doesn't depend on usage.
+			for _, partner := range append([]string{""}, accounts.service.partnerNames...) {
+				partnerCharges[partner] = payments.ProjectCharge{
+					ProjectUsage: accounting.ProjectUsage{Since: since, Before: before},
+				}
+			}
+		}
+
 		for partner, usage := range usages {
-			priceModel := accounts.GetProjectUsagePriceModel(partner)
-			usage.Egress = applyEgressDiscount(usage, priceModel)
+			priceModel := prices.GetProjectUsagePriceModel(partner)
+			chargedEgress := applyEgressDiscount(usage, priceModel)
+			freeEgress := usage.Egress - chargedEgress
+			usage.Egress = chargedEgress
 			price := accounts.service.calculateProjectUsagePrice(usage, priceModel)
 
 			partnerCharges[partner] = payments.ProjectCharge{
@@ -381,6 +617,8 @@ func (accounts *accounts) ProjectCharges(ctx context.Context, userID uuid.UUID,
 				EgressMBCents:       price.Egress.IntPart(),
 				SegmentMonthCents:   price.Segments.IntPart(),
 				StorageMBMonthCents: price.Storage.IntPart(),
+
+				FreeEgressBytes: freeEgress,
 			}
 		}
 
@@ -397,14 +635,123 @@ func (accounts *accounts) ProjectCharges(ctx context.Context, userID uuid.UUID,
 	return charges, nil
 }
 
+// ProjectChargesDetailed returns the project charges split into calendar month billing periods.
+// The charges of a project in a period are finalized when the period has closed and
+// the invoice project record of the project has been applied.
+func (accounts *accounts) ProjectChargesDetailed(ctx context.Context, userID uuid.UUID, since, before time.Time, prices *payments.PriceSnapshot) (response payments.ProjectChargesDetailedResponse, err error) {
+	defer mon.Task()(&ctx, userID, since, before)(&err)
+
+	if prices == nil {
+		current := accounts.GetPriceSnapshot()
+		prices = &current
+	}
+
+	projects, err := accounts.service.projectsDB.GetOwn(ctx, userID)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	projectIDs := make(map[uuid.UUID]uuid.UUID, len(projects))
+	for _, project := range projects {
+		projectIDs[project.PublicID] = project.ID
+	}
+
+	now := accounts.service.nowFn()
+
+	for periodStart := since; periodStart.Before(before); {
+		year, month, _ := periodStart.UTC().Date()
+		monthStart := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		monthEnd := monthStart.AddDate(0, 1, 0)
+
+		periodEnd := monthEnd
+		if periodEnd.After(before) {
+			periodEnd = before
+		}
+
+		charges, err := accounts.ProjectCharges(ctx, userID, periodStart, periodEnd, prices)
+		if err != nil {
+			return nil, err
+		}
+
+		// only whole months are invoiced, hence partial periods are never finalized.
+		closed := periodStart.Equal(monthStart) && periodEnd.Equal(monthEnd) && !monthEnd.After(now)
+
+		period := payments.ProjectChargesPeriod{
+			Since:    periodStart,
+			Before:   periodEnd,
+			Projects: make(map[uuid.UUID]payments.PeriodProjectCharges, len(charges)),
+		}
+		for publicID, partnerCharges := range charges {
+			finalized := false
+			if closed {
+				record, err := accounts.service.db.ProjectRecords().Get(ctx, projectIDs[publicID], monthStart, monthEnd)
+				if err != nil {
+					return nil, Error.Wrap(err)
+				}
+				// state = 0 means unapplied and not invoiced yet.
+				finalized = record != nil && record.State != 0
+			}
+
+			period.Projects[publicID] = payments.PeriodProjectCharges{
+				Finalized: finalized,
+				Charges:   partnerCharges,
+			}
+		}
+
+		response = append(response, period)
+		periodStart = periodEnd
+	}
+
+	return response, nil
+}
+
+// EstimateUpcomingInvoice returns an estimate of the next invoice of the user,
+// based on the usage of the current billing period so far.
+func (accounts *accounts) EstimateUpcomingInvoice(ctx context.Context, userID uuid.UUID) (estimate payments.UpcomingInvoiceEstimate, err error) {
+	defer mon.Task()(&ctx, userID)(&err)
+
+	now := accounts.service.nowFn().UTC()
+	year, month, _ := now.Date()
+	estimate.Since = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	estimate.Before = now
+
+	charges, err := accounts.ProjectCharges(ctx, userID, estimate.Since, estimate.Before, nil)
+	if err != nil {
+		return payments.UpcomingInvoiceEstimate{}, err
+	}
+	for _, partnerCharges := range charges {
+		for _, charge := range partnerCharges {
+			estimate.UsageCents += charge.StorageMBMonthCents + charge.EgressMBCents + charge.SegmentMonthCents
+		}
+	}
+
+	estimate.PackagePlan, _, err = accounts.GetPackageInfo(ctx, userID)
+	if err != nil {
+		return payments.UpcomingInvoiceEstimate{}, err
+	}
+
+	return estimate, nil
+}
+
 // GetProjectUsagePriceModel returns the project usage price model for a partner name.
 func (accounts *accounts) GetProjectUsagePriceModel(partner string) payments.ProjectUsagePriceModel {
-	if override, ok := accounts.service.usagePriceOverrides[partner]; ok {
+	if override, ok := accounts.service.usagePriceOverrides[payments.NormalizePartnerName(partner)]; ok {
 		return override
 	}
 	return accounts.service.usagePrices
 }
 
+// GetPriceSnapshot returns a snapshot of the current project usage prices.
+func (accounts *accounts) GetPriceSnapshot() payments.PriceSnapshot {
+	overrides := make(map[string]payments.ProjectUsagePriceModel, len(accounts.service.usagePriceOverrides))
+	for partner, model := range accounts.service.usagePriceOverrides {
+		overrides[partner] = model
+	}
+	return payments.PriceSnapshot{
+		UsagePrices:         accounts.service.usagePrices,
+		UsagePriceOverrides: overrides,
+	}
+}
+
 // CheckProjectInvoicingStatus returns error if for the given project there are outstanding project records and/or usage
 // which have not been applied/invoiced yet (meaning sent over to stripe).
 func (accounts *accounts) CheckProjectInvoicingStatus(ctx context.Context, projectID uuid.UUID) (err error) {
@@ -4,18 +4,27 @@
 package stripe_test
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 
+	"storj.io/common/memory"
+	"storj.io/common/pb"
 	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
 	"storj.io/storj/private/testplanet"
 	"storj.io/storj/private/testredis"
+	"storj.io/storj/satellite"
 	"storj.io/storj/satellite/accounting"
 	"storj.io/storj/satellite/accounting/live"
 	"storj.io/storj/satellite/analytics"
+	"storj.io/storj/satellite/attribution"
+	"storj.io/storj/satellite/buckets"
 	"storj.io/storj/satellite/console"
 	"storj.io/storj/satellite/console/consoleauth"
 	"storj.io/storj/satellite/console/restkeys"
@@ -53,7 +62,7 @@ func TestSignupCouponCodes(t *testing.T) {
 		prices, err := pc.UsagePrice.ToModel()
 		require.NoError(t, err)
 
-		priceOverrides, err := pc.UsagePriceOverrides.ToModels()
+		priceOverrides, err := pc.UsagePriceOverrides.ToModels(pc.UsagePrice)
 		require.NoError(t, err)
 
 		paymentsService, err := stripe.NewService(
@@ -173,6 +182,225 @@ func TestUpdateGetPackage(t *testing.T) {
 	})
 }
 
+func TestBillingParent(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 2,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		sat := planet.Satellites[0]
+		accounts := sat.API.Payments.Accounts
+		customers := sat.DB.StripeCoinPayments().Customers()
+
+		userID := planet.Uplinks[0].Projects[0].Owner.ID
+		parentID := planet.Uplinks[1].Projects[0].Owner.ID
+
+		cusID, err := customers.GetCustomerID(ctx, userID)
+		require.NoError(t, err)
+		parentCusID, err := customers.GetCustomerID(ctx, parentID)
+		require.NoError(t, err)
+
+		require.Error(t, accounts.SetBillingParent(ctx, userID, ""))
+		require.Error(t, accounts.SetBillingParent(ctx, userID, cusID))
+		require.Error(t, accounts.SetBillingParent(ctx, userID, "cus_unknown"))
+
+		require.NoError(t, accounts.SetBillingParent(ctx, userID, parentCusID))
+		billingID, gotCusID, err := customers.GetStripeIDs(ctx, userID)
+		require.NoError(t, err)
+		require.Equal(t, cusID, gotCusID)
+		require.NotNil(t, billingID)
+		require.Equal(t, parentCusID, *billingID)
+
+		require.NoError(t, accounts.ClearBillingParent(ctx, userID))
+		billingID, _, err = customers.GetStripeIDs(ctx, userID)
+		require.NoError(t, err)
+		require.Nil(t, billingID)
+	})
+}
+
+func TestProjectChargesPriceSnapshot(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		sat := planet.Satellites[0]
+		accounts := sat.API.Payments.Accounts
+		project := planet.Uplinks[0].Projects[0]
+
+		since := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+		before := since.AddDate(0, 1, 0)
+
+		err := sat.DB.Orders().UpdateBucketBandwidthSettle(ctx, project.ID, []byte("testbucket"),
+			pb.PieceAction_GET, memory.TB.Int64(), 0, since.Add(time.Hour))
+		require.NoError(t, err)
+
+		current, err := accounts.ProjectCharges(ctx, project.Owner.ID, since, before, nil)
+		require.NoError(t, err)
+
+		snapshot := accounts.GetPriceSnapshot()
+		charges, err := accounts.ProjectCharges(ctx, project.Owner.ID, since, before, &snapshot)
+		require.NoError(t, err)
+		require.Equal(t, current, charges)
+
+		frozen := payments.PriceSnapshot{
+			UsagePrices: payments.ProjectUsagePriceModel{
+				EgressMBCents: decimal.NewFromFloat(0.0005),
+			},
+		}
+		charges, err = accounts.ProjectCharges(ctx, project.Owner.ID, since, before, &frozen)
+		require.NoError(t, err)
+		require.Len(t, charges[project.PublicID], 1)
+
+		charge := charges[project.PublicID][""]
+		require.Equal(t, memory.TB.Int64(), charge.Egress)
+		require.Zero(t, charge.FreeEgressBytes)
+		require.EqualValues(t, 500, charge.EgressMBCents)
+		require.Zero(t, charge.StorageMBMonthCents)
+		require.Zero(t, charge.SegmentMonthCents)
+	})
+}
+
+func TestProjectChargesIncludeZeroUsage(t *testing.T) {
+	partnerPrice := paymentsconfig.ProjectUsagePrice{
+		StorageTB: "4",
+		EgressTB:  "5",
+		Segment:   "6",
+	}
+	partners := []string{"partner-a", "partner-b"}
+
+	for _, includeZeroUsage := range []bool{false, true} {
+		t.Run(fmt.Sprintf("includeZeroUsage=%t", includeZeroUsage), func(t *testing.T) {
+			testplanet.Run(t, testplanet.Config{
+				SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+				Reconfigure: testplanet.Reconfigure{
+					Satellite: func(log *zap.Logger, index int, config *satellite.Config) {
+						config.Payments.StripeCoinPayments.IncludeZeroUsage = includeZeroUsage
+						overrides := make(map[string]paymentsconfig.ProjectUsagePrice)
+						for _, partner := range partners {
+							overrides[partner] = partnerPrice
+						}
+						config.Payments.UsagePriceOverrides.SetMap(overrides)
+					},
+				},
+			}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+				sat := planet.Satellites[0]
+				accounts := sat.API.Payments.Accounts
+
+				since := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+				before := since.AddDate(0, 1, 0)
+				zeroCharge := payments.ProjectCharge{
+					ProjectUsage: accounting.ProjectUsage{Since: since, Before: before},
+				}
+
+				user, err := sat.AddUser(ctx, console.CreateUser{
+					FullName: "Test User",
+					Email:    "user@mail.test",
+				}, 2)
+				require.NoError(t, err)
+
+				idle, err := sat.AddProject(ctx, user.ID, "idle")
+				require.NoError(t, err)
+
+				active, err := sat.AddProject(ctx, user.ID, "active")
+				require.NoError(t, err)
+
+				userAgent := []byte(partners[0])
+				bucket, err := sat.DB.Buckets().CreateBucket(ctx, buckets.Bucket{
+					ID:        testrand.UUID(),
+					Name:      testrand.BucketName(),
+					ProjectID: active.ID,
+					UserAgent: userAgent,
+				})
+				require.NoError(t, err)
+
+				_, err = sat.DB.Attribution().Insert(ctx, &attribution.Info{
+					ProjectID:  active.ID,
+					BucketName: []byte(bucket.Name),
+					UserAgent:  userAgent,
+				})
+				require.NoError(t, err)
+
+				err = sat.DB.Orders().UpdateBucketBandwidthSettle(ctx, active.ID, []byte(bucket.Name),
+					pb.PieceAction_GET, memory.TB.Int64(), 0, since.Add(time.Hour))
+				require.NoError(t, err)
+
+				charges, err := accounts.ProjectCharges(ctx, user.ID, since, before, nil)
+				require.NoError(t, err)
+				require.Len(t, charges, 2)
+
+				idleCharges := charges[idle.PublicID]
+				activeCharges := charges[active.PublicID]
+
+				if includeZeroUsage {
+					require.Len(t, idleCharges, len(partners)+1)
+					require.Len(t, activeCharges, len(partners)+1)
+					for _, partner := range append([]string{""}, partners...) {
+						require.Equal(t, zeroCharge, idleCharges[partner], partner)
+						if partner != partners[0] {
+							require.Equal(t, zeroCharge, activeCharges[partner], partner)
+						}
+					}
+				} else {
+					require.Equal(t, map[string]payments.ProjectCharge{"": zeroCharge}, idleCharges)
+					require.Len(t, activeCharges, 1)
+				}
+
+				// usage overwrites the seeded entry of the partner.
+				charge, ok := activeCharges[partners[0]]
+				require.True(t, ok)
+				require.Equal(t, memory.TB.Int64(), charge.Egress)
+				require.EqualValues(t, 500, charge.EgressMBCents)
+			})
+		})
+	}
+}
+
+func TestGetProjectUsagePriceModelNormalizesPartner(t *testing.T) {
+	var (
+		defaultPrice = paymentsconfig.ProjectUsagePrice{
+			StorageTB: "1",
+			EgressTB:  "2",
+			Segment:   "3",
+		}
+		partnerPrice = paymentsconfig.ProjectUsagePrice{
+			StorageTB: "4",
+			EgressTB:  "5",
+			Segment:   "6",
+		}
+		duplicatePrice = paymentsconfig.ProjectUsagePrice{
+			StorageTB: "7",
+			EgressTB:  "8",
+			Segment:   "9",
+		}
+	)
+	defaultModel, err := defaultPrice.ToModel()
+	require.NoError(t, err)
+	partnerModel, err := partnerPrice.ToModel()
+	require.NoError(t, err)
+
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+		Reconfigure: testplanet.Reconfigure{
+			Satellite: func(log *zap.Logger, index int, config *satellite.Config) {
+				config.Payments.UsagePrice = defaultPrice
+				config.Payments.UsagePriceOverrides.SetMap(map[string]paymentsconfig.ProjectUsagePrice{
+					" Acme ": partnerPrice,
+					// differs only by case, hence it's ignored in favor of the first entry.
+					"ACME": duplicatePrice,
+				})
+			},
+		},
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		accounts := planet.Satellites[0].API.Payments.Accounts
+		snapshot := accounts.GetPriceSnapshot()
+		require.Contains(t, snapshot.UsagePriceOverrides, "acme")
+
+		for _, partner := range []string{"acme", "Acme", "ACME", "  aCmE\t"} {
+			require.Equal(t, partnerModel, accounts.GetProjectUsagePriceModel(partner), partner)
+			require.Equal(t, partnerModel, snapshot.GetProjectUsagePriceModel(partner), partner)
+		}
+		require.Equal(t, defaultModel, accounts.GetProjectUsagePriceModel("acme-other"))
+		require.Equal(t, defaultModel, snapshot.GetProjectUsagePriceModel(""))
+	})
+}
+
 func TestBillingInformation(t *testing.T) {
 	testplanet.Run(t, testplanet.Config{
 		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
@@ -243,3 +471,195 @@ func TestBillingInformation(t *testing.T) {
 		require.Empty(t, newInfo.TaxIDs)
 	})
 }
+
+func TestAutomaticTax(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		accounts := planet.Satellites[0].API.Payments.Accounts
+		userID := planet.Uplinks[0].Projects[0].Owner.ID
+
+		// there's no address to calculate the tax from.
+		_, err := accounts.SetAutomaticTax(ctx, userID, true)
+		require.True(t, stripe.ErrIncompleteTaxAddress.Has(err), err)
+
+		var us payments.TaxCountry
+		for _, country := range payments.TaxCountries {
+			if country.Code == "US" {
+				us = country
+				break
+			}
+		}
+		address := payments.BillingAddress{
+			Name:       "Some Company",
+			Line1:      "Some street",
+			City:       "Some city",
+			PostalCode: "12345",
+			Country:    us,
+		}
+		_, err = accounts.SaveBillingAddress(ctx, userID, address)
+		require.NoError(t, err)
+
+		info, err := accounts.SetAutomaticTax(ctx, userID, true)
+		require.NoError(t, err)
+		require.True(t, info.AutomaticTax)
+
+		info, err = accounts.GetBillingInformation(ctx, userID)
+		require.NoError(t, err)
+		require.True(t, info.AutomaticTax)
+
+		// the address must stay complete while automatic tax is enabled.
+		incomplete := address
+		incomplete.PostalCode = ""
+		_, err = accounts.SaveBillingAddress(ctx, userID, incomplete)
+		require.True(t, stripe.ErrIncompleteTaxAddress.Has(err), err)
+		require.ErrorContains(t, err, "postal code is required in US")
+
+		info, err = accounts.SetAutomaticTax(ctx, userID, false)
+		require.NoError(t, err)
+		require.False(t, info.AutomaticTax)
+
+		info, err = accounts.SaveBillingAddress(ctx, userID, incomplete)
+		require.NoError(t, err)
+		require.Equal(t, incomplete, *info.Address)
+		require.False(t, info.AutomaticTax)
+	})
+}
+
+func TestExportBillingData(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		sat := planet.Satellites[0]
+		accounts := sat.API.Payments.Accounts
+		userID := planet.Uplinks[0].Projects[0].Owner.ID
+
+		export, err := accounts.ExportBillingData(ctx, userID)
+		require.NoError(t, err)
+		require.False(t, export.CustomerMissing)
+		require.Nil(t, export.Address)
+		require.Empty(t, export.TaxIDs)
+		require.Empty(t, export.CreditCards)
+
+		address := payments.BillingAddress{
+			Name:       "Some Company",
+			Line1:      "Some street",
+			City:       "Some city",
+			PostalCode: "12345",
+		}
+		_, err = accounts.SaveBillingAddress(ctx, userID, address)
+		require.NoError(t, err)
+
+		require.NoError(t, accounts.Invoices().AddDefaultInvoiceReference(ctx, userID, "ref-1"))
+
+		packagePlan := "package-plan-1"
+		purchaseTime := time.Now()
+		require.NoError(t, accounts.UpdatePackage(ctx, userID, &packagePlan, &purchaseTime))
+
+		card, err := accounts.CreditCards().Add(ctx, userID, "test")
+		require.NoError(t, err)
+
+		export, err = accounts.ExportBillingData(ctx, userID)
+		require.NoError(t, err)
+		require.False(t, export.CustomerMissing)
+		require.NotNil(t, export.Address)
+		require.Equal(t, address.Name, export.Address.Name)
+		require.Equal(t, address.City, export.Address.City)
+		require.Equal(t, "ref-1", export.InvoiceReference)
+		require.NotNil(t, export.PackagePlan)
+		require.Equal(t, packagePlan, *export.PackagePlan)
+		require.Len(t, export.CreditCards, 1)
+		require.Equal(t, card.Last4, export.CreditCards[0].LastFour)
+
+		t.Run("no customer", func(t *testing.T) {
+			export, err := accounts.ExportBillingData(ctx, testrand.UUID())
+			require.NoError(t, err)
+			require.True(t, export.CustomerMissing)
+		})
+
+		t.Run("missing stripe customer", func(t *testing.T) {
+			missingUserID := testrand.UUID()
+			require.NoError(t, sat.DB.StripeCoinPayments().Customers().Insert(ctx, missingUserID, "cus_missing"))
+
+			export, err := accounts.ExportBillingData(ctx, missingUserID)
+			require.NoError(t, err)
+			require.True(t, export.CustomerMissing)
+			require.Nil(t, export.Address)
+			require.Nil(t, export.PackagePlan)
+		})
+	})
+}
+
+func TestEstimateUpcomingInvoice(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		sat := planet.Satellites[0]
+		accounts := sat.API.Payments.Accounts
+		project := planet.Uplinks[0].Projects[0]
+
+		now := time.Now().UTC()
+		since := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		err := sat.DB.Orders().UpdateBucketBandwidthSettle(ctx, project.ID, []byte("testbucket"),
+			pb.PieceAction_GET, memory.TB.Int64(), 0, since)
+		require.NoError(t, err)
+
+		estimate, err := accounts.EstimateUpcomingInvoice(ctx, project.Owner.ID)
+		require.NoError(t, err)
+		require.Equal(t, since, estimate.Since)
+		require.Nil(t, estimate.PackagePlan)
+
+		charges, err := accounts.ProjectCharges(ctx, project.Owner.ID, estimate.Since, estimate.Before, nil)
+		require.NoError(t, err)
+
+		charge := charges[project.PublicID][""]
+		expected := charge.StorageMBMonthCents + charge.EgressMBCents + charge.SegmentMonthCents
+		require.NotZero(t, expected)
+		require.Equal(t, expected, estimate.UsageCents)
+	})
+}
+
+func TestProjectChargesDetailed(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		sat := planet.Satellites[0]
+		accounts := sat.API.Payments.Accounts
+		project := planet.Uplinks[0].Projects[0]
+
+		now := time.Now().UTC()
+		currentMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		previousMonth := currentMonth.AddDate(0, -1, 0)
+
+		requirePeriods := func(finalized bool) {
+			response, err := accounts.ProjectChargesDetailed(ctx, project.Owner.ID, previousMonth, now, nil)
+			require.NoError(t, err)
+			require.Len(t, response, 2)
+
+			require.Equal(t, previousMonth, response[0].Since)
+			require.Equal(t, currentMonth, response[0].Before)
+			require.Contains(t, response[0].Projects, project.PublicID)
+			require.Equal(t, finalized, response[0].Projects[project.PublicID].Finalized)
+
+			require.Equal(t, currentMonth, response[1].Since)
+			require.Equal(t, now, response[1].Before)
+			require.Contains(t, response[1].Projects, project.PublicID)
+			require.False(t, response[1].Projects[project.PublicID].Finalized)
+		}
+
+		requirePeriods(false)
+
+		records := sat.DB.StripeCoinPayments().ProjectRecords()
+		require.NoError(t, records.Create(ctx, []stripe.CreateProjectRecord{{ProjectID: project.ID}}, previousMonth, currentMonth))
+
+		// the record hasn't been applied yet.
+		requirePeriods(false)
+
+		record, err := records.Get(ctx, project.ID, previousMonth, currentMonth)
+		require.NoError(t, err)
+		require.NoError(t, records.Consume(ctx, record.ID))
+
+		requirePeriods(true)
+	})
+}
@@ -16,3 +16,76 @@ func convertToCents(rate decimal.Decimal, amount currency.Amount) int64 {
 	usdCents := usd.Shift(2)
 	return usdCents.Round(0).IntPart()
 }
+
+// RoundingMode is a strategy for rounding usage prices to whole cents.
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds half away from zero.
+	RoundHalfUp RoundingMode = "half-up"
+	// RoundHalfEven rounds half to the nearest even value (banker's rounding).
+	RoundHalfEven RoundingMode = "half-even"
+	// RoundTruncate drops the fractional part.
+	RoundTruncate RoundingMode = "truncate"
+)
+
+// String implements pflag.Value.
+func (mode *RoundingMode) String() string {
+	if mode == nil {
+		return ""
+	}
+	return string(*mode)
+}
+
+// Set implements pflag.Value.
+func (mode *RoundingMode) Set(s string) error {
+	switch RoundingMode(s) {
+	case RoundHalfUp, RoundHalfEven, RoundTruncate:
+		*mode = RoundingMode(s)
+		return nil
+	case "":
+		*mode = RoundHalfUp
+		return nil
+	default:
+		return Error.New("invalid rounding mode %q (expected %s, %s or %s)", s, RoundHalfUp, RoundHalfEven, RoundTruncate)
+	}
+}
+
+// Type implements pflag.Value.
+func (RoundingMode) Type() string { return "stripe.RoundingMode" }
+
+// Round rounds the amount to a whole number using the rounding mode.
+// Unknown and empty modes round half up.
+func (mode RoundingMode) Round(amount decimal.Decimal) decimal.Decimal {
+	switch mode {
+	case RoundHalfEven:
+		return amount.RoundBank(0)
+	case RoundTruncate:
+		return amount.Truncate(0)
+	default:
+		return amount.Round(0)
+	}
+}
+
+// UsageCents returns the charge in whole cents for a usage quantity priced at unitCents
+// cents per unit. The exact product is rounded with the rounding mode. With RoundHalfUp
+// it's the same amount that Stripe computes for an invoice line from its quantity and
+// unit_amount_decimal. The charge estimates and the invoice lines both use it, so that
+// the estimate always matches the invoice.
+func (mode RoundingMode) UsageCents(quantity, unitCents decimal.Decimal) int64 {
+	return mode.Round(quantity.Mul(unitCents)).IntPart()
+}
+
+// stripeUnitAmountPlaces is the maximum number of decimal places of unit_amount_decimal.
+const stripeUnitAmountPlaces = 12
+
+// invoiceLineUnitAmount returns the unit_amount_decimal of an invoice line with the quantity,
+// such that Stripe computes the same amount as UsageCents. It's unitCents, unless the rounding
+// mode produces a different amount than Stripe, in which case the unit amount is adjusted.
+func (mode RoundingMode) invoiceLineUnitAmount(quantity, unitCents decimal.Decimal) decimal.Decimal {
+	cents := mode.UsageCents(quantity, unitCents)
+	if quantity.IsZero() || RoundHalfUp.UsageCents(quantity, unitCents) == cents {
+		return unitCents
+	}
+	return decimal.NewFromInt(cents).DivRound(quantity, stripeUnitAmountPlaces)
+}
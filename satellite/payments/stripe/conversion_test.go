@@ -0,0 +1,73 @@
+// Copyright (C) 2024 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package stripe_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"storj.io/storj/satellite/payments/stripe"
+)
+
+func TestRoundingMode(t *testing.T) {
+	tests := []struct {
+		amount   string
+		halfUp   int64
+		halfEven int64
+		truncate int64
+	}{
+		{"0", 0, 0, 0},
+		{"1.4", 1, 1, 1},
+		{"1.5", 2, 2, 1},
+		{"2.5", 3, 2, 2},
+		{"2.51", 3, 3, 2},
+		{"2.99", 3, 3, 2},
+	}
+	for _, tt := range tests {
+		amount := decimal.RequireFromString(tt.amount)
+		require.Equal(t, tt.halfUp, stripe.RoundHalfUp.Round(amount).IntPart(), tt.amount)
+		require.Equal(t, tt.halfEven, stripe.RoundHalfEven.Round(amount).IntPart(), tt.amount)
+		require.Equal(t, tt.truncate, stripe.RoundTruncate.Round(amount).IntPart(), tt.amount)
+
+		var unset stripe.RoundingMode
+		require.Equal(t, tt.halfUp, unset.Round(amount).IntPart(), tt.amount)
+	}
+
+	var mode stripe.RoundingMode
+	require.NoError(t, mode.Set("half-even"))
+	require.Equal(t, stripe.RoundHalfEven, mode)
+	require.NoError(t, mode.Set(""))
+	require.Equal(t, stripe.RoundHalfUp, mode)
+	require.Error(t, mode.Set("ceil"))
+}
+
+func TestUsageCents(t *testing.T) {
+	tests := []struct {
+		quantity  int64
+		unitCents string
+		halfUp    int64
+		halfEven  int64
+		truncate  int64
+	}{
+		{0, "0.0004", 0, 0, 0},
+		{1, "0.4999", 0, 0, 0},
+		{1, "0.5", 1, 0, 0},
+		{3, "0.5", 2, 2, 1},
+		{5, "0.5", 3, 2, 2},
+		{1000, "0.0015", 2, 2, 1},
+		{1000, "0.0025", 3, 2, 2},
+		{1000001, "0.0005", 500, 500, 500},
+		{3000, "0.0005", 2, 2, 1},
+		{1234567, "0.0006", 741, 741, 740},
+	}
+	for _, tt := range tests {
+		quantity := decimal.NewFromInt(tt.quantity)
+		unitCents := decimal.RequireFromString(tt.unitCents)
+		require.Equal(t, tt.halfUp, stripe.RoundHalfUp.UsageCents(quantity, unitCents), "%d × %s", tt.quantity, tt.unitCents)
+		require.Equal(t, tt.halfEven, stripe.RoundHalfEven.UsageCents(quantity, unitCents), "%d × %s", tt.quantity, tt.unitCents)
+		require.Equal(t, tt.truncate, stripe.RoundTruncate.UsageCents(quantity, unitCents), "%d × %s", tt.quantity, tt.unitCents)
+	}
+}
@@ -66,14 +66,13 @@ func (coupons *coupons) ApplyCoupon(ctx context.Context, userID uuid.UUID, coupo
 func (coupons *coupons) ApplyCouponCode(ctx context.Context, userID uuid.UUID, couponCode string) (_ *payments.Coupon, err error) {
 	defer mon.Task()(&ctx, userID, couponCode)(&err)
 
-	promoCodeIter := coupons.service.stripeClient.PromoCodes().List(&stripe.PromotionCodeListParams{
-		ListParams: stripe.ListParams{Context: ctx},
-		Code:       stripe.String(couponCode),
-	})
-	if !promoCodeIter.Next() {
+	promoCode, err := coupons.service.findPromoCode(ctx, couponCode)
+	if err != nil {
+		return nil, err
+	}
+	if promoCode == nil {
 		return nil, payments.ErrInvalidCoupon.New("Invalid coupon code")
 	}
-	promoCode := promoCodeIter.PromotionCode()
 
 	customerID, err := coupons.service.db.Customers().GetCustomerID(ctx, userID)
 	if err != nil {
@@ -98,10 +97,119 @@ func (coupons *coupons) ApplyCouponCode(ctx context.Context, userID uuid.UUID, c
 	return stripeDiscountToPaymentsCoupon(customer.Discount)
 }
 
+// ApplyPromoCode resolves the promotion code to its coupon and applies the coupon
+// to the existing customer of the user.
+func (coupons *coupons) ApplyPromoCode(ctx context.Context, userID uuid.UUID, promoCode string) (_ *payments.Coupon, err error) {
+	defer mon.Task()(&ctx, userID, promoCode)(&err)
+
+	code, err := coupons.service.findPromoCode(ctx, promoCode)
+	if err != nil {
+		return nil, err
+	}
+	if code == nil || code.Coupon == nil || !code.Active {
+		return nil, payments.ErrInvalidCoupon.New("Invalid promo code")
+	}
+	if promoCodeExpired(code, coupons.service.nowFn()) {
+		return nil, payments.ErrInvalidCoupon.New("Promo code has expired")
+	}
+
+	customerID, err := coupons.service.db.Customers().GetCustomerID(ctx, userID)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	customer, err := coupons.service.stripeClient.Customers().Update(customerID, &stripe.CustomerParams{
+		Params: stripe.Params{Context: ctx},
+		Coupon: stripe.String(code.Coupon.ID),
+	})
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	coupon, err := stripeDiscountToPaymentsCoupon(customer.Discount)
+	if err != nil {
+		return nil, err
+	}
+	coupon.PromoCode = code.Code
+	return coupon, nil
+}
+
+// findPromoCode returns the Stripe promotion code matching code or nil if there is none.
+func (service *Service) findPromoCode(ctx context.Context, code string) (*stripe.PromotionCode, error) {
+	promoCodeIter := service.stripeClient.PromoCodes().List(&stripe.PromotionCodeListParams{
+		ListParams: stripe.ListParams{Context: ctx},
+		Code:       stripe.String(code),
+	})
+	if !promoCodeIter.Next() {
+		return nil, Error.Wrap(promoCodeIter.Err())
+	}
+	return promoCodeIter.PromotionCode(), nil
+}
+
+// promoCodeExpired returns whether the promotion code or its coupon can no longer be redeemed.
+func promoCodeExpired(promoCode *stripe.PromotionCode, now time.Time) bool {
+	if promoCode.ExpiresAt != 0 && !now.Before(time.Unix(promoCode.ExpiresAt, 0)) {
+		return true
+	}
+	if promoCode.Coupon != nil && promoCode.Coupon.RedeemBy != 0 && !now.Before(time.Unix(promoCode.Coupon.RedeemBy, 0)) {
+		return true
+	}
+	return false
+}
+
 // GetByUserID returns the coupon applied to the user.
 func (coupons *coupons) GetByUserID(ctx context.Context, userID uuid.UUID) (_ *payments.Coupon, err error) {
 	defer mon.Task()(&ctx, userID)(&err)
 
+	discount, err := coupons.getDiscount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if discount == nil {
+		return nil, nil
+	}
+
+	return stripeDiscountToPaymentsCoupon(discount)
+}
+
+// GetApplied returns the coupon applied to the user and how much of it remains.
+func (coupons *coupons) GetApplied(ctx context.Context, userID uuid.UUID) (_ payments.AppliedCoupon, err error) {
+	defer mon.Task()(&ctx, userID)(&err)
+
+	discount, err := coupons.getDiscount(ctx, userID)
+	if err != nil {
+		return payments.AppliedCoupon{}, err
+	}
+	if discount == nil {
+		return payments.AppliedCoupon{}, nil
+	}
+
+	coupon, err := stripeDiscountToPaymentsCoupon(discount)
+	if err != nil {
+		return payments.AppliedCoupon{}, err
+	}
+
+	applied := payments.AppliedCoupon{
+		Coupon:  *coupon,
+		Applied: true,
+	}
+	if discount.Coupon.MaxRedemptions > 0 {
+		remaining := discount.Coupon.MaxRedemptions - discount.Coupon.TimesRedeemed
+		if remaining < 0 {
+			remaining = 0
+		}
+		applied.RemainingRedemptions = &remaining
+	}
+	if discount.Coupon.Duration == stripe.CouponDurationRepeating && discount.End != 0 {
+		remaining := remainingMonths(coupons.service.nowFn(), time.Unix(discount.End, 0))
+		applied.RemainingMonths = &remaining
+	}
+
+	return applied, nil
+}
+
+// getDiscount returns the discount of the user's customer, or nil when no coupon is applied.
+func (coupons *coupons) getDiscount(ctx context.Context, userID uuid.UUID) (_ *stripe.Discount, err error) {
 	customerID, err := coupons.service.db.Customers().GetCustomerID(ctx, userID)
 	if err != nil {
 		return nil, Error.Wrap(err)
@@ -118,8 +226,15 @@ func (coupons *coupons) GetByUserID(ctx context.Context, userID uuid.UUID) (_ *p
 	if customer.Discount == nil || customer.Discount.Coupon == nil {
 		return nil, nil
 	}
+	return customer.Discount, nil
+}
 
-	return stripeDiscountToPaymentsCoupon(customer.Discount)
+// remainingMonths returns the number of started months from now until the discount ends.
+func remainingMonths(now, end time.Time) (months int64) {
+	for now.AddDate(0, int(months), 0).Before(end) {
+		months++
+	}
+	return months
 }
 
 // stripeDiscountToPaymentsCoupon converts a Stripe discount to a payments.Coupon.
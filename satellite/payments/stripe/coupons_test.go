@@ -13,6 +13,7 @@ import (
 	"storj.io/common/testrand"
 	"storj.io/storj/private/testplanet"
 	"storj.io/storj/satellite"
+	"storj.io/storj/satellite/payments"
 	"storj.io/storj/satellite/payments/stripe"
 )
 
@@ -66,5 +67,73 @@ func TestCoupons(t *testing.T) {
 			require.Error(t, err)
 			require.Nil(t, coupon)
 		})
+		t.Run("ApplyPromoCode fails with invalid code", func(t *testing.T) {
+			coupon, err := c.ApplyPromoCode(ctx, userID, "unknown_promo_code")
+			require.True(t, payments.ErrInvalidCoupon.Has(err))
+			require.Nil(t, coupon)
+		})
+		t.Run("ApplyPromoCode fails with unknown user", func(t *testing.T) {
+			coupon, err := c.ApplyPromoCode(ctx, testrand.UUID(), "promo3")
+			require.Error(t, err)
+			require.Nil(t, coupon)
+		})
+		t.Run("ApplyPromoCode, GetByUserID succeeds", func(t *testing.T) {
+			coupon, err := c.ApplyPromoCode(ctx, userID, "promo3")
+			require.NoError(t, err)
+			require.NotNil(t, coupon)
+			require.Equal(t, stripe.MockCouponID3, coupon.ID)
+			require.Equal(t, "promo3", coupon.PromoCode)
+
+			coupon, err = c.GetByUserID(ctx, userID)
+			require.NoError(t, err)
+			require.Equal(t, stripe.MockCouponID3, coupon.ID)
+		})
+	})
+}
+
+func TestGetAppliedCoupon(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
+		Reconfigure: testplanet.Reconfigure{
+			Satellite: func(log *zap.Logger, index int, config *satellite.Config) {
+				config.Payments.StripeCoinPayments.StripeFreeTierCouponID = ""
+			},
+		},
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		c := planet.Satellites[0].API.Payments.Accounts.Coupons()
+		userID := planet.Uplinks[0].Projects[0].Owner.ID
+
+		applied, err := c.GetApplied(ctx, userID)
+		require.NoError(t, err)
+		require.Zero(t, applied)
+
+		_, err = c.GetApplied(ctx, testrand.UUID())
+		require.Error(t, err)
+
+		_, err = c.ApplyCoupon(ctx, userID, stripe.MockCouponID2)
+		require.NoError(t, err)
+
+		applied, err = c.GetApplied(ctx, userID)
+		require.NoError(t, err)
+		require.True(t, applied.Applied)
+		require.Equal(t, stripe.MockCouponID2, applied.ID)
+		require.Equal(t, "Test Promo Code 2", applied.Name)
+		require.EqualValues(t, 50, applied.PercentOff)
+		require.Nil(t, applied.RemainingMonths)
+		require.Nil(t, applied.RemainingRedemptions)
+
+		_, err = c.ApplyCoupon(ctx, userID, stripe.MockCouponID4)
+		require.NoError(t, err)
+
+		applied, err = c.GetApplied(ctx, userID)
+		require.NoError(t, err)
+		require.True(t, applied.Applied)
+		require.Equal(t, stripe.MockCouponID4, applied.ID)
+		require.EqualValues(t, 1000, applied.AmountOff)
+		require.Equal(t, payments.CouponDuration(payments.CouponRepeating), applied.Duration)
+		require.NotNil(t, applied.RemainingMonths)
+		require.EqualValues(t, 3, *applied.RemainingMonths)
+		require.NotNil(t, applied.RemainingRedemptions)
+		require.EqualValues(t, 6, *applied.RemainingRedemptions)
 	})
 }
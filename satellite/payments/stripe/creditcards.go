@@ -22,6 +22,8 @@ var (
 	ErrDefaultCard = errs.Class("default card")
 	// ErrDuplicateCard is returned when a user tries to add duplicate card.
 	ErrDuplicateCard = errs.Class("duplicate card")
+	// ErrDefaultCardNotSet is returned when a user has no default payment method.
+	ErrDefaultCardNotSet = errs.Class("default card not set")
 
 	// UnattachedErrString is part of the err string returned by stripe if a payment
 	// method does not belong to a customer.
@@ -255,6 +257,44 @@ func (creditCards *creditCards) MakeDefault(ctx context.Context, userID uuid.UUI
 	return Error.Wrap(err)
 }
 
+// GetDefault returns the card info of the customer's default payment method.
+func (creditCards *creditCards) GetDefault(ctx context.Context, userID uuid.UUID) (_ payments.CardInfo, err error) {
+	defer mon.Task()(&ctx, userID)(&err)
+
+	customerID, err := creditCards.service.db.Customers().GetCustomerID(ctx, userID)
+	if err != nil {
+		return payments.CardInfo{}, payments.ErrAccountNotSetup.Wrap(err)
+	}
+
+	cusParams := &stripe.CustomerParams{Params: stripe.Params{Context: ctx}}
+	customer, err := creditCards.service.stripeClient.Customers().Get(customerID, cusParams)
+	if err != nil {
+		return payments.CardInfo{}, Error.Wrap(err)
+	}
+	if customer.InvoiceSettings == nil ||
+		customer.InvoiceSettings.DefaultPaymentMethod == nil ||
+		customer.InvoiceSettings.DefaultPaymentMethod.ID == "" {
+		return payments.CardInfo{}, ErrDefaultCardNotSet.New("no default payment method is set for this account.")
+	}
+
+	// the default payment method of the customer isn't expanded.
+	card, err := creditCards.service.stripeClient.PaymentMethods().Get(customer.InvoiceSettings.DefaultPaymentMethod.ID, &stripe.PaymentMethodParams{
+		Params: stripe.Params{Context: ctx},
+	})
+	if err != nil {
+		return payments.CardInfo{}, Error.Wrap(err)
+	}
+	if card.Card == nil {
+		return payments.CardInfo{}, ErrCardNotFound.New("default payment method is not a card.")
+	}
+
+	return payments.CardInfo{
+		ID:       card.ID,
+		Brand:    string(card.Card.Brand),
+		LastFour: card.Card.Last4,
+	}, nil
+}
+
 // Remove is used to remove credit card from payment account.
 func (creditCards *creditCards) Remove(ctx context.Context, userID uuid.UUID, cardID string) (err error) {
 	defer mon.Task()(&ctx, cardID)(&err)
@@ -13,6 +13,7 @@ import (
 	"storj.io/common/testcontext"
 	"storj.io/storj/private/testplanet"
 	"storj.io/storj/satellite/console"
+	"storj.io/storj/satellite/payments"
 	"storj.io/storj/satellite/payments/stripe"
 )
 
@@ -192,6 +193,44 @@ func TestCreditCards_Remove(t *testing.T) {
 	})
 }
 
+func TestCreditCards_GetDefault(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		satellite := planet.Satellites[0]
+		userID := planet.Uplinks[0].Projects[0].Owner.ID
+
+		customerID, err := satellite.DB.StripeCoinPayments().Customers().GetCustomerID(ctx, userID)
+		require.NoError(t, err)
+
+		_, err = satellite.API.Payments.StripeClient.Customers().Update(customerID, &stripeLib.CustomerParams{
+			InvoiceSettings: &stripeLib.CustomerInvoiceSettingsParams{
+				DefaultPaymentMethod: stripeLib.String(""),
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = satellite.API.Payments.Accounts.CreditCards().GetDefault(ctx, userID)
+		require.Error(t, err)
+		require.True(t, stripe.ErrDefaultCardNotSet.Has(err))
+
+		_, err = satellite.API.Payments.Accounts.CreditCards().Add(ctx, userID, "test")
+		require.NoError(t, err)
+
+		// card2 becomes the default card
+		card2, err := satellite.API.Payments.Accounts.CreditCards().Add(ctx, userID, "test2")
+		require.NoError(t, err)
+
+		info, err := satellite.API.Payments.Accounts.CreditCards().GetDefault(ctx, userID)
+		require.NoError(t, err)
+		require.Equal(t, payments.CardInfo{
+			ID:       card2.ID,
+			Brand:    card2.Brand,
+			LastFour: card2.Last4,
+		}, info)
+	})
+}
+
 func TestCreditCards_RemoveAll(t *testing.T) {
 	testplanet.Run(t, testplanet.Config{
 		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 1,
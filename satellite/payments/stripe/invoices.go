@@ -5,16 +5,25 @@ package stripe
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/stripe/stripe-go/v75"
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
 
+	"storj.io/common/currency"
 	"storj.io/common/uuid"
 	"storj.io/storj/satellite/payments"
 )
 
+// ErrTooManyInvoiceCustomFields is returned when a customer would exceed the allowed number of invoice custom fields.
+var ErrTooManyInvoiceCustomFields = errs.Class("too many invoice custom fields")
+
+// invoiceReferenceField is the name of the invoice custom field containing the invoice reference.
+const invoiceReferenceField = "Reference"
+
 // invoices is an implementation of payments.Invoices.
 //
 // architecture: Service
@@ -254,6 +263,141 @@ func (invoices *invoices) List(ctx context.Context, userID uuid.UUID) (invoicesL
 	return invoicesList, nil
 }
 
+// TotalOwed returns the sum of the remaining amounts of the user's open and uncollectible invoices.
+// Zero is returned if the user has no customer.
+func (invoices *invoices) TotalOwed(ctx context.Context, userID uuid.UUID) (_ currency.Amount, err error) {
+	defer mon.Task()(&ctx, userID)(&err)
+
+	customerID, err := invoices.service.db.Customers().GetCustomerID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNoCustomer) {
+			return currency.AmountFromBaseUnits(0, currency.USDollars), nil
+		}
+		return currency.Amount{}, Error.Wrap(err)
+	}
+
+	var total int64
+	for _, status := range []stripe.InvoiceStatus{stripe.InvoiceStatusOpen, stripe.InvoiceStatusUncollectible} {
+		params := &stripe.InvoiceListParams{
+			ListParams: stripe.ListParams{Context: ctx},
+			Customer:   &customerID,
+			Status:     stripe.String(string(status)),
+		}
+
+		// the iterator fetches the following pages on demand.
+		invoicesIterator := invoices.service.stripeClient.Invoices().List(params)
+		for invoicesIterator.Next() {
+			total += invoicesIterator.Invoice().AmountRemaining
+		}
+		if err = invoicesIterator.Err(); err != nil {
+			return currency.Amount{}, Error.Wrap(err)
+		}
+	}
+
+	return currency.AmountFromBaseUnits(total, currency.USDollars), nil
+}
+
+// AddManualLineItem creates a pending invoice item on the user's customer, which is
+// included in the next invoice. A negative amount creates a credit.
+func (invoices *invoices) AddManualLineItem(ctx context.Context, userID uuid.UUID, amount currency.Amount, description string) (err error) {
+	defer mon.Task()(&ctx, userID)(&err)
+
+	if amount.Currency() != currency.USDollars && amount.Currency() != currency.USDollarsMicro {
+		return Error.New("unsupported currency: %s", amount.Currency().Symbol())
+	}
+
+	cents := convertToCents(decimal.NewFromInt(1), amount)
+	if cents == 0 {
+		return Error.New("line item amount must not be zero")
+	}
+
+	customerID, err := invoices.service.db.Customers().GetCustomerID(ctx, userID)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	_, err = invoices.service.stripeClient.InvoiceItems().New(&stripe.InvoiceItemParams{
+		Params:      stripe.Params{Context: ctx},
+		Customer:    stripe.String(customerID),
+		Amount:      stripe.Int64(cents),
+		Description: stripe.String(description),
+		Currency:    stripe.String(string(stripe.CurrencyUSD)),
+	})
+	return Error.Wrap(err)
+}
+
+// AddDefaultInvoiceReference sets the reference shown on all future invoices of the user.
+func (invoices *invoices) AddDefaultInvoiceReference(ctx context.Context, userID uuid.UUID, reference string) (err error) {
+	defer mon.Task()(&ctx, userID)(&err)
+
+	customerID, err := invoices.service.db.Customers().GetCustomerID(ctx, userID)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	customer, err := invoices.service.stripeClient.Customers().Get(customerID, &stripe.CustomerParams{
+		Params: stripe.Params{Context: ctx},
+	})
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	fields, err := invoices.service.mergeInvoiceCustomFields(customer, invoiceReferenceField, reference)
+	if err != nil {
+		return err
+	}
+
+	_, err = invoices.service.stripeClient.Customers().Update(customerID, &stripe.CustomerParams{
+		Params: stripe.Params{Context: ctx},
+		InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
+			CustomFields: fields,
+		},
+	})
+	return Error.Wrap(err)
+}
+
+// mergeInvoiceCustomFields returns the invoice custom fields of the customer with the field
+// of the given name set to value. An empty value removes the field.
+//
+// Stripe replaces all the custom fields on update, hence every method changing them must
+// start from the existing fields.
+func (service *Service) mergeInvoiceCustomFields(customer *stripe.Customer, name, value string) ([]*stripe.CustomerInvoiceSettingsCustomFieldParams, error) {
+	fields := []*stripe.CustomerInvoiceSettingsCustomFieldParams{}
+	found := false
+	if customer.InvoiceSettings != nil {
+		for _, field := range customer.InvoiceSettings.CustomFields {
+			if field.Name == name {
+				found = true
+				if value == "" {
+					continue
+				}
+				fields = append(fields, &stripe.CustomerInvoiceSettingsCustomFieldParams{
+					Name:  stripe.String(name),
+					Value: stripe.String(value),
+				})
+				continue
+			}
+			fields = append(fields, &stripe.CustomerInvoiceSettingsCustomFieldParams{
+				Name:  stripe.String(field.Name),
+				Value: stripe.String(field.Value),
+			})
+		}
+	}
+
+	if !found && value != "" {
+		fields = append(fields, &stripe.CustomerInvoiceSettingsCustomFieldParams{
+			Name:  stripe.String(name),
+			Value: stripe.String(value),
+		})
+	}
+
+	if len(fields) > service.maxCustomFields {
+		return nil, ErrTooManyInvoiceCustomFields.New("customer would have %d invoice custom fields, the limit is %d", len(fields), service.maxCustomFields)
+	}
+
+	return fields, nil
+}
+
 func (invoices *invoices) ListFailed(ctx context.Context, userID *uuid.UUID) (invoicesList []payments.Invoice, err error) {
 	defer mon.Task()(&ctx)(&err)
 
@@ -9,12 +9,14 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"github.com/stripe/stripe-go/v75"
+	"go.uber.org/zap"
 
 	"storj.io/common/currency"
 	"storj.io/common/testcontext"
 	"storj.io/common/testrand"
 	"storj.io/storj/private/blockchain"
 	"storj.io/storj/private/testplanet"
+	"storj.io/storj/satellite"
 	"storj.io/storj/satellite/console"
 	"storj.io/storj/satellite/payments/billing"
 	stripe1 "storj.io/storj/satellite/payments/stripe"
@@ -215,3 +217,216 @@ func TestPayOverdueInvoices(t *testing.T) {
 		require.Zero(t, balance.BaseUnits())
 	})
 }
+
+func TestTotalOwed(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		satellite := planet.Satellites[0]
+
+		owed, err := satellite.API.Payments.Accounts.Invoices().TotalOwed(ctx, testrand.UUID())
+		require.NoError(t, err)
+		require.Equal(t, currency.AmountFromBaseUnits(0, currency.USDollars), owed)
+
+		user, err := satellite.AddUser(ctx, console.CreateUser{
+			FullName: "testuser",
+			Email:    "user@test",
+		}, 1)
+		require.NoError(t, err)
+		customer, err := satellite.DB.StripeCoinPayments().Customers().GetCustomerID(ctx, user.ID)
+		require.NoError(t, err)
+
+		createInvoice := func(amount int64) *stripe.Invoice {
+			inv, err := satellite.API.Payments.StripeClient.Invoices().New(&stripe.InvoiceParams{
+				Params:   stripe.Params{Context: ctx},
+				Customer: &customer,
+			})
+			require.NoError(t, err)
+
+			_, err = satellite.API.Payments.StripeClient.InvoiceItems().New(&stripe.InvoiceItemParams{
+				Params:   stripe.Params{Context: ctx},
+				Amount:   stripe.Int64(amount),
+				Currency: stripe.String(string(stripe.CurrencyUSD)),
+				Customer: &customer,
+				Invoice:  stripe.String(inv.ID),
+			})
+			require.NoError(t, err)
+			return inv
+		}
+
+		finalizeParams := &stripe.InvoiceFinalizeInvoiceParams{Params: stripe.Params{Context: ctx}}
+
+		// open invoice is counted.
+		inv1 := createInvoice(75)
+		_, err = satellite.API.Payments.StripeClient.Invoices().FinalizeInvoice(inv1.ID, finalizeParams)
+		require.NoError(t, err)
+
+		// uncollectible invoice is counted.
+		inv2 := createInvoice(100)
+		_, err = satellite.API.Payments.StripeClient.Invoices().FinalizeInvoice(inv2.ID, finalizeParams)
+		require.NoError(t, err)
+		_, err = satellite.API.Payments.StripeClient.Invoices().MarkUncollectible(inv2.ID, &stripe.InvoiceMarkUncollectibleParams{
+			Params: stripe.Params{Context: ctx},
+		})
+		require.NoError(t, err)
+
+		// draft invoice is not counted.
+		createInvoice(50)
+
+		owed, err = satellite.API.Payments.Accounts.Invoices().TotalOwed(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, currency.AmountFromBaseUnits(175, currency.USDollars), owed)
+	})
+}
+
+func TestAddManualLineItem(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		satellite := planet.Satellites[0]
+		invoices := satellite.API.Payments.Accounts.Invoices()
+
+		user, err := satellite.AddUser(ctx, console.CreateUser{
+			FullName: "testuser",
+			Email:    "user@test",
+		}, 1)
+		require.NoError(t, err)
+		customer, err := satellite.DB.StripeCoinPayments().Customers().GetCustomerID(ctx, user.ID)
+		require.NoError(t, err)
+
+		// zero amounts are rejected.
+		err = invoices.AddManualLineItem(ctx, user.ID, currency.AmountFromBaseUnits(0, currency.USDollars), "zero")
+		require.Error(t, err)
+		err = invoices.AddManualLineItem(ctx, user.ID, currency.AmountFromBaseUnits(1, currency.USDollarsMicro), "less than a cent")
+		require.Error(t, err)
+
+		// non-USD amounts are rejected.
+		err = invoices.AddManualLineItem(ctx, user.ID, currency.AmountFromBaseUnits(100, currency.StorjToken), "tokens")
+		require.Error(t, err)
+
+		require.NoError(t, invoices.AddManualLineItem(ctx, user.ID, currency.AmountFromBaseUnits(500, currency.USDollars), "charge"))
+		require.NoError(t, invoices.AddManualLineItem(ctx, user.ID, currency.AmountFromBaseUnits(-2500000, currency.USDollarsMicro), "SLA credit"))
+
+		itemsIter := satellite.API.Payments.StripeClient.InvoiceItems().List(&stripe.InvoiceItemListParams{
+			ListParams: stripe.ListParams{Context: ctx},
+			Customer:   &customer,
+			Pending:    stripe.Bool(true),
+		})
+		amounts := map[string]int64{}
+		for itemsIter.Next() {
+			item := itemsIter.InvoiceItem()
+			amounts[item.Description] = item.Amount
+		}
+		require.NoError(t, itemsIter.Err())
+		require.Equal(t, map[string]int64{"charge": 500, "SLA credit": -250}, amounts)
+	})
+}
+
+func TestAddDefaultInvoiceReference(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+		Reconfigure: testplanet.Reconfigure{
+			Satellite: func(log *zap.Logger, index int, config *satellite.Config) {
+				config.Payments.StripeCoinPayments.MaxInvoiceCustomFields = 2
+			},
+		},
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		sat := planet.Satellites[0]
+		invoices := sat.API.Payments.Accounts.Invoices()
+		customers := sat.API.Payments.StripeClient.Customers()
+
+		user, err := sat.AddUser(ctx, console.CreateUser{
+			FullName: "testuser",
+			Email:    "user@test",
+		}, 1)
+		require.NoError(t, err)
+		customerID, err := sat.DB.StripeCoinPayments().Customers().GetCustomerID(ctx, user.ID)
+		require.NoError(t, err)
+
+		requireFields := func(expected map[string]string) {
+			customer, err := customers.Get(customerID, nil)
+			require.NoError(t, err)
+			fields := map[string]string{}
+			for _, field := range customer.InvoiceSettings.CustomFields {
+				fields[field.Name] = field.Value
+			}
+			require.Equal(t, expected, fields)
+		}
+
+		require.NoError(t, invoices.AddDefaultInvoiceReference(ctx, user.ID, "ref-1"))
+		requireFields(map[string]string{"Reference": "ref-1"})
+
+		// other custom fields are kept.
+		_, err = customers.Update(customerID, &stripe.CustomerParams{
+			InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
+				CustomFields: []*stripe.CustomerInvoiceSettingsCustomFieldParams{
+					{Name: stripe.String("Reference"), Value: stripe.String("ref-1")},
+					{Name: stripe.String("PO"), Value: stripe.String("po-1")},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, invoices.AddDefaultInvoiceReference(ctx, user.ID, "ref-2"))
+		requireFields(map[string]string{"Reference": "ref-2", "PO": "po-1"})
+
+		// an empty reference removes the field.
+		require.NoError(t, invoices.AddDefaultInvoiceReference(ctx, user.ID, ""))
+		requireFields(map[string]string{"PO": "po-1"})
+
+		_, err = customers.Update(customerID, &stripe.CustomerParams{
+			InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
+				CustomFields: []*stripe.CustomerInvoiceSettingsCustomFieldParams{
+					{Name: stripe.String("PO"), Value: stripe.String("po-1")},
+					{Name: stripe.String("VAT"), Value: stripe.String("vat-1")},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		err = invoices.AddDefaultInvoiceReference(ctx, user.ID, "ref-3")
+		require.True(t, stripe1.ErrTooManyInvoiceCustomFields.Has(err), err)
+		requireFields(map[string]string{"PO": "po-1", "VAT": "vat-1"})
+	})
+}
+
+func TestClearInvoiceCustomFields(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		sat := planet.Satellites[0]
+		accounts := sat.API.Payments.Accounts
+		customers := sat.API.Payments.StripeClient.Customers()
+
+		user, err := sat.AddUser(ctx, console.CreateUser{
+			FullName: "testuser",
+			Email:    "user@test",
+		}, 1)
+		require.NoError(t, err)
+		customerID, err := sat.DB.StripeCoinPayments().Customers().GetCustomerID(ctx, user.ID)
+		require.NoError(t, err)
+
+		// clearing without any custom fields is fine.
+		info, err := accounts.ClearInvoiceCustomFields(ctx, user.ID)
+		require.NoError(t, err)
+		require.NotNil(t, info)
+
+		require.NoError(t, accounts.Invoices().AddDefaultInvoiceReference(ctx, user.ID, "ref-1"))
+		_, err = customers.Update(customerID, &stripe.CustomerParams{
+			InvoiceSettings: &stripe.CustomerInvoiceSettingsParams{
+				CustomFields: []*stripe.CustomerInvoiceSettingsCustomFieldParams{
+					{Name: stripe.String("Reference"), Value: stripe.String("ref-1")},
+					{Name: stripe.String("PO"), Value: stripe.String("po-1")},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		_, err = accounts.ClearInvoiceCustomFields(ctx, user.ID)
+		require.NoError(t, err)
+
+		customer, err := customers.Get(customerID, nil)
+		require.NoError(t, err)
+		require.Empty(t, customer.InvoiceSettings.CustomFields)
+	})
+}
@@ -56,15 +56,18 @@ const (
 
 // Config stores needed information for payment service initialization.
 type Config struct {
-	StripeSecretKey        string `help:"stripe API secret key" default:""`
-	StripePublicKey        string `help:"stripe API public key" default:""`
-	StripeFreeTierCouponID string `help:"stripe free tier coupon ID" default:""`
-	AutoAdvance            bool   `help:"toggle autoadvance feature for invoice creation" default:"false"`
-	ListingLimit           int    `help:"sets the maximum amount of items before we start paging on requests" default:"100" hidden:"true"`
-	SkipEmptyInvoices      bool   `help:"if set, skips the creation of empty invoices for customers with zero usage for the billing period" default:"true"`
-	MaxParallelCalls       int    `help:"the maximum number of concurrent Stripe API calls in invoicing methods" default:"10"`
-	RemoveExpiredCredit    bool   `help:"whether to remove expired package credit or not" default:"true"`
-	UseIdempotency         bool   `help:"whether to use idempotency for create/update requests" default:"false"`
+	StripeSecretKey        string       `help:"stripe API secret key" default:""`
+	StripePublicKey        string       `help:"stripe API public key" default:""`
+	StripeFreeTierCouponID string       `help:"stripe free tier coupon ID" default:""`
+	AutoAdvance            bool         `help:"toggle autoadvance feature for invoice creation" default:"false"`
+	ListingLimit           int          `help:"sets the maximum amount of items before we start paging on requests" default:"100" hidden:"true"`
+	SkipEmptyInvoices      bool         `help:"if set, skips the creation of empty invoices for customers with zero usage for the billing period" default:"true"`
+	MaxParallelCalls       int          `help:"the maximum number of concurrent Stripe API calls in invoicing methods" default:"10"`
+	RemoveExpiredCredit    bool         `help:"whether to remove expired package credit or not" default:"true"`
+	UseIdempotency         bool         `help:"whether to use idempotency for create/update requests" default:"false"`
+	UsagePriceRounding     RoundingMode `help:"how usage prices are rounded to whole cents: half-up, half-even or truncate" default:"half-up"`
+	IncludeZeroUsage       bool         `help:"if set, project charges contain an entry for every partner with a price model, even when it has no usage" default:"false"`
+	MaxInvoiceCustomFields int          `help:"the maximum number of custom fields on customer invoices, it must match the limit of the Stripe account" default:"4"`
 	Retries                RetryConfig
 }
 
@@ -103,14 +106,32 @@ type Service struct {
 	maxParallelCalls     int
 	removeExpiredCredit  bool
 	useIdempotency       bool
+	usagePriceRounding   RoundingMode
+	includeZeroUsage     bool
+	maxCustomFields      int
 	deleteAccountEnabled bool
 	nowFn                func() time.Time
 }
 
 // NewService creates a Service instance.
 func NewService(log *zap.Logger, stripeClient Client, config Config, db DB, walletsDB storjscan.WalletsDB, billingDB billing.TransactionsDB, projectsDB console.Projects, usersDB console.Users, usageDB accounting.ProjectAccounting, usagePrices payments.ProjectUsagePriceModel, usagePriceOverrides map[string]payments.ProjectUsagePriceModel, packagePlans map[string]payments.PackagePlan, bonusRate int64, analyticsService *analytics.Service, emissionService *emission.Service, deleteAccountEnabled bool) (*Service, error) {
-	var partners []string
+	configured := make([]string, 0, len(usagePriceOverrides))
 	for partner := range usagePriceOverrides {
+		configured = append(configured, partner)
+	}
+	sort.Strings(configured)
+
+	// partner names are matched case-insensitively, but the configured names are
+	// returned with the usage.
+	var partners []string
+	normalizedOverrides := make(map[string]payments.ProjectUsagePriceModel, len(usagePriceOverrides))
+	for _, partner := range configured {
+		normalized := payments.NormalizePartnerName(partner)
+		if _, ok := normalizedOverrides[normalized]; ok {
+			log.Warn("ignoring duplicate price override for partner", zap.String("partner", partner))
+			continue
+		}
+		normalizedOverrides[normalized] = usagePriceOverrides[partner]
 		partners = append(partners, partner)
 	}
 
@@ -126,7 +147,7 @@ func NewService(log *zap.Logger, stripeClient Client, config Config, db DB, wall
 		analytics:              analyticsService,
 		emission:               emissionService,
 		usagePrices:            usagePrices,
-		usagePriceOverrides:    usagePriceOverrides,
+		usagePriceOverrides:    normalizedOverrides,
 		packagePlans:           packagePlans,
 		partnerNames:           partners,
 		BonusRate:              bonusRate,
@@ -137,6 +158,9 @@ func NewService(log *zap.Logger, stripeClient Client, config Config, db DB, wall
 		maxParallelCalls:       config.MaxParallelCalls,
 		removeExpiredCredit:    config.RemoveExpiredCredit,
 		useIdempotency:         config.UseIdempotency,
+		usagePriceRounding:     config.UsagePriceRounding,
+		includeZeroUsage:       config.IncludeZeroUsage,
+		maxCustomFields:        config.MaxInvoiceCustomFields,
 		deleteAccountEnabled:   deleteAccountEnabled,
 		nowFn:                  time.Now,
 	}, nil
@@ -749,7 +773,10 @@ func (service *Service) createInvoiceItems(ctx context.Context, billingID *strin
 		billingID = &cusID
 	} else {
 		// create parent invoice
-		invoiceID, err = service.createParentInvoice(ctx, *billingID, cusID, projName, period)
+		invoiceID, err = service.createParentInvoice(ctx, *billingID, cusID, projName, record.ProjectID, period)
+		if err != nil {
+			return false, err
+		}
 	}
 	for _, item := range items {
 		item.Params = stripe.Params{Context: ctx}
@@ -885,6 +912,23 @@ func getIdempotencyKey(projectID uuid.UUID, itemDesc string, period time.Time) s
 	return key
 }
 
+// getInvoiceIdempotencyKey creates unique idempotency key for the invoice of given customer and billing period.
+// The automatic tax state is part of the key, because Stripe rejects a retry with the same key, whose
+// parameters differ, e.g. when automatic tax was toggled between a failed invoicing run and its retry.
+func getInvoiceIdempotencyKey(cusID string, period time.Time, automaticTax bool) string {
+	// Stripe customer IDs are case-sensitive, hence the key must not be lowercased.
+	key := fmt.Sprintf("%s-invoice-%s", cusID, period.Format("2006-01"))
+	if automaticTax {
+		key += "-automatic-tax"
+	}
+	return key
+}
+
+// getParentInvoiceIdempotencyKey creates unique idempotency key for the parent invoice of given project and billing period.
+func getParentInvoiceIdempotencyKey(projectID uuid.UUID, period time.Time) string {
+	return fmt.Sprintf("%s-parent-invoice-%s", projectID, period.Format("2006-01"))
+}
+
 // getExistingInvoiceItems lists 3 existing pending invoice line items for stripe customer.
 func (service *Service) getExistingInvoiceItems(ctx context.Context, cusID string) (map[usage]*stripe.InvoiceItem, error) {
 	existingItemsIter := service.stripeClient.InvoiceItems().List(&stripe.InvoiceItemListParams{
@@ -975,24 +1019,30 @@ func (service *Service) InvoiceItemsFromProjectUsage(projName string, partnerUsa
 			prefix = "All projects"
 		}
 
+		// the unit amounts are chosen such that the line amounts match calculateProjectUsagePrice.
+		mode := service.usagePriceRounding
+
+		storageQuantity := storageMBMonthDecimal(usage.Storage)
 		projectItem := &stripe.InvoiceItemParams{}
 		projectItem.Description = stripe.String(prefix + storageInvoiceItemDesc)
-		projectItem.Quantity = stripe.Int64(storageMBMonthDecimal(usage.Storage).IntPart())
-		storagePrice, _ := priceModel.StorageMBMonthCents.Float64()
+		projectItem.Quantity = stripe.Int64(storageQuantity.IntPart())
+		storagePrice, _ := mode.invoiceLineUnitAmount(storageQuantity, priceModel.StorageMBMonthCents).Float64()
 		projectItem.UnitAmountDecimal = stripe.Float64(storagePrice)
 		result = append(result, projectItem)
 
+		egressQuantity := egressMBDecimal(usage.Egress)
 		projectItem = &stripe.InvoiceItemParams{}
 		projectItem.Description = stripe.String(prefix + egressInvoiceItemDesc)
-		projectItem.Quantity = stripe.Int64(egressMBDecimal(usage.Egress).IntPart())
-		egressPrice, _ := priceModel.EgressMBCents.Float64()
+		projectItem.Quantity = stripe.Int64(egressQuantity.IntPart())
+		egressPrice, _ := mode.invoiceLineUnitAmount(egressQuantity, priceModel.EgressMBCents).Float64()
 		projectItem.UnitAmountDecimal = stripe.Float64(egressPrice)
 		result = append(result, projectItem)
 
+		segmentQuantity := segmentMonthDecimal(usage.SegmentCount)
 		projectItem = &stripe.InvoiceItemParams{}
 		projectItem.Description = stripe.String(prefix + segmentInvoiceItemDesc)
-		projectItem.Quantity = stripe.Int64(segmentMonthDecimal(usage.SegmentCount).IntPart())
-		segmentPrice, _ := priceModel.SegmentMonthCents.Float64()
+		projectItem.Quantity = stripe.Int64(segmentQuantity.IntPart())
+		segmentPrice, _ := mode.invoiceLineUnitAmount(segmentQuantity, priceModel.SegmentMonthCents).Float64()
 		projectItem.UnitAmountDecimal = stripe.Float64(segmentPrice)
 		result = append(result, projectItem)
 	}
@@ -1182,6 +1232,11 @@ func (service *Service) CreateInvoices(ctx context.Context, period time.Time, in
 		return Error.New("allowed for past periods only")
 	}
 
+	automaticTax, err := service.listAutomaticTaxCustomers(ctx)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
 	var nextCursor uuid.UUID
 	var totalDraft, totalScheduled int
 	for {
@@ -1200,7 +1255,7 @@ func (service *Service) CreateInvoices(ctx context.Context, period time.Time, in
 			}
 		}
 
-		scheduled, draft, err := service.createInvoices(ctx, cusPage.Customers, start, includeEmissionInfo)
+		scheduled, draft, err := service.createInvoices(ctx, cusPage.Customers, start, includeEmissionInfo, automaticTax)
 		if err != nil {
 			return Error.Wrap(err)
 		}
@@ -1217,8 +1272,32 @@ func (service *Service) CreateInvoices(ctx context.Context, period time.Time, in
 	return nil
 }
 
+// listAutomaticTaxCustomers returns the IDs of the Stripe customers, which have automatic tax enabled.
+// The customers are listed once per invoicing run, instead of retrieving every customer separately.
+func (service *Service) listAutomaticTaxCustomers(ctx context.Context) (_ map[string]struct{}, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	customers := make(map[string]struct{})
+	iter := service.stripeClient.Customers().List(&stripe.CustomerListParams{
+		ListParams: stripe.ListParams{
+			Context: ctx,
+			Limit:   stripe.Int64(100), // Max limit per request
+		},
+	})
+	for iter.Next() {
+		if customer := iter.Customer(); automaticTaxEnabled(customer) {
+			customers[customer.ID] = struct{}{}
+		}
+	}
+	if err = iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return customers, nil
+}
+
 // createInvoice creates invoice for Stripe customer.
-func (service *Service) createInvoice(ctx context.Context, cusID string, period time.Time, includeEmissionInfo bool) (stripeInvoice *stripe.Invoice, err error) {
+func (service *Service) createInvoice(ctx context.Context, cusID string, period time.Time, includeEmissionInfo, automaticTax bool) (stripeInvoice *stripe.Invoice, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	var footer *string
@@ -1321,16 +1400,22 @@ func (service *Service) createInvoice(ctx context.Context, cusID string, period
 	}
 
 	description := fmt.Sprintf("Storj Cloud Storage for %s %d", period.Month(), period.Year())
-	stripeInvoice, err = service.stripeClient.Invoices().New(
-		&stripe.InvoiceParams{
-			Params:                      stripe.Params{Context: ctx},
-			Customer:                    stripe.String(cusID),
-			AutoAdvance:                 stripe.Bool(service.AutoAdvance),
-			Description:                 stripe.String(description),
-			PendingInvoiceItemsBehavior: stripe.String("include"),
-			Footer:                      footer,
-		},
-	)
+	params := &stripe.InvoiceParams{
+		Params:                      stripe.Params{Context: ctx},
+		Customer:                    stripe.String(cusID),
+		AutoAdvance:                 stripe.Bool(service.AutoAdvance),
+		Description:                 stripe.String(description),
+		PendingInvoiceItemsBehavior: stripe.String("include"),
+		Footer:                      footer,
+	}
+	if automaticTax {
+		params.AutomaticTax = &stripe.InvoiceAutomaticTaxParams{Enabled: stripe.Bool(true)}
+	}
+	if service.useIdempotency {
+		params.SetIdempotencyKey(getInvoiceIdempotencyKey(cusID, period, automaticTax))
+	}
+
+	stripeInvoice, err = service.stripeClient.Invoices().New(params)
 	if err != nil {
 		return nil, err
 	}
@@ -1351,7 +1436,7 @@ func (service *Service) createInvoice(ctx context.Context, cusID string, period
 }
 
 // createInvoices creates invoices for Stripe customers.
-func (service *Service) createInvoices(ctx context.Context, customers []Customer, period time.Time, includeEmissionInfo bool) (scheduled, draft int, err error) {
+func (service *Service) createInvoices(ctx context.Context, customers []Customer, period time.Time, includeEmissionInfo bool, automaticTax map[string]struct{}) (scheduled, draft int, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	limiter := sync2.NewLimiter(service.maxParallelCalls)
@@ -1370,7 +1455,8 @@ func (service *Service) createInvoices(ctx context.Context, customers []Customer
 				return
 			}
 
-			inv, err := service.createInvoice(ctx, cus.ID, period, includeEmissionInfo)
+			_, taxed := automaticTax[cus.ID]
+			inv, err := service.createInvoice(ctx, cus.ID, period, includeEmissionInfo, taxed)
 			if err != nil {
 				mu.Lock()
 				errGrp.Add(err)
@@ -1395,20 +1481,23 @@ func (service *Service) createInvoices(ctx context.Context, customers []Customer
 }
 
 // createParentInvoice creates a parent invoice for the customer.
-func (service *Service) createParentInvoice(ctx context.Context, billingID, cusID, projName string, period time.Time) (invoiceID *string, err error) {
+func (service *Service) createParentInvoice(ctx context.Context, billingID, cusID, projName string, projectID uuid.UUID, period time.Time) (invoiceID *string, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	description := fmt.Sprintf("Storj Cloud Storage for child project %s and period %s %d", projName, period.UTC().Month(), period.UTC().Year())
-	stripeInvoice, err := service.stripeClient.Invoices().New(
-		&stripe.InvoiceParams{
-			Params:                      stripe.Params{Context: ctx},
-			Customer:                    stripe.String(billingID),
-			AutoAdvance:                 stripe.Bool(false),
-			Description:                 stripe.String(description),
-			PendingInvoiceItemsBehavior: stripe.String("exclude"),
-			Metadata:                    map[string]string{"Child Account": cusID},
-		},
-	)
+	params := &stripe.InvoiceParams{
+		Params:                      stripe.Params{Context: ctx},
+		Customer:                    stripe.String(billingID),
+		AutoAdvance:                 stripe.Bool(false),
+		Description:                 stripe.String(description),
+		PendingInvoiceItemsBehavior: stripe.String("exclude"),
+		Metadata:                    map[string]string{"Child Account": cusID},
+	}
+	if service.useIdempotency {
+		params.SetIdempotencyKey(getParentInvoiceIdempotencyKey(projectID, period))
+	}
+
+	stripeInvoice, err := service.stripeClient.Invoices().New(params)
 	if err != nil {
 		return nil, err
 	}
@@ -1923,10 +2012,11 @@ func (price projectUsagePrice) TotalInt64() int64 {
 
 // calculateProjectUsagePrice calculate project usage price.
 func (service *Service) calculateProjectUsagePrice(usage accounting.ProjectUsage, pricing payments.ProjectUsagePriceModel) projectUsagePrice {
+	mode := service.usagePriceRounding
 	return projectUsagePrice{
-		Storage:  pricing.StorageMBMonthCents.Mul(storageMBMonthDecimal(usage.Storage)).Round(0),
-		Egress:   pricing.EgressMBCents.Mul(egressMBDecimal(usage.Egress)).Round(0),
-		Segments: pricing.SegmentMonthCents.Mul(segmentMonthDecimal(usage.SegmentCount)).Round(0),
+		Storage:  decimal.NewFromInt(mode.UsageCents(storageMBMonthDecimal(usage.Storage), pricing.StorageMBMonthCents)),
+		Egress:   decimal.NewFromInt(mode.UsageCents(egressMBDecimal(usage.Egress), pricing.EgressMBCents)),
+		Segments: decimal.NewFromInt(mode.UsageCents(segmentMonthDecimal(usage.SegmentCount), pricing.SegmentMonthCents)),
 	}
 }
 
@@ -567,6 +567,170 @@ func TestService_InvoiceElementsProcessingGrouped(t *testing.T) {
 	})
 }
 
+func TestService_InvoiceItemsIdempotency(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+		Reconfigure: testplanet.Reconfigure{
+			Satellite: func(log *zap.Logger, index int, config *satellite.Config) {
+				config.Payments.StripeCoinPayments.UseIdempotency = true
+			},
+		},
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		satellite := planet.Satellites[0]
+		payments := satellite.API.Payments
+
+		// pick a specific date so that it doesn't fail if it's the last day of the month
+		// keep month + 1 because user needs to be created before calculation
+		period := time.Date(time.Now().Year(), time.Now().Month()+1, 20, 0, 0, 0, 0, time.UTC)
+
+		user, err := satellite.AddUser(ctx, console.CreateUser{
+			FullName: "testuser",
+			Email:    "user@test",
+			PaidTier: true,
+		}, 1)
+		require.NoError(t, err)
+
+		project, err := satellite.AddProject(ctx, user.ID, "testproject")
+		require.NoError(t, err)
+
+		err = satellite.DB.Orders().UpdateBucketBandwidthSettle(ctx, project.ID, []byte("testbucket"),
+			pb.PieceAction_GET, 10*memory.GiB.Int64(), 0, period)
+		require.NoError(t, err)
+
+		payments.StripeService.SetNow(func() time.Time {
+			return time.Date(period.Year(), period.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		})
+		require.NoError(t, payments.StripeService.PrepareInvoiceProjectRecords(ctx, period, false))
+
+		cusID, err := satellite.DB.StripeCoinPayments().Customers().GetCustomerID(ctx, user.ID)
+		require.NoError(t, err)
+
+		countItems := func() (count int) {
+			itr := payments.StripeClient.InvoiceItems().List(&stripe.InvoiceItemListParams{
+				Customer: stripe.String(cusID),
+			})
+			for itr.Next() {
+				count++
+			}
+			require.NoError(t, itr.Err())
+			return count
+		}
+
+		require.NoError(t, payments.StripeService.InvoiceApplyProjectRecords(ctx, period))
+		items := countItems()
+		require.NotZero(t, items)
+
+		// simulate a retry of a run, which created the invoice items,
+		// but failed before consuming the project record.
+		_, err = satellite.DB.Testing().RawDB().ExecContext(ctx,
+			"UPDATE stripecoinpayments_invoice_project_records SET state = 0 WHERE project_id = $1", project.ID[:])
+		require.NoError(t, err)
+
+		require.NoError(t, payments.StripeService.InvoiceApplyProjectRecords(ctx, period))
+		require.Equal(t, items, countItems())
+	})
+}
+
+func TestService_InvoiceIdempotency(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+		Reconfigure: testplanet.Reconfigure{
+			Satellite: func(log *zap.Logger, index int, config *satellite.Config) {
+				config.Payments.StripeCoinPayments.UseIdempotency = true
+			},
+		},
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		satellite := planet.Satellites[0]
+		payments := satellite.API.Payments
+
+		// pick a specific date so that it doesn't fail if it's the last day of the month
+		// keep month + 1 because user needs to be created before calculation
+		period := time.Date(time.Now().Year(), time.Now().Month()+1, 20, 0, 0, 0, 0, time.UTC)
+
+		payments.StripeService.SetNow(func() time.Time {
+			return time.Date(period.Year(), period.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		})
+
+		user, err := satellite.AddUser(ctx, console.CreateUser{
+			FullName: "testuser",
+			Email:    "user@test",
+			PaidTier: true,
+		}, 1)
+		require.NoError(t, err)
+		project, err := satellite.AddProject(ctx, user.ID, "testproject")
+		require.NoError(t, err)
+
+		childUser, err := satellite.AddUser(ctx, console.CreateUser{
+			FullName: "childuser",
+			Email:    "child@test",
+			PaidTier: true,
+		}, 1)
+		require.NoError(t, err)
+		childProject, err := satellite.AddProject(ctx, childUser.ID, "childproject")
+		require.NoError(t, err)
+
+		billingUser, err := satellite.AddUser(ctx, console.CreateUser{
+			FullName: "billinguser",
+			Email:    "billing@test",
+		}, 1)
+		require.NoError(t, err)
+		billingCustomer, err := satellite.DB.StripeCoinPayments().Customers().GetCustomerID(ctx, billingUser.ID)
+		require.NoError(t, err)
+		_, err = satellite.DB.StripeCoinPayments().Customers().UpdateBillingCustomerID(ctx, childUser.ID, &billingCustomer)
+		require.NoError(t, err)
+
+		for _, projectID := range []uuid.UUID{project.ID, childProject.ID} {
+			err = satellite.DB.Orders().UpdateBucketBandwidthSettle(ctx, projectID, []byte("testbucket"),
+				pb.PieceAction_GET, 10*memory.GiB.Int64(), 0, period)
+			require.NoError(t, err)
+		}
+
+		cusID, err := satellite.DB.StripeCoinPayments().Customers().GetCustomerID(ctx, user.ID)
+		require.NoError(t, err)
+
+		countInvoices := func(customerID string) (count int) {
+			itr := payments.StripeClient.Invoices().List(&stripe.InvoiceListParams{
+				Customer: stripe.String(customerID),
+			})
+			for itr.Next() {
+				count++
+			}
+			require.NoError(t, itr.Err())
+			return count
+		}
+
+		require.NoError(t, payments.StripeService.PrepareInvoiceProjectRecords(ctx, period, false))
+		require.NoError(t, payments.StripeService.InvoiceApplyProjectRecords(ctx, period))
+		// the parent invoice of the child project is created together with its items.
+		require.Equal(t, 1, countInvoices(billingCustomer))
+
+		// simulate a retry of a run, which created the parent invoice,
+		// but failed before consuming the project record.
+		_, err = satellite.DB.Testing().RawDB().ExecContext(ctx,
+			"UPDATE stripecoinpayments_invoice_project_records SET state = 0 WHERE project_id = $1", childProject.ID[:])
+		require.NoError(t, err)
+
+		require.NoError(t, payments.StripeService.InvoiceApplyProjectRecords(ctx, period))
+		require.Equal(t, 1, countInvoices(billingCustomer))
+
+		require.NoError(t, payments.StripeService.CreateInvoices(ctx, period, false))
+		require.Equal(t, 1, countInvoices(cusID))
+
+		// simulate a retry of a run, which created the invoice, but failed before
+		// the pending items were attached, so that there's something to invoice.
+		_, err = payments.StripeClient.InvoiceItems().New(&stripe.InvoiceItemParams{
+			Customer:    stripe.String(cusID),
+			Amount:      stripe.Int64(100),
+			Currency:    stripe.String(string(stripe.CurrencyUSD)),
+			Description: stripe.String("retry item"),
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, payments.StripeService.CreateInvoices(ctx, period, false))
+		require.Equal(t, 1, countInvoices(cusID))
+	})
+}
+
 func TestService_InvoiceUserWithManyProjects(t *testing.T) {
 	testplanet.Run(t, testplanet.Config{
 		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
@@ -917,6 +1081,50 @@ func TestService_InvoiceItemsFromProjectUsage(t *testing.T) {
 	})
 }
 
+func TestService_InvoiceItemsMatchUsageCents(t *testing.T) {
+	const hoursPerMonth = 24 * 30
+
+	price := paymentsconfig.ProjectUsagePrice{
+		StorageTB: "4",
+		EgressTB:  "7",
+		Segment:   "0.0000088",
+	}
+	model, err := price.ToModel()
+	require.NoError(t, err)
+
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+		Reconfigure: testplanet.Reconfigure{
+			Satellite: func(log *zap.Logger, index int, config *satellite.Config) {
+				config.Payments.UsagePrice = price
+				config.Payments.StripeCoinPayments.UsagePriceRounding = stripe1.RoundTruncate
+			},
+		},
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		usage := map[string]accounting.ProjectUsage{
+			"": {
+				Storage:      4500 * float64(memory.MB) * hoursPerMonth, // 4500 MB-month
+				Egress:       2500 * memory.MB.Int64(),                  // 2500 MB
+				SegmentCount: 2000 * hoursPerMonth,                      // 2000 segment-month
+			},
+		}
+
+		items := planet.Satellites[0].API.Payments.StripeService.InvoiceItemsFromProjectUsage("my-project", usage, false)
+		require.Len(t, items, 3)
+
+		for i, unitCents := range []decimal.Decimal{model.StorageMBMonthCents, model.EgressMBCents, model.SegmentMonthCents} {
+			quantity := decimal.NewFromInt(*items[i].Quantity)
+			expected := stripe1.RoundTruncate.UsageCents(quantity, unitCents)
+			require.EqualValues(t, 1, expected, *items[i].Description)
+			require.NotEqual(t, expected, stripe1.RoundHalfUp.UsageCents(quantity, unitCents), *items[i].Description)
+
+			// Stripe rounds quantity × unit_amount_decimal half up.
+			invoiced := decimal.NewFromFloat(*items[i].UnitAmountDecimal).Mul(quantity).Round(0).IntPart()
+			require.Equal(t, expected, invoiced, *items[i].Description)
+		}
+	})
+}
+
 func TestService_PayInvoiceFromTokenBalance(t *testing.T) {
 	testplanet.Run(t, testplanet.Config{
 		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
@@ -1548,6 +1756,7 @@ func TestProjectUsagePrice(t *testing.T) {
 			{"default pricing - user agent is not valid partner name", []byte("invalid/v0.0"), defaultModel},
 			{"partner pricing - user agent is partner name", []byte(partnerName), partnerModel},
 			{"partner pricing - user agent prefixed with partner name", []byte(partnerName + " invalid/v0.0"), partnerModel},
+			{"partner pricing - user agent is mixed case partner name", []byte("PaRtNeR/v1.0"), partnerModel},
 		} {
 			t.Run(tt.name, func(t *testing.T) {
 				user, err := sat.AddUser(ctx, console.CreateUser{
@@ -1800,6 +2009,73 @@ func TestRemoveExpiredPackageCredit(t *testing.T) {
 	})
 }
 
+func TestService_CreateInvoicesAutomaticTax(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		satellite := planet.Satellites[0]
+		p := satellite.API.Payments
+
+		// keep month + 1 because users need to be created before the period ends.
+		period := time.Date(time.Now().Year(), time.Now().Month()+1, 20, 0, 0, 0, 0, time.UTC)
+		p.StripeService.SetNow(func() time.Time {
+			return time.Date(period.Year(), period.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		})
+
+		var us payments.TaxCountry
+		for _, country := range payments.TaxCountries {
+			if country.Code == "US" {
+				us = country
+				break
+			}
+		}
+
+		automaticTax := map[string]bool{}
+		for i, enabled := range []bool{false, true} {
+			user, err := satellite.AddUser(ctx, console.CreateUser{
+				FullName: "testuser",
+				Email:    fmt.Sprintf("user%d@test", i),
+				PaidTier: true,
+			}, 1)
+			require.NoError(t, err)
+			cusID, err := satellite.DB.StripeCoinPayments().Customers().GetCustomerID(ctx, user.ID)
+			require.NoError(t, err)
+
+			_, err = p.Accounts.SaveBillingAddress(ctx, user.ID, payments.BillingAddress{
+				Line1:      "Some street",
+				PostalCode: "12345",
+				Country:    us,
+			})
+			require.NoError(t, err)
+			_, err = p.Accounts.SetAutomaticTax(ctx, user.ID, enabled)
+			require.NoError(t, err)
+
+			_, err = p.StripeClient.InvoiceItems().New(&stripe.InvoiceItemParams{
+				Params:   stripe.Params{Context: ctx},
+				Amount:   stripe.Int64(100),
+				Currency: stripe.String(string(stripe.CurrencyUSD)),
+				Customer: &cusID,
+			})
+			require.NoError(t, err)
+			automaticTax[cusID] = enabled
+		}
+
+		require.NoError(t, p.StripeService.CreateInvoices(ctx, period, false))
+
+		itr := p.StripeClient.Invoices().List(&stripe.InvoiceListParams{})
+		var count int
+		for itr.Next() {
+			invoice := itr.Invoice()
+			enabled, ok := automaticTax[invoice.Customer.ID]
+			require.True(t, ok)
+			require.Equal(t, enabled, invoice.AutomaticTax != nil && invoice.AutomaticTax.Enabled)
+			count++
+		}
+		require.NoError(t, itr.Err())
+		require.Equal(t, 2, count)
+	})
+}
+
 func TestService_PayInvoiceBillingID(t *testing.T) {
 	testplanet.Run(t, testplanet.Config{
 		SatelliteCount: 1, StorageNodeCount: 0, UplinkCount: 0,
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/stripe/stripe-go/v75"
 	"github.com/stripe/stripe-go/v75/charge"
 	"github.com/stripe/stripe-go/v75/customer"
@@ -32,6 +33,8 @@ const (
 	MockCouponID2 = "c2"
 	// MockCouponID3 is a coupon that stripe mock is aware of. Applying unknown coupons results in failure.
 	MockCouponID3 = "c3"
+	// MockCouponID4 is a repeating coupon with limited redemptions that stripe mock is aware of.
+	MockCouponID4 = "c4"
 
 	// MockInvoicesNewFailure can be passed to mockInvoices.New as `desc` argument to cause it to return
 	// an error.
@@ -61,7 +64,9 @@ const (
 var (
 	testPromoCodes = map[string]*stripe.PromotionCode{
 		"promo1": {
-			ID: "p1",
+			ID:     "p1",
+			Code:   "promo1",
+			Active: true,
 			Coupon: &stripe.Coupon{
 				AmountOff: 500,
 				Currency:  stripe.CurrencyUSD,
@@ -70,7 +75,9 @@ var (
 			},
 		},
 		"promo2": {
-			ID: "p2",
+			ID:     "p2",
+			Code:   "promo2",
+			Active: true,
 			Coupon: &stripe.Coupon{
 				PercentOff: 50,
 				Name:       "Test Promo Code 2",
@@ -78,7 +85,9 @@ var (
 			},
 		},
 		"promo3": {
-			ID: "p3",
+			ID:     "p3",
+			Code:   "promo3",
+			Active: true,
 			Coupon: &stripe.Coupon{
 				AmountOff: 100,
 				Currency:  stripe.CurrencyUSD,
@@ -95,6 +104,16 @@ var (
 		MockCouponID1: testPromoCodes["promo1"].Coupon,
 		MockCouponID2: testPromoCodes["promo2"].Coupon,
 		MockCouponID3: testPromoCodes["promo3"].Coupon,
+		MockCouponID4: {
+			AmountOff:        1000,
+			Currency:         stripe.CurrencyUSD,
+			Name:             "Test Coupon 4",
+			ID:               MockCouponID4,
+			Duration:         stripe.CouponDurationRepeating,
+			DurationInMonths: 3,
+			MaxRedemptions:   10,
+			TimesRedeemed:    4,
+		},
 	}
 )
 
@@ -326,7 +345,7 @@ func (m *mockCustomers) Get(id string, params *stripe.CustomerParams) (*stripe.C
 		}
 	}
 
-	return nil, errors.New("customer not found")
+	return nil, &stripe.Error{Code: stripe.ErrorCodeResourceMissing, Msg: "customer not found"}
 }
 
 func (m *mockCustomers) Update(id string, params *stripe.CustomerParams) (*stripe.Customer, error) {
@@ -357,17 +376,39 @@ func (m *mockCustomers) Update(id string, params *stripe.CustomerParams) (*strip
 		if !ok {
 			return nil, &stripe.Error{}
 		}
-		customer.Discount = &stripe.Discount{Coupon: &stripe.Coupon{ID: c.ID}}
+		customer.Discount = &stripe.Discount{Coupon: c}
+		if c.Duration == stripe.CouponDurationRepeating {
+			start := time.Now()
+			customer.Discount.Start = start.Unix()
+			customer.Discount.End = start.AddDate(0, int(c.DurationInMonths), 0).Unix()
+		}
 	}
 	if params.Balance != nil {
 		customer.Balance = *params.Balance
 	}
 	if params.InvoiceSettings != nil {
+		if customer.InvoiceSettings == nil {
+			customer.InvoiceSettings = &stripe.CustomerInvoiceSettings{}
+		}
 		if params.InvoiceSettings.DefaultPaymentMethod != nil {
-			customer.InvoiceSettings = &stripe.CustomerInvoiceSettings{
-				DefaultPaymentMethod: &stripe.PaymentMethod{
-					ID: *params.InvoiceSettings.DefaultPaymentMethod,
-				},
+			customer.InvoiceSettings.DefaultPaymentMethod = &stripe.PaymentMethod{
+				ID: *params.InvoiceSettings.DefaultPaymentMethod,
+			}
+		}
+		if params.InvoiceSettings.CustomFields != nil {
+			customer.InvoiceSettings.CustomFields = nil
+			for _, field := range params.InvoiceSettings.CustomFields {
+				customer.InvoiceSettings.CustomFields = append(customer.InvoiceSettings.CustomFields, &stripe.CustomerInvoiceSettingsCustomField{
+					Name:  *field.Name,
+					Value: *field.Value,
+				})
+			}
+		}
+	}
+	if params.Extra != nil {
+		if fields, ok := params.Extra.Values["invoice_settings[custom_fields]"]; ok && len(fields) == 1 && fields[0] == "" {
+			if customer.InvoiceSettings != nil {
+				customer.InvoiceSettings.CustomFields = nil
 			}
 		}
 	}
@@ -546,6 +587,8 @@ type mockInvoices struct {
 
 	invoices     map[string][]*stripe.Invoice
 	invoiceItems *mockInvoiceItems
+	// idempotent contains the invoices created with an idempotency key.
+	idempotent map[string]*stripe.Invoice
 }
 
 func (m *mockInvoices) MarkUncollectible(id string, params *stripe.InvoiceMarkUncollectibleParams) (*stripe.Invoice, error) {
@@ -579,6 +622,7 @@ func newMockInvoices(root *mockStripeState, invoiceItems *mockInvoiceItems) *moc
 		root:         root,
 		invoices:     make(map[string][]*stripe.Invoice),
 		invoiceItems: invoiceItems,
+		idempotent:   make(map[string]*stripe.Invoice),
 	}
 }
 
@@ -586,6 +630,12 @@ func (m *mockInvoices) New(params *stripe.InvoiceParams) (*stripe.Invoice, error
 	m.root.mu.Lock()
 	defer m.root.mu.Unlock()
 
+	if params.IdempotencyKey != nil {
+		if invoice, ok := m.idempotent[*params.IdempotencyKey]; ok {
+			return invoice, nil
+		}
+	}
+
 	var invoiceItems []*stripe.InvoiceItem
 	if params.PendingInvoiceItemsBehavior != nil {
 		switch *params.PendingInvoiceItemsBehavior {
@@ -642,11 +692,17 @@ func (m *mockInvoices) New(params *stripe.InvoiceParams) (*stripe.Invoice, error
 	if params.DefaultPaymentMethod != nil {
 		invoice.DefaultPaymentMethod = &stripe.PaymentMethod{ID: *params.DefaultPaymentMethod}
 	}
+	if params.AutomaticTax != nil && params.AutomaticTax.Enabled != nil {
+		invoice.AutomaticTax = &stripe.InvoiceAutomaticTax{Enabled: *params.AutomaticTax.Enabled}
+	}
 
 	m.invoices[*params.Customer] = append(m.invoices[*params.Customer], invoice)
 	for _, item := range invoiceItems {
 		item.Invoice = invoice
 	}
+	if params.IdempotencyKey != nil {
+		m.idempotent[*params.IdempotencyKey] = invoice
+	}
 
 	return invoice, nil
 }
@@ -785,12 +841,15 @@ func (m *mockInvoices) Get(id string, params *stripe.InvoiceParams) (*stripe.Inv
 type mockInvoiceItems struct {
 	root  *mockStripeState
 	items map[string][]*stripe.InvoiceItem
+	// idempotent contains the invoice items created with an idempotency key.
+	idempotent map[string]*stripe.InvoiceItem
 }
 
 func newMockInvoiceItems(root *mockStripeState) *mockInvoiceItems {
 	return &mockInvoiceItems{
-		root:  root,
-		items: make(map[string][]*stripe.InvoiceItem),
+		root:       root,
+		items:      make(map[string][]*stripe.InvoiceItem),
+		idempotent: make(map[string]*stripe.InvoiceItem),
 	}
 }
 
@@ -810,6 +869,12 @@ func (m *mockInvoiceItems) New(params *stripe.InvoiceItemParams) (*stripe.Invoic
 		return nil, &stripe.Error{Code: stripe.ErrorCodeParameterMissing}
 	}
 
+	if params.IdempotencyKey != nil {
+		if item, ok := m.idempotent[*params.IdempotencyKey]; ok {
+			return item, nil
+		}
+	}
+
 	item := &stripe.InvoiceItem{
 		ID:       "ii_" + string(testrand.RandAlphaNumeric(25)),
 		Metadata: params.Metadata,
@@ -829,7 +894,8 @@ func (m *mockInvoiceItems) New(params *stripe.InvoiceItemParams) (*stripe.Invoic
 			item.Amount = item.UnitAmount * item.Quantity
 		} else if params.UnitAmountDecimal != nil {
 			item.UnitAmountDecimal = *params.UnitAmountDecimal
-			item.Amount = int64(*params.UnitAmountDecimal * float64(item.Quantity))
+			// Stripe rounds the amount half up to whole cents.
+			item.Amount = decimal.NewFromFloat(*params.UnitAmountDecimal).Mul(decimal.NewFromInt(item.Quantity)).Round(0).IntPart()
 		} else {
 			return nil, &stripe.Error{Code: stripe.ErrorCodeParameterMissing}
 		}
@@ -871,6 +937,9 @@ func (m *mockInvoiceItems) New(params *stripe.InvoiceItemParams) (*stripe.Invoic
 		}
 	}
 	m.items[*params.Customer] = append(m.items[*params.Customer], item)
+	if params.IdempotencyKey != nil {
+		m.idempotent[*params.IdempotencyKey] = item
+	}
 
 	return item, nil
 }
@@ -23,6 +23,7 @@ import (
 	satbuckets "storj.io/storj/satellite/buckets"
 	"storj.io/storj/satellite/metabase"
 	"storj.io/storj/satellite/orders"
+	"storj.io/storj/satellite/payments"
 	"storj.io/storj/satellite/satellitedb/dbx"
 	"storj.io/storj/shared/dbutil"
 	"storj.io/storj/shared/dbutil/pgutil"
@@ -688,8 +689,9 @@ func tryFindPartnerByUserAgent(userAgent []byte, partnerNames []string) (string,
 
 	var partner string
 	if len(entries) != 0 {
+		product := payments.NormalizePartnerName(entries[0].Product)
 		for _, iterPartner := range partnerNames {
-			if entries[0].Product == iterPartner {
+			if product == payments.NormalizePartnerName(iterPartner) {
 				partner = iterPartner
 				break
 			}
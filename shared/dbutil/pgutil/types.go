@@ -132,6 +132,21 @@ func DateArray(timeSlice []time.Time) *pgtype.DateArray {
 	}
 }
 
+// BoolArray returns an object usable by pg drivers for passing a []bool slice
+// into a database as type BOOL[].
+func BoolArray(bools []bool) *pgtype.BoolArray {
+	pgtypeBoolArray := make([]pgtype.Bool, len(bools))
+	for i, someBool := range bools {
+		pgtypeBoolArray[i].Bool = someBool
+		pgtypeBoolArray[i].Status = pgtype.Present
+	}
+	return &pgtype.BoolArray{
+		Elements:   pgtypeBoolArray,
+		Dimensions: []pgtype.ArrayDimension{{Length: int32(len(bools)), LowerBound: 1}},
+		Status:     pgtype.Present,
+	}
+}
+
 // Int2Array returns an object usable by pg drivers for passing a []int16 slice
 // into a database as type INT2[].
 func Int2Array(ints []int16) *pgtype.Int2Array {